@@ -65,6 +65,7 @@ var (
 
 	// kvstore
 	flagPersist string
+	flagLanes   bool
 )
 
 func RootCmmand(logger log.Logger) *cobra.Command {
@@ -272,6 +273,11 @@ func getKVStoreCmd(logger log.Logger) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVarP(&flagPersist, "persist", "", "", "directory to use for a database")
+	cmd.PersistentFlags().BoolVarP(&flagLanes,
+		"lanes",
+		"",
+		false,
+		"serve the socket requests of different client lanes concurrently")
 	return cmd
 
 }
@@ -631,7 +637,11 @@ func makeKVStoreCmd(logger log.Logger) func(*cobra.Command, []string) error {
 		}
 
 		// Start the listener
-		srv, err := server.NewServer(logger.With("module", "abci-server"), flagAddress, flagAbci, app)
+		var options []server.SocketServerOption
+		if flagLanes {
+			options = append(options, server.SocketServerWithLanes())
+		}
+		srv, err := server.NewServer(logger.With("module", "abci-server"), flagAddress, flagAbci, app, options...)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,314 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+
+	auto "github.com/bhojpur/state/internal/libs/autofile"
+	"github.com/bhojpur/state/internal/proxy"
+	abciclient "github.com/bhojpur/state/pkg/abci/client"
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+// MakeABCICommand constructs a command to debug the ABCI application.
+func MakeABCICommand(conf *config.Config, logger log.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "abci",
+		Short: "debug the ABCI application",
+	}
+	cmd.AddCommand(makeABCIReplayCommand(conf, logger))
+	return cmd
+}
+
+func makeABCIReplayCommand(conf *config.Config, logger log.Logger) *cobra.Command {
+	var (
+		proxyApp  string
+		transport string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay [record-file]",
+		Short: "replay recorded ABCI calls against a fresh application",
+		Long: `
+replay sends the ABCI calls recorded by a node with abci-record-file set, in
+order, to a fresh application, and compares its responses with the recorded
+ones. It stops at the first divergence in the app hash, the transaction
+results or the validator updates, and reports the call and height at which it
+occurred. The application must start from an empty state, as the node did when
+recording began.
+
+The record file defaults to abci-record-file in the config file.
+`,
+		Example: `
+	statectl abci replay --proxy-app tcp://127.0.0.1:26658 data/abci.rec
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := conf.ABCIRecordFilePath()
+			if len(args) > 0 {
+				path = args[0]
+			}
+			if path == "" {
+				return errors.New("no record file given, and abci-record-file is not set")
+			}
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			client, closer, err := proxy.ClientFactory(logger, proxyApp, transport, conf.DBDir())
+			if err != nil {
+				return err
+			}
+			defer closer.Close()
+			if err := client.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start ABCI client: %w", err)
+			}
+
+			return replayABCIRecords(ctx, logger, client, path, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&proxyApp, "proxy-app", conf.ProxyApp,
+		"address of the application to replay against, or the name of a built-in application")
+	cmd.Flags().StringVar(&transport, "abci", conf.ABCI, "mechanism to connect to the application: socket | grpc")
+	return cmd
+}
+
+// replayABCIRecords replays the calls recorded in the autofile group at path
+// against client, and returns an error describing the first divergence.
+func replayABCIRecords(
+	ctx context.Context,
+	logger log.Logger,
+	client abciclient.Client,
+	path string,
+	out io.Writer,
+) error {
+	group, err := auto.OpenGroup(ctx, logger, path, auto.GroupTotalSizeLimit(0))
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+	rd, err := group.NewReader(group.MinIndex())
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	var (
+		dec    = abciclient.NewRecordDecoder(rd)
+		height int64
+		n      int
+	)
+	for i := 0; ; i++ {
+		rec, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			logger.Info("ignoring truncated record at the end of the recording", "record", i)
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read record %d: %w", i, err)
+		}
+
+		if req, ok := rec.Request.Value.(*abci.Request_FinalizeBlock); ok {
+			height = req.FinalizeBlock.Height
+		}
+		res, ok, err := replayABCIRequest(ctx, client, rec.Request)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		} else if !ok {
+			continue
+		}
+		n++
+
+		if diff := diffABCIResponses(rec.Response, res); diff != "" {
+			fmt.Fprintf(out, "divergence at record %d (%s, height %d): %s\n",
+				i, abciMethod(rec.Request), height, diff)
+			return fmt.Errorf("replayed responses diverge at height %d", height)
+		}
+	}
+
+	fmt.Fprintf(out, "replayed %d ABCI calls up to height %d without divergence\n", n, height)
+	return nil
+}
+
+// replayABCIRequest sends req to client. It returns false for requests that
+// are not worth replaying.
+func replayABCIRequest(ctx context.Context, client abciclient.Client, req *abci.Request) (*abci.Response, bool, error) {
+	var (
+		res *abci.Response
+		err error
+	)
+	switch r := req.Value.(type) {
+	case *abci.Request_Echo, *abci.Request_Flush:
+		return nil, false, nil
+	case *abci.Request_Info:
+		var v *abci.ResponseInfo
+		v, err = client.Info(ctx, r.Info)
+		res = abci.ToResponseInfo(v)
+	case *abci.Request_Query:
+		var v *abci.ResponseQuery
+		v, err = client.Query(ctx, r.Query)
+		res = abci.ToResponseQuery(v)
+	case *abci.Request_CheckTx:
+		var v *abci.ResponseCheckTx
+		v, err = client.CheckTx(ctx, r.CheckTx)
+		res = abci.ToResponseCheckTx(v)
+	case *abci.Request_InitChain:
+		var v *abci.ResponseInitChain
+		v, err = client.InitChain(ctx, r.InitChain)
+		res = abci.ToResponseInitChain(v)
+	case *abci.Request_PrepareProposal:
+		var v *abci.ResponsePrepareProposal
+		v, err = client.PrepareProposal(ctx, r.PrepareProposal)
+		res = abci.ToResponsePrepareProposal(v)
+	case *abci.Request_ProcessProposal:
+		var v *abci.ResponseProcessProposal
+		v, err = client.ProcessProposal(ctx, r.ProcessProposal)
+		res = abci.ToResponseProcessProposal(v)
+	case *abci.Request_ExtendVote:
+		var v *abci.ResponseExtendVote
+		v, err = client.ExtendVote(ctx, r.ExtendVote)
+		res = abci.ToResponseExtendVote(v)
+	case *abci.Request_VerifyVoteExtension:
+		var v *abci.ResponseVerifyVoteExtension
+		v, err = client.VerifyVoteExtension(ctx, r.VerifyVoteExtension)
+		res = abci.ToResponseVerifyVoteExtension(v)
+	case *abci.Request_FinalizeBlock:
+		var v *abci.ResponseFinalizeBlock
+		v, err = client.FinalizeBlock(ctx, r.FinalizeBlock)
+		res = abci.ToResponseFinalizeBlock(v)
+	case *abci.Request_Commit:
+		var v *abci.ResponseCommit
+		v, err = client.Commit(ctx)
+		res = abci.ToResponseCommit(v)
+	case *abci.Request_ListSnapshots:
+		var v *abci.ResponseListSnapshots
+		v, err = client.ListSnapshots(ctx, r.ListSnapshots)
+		res = abci.ToResponseListSnapshots(v)
+	case *abci.Request_OfferSnapshot:
+		var v *abci.ResponseOfferSnapshot
+		v, err = client.OfferSnapshot(ctx, r.OfferSnapshot)
+		res = abci.ToResponseOfferSnapshot(v)
+	case *abci.Request_LoadSnapshotChunk:
+		var v *abci.ResponseLoadSnapshotChunk
+		v, err = client.LoadSnapshotChunk(ctx, r.LoadSnapshotChunk)
+		res = abci.ToResponseLoadSnapshotChunk(v)
+	case *abci.Request_ApplySnapshotChunk:
+		var v *abci.ResponseApplySnapshotChunk
+		v, err = client.ApplySnapshotChunk(ctx, r.ApplySnapshotChunk)
+		res = abci.ToResponseApplySnapshotChunk(v)
+	default:
+		return nil, false, fmt.Errorf("unknown request %T", req.Value)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		res = abci.ToResponseException(err.Error())
+	}
+	return res, true, nil
+}
+
+// diffABCIResponses describes the first consensus-relevant difference
+// between a recorded and a replayed response, or returns "" if there is none.
+func diffABCIResponses(recorded, replayed *abci.Response) string {
+	recEx, repEx := recorded.GetException(), replayed.GetException()
+	switch {
+	case recEx != nil && repEx == nil:
+		return fmt.Sprintf("recorded error %q, replayed call succeeded", recEx.Error)
+	case recEx == nil && repEx != nil:
+		return fmt.Sprintf("recorded call succeeded, replayed error %q", repEx.Error)
+	case recEx != nil:
+		return ""
+	}
+
+	switch rec := recorded.Value.(type) {
+	case *abci.Response_InitChain:
+		rep := replayed.GetInitChain()
+		if !bytes.Equal(rec.InitChain.AppHash, rep.GetAppHash()) {
+			return fmt.Sprintf("app hash: recorded %X, replayed %X", rec.InitChain.AppHash, rep.GetAppHash())
+		}
+		return diffValidatorUpdates(rec.InitChain.Validators, rep.GetValidators())
+
+	case *abci.Response_FinalizeBlock:
+		rep := replayed.GetFinalizeBlock()
+		if !bytes.Equal(rec.FinalizeBlock.AppHash, rep.GetAppHash()) {
+			return fmt.Sprintf("app hash: recorded %X, replayed %X", rec.FinalizeBlock.AppHash, rep.GetAppHash())
+		}
+		if diff := diffTxResults(rec.FinalizeBlock.TxResults, rep.GetTxResults()); diff != "" {
+			return diff
+		}
+		return diffValidatorUpdates(rec.FinalizeBlock.ValidatorUpdates, rep.GetValidatorUpdates())
+	}
+	return ""
+}
+
+func diffTxResults(recorded, replayed []*abci.ExecTxResult) string {
+	if len(recorded) != len(replayed) {
+		return fmt.Sprintf("tx results: recorded %d, replayed %d", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		rec, rep := recorded[i], replayed[i]
+		switch {
+		case rec.Code != rep.Code:
+			return fmt.Sprintf("tx result %d: code: recorded %d, replayed %d", i, rec.Code, rep.Code)
+		case !bytes.Equal(rec.Data, rep.Data):
+			return fmt.Sprintf("tx result %d: data: recorded %X, replayed %X", i, rec.Data, rep.Data)
+		case rec.GasWanted != rep.GasWanted:
+			return fmt.Sprintf("tx result %d: gas wanted: recorded %d, replayed %d", i, rec.GasWanted, rep.GasWanted)
+		case rec.GasUsed != rep.GasUsed:
+			return fmt.Sprintf("tx result %d: gas used: recorded %d, replayed %d", i, rec.GasUsed, rep.GasUsed)
+		}
+	}
+	return ""
+}
+
+func diffValidatorUpdates(recorded, replayed []*abci.ValidatorUpdate) string {
+	if len(recorded) != len(replayed) {
+		return fmt.Sprintf("validator updates: recorded %d, replayed %d", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		if !proto.Equal(recorded[i], replayed[i]) {
+			return fmt.Sprintf("validator update %d: recorded %v, replayed %v", i, recorded[i], replayed[i])
+		}
+	}
+	return ""
+}
+
+// abciMethod returns the name of the method req calls, e.g. "FinalizeBlock".
+func abciMethod(req *abci.Request) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", req.Value), "*types.Request_")
+}
@@ -0,0 +1,209 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bhojpur/state/internal/blocksync"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// MakeArchiveCommand constructs a command to export and import block archives.
+func MakeArchiveCommand(conf *config.Config, logger log.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "export and import block archives used to bootstrap block sync",
+		Long: `
+A block archive is a directory of chunk files holding blocks together with their
+commits, and a manifest recording the height range and a hash of every chunk.
+Archives are exported from the block store of an existing node and imported into
+the block sync archive directory of a new node (see [blocksync] archive-dir).
+On start, block sync replays the archive, verifying every commit against the
+validator sets in the state store, before it continues syncing from peers.
+`,
+	}
+	cmd.AddCommand(
+		makeArchiveExportCommand(conf, logger),
+		makeArchiveImportCommand(conf, logger),
+	)
+	return cmd
+}
+
+func makeArchiveExportCommand(conf *config.Config, logger log.Logger) *cobra.Command {
+	var (
+		startHeight int64
+		endHeight   int64
+		chunkSize   int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [output-dir]",
+		Short: "export blocks and commits from the block store into an archive",
+		Example: `
+	statectl archive export /backups/archive
+	statectl archive export /backups/archive --start-height 2 --end-height 10000
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			genDoc, err := types.GenesisDocFromFile(conf.GenesisFile())
+			if err != nil {
+				return fmt.Errorf("failed to load genesis: %w", err)
+			}
+
+			bs, ss, err := loadStateAndBlockStore(conf)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = bs.Close()
+				_ = ss.Close()
+			}()
+
+			manifest, err := blocksync.ExportArchive(bs, genDoc.ChainID, args[0], startHeight, endHeight, chunkSize)
+			if err != nil {
+				return fmt.Errorf("failed to export archive: %w", err)
+			}
+
+			logger.Info("exported block archive",
+				"dir", args[0],
+				"start_height", manifest.StartHeight,
+				"end_height", manifest.EndHeight,
+				"chunks", len(manifest.Chunks))
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&startHeight, "start-height", 0, "the first height to export (default: base height of the block store)")
+	cmd.Flags().Int64Var(&endHeight, "end-height", 0, "the last height to export (default: latest height with a commit)")
+	cmd.Flags().Int64Var(&chunkSize, "chunk-size", blocksync.DefaultArchiveChunkSize, "the number of blocks per chunk file")
+	return cmd
+}
+
+func makeArchiveImportCommand(conf *config.Config, logger log.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import [archive-dir]",
+		Short: "verify an archive and install it for block sync",
+		Long: `
+import checks that the archive belongs to this node's chain, that every chunk
+matches the hash in its manifest and that every block links to its predecessor
+and commit. It then copies the archive into the block sync archive directory.
+Commit signatures are verified by block sync when the archive is replayed.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			genDoc, err := types.GenesisDocFromFile(conf.GenesisFile())
+			if err != nil {
+				return fmt.Errorf("failed to load genesis: %w", err)
+			}
+
+			manifest, err := verifyArchive(args[0], genDoc.ChainID)
+			if err != nil {
+				return fmt.Errorf("invalid archive: %w", err)
+			}
+
+			dst := conf.BlockSync.ArchivePath()
+			if err := copyArchive(args[0], dst, manifest); err != nil {
+				return fmt.Errorf("failed to import archive: %w", err)
+			}
+
+			logger.Info("imported block archive",
+				"dir", dst,
+				"start_height", manifest.StartHeight,
+				"end_height", manifest.EndHeight)
+			return nil
+		},
+	}
+}
+
+func verifyArchive(dir, chainID string) (*blocksync.ArchiveManifest, error) {
+	manifest, err := blocksync.LoadArchiveManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.ChainID != chainID {
+		return nil, fmt.Errorf("archive is for chain %q, expected %q", manifest.ChainID, chainID)
+	}
+
+	reader, err := blocksync.NewArchiveReader(dir, manifest.StartHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for {
+		if _, _, err := reader.Next(); errors.Is(err, io.EOF) {
+			return manifest, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func copyArchive(src, dst string, manifest *blocksync.ArchiveManifest) error {
+	if _, err := os.Stat(filepath.Join(dst, blocksync.ArchiveManifestFile)); err == nil {
+		return fmt.Errorf("an archive is already installed in %s", dst)
+	}
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+
+	// The manifest is copied last, so an interrupted import leaves no archive
+	// behind that block sync would try to use.
+	files := make([]string, 0, len(manifest.Chunks)+1)
+	for _, c := range manifest.Chunks {
+		files = append(files, c.File)
+	}
+	files = append(files, blocksync.ArchiveManifestFile)
+
+	for _, name := range files {
+		if err := copyFile(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
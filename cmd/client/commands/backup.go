@@ -0,0 +1,134 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bhojpur/state/pkg/config"
+	dbm "github.com/bhojpur/state/pkg/database"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	rpcclient "github.com/bhojpur/state/pkg/rpc/jsonrpc/client"
+)
+
+// MakeBackupCommand constructs a command to back up the node databases. The
+// databases of a stopped node are opened through dbProvider.
+func MakeBackupCommand(conf *config.Config, logger log.Logger, dbProvider config.DBProvider) *cobra.Command {
+	var nodeAddr string
+
+	cmd := &cobra.Command{
+		Use:   "backup [dir]",
+		Short: "write a consistent copy of the node databases to a directory",
+		Long: `
+backup writes a consistent copy of every node database (blockstore, state,
+evidence, peerstore and tx_index) into the given directory, which can then be
+used as the db-dir of a node. Backends that support it, such as rocksdb,
+hard-link their immutable files into the backup rather than copying them, so
+the directory should be on the same filesystem as the databases.
+
+With --node, a running node writes the backup through its "/unsafe_backup" RPC
+endpoint, which requires rpc.unsafe to be enabled. The directory then refers
+to the host of the node. Otherwise the node must be stopped, and the databases
+are opened directly.
+`,
+		Example: `
+	statectl backup /var/backups/state-20260101
+	statectl backup --node tcp://127.0.0.1:26657 /var/backups/state-20260101
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if nodeAddr != "" {
+				return backupRunningNode(cmd.Context(), logger, nodeAddr, args[0])
+			}
+			return backupDBs(cmd.Context(), logger, dbProvider, conf, args[0])
+		},
+	}
+
+	// allow the database to be overridden via cli
+	addDBFlags(cmd, conf)
+	cmd.Flags().StringVar(&nodeAddr, "node", "",
+		"RPC address of a running node to back up, instead of opening the databases directly")
+	return cmd
+}
+
+// backupRunningNode asks the node at addr to back up its databases into dir.
+func backupRunningNode(ctx context.Context, logger log.Logger, addr, dir string) error {
+	client, err := rpcclient.New(addr)
+	if err != nil {
+		return err
+	}
+	var res coretypes.ResultUnsafeBackup
+	if err := client.Call(ctx, "unsafe_backup", map[string]interface{}{"dir": dir}, &res); err != nil {
+		return err
+	}
+	logger.Info("completed backup successfully", "dir", res.Dir, "databases", res.Databases)
+	return nil
+}
+
+// backupDBs checkpoints the node databases of conf into dir.
+func backupDBs(
+	ctx context.Context,
+	logger log.Logger,
+	dbProvider config.DBProvider,
+	conf *config.Config,
+	dir string,
+) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// Every database is opened before the first checkpoint and closed after
+	// the last one. Databases stored as column families of one RocksDB
+	// instance share it only while they are open, and the instance must be
+	// checkpointed once for all of them.
+	dbs := make([]dbm.DB, 0, len(nodeDBContexts))
+	defer func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}()
+	for _, id := range nodeDBContexts {
+		db, err := dbProvider(&config.DBContext{ID: id, Config: conf})
+		if err != nil {
+			return fmt.Errorf("opening %q: %w", id, err)
+		}
+		dbs = append(dbs, db)
+	}
+
+	checkpoints := dbm.NewCheckpointSet(dir)
+	for i, id := range nodeDBContexts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := checkpoints.Checkpoint(dbs[i]); err != nil {
+			return fmt.Errorf("backing up %q: %w", id, err)
+		}
+		logger.Info("backed up database", "dbctx", id)
+	}
+
+	logger.Info("completed backup successfully", "dir", dir)
+	return nil
+}
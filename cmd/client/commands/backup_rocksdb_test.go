@@ -0,0 +1,68 @@
+//go:build rocksdb
+// +build rocksdb
+
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/bhojpur/state/pkg/config"
+	dbm "github.com/bhojpur/state/pkg/database"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+func Test_BackupDBsColumnFamilies(t *testing.T) {
+	ctx := context.Background()
+
+	conf := cfg.TestConfig()
+	conf.SetRoot(t.TempDir())
+	conf.DBBackend = string(dbm.RocksDBBackend)
+	conf.RocksDB.ColumnFamilies = true
+
+	for _, id := range nodeDBContexts {
+		db, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: id, Config: conf})
+		require.NoError(t, err)
+		require.NoError(t, db.Set([]byte("key"), []byte(id)))
+		require.NoError(t, db.Close())
+	}
+
+	// the databases share one instance, which is checkpointed once
+	dir := filepath.Join(t.TempDir(), "backup")
+	require.NoError(t, backupDBs(ctx, log.NewNopLogger(), cfg.DefaultDBProvider, conf, dir))
+	require.DirExists(t, filepath.Join(dir, dbm.RocksDBSharedName+".db"))
+
+	backup := *conf
+	backup.DBPath = dir
+	for _, id := range nodeDBContexts {
+		db, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: id, Config: &backup})
+		require.NoError(t, err)
+		value, err := db.Get([]byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte(id), value)
+		require.NoError(t, db.Close())
+	}
+}
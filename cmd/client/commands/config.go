@@ -0,0 +1,389 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/creachadair/atomicfile"
+	"github.com/creachadair/tomledit"
+	"github.com/creachadair/tomledit/transform"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/bhojpur/state/internal/confix"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/cli"
+)
+
+// MakeConfigCommand constructs a command to migrate, check and edit the
+// config file of the node.
+func MakeConfigCommand() *cobra.Command {
+	var path string
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "migrate, check and edit the config file",
+		Long: `
+The config commands operate on the config file of the node, config/config.toml
+in the home directory, or the file given by --file. They check keys against the
+config file layout of this version, and suggest the intended key for unknown
+or misspelled ones. Files written by an older version are updated to the
+current layout with "config migrate".
+`,
+		// The config file may be outdated or invalid, which these commands
+		// are meant to fix, so unlike other commands they don't load it.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if path != "" {
+				return nil
+			}
+			if err := viper.BindPFlags(cmd.Flags()); err != nil {
+				return err
+			}
+			path = config.ConfigFilePath(viper.GetString(cli.HomeFlag))
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVar(&path, "file", "", "config file to operate on (default config/config.toml in the home directory)")
+
+	cmd.AddCommand(
+		makeConfigMigrateCommand(&path),
+		makeConfigDiffCommand(&path),
+		makeConfigValidateCommand(&path),
+		makeConfigGetCommand(&path),
+		makeConfigSetCommand(&path),
+	)
+	return cmd
+}
+
+func makeConfigMigrateCommand(path *string) *cobra.Command {
+	var (
+		write   bool
+		outPath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "update a config file written by an older version to the current layout",
+		Long: `
+migrate renames, moves and removes the settings of a config file written by an
+older version of Bhojpur State, and adds new settings, so that it has the
+layout of the current version. It logs each migration step, and prints the
+keys that were added or removed.
+
+By default no file is written. With --write, the file is replaced atomically
+with the updated one, and with --out, the updated file is written to another
+path instead.
+`,
+		Example: `
+	statectl config migrate
+	statectl config migrate --write
+	statectl config migrate --file old/config.toml --out new/config.toml
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(*path)
+			if err != nil {
+				return err
+			}
+			before, err := tomledit.Parse(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("parsing %s: %w", *path, err)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Migrating %s from the %s layout\n", *path, confix.GuessConfigVersion(before))
+			ctx := transform.WithLogWriter(cmd.Context(), cmd.ErrOrStderr())
+			out, err := confix.Migrate(ctx, data)
+			if err != nil {
+				return err
+			}
+			after, err := tomledit.Parse(bytes.NewReader(out))
+			if err != nil {
+				return err
+			}
+			confix.DiffKeys(cmd.OutOrStdout(), before, after)
+
+			switch {
+			case bytes.Equal(data, out):
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is up to date\n", *path)
+			case outPath != "":
+				if err := writeConfigFile(outPath, *path, out); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote the updated config to %s\n", outPath)
+			case write:
+				if err := writeConfigFile(*path, *path, out); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "updated %s\n", *path)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "dry run: rerun with --write to update %s\n", *path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&write, "write", false, "replace the config file with the updated one")
+	cmd.Flags().StringVar(&outPath, "out", "", "write the updated config file to this path")
+	return cmd
+}
+
+func makeConfigDiffCommand(path *string) *cobra.Command {
+	var desnake bool
+
+	cmd := &cobra.Command{
+		Use:   "diff [other-file]",
+		Short: "compare the keys of the config file with another file or the defaults",
+		Long: `
+diff prints one line per key that differs between the config file and the
+other file, or the default config file of this version if none is given:
+
+   -S name    -- section exists in the config file but not the other
+   +S name    -- section exists in the other file but not the config file
+   -M name    -- mapping exists in the config file but not the other
+   +M name    -- mapping exists in the other file but not the config file
+
+Comments, order, and values are ignored for comparison purposes.
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lhs, err := confix.LoadConfig(*path)
+			if err != nil {
+				return err
+			}
+			var rhs *tomledit.Document
+			if len(args) > 0 {
+				rhs, err = confix.LoadConfig(args[0])
+			} else {
+				rhs, err = confix.DefaultConfig()
+			}
+			if err != nil {
+				return err
+			}
+
+			if desnake {
+				fix := transform.SnakeToKebab()
+				_ = fix(cmd.Context(), lhs)
+				_ = fix(cmd.Context(), rhs)
+			}
+			confix.DiffKeys(cmd.OutOrStdout(), lhs, rhs)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&desnake, "desnake", false, "convert snake_case to kebab-case before comparing")
+	return cmd
+}
+
+func makeConfigValidateCommand(path *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "check the config file for unknown keys and invalid values",
+		Long: `
+validate reports the keys and tables of the config file that are not part of
+the config file layout of this version, with the keys they may be a
+misspelling of, and checks that the node accepts its values.
+
+A file written by an older version is checked as "config migrate" would
+update it, without writing it, so only keys unknown to both layouts are
+reported. Files older than the v0.34 layout, which migrate does not support,
+can't be validated.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(*path)
+			if err != nil {
+				return err
+			}
+			return validateConfig(cmd.Context(), cmd.OutOrStdout(), *path, data)
+		},
+	}
+}
+
+// validateConfig checks the config file data, read from path, against the
+// schema of the current version, and writes the problems it finds to w.
+func validateConfig(ctx context.Context, w io.Writer, path string, data []byte) error {
+	doc, err := tomledit.Parse(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if version := confix.GuessConfigVersion(doc); version != confix.CurrentVersion && version != "" {
+		fmt.Fprintf(w, "%s has the layout of Bhojpur State %s; checking it as migrated to the current layout\n", path, version)
+	}
+
+	schema, err := confix.CurrentSchema()
+	if err != nil {
+		return err
+	}
+	problems, migrated, err := confix.CheckConfig(ctx, schema, data)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", path, err)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+	if err := confix.CheckValid(migrated); err != nil {
+		fmt.Fprintf(w, "invalid config: %v\n", err)
+		return fmt.Errorf("%s is invalid", path)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s has %d unknown keys", path, len(problems))
+	}
+	fmt.Fprintf(w, "%s is valid\n", path)
+	return nil
+}
+
+func makeConfigGetCommand(path *string) *cobra.Command {
+	var explain bool
+
+	cmd := &cobra.Command{
+		Use:   "get [key]",
+		Short: "print the value of a setting of the config file",
+		Long: `
+get prints the value of a setting in TOML syntax, or its default value if the
+config file does not set it. With --explain, it also prints the description of
+the setting and its default value.
+`,
+		Example: `
+	statectl config get mempool.size
+	statectl config get --explain p2p.max-connections
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := confix.CurrentSchema()
+			if err != nil {
+				return err
+			}
+			doc, err := confix.LoadConfig(*path)
+			if err != nil {
+				return err
+			}
+			setting, value, set, err := schema.Get(doc, args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if !explain {
+				fmt.Fprintln(out, value)
+				return nil
+			}
+			if setting.Doc != "" {
+				fmt.Fprintln(out, setting.Doc)
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "default: %s\n", setting.Default)
+			if set {
+				fmt.Fprintf(out, "value:   %s\n", value)
+			} else {
+				fmt.Fprintln(out, "value:   not set, the default applies")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&explain, "explain", false, "print the description and default value of the setting")
+	return cmd
+}
+
+func makeConfigSetCommand(path *string) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "change a setting of the config file",
+		Long: `
+set changes the value of a setting, which is given in TOML syntax, except that
+the quotes of string values may be omitted. A setting the config file does not
+have yet is added with its description. The file is only replaced, atomically,
+if the node accepts the updated file.
+`,
+		Example: `
+	statectl config set mempool.size 10000
+	statectl config set moniker my-node
+	statectl config set p2p.persistent-peers '"id@host:26656"'
+	`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setConfigValue(cmd.OutOrStdout(), *path, args[0], args[1], dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "check the change, but do not write the config file")
+	return cmd
+}
+
+// setConfigValue sets key to value in the config file at path.
+func setConfigValue(w io.Writer, path, key, value string, dryRun bool) error {
+	schema, err := confix.CurrentSchema()
+	if err != nil {
+		return err
+	}
+	doc, err := confix.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := schema.Set(doc, key, value); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tomledit.Format(&buf, doc); err != nil {
+		return err
+	}
+	if err := confix.CheckValid(buf.Bytes()); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	_, value, _, err = schema.Get(doc, key)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "dry run: would set %s = %s in %s\n", key, value, path)
+		return nil
+	}
+	if err := writeConfigFile(path, path, buf.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "set %s = %s in %s\n", key, value, path)
+	return nil
+}
+
+// writeConfigFile atomically replaces the file at path with data, with the
+// permissions of the file at src.
+func writeConfigFile(path, src string, data []byte) error {
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(src); err == nil {
+		mode = fi.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := atomicfile.WriteData(path, data, mode); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
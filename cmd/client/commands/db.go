@@ -0,0 +1,267 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bhojpur/state/pkg/config"
+	dbm "github.com/bhojpur/state/pkg/database"
+	"github.com/bhojpur/state/pkg/database/remote/rpc"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/scripts/dbmigrate"
+)
+
+// nodeDBContexts are the IDs of the databases a node keeps.
+var nodeDBContexts = []string{
+	"blockstore",
+	"state",
+	"evidence",
+	"peerstore",
+	"tx_index",
+}
+
+// MakeDBCommand constructs a command to manage the node databases. The
+// databases are opened through dbProvider, so that the command also serves
+// custom database implementations.
+func MakeDBCommand(conf *config.Config, logger log.Logger, dbProvider config.DBProvider) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "manage the node databases",
+	}
+	cmd.AddCommand(
+		makeDBMigrateCommand(conf, logger, dbProvider),
+		makeDBServeCommand(conf, logger, dbProvider),
+	)
+	return cmd
+}
+
+func makeDBMigrateCommand(conf *config.Config, logger log.Logger, dbProvider config.DBProvider) *cobra.Command {
+	var (
+		targetBackend string
+		targetDir     string
+		batchSize     int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "copy the node databases to another database backend",
+		Long: `
+migrate copies every node database (blockstore, state, evidence, peerstore and
+tx_index) from the configured backend and directory into a new backend and
+directory. The node must be stopped while migrating.
+
+Progress is recorded in the target directory after every batch, so an
+interrupted migration resumes where it stopped when the command is run again.
+Once copied, every database is verified by comparing the key counts and the
+checksums of the source and the target. To switch the node to the migrated
+databases, set db-backend and db-dir in the config file to the target.
+`,
+		Example: `
+	statectl db migrate --target-backend rocksdb --target-dir data-rocksdb
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if targetBackend == "" || targetDir == "" {
+				return errors.New("--target-backend and --target-dir are required")
+			}
+			target := *conf
+			target.DBBackend = targetBackend
+			target.DBPath = targetDir
+
+			return migrateDBs(cmd.Context(), logger, dbProvider, conf, &target, batchSize)
+		},
+	}
+
+	// allow the source database to be overridden via cli
+	addDBFlags(cmd, conf)
+	cmd.Flags().StringVar(&targetBackend, "target-backend", "",
+		"database backend to migrate to: goleveldb | cleveldb | boltdb | rocksdb | badgerdb")
+	cmd.Flags().StringVar(&targetDir, "target-dir", "", "database directory to migrate to")
+	cmd.Flags().IntVar(&batchSize, "batch-size", dbmigrate.DefaultBatchSize, "number of keys written per batch")
+	return cmd
+}
+
+func makeDBServeCommand(conf *config.Config, logger log.Logger, dbProvider config.DBProvider) *cobra.Command {
+	var (
+		listenAddr   string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "run a remote database server for nodes using the remote db-backend",
+		Long: `
+serve hosts databases for nodes whose db-backend is "remote", so that their
+block stores and indexers can live on a separate storage host. All databases
+are kept in a single database of the configured backend and directory, each
+under the prefix of its name.
+
+Clients must present a certificate signed by a CA in --client-ca-file. Nodes
+configure the matching client certificate in their [remote-db] section.
+`,
+		Example: `
+	statectl db serve --laddr 0.0.0.0:26670 --cert-file server.crt --key-file server.key --client-ca-file ca.crt
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if certFile == "" || keyFile == "" || clientCAFile == "" {
+				return errors.New("--cert-file, --key-file and --client-ca-file are required")
+			}
+			if dbm.BackendType(conf.DBBackend) == dbm.RemoteDBBackend {
+				return errors.New("the remote db-backend cannot serve databases")
+			}
+			creds, err := rpc.ServerCredentials(certFile, keyFile, clientCAFile)
+			if err != nil {
+				return err
+			}
+			store, err := dbProvider(&config.DBContext{ID: "remotedb", Config: conf})
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			ln, err := net.Listen("tcp", listenAddr)
+			if err != nil {
+				return err
+			}
+			srv := rpc.NewServer(store, creds)
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			go func() {
+				<-ctx.Done()
+				srv.GracefulStop()
+			}()
+
+			logger.Info("serving databases", "laddr", ln.Addr().String(),
+				"db_backend", conf.DBBackend, "db_dir", conf.DBDir())
+			return srv.Serve(ln)
+		},
+	}
+
+	// allow the backing database to be overridden via cli
+	addDBFlags(cmd, conf)
+	cmd.Flags().StringVar(&listenAddr, "laddr", "127.0.0.1:26670", "address to listen on (host:port)")
+	cmd.Flags().StringVar(&certFile, "cert-file", "", "path to the server certificate")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "path to the key of the server certificate")
+	cmd.Flags().StringVar(&clientCAFile, "client-ca-file", "", "path to the CA that signs client certificates")
+	return cmd
+}
+
+// migrateDBs copies and verifies the node databases of source into target.
+func migrateDBs(
+	ctx context.Context,
+	logger log.Logger,
+	dbProvider config.DBProvider,
+	source, target *config.Config,
+	batchSize int,
+) error {
+	if source.DBBackend == target.DBBackend && source.DBDir() == target.DBDir() {
+		return errors.New("the target database is the source database")
+	}
+	if err := os.MkdirAll(target.DBDir(), 0700); err != nil {
+		return err
+	}
+
+	for idx, id := range nodeDBContexts {
+		logger.Info("migrating database",
+			"dbctx", id,
+			"num", idx+1,
+			"total", len(nodeDBContexts),
+		)
+		if err := migrateDB(ctx, logger, dbProvider, id, source, target, batchSize); err != nil {
+			return fmt.Errorf("migrating %q: %w", id, err)
+		}
+	}
+
+	logger.Info("completed database migration successfully",
+		"db_backend", target.DBBackend, "db_dir", target.DBDir())
+	return nil
+}
+
+func migrateDB(
+	ctx context.Context,
+	logger log.Logger,
+	dbProvider config.DBProvider,
+	id string,
+	source, target *config.Config,
+	batchSize int,
+) error {
+	src, err := dbProvider(&config.DBContext{ID: id, Config: source})
+	if err != nil {
+		return fmt.Errorf("opening source: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := dbProvider(&config.DBContext{ID: id, Config: target})
+	if err != nil {
+		return fmt.Errorf("opening target: %w", err)
+	}
+	defer dst.Close()
+
+	progressFile := filepath.Join(target.DBDir(), id+".migrate.json")
+	progress, err := dbmigrate.LoadProgress(progressFile)
+	if err != nil {
+		return err
+	}
+
+	if progress.Keys == 0 && !progress.Done {
+		// Don't mix the source into a database we didn't start to fill.
+		iter, err := dst.Iterator(nil, nil)
+		if err != nil {
+			return err
+		}
+		empty := !iter.Valid()
+		if err := iter.Close(); err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("target database is not empty and there is no progress file %s", progressFile)
+		}
+	} else if !progress.Done {
+		logger.Info("resuming migration", "dbctx", id, "keys", progress.Keys)
+	}
+
+	err = dbmigrate.Copy(ctx, src, dst, batchSize, progress, func(p *dbmigrate.Progress) error {
+		logger.Debug("migrated batch", "dbctx", id, "keys", p.Keys)
+		return p.Save(progressFile)
+	})
+	if err != nil {
+		return err
+	}
+
+	sum, err := dbmigrate.Verify(ctx, src, dst)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	logger.Info("verified database", "dbctx", id, "keys", sum.Keys, "checksum", fmt.Sprintf("%X", sum.Checksum))
+	return nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+func Test_MigrateDBs(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	source := cfg.TestConfig()
+	source.SetRoot(t.TempDir())
+	source.DBBackend = "goleveldb"
+
+	for _, id := range nodeDBContexts {
+		db, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: id, Config: source})
+		require.NoError(t, err)
+		for i := 0; i < 25; i++ {
+			require.NoError(t, db.Set([]byte(fmt.Sprintf("%s-%d", id, i)), []byte{byte(i)}))
+		}
+		require.NoError(t, db.Close())
+	}
+
+	target := *source
+	target.DBPath = "data-migrated"
+
+	require.Error(t, migrateDBs(ctx, logger, cfg.DefaultDBProvider, source, source, 10),
+		"migrating into the source")
+	require.NoError(t, migrateDBs(ctx, logger, cfg.DefaultDBProvider, source, &target, 10))
+
+	for _, id := range nodeDBContexts {
+		db, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: id, Config: &target})
+		require.NoError(t, err)
+		value, err := db.Get([]byte(id + "-24"))
+		require.NoError(t, err)
+		require.Equal(t, []byte{24}, value)
+		require.NoError(t, db.Close())
+	}
+
+	// a completed migration is only verified again
+	require.NoError(t, migrateDBs(ctx, logger, cfg.DefaultDBProvider, source, &target, 10))
+
+	// without a progress file, a non-empty target is rejected
+	require.Error(t, migrateDBs(ctx, logger, cfg.DefaultDBProvider, &target, source, 10))
+}
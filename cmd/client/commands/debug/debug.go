@@ -46,6 +46,8 @@ func GetDebugCommand(logger log.Logger) *cobra.Command {
 
 	cmd.AddCommand(getKillCmd(logger))
 	cmd.AddCommand(getDumpCmd(logger))
+	cmd.AddCommand(getP2PCaptureCmd(logger))
+	cmd.AddCommand(getP2PDecodeCmd(logger))
 	return cmd
 
 }
@@ -0,0 +1,254 @@
+package debug
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	auto "github.com/bhojpur/state/internal/libs/autofile"
+	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	rpcclient "github.com/bhojpur/state/pkg/rpc/jsonrpc/client"
+
+	// register the message types of the p2p channels for --json
+	_ "github.com/bhojpur/state/pkg/api/v1/blocksync"
+	_ "github.com/bhojpur/state/pkg/api/v1/consensus"
+	_ "github.com/bhojpur/state/pkg/api/v1/mempool"
+	_ "github.com/bhojpur/state/pkg/api/v1/p2p"
+	_ "github.com/bhojpur/state/pkg/api/v1/statesync"
+	_ "github.com/bhojpur/state/pkg/api/v1/types"
+)
+
+func getP2PCaptureCmd(logger log.Logger) *cobra.Command {
+	var duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "p2p-capture [capture-file]",
+		Short: "Capture the p2p messages of a running node to a file",
+		Long: `Capture the envelopes a running node sends to and receives from its peers,
+through its "/unsafe_p2p_capture" RPC endpoint, which requires rpc.unsafe to be
+enabled. The capture file is written on the host of the node, and rotated like
+other autofile groups. The command waits until the capture ends, and stops it
+early if interrupted. Read the capture with "statectl debug p2p-decode".
+
+Example:
+$ statectl debug p2p-capture --duration 5m /path/to/p2p.capture`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if duration <= 0 {
+				return errors.New("the capture duration must be positive")
+			}
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			nodeRPCAddr, err := cmd.Flags().GetString(flagNodeRPCAddr)
+			if err != nil {
+				return fmt.Errorf("flag %q not defined: %w", flagNodeRPCAddr, err)
+			}
+			client, err := rpcclient.New(nodeRPCAddr)
+			if err != nil {
+				return fmt.Errorf("failed to create new http client: %w", err)
+			}
+
+			var res coretypes.ResultUnsafeP2PCapture
+			if err := client.Call(cmd.Context(), "unsafe_p2p_capture", map[string]interface{}{
+				"path":     path,
+				"duration": duration,
+			}, &res); err != nil {
+				return err
+			}
+			logger.Info("capturing p2p messages", "path", res.Path, "until", res.Until)
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			select {
+			case <-time.After(time.Until(res.Until)):
+			case <-ctx.Done():
+				logger.Info("stopping capture")
+				if err := client.Call(cmd.Context(), "unsafe_p2p_capture", map[string]interface{}{
+					"path": "",
+				}, &res); err != nil {
+					return err
+				}
+			}
+			logger.Info("captured p2p messages", "path", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&duration, "duration", time.Minute, "how long to capture messages for")
+	return cmd
+}
+
+func getP2PDecodeCmd(logger log.Logger) *cobra.Command {
+	var (
+		channel  string
+		msgType  string
+		peerID   string
+		jsonMode bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "p2p-decode [capture-file]",
+		Short: "Print the p2p messages of a capture file",
+		Long: `Print the envelopes recorded by "statectl debug p2p-capture", one per line:
+the time, the direction ("send" or "recv"), the peer, the channel, the message
+type and its size in bytes. Messages can be filtered by channel, message type
+and peer. The message type matches either the full protobuf name of the
+message, such as v1.consensus.Vote, or its last component, such as Vote. With
+--json, each message is printed in full as JSON.
+
+Example:
+$ statectl debug p2p-decode --channel 0x22 --type Vote --json /path/to/p2p.capture`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := captureFilter{msgType: msgType, peerID: peerID}
+			if channel != "" {
+				chID, err := strconv.ParseUint(channel, 0, 16)
+				if err != nil {
+					return fmt.Errorf("invalid channel %q: %w", channel, err)
+				}
+				filter.channel = p2p.ChannelID(chID)
+				filter.hasChannel = true
+			}
+			return decodeCapture(cmd.Context(), logger, args[0], filter, jsonMode, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&channel, "channel", "", "only print messages on this channel, such as 0x20")
+	cmd.Flags().StringVar(&msgType, "type", "", "only print messages of this type")
+	cmd.Flags().StringVar(&peerID, "peer", "", "only print messages sent to or received from this peer")
+	cmd.Flags().BoolVar(&jsonMode, "json", false, "print the decoded messages as JSON")
+	return cmd
+}
+
+// captureFilter selects the capture records printed by p2p-decode.
+type captureFilter struct {
+	channel    p2p.ChannelID
+	hasChannel bool
+	msgType    string
+	peerID     string
+}
+
+func (f captureFilter) match(rec *p2p.CaptureRecord) bool {
+	if f.hasChannel && rec.ChannelID != f.channel {
+		return false
+	}
+	if f.peerID != "" && string(rec.PeerID) != f.peerID {
+		return false
+	}
+	if f.msgType != "" && !matchMessageType(rec.MessageType, f.msgType) &&
+		!matchMessageType(rec.WireType, f.msgType) {
+		return false
+	}
+	return true
+}
+
+func matchMessageType(name, msgType string) bool {
+	return name == msgType || strings.HasSuffix(name, "."+msgType)
+}
+
+// decodeCapture prints the records of the capture at path that match filter.
+func decodeCapture(
+	ctx context.Context,
+	logger log.Logger,
+	path string,
+	filter captureFilter,
+	jsonMode bool,
+	out io.Writer,
+) error {
+	group, err := auto.OpenGroup(ctx, logger, path, auto.GroupTotalSizeLimit(0))
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+	rd, err := group.NewReader(group.MinIndex())
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	dec := p2p.NewCaptureDecoder(rd)
+	for i := 0; ; i++ {
+		rec, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			logger.Info("ignoring truncated record at the end of the capture", "record", i)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read record %d: %w", i, err)
+		}
+		if !filter.match(rec) {
+			continue
+		}
+
+		direction := "recv"
+		if rec.Outbound {
+			direction = "send"
+		}
+		fmt.Fprintf(out, "%s %s %s %#02x %s %d",
+			rec.Time.UTC().Format(time.RFC3339Nano), direction, rec.PeerID,
+			uint16(rec.ChannelID), rec.MessageType, len(rec.Message))
+		if jsonMode {
+			bz, err := captureMessageJSON(rec)
+			if err != nil {
+				fmt.Fprintf(out, " (%v)", err)
+			} else {
+				fmt.Fprintf(out, " %s", bz)
+			}
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// captureMessageJSON decodes the message of a capture record as its wire
+// type, and encodes it as JSON.
+func captureMessageJSON(rec *p2p.CaptureRecord) ([]byte, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.WireType))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message type %s", rec.WireType)
+	}
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(rec.Message, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return protojson.Marshal(msg)
+}
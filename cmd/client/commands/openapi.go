@@ -0,0 +1,68 @@
+package commands
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bhojpur/state/internal/rpc/core"
+	"github.com/bhojpur/state/internal/rpc/openapi"
+	"github.com/bhojpur/state/pkg/config"
+)
+
+// MakeOpenAPICommand constructs a command that prints an OpenAPI document
+// describing the RPC routes of a node.
+func MakeOpenAPICommand(conf *config.Config) *cobra.Command {
+	var (
+		output string
+		unsafe bool
+	)
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "Generate an OpenAPI document describing the RPC interface",
+		Long: `Generate an OpenAPI document describing the RPC routes of a node, including
+the types of their parameters and results. Routes that are only available over
+the websocket endpoint are listed under the "x-jsonrpc-methods" extension.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			routes := core.NewRoutesMap(&core.Environment{}, &core.RouteOptions{Unsafe: unsafe})
+			bz, err := json.MarshalIndent(openapi.Generate(routes), "", "  ")
+			if err != nil {
+				return fmt.Errorf("openapi -> json: %w", err)
+			}
+			bz = append(bz, '\n')
+
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(bz)
+				return err
+			}
+			return os.WriteFile(output, bz, 0644)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the document to this file instead of stdout")
+	cmd.Flags().BoolVar(&unsafe, "unsafe", conf.RPC.Unsafe, "include unsafe routes")
+
+	return cmd
+}
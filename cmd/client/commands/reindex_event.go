@@ -163,24 +163,32 @@ func loadEventSinks(cfg *cfgsvc.Config) ([]indexer.EventSink, error) {
 
 func loadStateAndBlockStore(cfg *cfgsvc.Config) (*store.BlockStore, state.Store, error) {
 	dbType := dbm.BackendType(cfg.DBBackend)
+	dbOpt := dbm.WithRocksDBOptions(cfg.RocksDB.Options())
 
-	if !os.FileExists(filepath.Join(cfg.DBDir(), "blockstore.db")) {
+	// With column families, all databases live in a single RocksDB instance.
+	blockStorePath, statePath := "blockstore.db", "state.db"
+	if dbType == dbm.RocksDBBackend && cfg.RocksDB.ColumnFamilies {
+		blockStorePath = dbm.RocksDBSharedName + ".db"
+		statePath = blockStorePath
+	}
+
+	if !os.FileExists(filepath.Join(cfg.DBDir(), blockStorePath)) {
 		return nil, nil, fmt.Errorf("no blockstore found in %v", cfg.DBDir())
 	}
 
 	// Get BlockStore
-	blockStoreDB, err := dbm.NewDB("blockstore", dbType, cfg.DBDir())
+	blockStoreDB, err := dbm.NewDB("blockstore", dbType, cfg.DBDir(), dbOpt)
 	if err != nil {
 		return nil, nil, err
 	}
 	blockStore := store.NewBlockStore(blockStoreDB)
 
-	if !os.FileExists(filepath.Join(cfg.DBDir(), "state.db")) {
+	if !os.FileExists(filepath.Join(cfg.DBDir(), statePath)) {
 		return nil, nil, fmt.Errorf("no blockstore found in %v", cfg.DBDir())
 	}
 
 	// Get StateStore
-	stateDB, err := dbm.NewDB("state", dbType, cfg.DBDir())
+	stateDB, err := dbm.NewDB("state", dbType, cfg.DBDir(), dbOpt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -22,17 +22,22 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/bhojpur/state/internal/libs/autofile"
 	cfg "github.com/bhojpur/state/pkg/config"
 	"github.com/bhojpur/state/pkg/libs/log"
+	bos "github.com/bhojpur/state/pkg/libs/os"
 )
 
 var (
@@ -128,6 +133,14 @@ func NewRunNodeCmd(nodeProvider cfg.ServiceProvider, conf *cfg.Config, logger lo
 			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 			defer cancel()
 
+			if conf.LogFile != "" {
+				closeLogFile, err := startLogFile(ctx, conf, logger)
+				if err != nil {
+					return err
+				}
+				defer closeLogFile()
+			}
+
 			n, err := nodeProvider(ctx, conf, logger)
 			if err != nil {
 				return fmt.Errorf("failed to create node: %w", err)
@@ -139,8 +152,18 @@ func NewRunNodeCmd(nodeProvider cfg.ServiceProvider, conf *cfg.Config, logger lo
 
 			logger.Info("started node", "chain", conf.ChainID())
 
-			<-ctx.Done()
-			return nil
+			// The config file is reloaded on SIGHUP.
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			defer signal.Stop(hup)
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-hup:
+					reloadConfig(ctx, n, logger)
+				}
+			}
 		},
 	}
 
@@ -148,6 +171,77 @@ func NewRunNodeCmd(nodeProvider cfg.ServiceProvider, conf *cfg.Config, logger lo
 	return cmd
 }
 
+// startLogFile opens the rotating log file configured in conf, and adds it as
+// an output of logger. The returned function removes the output again and
+// closes the file.
+func startLogFile(ctx context.Context, conf *cfg.Config, logger log.Logger) (func(), error) {
+	path := conf.LogFilePath()
+	if err := bos.EnsureDir(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log file directory: %w", err)
+	}
+	group, err := autofile.OpenGroup(ctx, log.NewNopLogger(), path,
+		autofile.GroupHeadSizeLimit(conf.LogFileMaxSize),
+		autofile.GroupTotalSizeLimit(conf.LogFileMaxTotalSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	if err := group.Start(ctx); err != nil {
+		group.Close()
+		return nil, fmt.Errorf("failed to start log file: %w", err)
+	}
+	err = log.OverrideWithNewLogger(logger, conf.LogFormat, conf.LogLevel,
+		log.WithSampling(conf.LogSampling), log.WithJSONWriter(group))
+	if err != nil {
+		group.Stop()
+		group.Close()
+		return nil, err
+	}
+
+	// Writes to the group are buffered, so flush them periodically to keep
+	// the file current.
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = group.FlushAndSync()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := log.OverrideWithNewLogger(logger, conf.LogFormat, conf.LogLevel,
+			log.WithSampling(conf.LogSampling)); err != nil {
+			logger.Error("failed to detach log file", "err", err)
+		}
+		group.Stop()
+		group.Close()
+	}, nil
+}
+
+// configReloader is implemented by nodes that can reload their config file.
+type configReloader interface {
+	ReloadConfig(context.Context) (*cfg.ReloadReport, error)
+}
+
+// reloadConfig reloads the config file of node n, if it supports it. The
+// outcome is logged by the node.
+func reloadConfig(ctx context.Context, n interface{}, logger log.Logger) {
+	r, ok := n.(configReloader)
+	if !ok {
+		logger.Error("node does not support reloading its configuration")
+		return
+	}
+	if _, err := r.ReloadConfig(ctx); err != nil {
+		logger.Error("failed to reload configuration", "err", err)
+	}
+}
+
 func checkGenesisHash(config *cfg.Config) error {
 	if len(genesisHash) == 0 || config.Genesis == "" {
 		return nil
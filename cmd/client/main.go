@@ -62,6 +62,12 @@ func main() {
 		commands.MakeInspectCommand(conf, logger),
 		commands.MakeRollbackStateCommand(conf),
 		commands.MakeKeyMigrateCommand(conf, logger),
+		commands.MakeArchiveCommand(conf, logger),
+		commands.MakeDBCommand(conf, logger, config.DefaultDBProvider),
+		commands.MakeABCICommand(conf, logger),
+		commands.MakeBackupCommand(conf, logger, config.DefaultDBProvider),
+		commands.MakeConfigCommand(),
+		commands.MakeOpenAPICommand(conf),
 		debug.GetDebugCommand(logger),
 		commands.NewCompletionCmd(rcmd, true),
 	)
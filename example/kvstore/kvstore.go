@@ -24,8 +24,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -96,6 +96,11 @@ type Application struct {
 	RetainBlocks int64 // blocks to retain after commit (via ResponseCommit.RetainHeight)
 	logger       log.Logger
 
+	// state sync snapshots, taken every SnapshotInterval heights if non-zero
+	SnapshotInterval uint64
+	KeepSnapshots    int // snapshots to keep, all if zero
+	restore          *restore
+
 	// validator set
 	ValUpdates         []abcipb.ValidatorUpdate
 	valAddrToPubKeyMap map[string]v1.PublicKey
@@ -197,7 +202,7 @@ func (app *Application) FinalizeBlock(_ context.Context, req *abcipb.RequestFina
 		if ev.Type == abcipb.MisbehaviorType_DUPLICATE_VOTE {
 			addr := string(ev.Validator.Address)
 			if pubKey, ok := app.valAddrToPubKeyMap[addr]; ok {
-				app.updateValidator(types.ValidatorUpdate{
+				app.updateValidator(abcipb.ValidatorUpdate{
 					PubKey: pubKey,
 					Power:  ev.Validator.Power - 1,
 				})
@@ -214,7 +219,17 @@ func (app *Application) FinalizeBlock(_ context.Context, req *abcipb.RequestFina
 		respTxs[i] = app.handleTx(tx)
 	}
 
-	return &abcipb.ResponseFinalizeBlock{TxResults: respTxs, ValidatorUpdates: app.ValUpdates}, nil
+	appHash, err := hashState(app.state.db)
+	if err != nil {
+		panic(err)
+	}
+	app.state.AppHash = appHash
+
+	return &abcipb.ResponseFinalizeBlock{
+		TxResults:        respTxs,
+		ValidatorUpdates: app.ValUpdates,
+		AppHash:          appHash,
+	}, nil
 }
 
 func (*Application) CheckTx(_ context.Context, req *abcipb.RequestCheckTx) (*abcipb.ResponseCheckTx, error) {
@@ -225,21 +240,28 @@ func (app *Application) Commit(_ context.Context) (*abcipb.ResponseCommit, error
 	app.mu.Lock()
 	defer app.mu.Unlock()
 
-	// Using a memory database - just return the big endian size of the database
-	appHash := make([]byte, 8)
-	binary.PutVarint(appHash, app.state.Size)
-	app.state.AppHash = appHash
+	// the app hash was computed by FinalizeBlock
 	app.state.Height++
 	saveState(app.state)
 
-	resp := &types.ResponseCommit{Data: appHash}
+	if app.SnapshotInterval > 0 && uint64(app.state.Height)%app.SnapshotInterval == 0 {
+		if err := createSnapshot(app.state.db, uint64(app.state.Height), app.KeepSnapshots); err != nil {
+			app.logger.Error("failed to create snapshot", "height", app.state.Height, "err", err)
+		}
+	}
+
+	resp := &abcipb.ResponseCommit{Data: app.state.AppHash}
 	if app.RetainBlocks > 0 && app.state.Height >= app.RetainBlocks {
 		resp.RetainHeight = app.state.Height - app.RetainBlocks + 1
 	}
 	return resp, nil
 }
 
-// Returns an associated value or nil if missing.
+// Returns an associated value or nil if missing. If a proof is requested,
+// the value is proven against the app hash of the returned height with the
+// key path "/kv/<key>", as built by the light client proxy for the query path
+// "/store/kv/key". Validators are proven with the path "/store/val/key" and
+// their public key as data.
 func (app *Application) Query(_ context.Context, reqQuery *abcipb.RequestQuery) (*abcipb.ResponseQuery, error) {
 	app.mu.Lock()
 	defer app.mu.Unlock()
@@ -251,23 +273,32 @@ func (app *Application) Query(_ context.Context, reqQuery *abcipb.RequestQuery)
 			panic(err)
 		}
 
-		return &types.ResponseQuery{
+		return &abcipb.ResponseQuery{
 			Key:   reqQuery.Data,
 			Value: value,
 		}, nil
 	}
 
 	if reqQuery.Prove {
-		value, err := app.state.db.Get(prefixKey(reqQuery.Data))
+		storeName, key := kvStoreName, prefixKey(reqQuery.Data)
+		if reqQuery.Path == "/store/val/key" {
+			storeName, key = valStoreName, []byte(ValidatorSetChangePrefix+string(reqQuery.Data))
+		}
+		value, err := app.state.db.Get(key)
+		if err != nil {
+			panic(err)
+		}
+		proofOps, err := proveKey(app.state.db, storeName, reqQuery.Data)
 		if err != nil {
 			panic(err)
 		}
 
-		resQuery := types.ResponseQuery{
-			Index:  -1,
-			Key:    reqQuery.Data,
-			Value:  value,
-			Height: app.state.Height,
+		resQuery := abcipb.ResponseQuery{
+			Index:    -1,
+			Key:      reqQuery.Data,
+			Value:    value,
+			ProofOps: proofOps,
+			Height:   app.state.Height,
 		}
 
 		if value == nil {
@@ -284,7 +315,7 @@ func (app *Application) Query(_ context.Context, reqQuery *abcipb.RequestQuery)
 		panic(err)
 	}
 
-	resQuery := types.ResponseQuery{
+	resQuery := abcipb.ResponseQuery{
 		Key:    reqQuery.Data,
 		Value:  value,
 		Height: app.state.Height,
@@ -299,22 +330,121 @@ func (app *Application) Query(_ context.Context, reqQuery *abcipb.RequestQuery)
 	return &resQuery, nil
 }
 
-func (app *Application) PrepareProposal(_ context.Context, req *types.RequestPrepareProposal) (*types.ResponsePrepareProposal, error) {
+func (app *Application) PrepareProposal(_ context.Context, req *abcipb.RequestPrepareProposal) (*abcipb.ResponsePrepareProposal, error) {
 	app.mu.Lock()
 	defer app.mu.Unlock()
 
-	return &types.ResponsePrepareProposal{
+	return &abcipb.ResponsePrepareProposal{
 		TxRecords: app.substPrepareTx(req.Txs, req.MaxTxBytes),
 	}, nil
 }
 
-func (*Application) ProcessProposal(_ context.Context, req *types.RequestProcessProposal) (*types.ResponseProcessProposal, error) {
+func (*Application) ProcessProposal(_ context.Context, req *abcipb.RequestProcessProposal) (*abcipb.ResponseProcessProposal, error) {
 	for _, tx := range req.Txs {
 		if len(tx) == 0 {
-			return &types.ResponseProcessProposal{Status: types.ResponseProcessProposal_REJECT}, nil
+			return &abcipb.ResponseProcessProposal{Status: abcipb.ResponseProcessProposal_REJECT}, nil
+		}
+	}
+	return &abcipb.ResponseProcessProposal{Status: abcipb.ResponseProcessProposal_ACCEPT}, nil
+}
+
+// state sync snapshots
+
+func (app *Application) ListSnapshots(_ context.Context, req *abcipb.RequestListSnapshots) (*abcipb.ResponseListSnapshots, error) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	snapshots, err := loadSnapshots(app.state.db)
+	if err != nil {
+		return nil, err
+	}
+	return &abcipb.ResponseListSnapshots{Snapshots: snapshots}, nil
+}
+
+func (app *Application) LoadSnapshotChunk(_ context.Context, req *abcipb.RequestLoadSnapshotChunk) (*abcipb.ResponseLoadSnapshotChunk, error) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	chunk, err := loadSnapshotChunk(app.state.db, req.Height, req.Format, req.Chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &abcipb.ResponseLoadSnapshotChunk{Chunk: chunk}, nil
+}
+
+func (app *Application) OfferSnapshot(_ context.Context, req *abcipb.RequestOfferSnapshot) (*abcipb.ResponseOfferSnapshot, error) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if req.Snapshot.GetFormat() != snapshotFormat {
+		return &abcipb.ResponseOfferSnapshot{Result: abcipb.ResponseOfferSnapshot_REJECT_FORMAT}, nil
+	}
+	if req.Snapshot.Chunks == 0 || len(req.AppHash) == 0 {
+		return &abcipb.ResponseOfferSnapshot{Result: abcipb.ResponseOfferSnapshot_REJECT}, nil
+	}
+	app.restore = &restore{snapshot: req.Snapshot, appHash: req.AppHash}
+	return &abcipb.ResponseOfferSnapshot{Result: abcipb.ResponseOfferSnapshot_ACCEPT}, nil
+}
+
+func (app *Application) ApplySnapshotChunk(_ context.Context, req *abcipb.RequestApplySnapshotChunk) (*abcipb.ResponseApplySnapshotChunk, error) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	if app.restore == nil {
+		return &abcipb.ResponseApplySnapshotChunk{Result: abcipb.ResponseApplySnapshotChunk_ABORT}, nil
+	}
+	done, err := app.restore.apply(req.Index, req.Chunk)
+	if err != nil {
+		return &abcipb.ResponseApplySnapshotChunk{
+			Result:        abcipb.ResponseApplySnapshotChunk_RETRY,
+			RefetchChunks: []uint32{req.Index},
+		}, nil
+	}
+	if !done {
+		return &abcipb.ResponseApplySnapshotChunk{Result: abcipb.ResponseApplySnapshotChunk_ACCEPT}, nil
+	}
+
+	restore := app.restore
+	app.restore = nil
+	size, err := restore.finish(app.state.db)
+	if errors.Is(err, errInvalidSnapshot) {
+		app.logger.Error("rejecting snapshot", "height", restore.snapshot.Height, "err", err)
+		return &abcipb.ResponseApplySnapshotChunk{Result: abcipb.ResponseApplySnapshotChunk_REJECT_SNAPSHOT}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	app.state.Size = size
+	app.state.Height = int64(restore.snapshot.Height)
+	app.state.AppHash = restore.appHash
+	saveState(app.state)
+	app.loadValidatorAddresses()
+	return &abcipb.ResponseApplySnapshotChunk{Result: abcipb.ResponseApplySnapshotChunk_ACCEPT}, nil
+}
+
+// loadValidatorAddresses rebuilds the map of validator addresses from the
+// validators in the database.
+func (app *Application) loadValidatorAddresses() {
+	app.valAddrToPubKeyMap = make(map[string]v1.PublicKey)
+	itr, err := dbm.IteratePrefix(app.state.db, []byte(ValidatorSetChangePrefix))
+	if err != nil {
+		panic(err)
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		validator := new(abcipb.ValidatorUpdate)
+		if err := abcipb.ReadMessage(bytes.NewBuffer(itr.Value()), validator); err != nil {
+			panic(err)
+		}
+		pubkey, err := encoding.PubKeyFromProto(*validator.PubKey)
+		if err != nil {
+			panic(err)
 		}
+		app.valAddrToPubKeyMap[string(pubkey.Address())] = *validator.PubKey
+	}
+	if err := itr.Error(); err != nil {
+		panic(err)
 	}
-	return &types.ResponseProcessProposal{Status: types.ResponseProcessProposal_ACCEPT}, nil
 }
 
 // update validators
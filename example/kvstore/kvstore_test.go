@@ -36,6 +36,7 @@ import (
 	abciclient "github.com/bhojpur/state/pkg/abci/client"
 	abciserver "github.com/bhojpur/state/pkg/abci/server"
 	"github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/crypto/merkle"
 )
 
 const (
@@ -410,3 +411,109 @@ func testClient(ctx context.Context, t *testing.T, app abciclient.Client, tx []b
 	require.Equal(t, value, string(resQuery.Value))
 	require.EqualValues(t, info.LastBlockHeight, resQuery.Height)
 }
+
+func TestKVStoreProofs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kvstore := NewApplication()
+	res, err := kvstore.FinalizeBlock(ctx, &types.RequestFinalizeBlock{
+		Txs: [][]byte{[]byte("abc=def"), []byte("foo=bar"), []byte("a=b")},
+	})
+	require.NoError(t, err)
+	_, err = kvstore.Commit(ctx)
+	require.NoError(t, err)
+
+	prt := merkle.DefaultProofRuntime()
+	for _, key := range []string{"abc", "foo", "a"} {
+		resQuery, err := kvstore.Query(ctx, &types.RequestQuery{
+			Path:  "/store/kv/key",
+			Data:  []byte(key),
+			Prove: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, resQuery.ProofOps)
+
+		kp := merkle.KeyPath{}.
+			AppendKey([]byte(kvStoreName), merkle.KeyEncodingURL).
+			AppendKey([]byte(key), merkle.KeyEncodingURL)
+		require.NoError(t, prt.VerifyValue(resQuery.ProofOps, res.AppHash, kp.String(), resQuery.Value))
+		require.Error(t, prt.VerifyValue(resQuery.ProofOps, res.AppHash, kp.String(), []byte("forged")))
+	}
+
+	resQuery, err := kvstore.Query(ctx, &types.RequestQuery{
+		Path:  "/store/kv/key",
+		Data:  []byte("missing"),
+		Prove: true,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resQuery.Value)
+	require.Nil(t, resQuery.ProofOps)
+}
+
+func TestKVStoreSnapshots(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := NewApplication()
+	source.SnapshotInterval = 2
+	source.KeepSnapshots = 1
+	for i := 0; i < 4; i++ {
+		_, err := source.FinalizeBlock(ctx, &types.RequestFinalizeBlock{
+			Txs: [][]byte{[]byte(fmt.Sprintf("key%d=value%d", i, i))},
+		})
+		require.NoError(t, err)
+		_, err = source.Commit(ctx)
+		require.NoError(t, err)
+	}
+	info, err := source.Info(ctx, &types.RequestInfo{})
+	require.NoError(t, err)
+
+	list, err := source.ListSnapshots(ctx, &types.RequestListSnapshots{})
+	require.NoError(t, err)
+	require.Len(t, list.Snapshots, 1)
+	snapshot := list.Snapshots[0]
+	require.EqualValues(t, 4, snapshot.Height)
+
+	target := NewApplication()
+	offer, err := target.OfferSnapshot(ctx, &types.RequestOfferSnapshot{
+		Snapshot: snapshot,
+		AppHash:  info.LastBlockAppHash,
+	})
+	require.NoError(t, err)
+	require.Equal(t, types.ResponseOfferSnapshot_ACCEPT, offer.Result)
+	for i := uint32(0); i < snapshot.Chunks; i++ {
+		chunk, err := source.LoadSnapshotChunk(ctx, &types.RequestLoadSnapshotChunk{
+			Height: snapshot.Height,
+			Format: snapshot.Format,
+			Chunk:  i,
+		})
+		require.NoError(t, err)
+		res, err := target.ApplySnapshotChunk(ctx, &types.RequestApplySnapshotChunk{Index: i, Chunk: chunk.Chunk})
+		require.NoError(t, err)
+		require.Equal(t, types.ResponseApplySnapshotChunk_ACCEPT, res.Result)
+	}
+
+	restored, err := target.Info(ctx, &types.RequestInfo{})
+	require.NoError(t, err)
+	require.Equal(t, info.LastBlockHeight, restored.LastBlockHeight)
+	require.Equal(t, info.LastBlockAppHash, restored.LastBlockAppHash)
+	require.Equal(t, info.Data, restored.Data)
+
+	// a snapshot not matching the trusted app hash is rejected
+	target = NewApplication()
+	_, err = target.OfferSnapshot(ctx, &types.RequestOfferSnapshot{Snapshot: snapshot, AppHash: []byte("forged")})
+	require.NoError(t, err)
+	var res *types.ResponseApplySnapshotChunk
+	for i := uint32(0); i < snapshot.Chunks; i++ {
+		chunk, err := source.LoadSnapshotChunk(ctx, &types.RequestLoadSnapshotChunk{
+			Height: snapshot.Height,
+			Format: snapshot.Format,
+			Chunk:  i,
+		})
+		require.NoError(t, err)
+		res, err = target.ApplySnapshotChunk(ctx, &types.RequestApplySnapshotChunk{Index: i, Chunk: chunk.Chunk})
+		require.NoError(t, err)
+	}
+	require.Equal(t, types.ResponseApplySnapshotChunk_REJECT_SNAPSHOT, res.Result)
+}
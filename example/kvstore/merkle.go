@@ -0,0 +1,154 @@
+package kvstore
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/bhojpur/state/pkg/api/v1/crypto"
+	"github.com/bhojpur/state/pkg/crypto/merkle"
+	dbm "github.com/bhojpur/state/pkg/database"
+)
+
+// The app hash is the root of a Merkle tree of stores, each of which is a
+// Merkle tree of its key/value pairs sorted by key. The leaves of both
+// levels are the pairs <key, sha256(value)>, with the root hash of a store as
+// its value in the upper level, as verified by merkle.ValueOp. Values can thus
+// be proven with the default proof runtime of the light client, using key
+// paths built by its DefaultMerkleKeyPathFn from queries to "/store/kv/key".
+const (
+	kvStoreName  = "kv"
+	valStoreName = "val"
+)
+
+// stores are the stores of the app hash, sorted by name, with the prefix of
+// their keys in the database.
+var stores = []struct {
+	name   string
+	prefix []byte
+}{
+	{kvStoreName, kvPairPrefixKey},
+	{valStoreName, []byte(ValidatorSetChangePrefix)},
+}
+
+// storeIndex returns the index of the named store in stores.
+func storeIndex(name string) (int, error) {
+	for i, s := range stores {
+		if s.name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown store %q", name)
+}
+
+// kvPairBytes encodes the Merkle leaf of a key/value pair.
+func kvPairBytes(key, value []byte) []byte {
+	vhash := sha256.Sum256(value)
+	buf := new(bytes.Buffer)
+	writeByteSlice(buf, key)
+	writeByteSlice(buf, vhash[:])
+	return buf.Bytes()
+}
+
+// writeByteSlice writes bz prefixed with its uvarint length.
+func writeByteSlice(buf *bytes.Buffer, bz []byte) {
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(bz)))])
+	buf.Write(bz)
+}
+
+// storeLeaves returns the keys of a store, without their prefix, and the
+// Merkle leaves of its pairs, both sorted by key.
+func storeLeaves(db dbm.DB, prefix []byte) (keys, leaves [][]byte, err error) {
+	itr, err := dbm.IteratePrefix(db, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := bytes.TrimPrefix(itr.Key(), prefix)
+		keys = append(keys, key)
+		leaves = append(leaves, kvPairBytes(key, itr.Value()))
+	}
+	return keys, leaves, itr.Error()
+}
+
+// rootLeaves returns the Merkle leaves of the stores.
+func rootLeaves(db dbm.DB) ([][]byte, error) {
+	leaves := make([][]byte, len(stores))
+	for i, s := range stores {
+		_, storeLeaves, err := storeLeaves(db, s.prefix)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = kvPairBytes([]byte(s.name), merkle.HashFromByteSlices(storeLeaves))
+	}
+	return leaves, nil
+}
+
+// hashState computes the app hash of the state in db. It hashes every pair,
+// which is fine for an example, but a real application would keep the tree
+// in the database and only rehash the updated paths.
+func hashState(db dbm.DB) ([]byte, error) {
+	leaves, err := rootLeaves(db)
+	if err != nil {
+		return nil, err
+	}
+	return merkle.HashFromByteSlices(leaves), nil
+}
+
+// proveKey returns the proof of the value of key in the named store against
+// the app hash. It returns nil if the key does not exist, since absence can't
+// be proven with value operations.
+func proveKey(db dbm.DB, storeName string, key []byte) (*crypto.ProofOps, error) {
+	storeIdx, err := storeIndex(storeName)
+	if err != nil {
+		return nil, err
+	}
+	keys, leaves, err := storeLeaves(db, stores[storeIdx].prefix)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, k := range keys {
+		if bytes.Equal(k, key) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil
+	}
+	_, proofs := merkle.ProofsFromByteSlices(leaves)
+
+	roots, err := rootLeaves(db)
+	if err != nil {
+		return nil, err
+	}
+	_, rootProofs := merkle.ProofsFromByteSlices(roots)
+
+	valueOp := merkle.NewValueOp(key, proofs[index]).ProofOp()
+	storeOp := merkle.NewValueOp([]byte(storeName), rootProofs[storeIdx]).ProofOp()
+	return &crypto.ProofOps{Ops: []*crypto.ProofOp{&valueOp, &storeOp}}, nil
+}
@@ -21,8 +21,6 @@ package kvstore
 // THE SOFTWARE.
 
 import (
-	"context"
-
 	dbm "github.com/bhojpur/state/pkg/database"
 
 	"github.com/bhojpur/state/pkg/abci/types"
@@ -51,14 +49,8 @@ func NewPersistentKVStoreApplication(logger log.Logger, dbDir string) *Persisten
 			valAddrToPubKeyMap: make(map[string]v1.PublicKey),
 			state:              loadState(db),
 			logger:             logger,
+			SnapshotInterval:   100,
+			KeepSnapshots:      10,
 		},
 	}
 }
-
-func (app *PersistentKVStoreApplication) OfferSnapshot(_ context.Context, req *types.RequestOfferSnapshot) (*types.ResponseOfferSnapshot, error) {
-	return &types.ResponseOfferSnapshot{Result: types.ResponseOfferSnapshot_ABORT}, nil
-}
-
-func (app *PersistentKVStoreApplication) ApplySnapshotChunk(_ context.Context, req *types.RequestApplySnapshotChunk) (*types.ResponseApplySnapshotChunk, error) {
-	return &types.ResponseApplySnapshotChunk{Result: types.ResponseApplySnapshotChunk_ABORT}, nil
-}
@@ -0,0 +1,257 @@
+package kvstore
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bhojpur/state/pkg/abci/types"
+	dbm "github.com/bhojpur/state/pkg/database"
+)
+
+const (
+	// snapshotFormat is the format of the snapshots: the pairs of every store,
+	// each as the uvarint length prefixed key and value, split into chunks.
+	snapshotFormat = 1
+	// snapshotChunkSize is the size of a snapshot chunk.
+	snapshotChunkSize = 1 << 20
+)
+
+var (
+	snapshotsKey      = []byte("snapshots")
+	snapshotPrefixKey = []byte("snapshot:")
+)
+
+// snapshotKey returns the database key of a snapshot chunk. Snapshots are
+// kept in the application database, outside of the stores.
+func snapshotKey(height uint64, chunk uint32) []byte {
+	key := make([]byte, len(snapshotPrefixKey)+12)
+	copy(key, snapshotPrefixKey)
+	binary.BigEndian.PutUint64(key[len(snapshotPrefixKey):], height)
+	binary.BigEndian.PutUint32(key[len(snapshotPrefixKey)+8:], chunk)
+	return key
+}
+
+// loadSnapshots returns the metadata of the stored snapshots, oldest first.
+func loadSnapshots(db dbm.DB) ([]*types.Snapshot, error) {
+	bz, err := db.Get(snapshotsKey)
+	if err != nil || len(bz) == 0 {
+		return nil, err
+	}
+	var snapshots []*types.Snapshot
+	if err := json.Unmarshal(bz, &snapshots); err != nil {
+		return nil, fmt.Errorf("invalid snapshot metadata: %w", err)
+	}
+	return snapshots, nil
+}
+
+// exportState encodes the pairs of every store.
+func exportState(db dbm.DB) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, s := range stores {
+		itr, err := dbm.IteratePrefix(db, s.prefix)
+		if err != nil {
+			return nil, err
+		}
+		for ; itr.Valid(); itr.Next() {
+			writeByteSlice(buf, itr.Key())
+			writeByteSlice(buf, itr.Value())
+		}
+		err = itr.Error()
+		itr.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// importState replaces the pairs of every store with the ones encoded in bz,
+// and returns the number of key/value pairs.
+func importState(db dbm.DB, bz []byte) (int64, error) {
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	for _, s := range stores {
+		itr, err := dbm.IteratePrefix(db, s.prefix)
+		if err != nil {
+			return 0, err
+		}
+		for ; itr.Valid(); itr.Next() {
+			if err := batch.Delete(itr.Key()); err != nil {
+				itr.Close()
+				return 0, err
+			}
+		}
+		err = itr.Error()
+		itr.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var size int64
+	r := bytes.NewReader(bz)
+	for r.Len() > 0 {
+		key, err := readByteSlice(r)
+		if err != nil {
+			return 0, err
+		}
+		value, err := readByteSlice(r)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.HasPrefix(key, kvPairPrefixKey) && !bytes.HasPrefix(key, []byte(ValidatorSetChangePrefix)) {
+			return 0, fmt.Errorf("snapshot key %q is outside of the stores", key)
+		}
+		if bytes.HasPrefix(key, kvPairPrefixKey) {
+			size++
+		}
+		if err := batch.Set(key, value); err != nil {
+			return 0, err
+		}
+	}
+	return size, batch.WriteSync()
+}
+
+func readByteSlice(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	bz := make([]byte, n)
+	_, err = io.ReadFull(r, bz)
+	return bz, err
+}
+
+// createSnapshot stores a snapshot of the state at height, and prunes the
+// oldest snapshots beyond keep.
+func createSnapshot(db dbm.DB, height uint64, keep int) error {
+	bz, err := exportState(db)
+	if err != nil {
+		return err
+	}
+	snapshots, err := loadSnapshots(db)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(bz)
+	snapshot := &types.Snapshot{
+		Height: height,
+		Format: snapshotFormat,
+		Chunks: uint32((len(bz) + snapshotChunkSize - 1) / snapshotChunkSize),
+		Hash:   hash[:],
+	}
+	if snapshot.Chunks == 0 {
+		snapshot.Chunks = 1
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	for i := uint32(0); i < snapshot.Chunks; i++ {
+		start := int(i) * snapshotChunkSize
+		end := start + snapshotChunkSize
+		if end > len(bz) {
+			end = len(bz)
+		}
+		if err := batch.Set(snapshotKey(height, i), bz[start:end]); err != nil {
+			return err
+		}
+	}
+	snapshots = append(snapshots, snapshot)
+	for keep > 0 && len(snapshots) > keep {
+		for i := uint32(0); i < snapshots[0].Chunks; i++ {
+			if err := batch.Delete(snapshotKey(snapshots[0].Height, i)); err != nil {
+				return err
+			}
+		}
+		snapshots = snapshots[1:]
+	}
+	meta, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	if err := batch.Set(snapshotsKey, meta); err != nil {
+		return err
+	}
+	return batch.WriteSync()
+}
+
+// loadSnapshotChunk returns a chunk of a stored snapshot, or nil if there is
+// no such chunk.
+func loadSnapshotChunk(db dbm.DB, height uint64, format, chunk uint32) ([]byte, error) {
+	if format != snapshotFormat {
+		return nil, nil
+	}
+	return db.Get(snapshotKey(height, chunk))
+}
+
+// restore is a snapshot being restored.
+type restore struct {
+	snapshot *types.Snapshot
+	appHash  []byte
+	chunks   [][]byte
+}
+
+// errInvalidSnapshot is returned when a restored snapshot does not match its
+// hash or the app hash.
+var errInvalidSnapshot = errors.New("invalid snapshot")
+
+// apply adds the chunk at index, and returns true once all chunks have been
+// applied.
+func (r *restore) apply(index uint32, chunk []byte) (bool, error) {
+	if index != uint32(len(r.chunks)) {
+		return false, fmt.Errorf("expected chunk %d, got %d", len(r.chunks), index)
+	}
+	r.chunks = append(r.chunks, chunk)
+	return len(r.chunks) == int(r.snapshot.Chunks), nil
+}
+
+// finish imports the restored state into db, and checks it against the
+// snapshot hash and the trusted app hash.
+func (r *restore) finish(db dbm.DB) (int64, error) {
+	bz := bytes.Join(r.chunks, nil)
+	if hash := sha256.Sum256(bz); !bytes.Equal(hash[:], r.snapshot.Hash) {
+		return 0, fmt.Errorf("%w: hash %X does not match %X", errInvalidSnapshot, hash, r.snapshot.Hash)
+	}
+	size, err := importState(db, bz)
+	if err != nil {
+		return 0, err
+	}
+	appHash, err := hashState(db)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(appHash, r.appHash) {
+		return 0, fmt.Errorf("%w: app hash %X does not match %X", errInvalidSnapshot, appHash, r.appHash)
+	}
+	return size, nil
+}
@@ -0,0 +1,373 @@
+package blocksync
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bhojpur/state/internal/libs/protoio"
+	sm "github.com/bhojpur/state/internal/state"
+	typespb "github.com/bhojpur/state/pkg/api/v1/types"
+	libytes "github.com/bhojpur/state/pkg/libs/bytes"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// A block archive is a directory holding a JSON manifest and a sequence of
+// chunk files. Each chunk file contains, for every height in its range, a
+// varint-delimited Block protobuf followed by a varint-delimited Commit
+// protobuf for that block. The manifest records the height range and the
+// SHA-256 hash of every chunk so an archive can be checked before use.
+
+const (
+	// ArchiveManifestFile is the name of the manifest within an archive directory.
+	ArchiveManifestFile = "manifest.json"
+
+	// ArchiveVersion is the version of the archive format written by ExportArchive.
+	ArchiveVersion = 1
+
+	// DefaultArchiveChunkSize is the default number of blocks stored per chunk.
+	DefaultArchiveChunkSize = 100
+
+	// maximum size of a single commit message in an archive chunk
+	maxArchiveCommitSize = 16 * 1024 * 1024
+)
+
+// ArchiveChunk describes a single chunk file of a block archive.
+type ArchiveChunk struct {
+	File        string           `json:"file"`
+	StartHeight int64            `json:"start_height,string"`
+	EndHeight   int64            `json:"end_height,string"`
+	Hash        libytes.HexBytes `json:"hash"`
+}
+
+// ArchiveManifest describes the contents of a block archive.
+type ArchiveManifest struct {
+	Version     uint32         `json:"version"`
+	ChainID     string         `json:"chain_id"`
+	StartHeight int64          `json:"start_height,string"`
+	EndHeight   int64          `json:"end_height,string"`
+	Chunks      []ArchiveChunk `json:"chunks"`
+}
+
+// ValidateBasic performs basic consistency checks on the manifest.
+func (m *ArchiveManifest) ValidateBasic() error {
+	if m.Version != ArchiveVersion {
+		return fmt.Errorf("unsupported archive version %d", m.Version)
+	}
+	if m.ChainID == "" {
+		return errors.New("archive has no chain ID")
+	}
+	if m.StartHeight <= 0 || m.EndHeight < m.StartHeight {
+		return fmt.Errorf("invalid archive height range %d-%d", m.StartHeight, m.EndHeight)
+	}
+	next := m.StartHeight
+	for i, c := range m.Chunks {
+		if c.StartHeight != next || c.EndHeight < c.StartHeight {
+			return fmt.Errorf("chunk %d has invalid height range %d-%d", i, c.StartHeight, c.EndHeight)
+		}
+		if filepath.Base(c.File) != c.File {
+			return fmt.Errorf("chunk %d has invalid file name %q", i, c.File)
+		}
+		if len(c.Hash) != sha256.Size {
+			return fmt.Errorf("chunk %d has invalid hash length %d", i, len(c.Hash))
+		}
+		next = c.EndHeight + 1
+	}
+	if next != m.EndHeight+1 {
+		return fmt.Errorf("chunks end at height %d, manifest ends at %d", next-1, m.EndHeight)
+	}
+	return nil
+}
+
+// ExportArchive writes the blocks in [startHeight, endHeight] together with
+// their commits from the block store into dir, which must not already contain
+// an archive. A zero startHeight or endHeight selects the base or the latest
+// height of the store respectively. The commit of the latest height is only
+// available if the store holds the seen commit for it; otherwise the export
+// stops one height earlier.
+func ExportArchive(
+	bs sm.BlockStore,
+	chainID string,
+	dir string,
+	startHeight, endHeight, chunkSize int64,
+) (*ArchiveManifest, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultArchiveChunkSize
+	}
+	if startHeight == 0 {
+		startHeight = bs.Base()
+	}
+	if endHeight == 0 {
+		endHeight = bs.Height()
+	}
+	if endHeight == bs.Height() {
+		if seen := bs.LoadSeenCommit(); seen == nil || seen.Height != endHeight {
+			endHeight--
+		}
+	}
+	if startHeight < bs.Base() || endHeight > bs.Height() || startHeight > endHeight || startHeight <= 0 {
+		return nil, fmt.Errorf("height range %d-%d not available (base %d, height %d)",
+			startHeight, endHeight, bs.Base(), bs.Height())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ArchiveManifestFile)); err == nil {
+		return nil, fmt.Errorf("archive already exists in %s", dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{
+		Version:     ArchiveVersion,
+		ChainID:     chainID,
+		StartHeight: startHeight,
+		EndHeight:   endHeight,
+	}
+	for start := startHeight; start <= endHeight; start += chunkSize {
+		end := start + chunkSize - 1
+		if end > endHeight {
+			end = endHeight
+		}
+		chunk, err := writeArchiveChunk(bs, dir, start, end)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+	}
+
+	bz, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ArchiveManifestFile), bz, 0600); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeArchiveChunk(bs sm.BlockStore, dir string, start, end int64) (ArchiveChunk, error) {
+	chunk := ArchiveChunk{
+		File:        fmt.Sprintf("blocks-%020d.bin", start),
+		StartHeight: start,
+		EndHeight:   end,
+	}
+
+	var buf bytes.Buffer
+	w := protoio.NewDelimitedWriter(&buf)
+	for height := start; height <= end; height++ {
+		block := bs.LoadBlock(height)
+		if block == nil {
+			return chunk, fmt.Errorf("block at height %d not found", height)
+		}
+		commit := bs.LoadBlockCommit(height)
+		if commit == nil {
+			if seen := bs.LoadSeenCommit(); seen != nil && seen.Height == height {
+				commit = seen
+			} else {
+				return chunk, fmt.Errorf("commit for height %d not found", height)
+			}
+		}
+
+		pb, err := block.ToProto()
+		if err != nil {
+			return chunk, fmt.Errorf("converting block %d to proto: %w", height, err)
+		}
+		if _, err := w.WriteMsg(pb); err != nil {
+			return chunk, err
+		}
+		if _, err := w.WriteMsg(commit.ToProto()); err != nil {
+			return chunk, err
+		}
+	}
+
+	hash := sha256.Sum256(buf.Bytes())
+	chunk.Hash = hash[:]
+	if err := os.WriteFile(filepath.Join(dir, chunk.File), buf.Bytes(), 0600); err != nil {
+		return chunk, err
+	}
+	return chunk, nil
+}
+
+// LoadArchiveManifest reads and validates the manifest of the archive in dir.
+// If dir holds no archive, the returned error satisfies os.IsNotExist.
+func LoadArchiveManifest(dir string) (*ArchiveManifest, error) {
+	bz, err := os.ReadFile(filepath.Join(dir, ArchiveManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &ArchiveManifest{}
+	if err := json.Unmarshal(bz, manifest); err != nil {
+		return nil, fmt.Errorf("decoding archive manifest: %w", err)
+	}
+	if err := manifest.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// ArchiveReader reads blocks and their commits sequentially from a block
+// archive. Every chunk is checked against the hash in the manifest before
+// any block in it is returned, and every commit is checked to refer to the
+// block it accompanies. Commit signatures are not verified; that requires
+// the validator set and is done by the caller.
+type ArchiveReader struct {
+	dir      string
+	manifest *ArchiveManifest
+
+	height   int64
+	chunkIdx int
+	chunk    protoio.ReadCloser
+	prevHash []byte
+}
+
+// NewArchiveReader opens the archive in dir and positions the reader at
+// fromHeight, which must lie within the archive's height range.
+func NewArchiveReader(dir string, fromHeight int64) (*ArchiveReader, error) {
+	manifest, err := LoadArchiveManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if fromHeight < manifest.StartHeight || fromHeight > manifest.EndHeight {
+		return nil, fmt.Errorf("height %d is outside of archive range %d-%d",
+			fromHeight, manifest.StartHeight, manifest.EndHeight)
+	}
+
+	r := &ArchiveReader{
+		dir:      dir,
+		manifest: manifest,
+		height:   fromHeight,
+		chunkIdx: -1,
+	}
+	return r, nil
+}
+
+// Manifest returns the manifest of the archive.
+func (r *ArchiveReader) Manifest() *ArchiveManifest {
+	return r.manifest
+}
+
+// Next returns the next block and its commit. It returns io.EOF once the end
+// of the archive has been reached.
+func (r *ArchiveReader) Next() (*types.Block, *types.Commit, error) {
+	if r.height > r.manifest.EndHeight {
+		return nil, nil, io.EOF
+	}
+	if r.chunkIdx < 0 || r.height > r.manifest.Chunks[r.chunkIdx].EndHeight {
+		if err := r.openChunk(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for {
+		block, commit, err := r.readEntry()
+		if err != nil {
+			return nil, nil, err
+		}
+		if block.Height < r.height {
+			// skip entries preceding the requested start height
+			continue
+		}
+		if block.Height != r.height {
+			return nil, nil, fmt.Errorf("expected block at height %d, got %d", r.height, block.Height)
+		}
+		if block.ChainID != r.manifest.ChainID {
+			return nil, nil, fmt.Errorf("block %d has chain ID %q, archive is for %q",
+				block.Height, block.ChainID, r.manifest.ChainID)
+		}
+		hash := block.Hash()
+		if commit.Height != block.Height || !bytes.Equal(commit.BlockID.Hash, hash) {
+			return nil, nil, fmt.Errorf("commit for height %d does not match block %X", block.Height, hash)
+		}
+		if r.prevHash != nil && !bytes.Equal(block.LastBlockID.Hash, r.prevHash) {
+			return nil, nil, fmt.Errorf("block %d does not link to the previous block %X", block.Height, r.prevHash)
+		}
+
+		r.prevHash = hash
+		r.height++
+		return block, commit, nil
+	}
+}
+
+// Close releases the resources held by the reader.
+func (r *ArchiveReader) Close() error {
+	if r.chunk == nil {
+		return nil
+	}
+	return r.chunk.Close()
+}
+
+func (r *ArchiveReader) openChunk() error {
+	if err := r.Close(); err != nil {
+		return err
+	}
+	for i, c := range r.manifest.Chunks {
+		if r.height < c.StartHeight || r.height > c.EndHeight {
+			continue
+		}
+
+		bz, err := os.ReadFile(filepath.Join(r.dir, c.File))
+		if err != nil {
+			return err
+		}
+		if hash := sha256.Sum256(bz); !bytes.Equal(hash[:], c.Hash) {
+			return fmt.Errorf("chunk %s hash mismatch: expected %X, got %X", c.File, c.Hash, hash[:])
+		}
+		r.chunkIdx = i
+		r.chunk = protoio.NewDelimitedReader(bufio.NewReader(bytes.NewReader(bz)), types.MaxBlockSizeBytes+maxArchiveCommitSize)
+		return nil
+	}
+	return fmt.Errorf("no chunk contains height %d", r.height)
+}
+
+func (r *ArchiveReader) readEntry() (*types.Block, *types.Commit, error) {
+	pbb := new(typespb.Block)
+	if _, err := r.chunk.ReadMsg(pbb); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, fmt.Errorf("reading block at height %d: %w", r.height, err)
+	}
+	block, err := types.BlockFromProto(pbb)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding block at height %d: %w", r.height, err)
+	}
+
+	pbc := new(typespb.Commit)
+	if _, err := r.chunk.ReadMsg(pbc); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, fmt.Errorf("reading commit at height %d: %w", block.Height, err)
+	}
+	commit, err := types.CommitFromProto(pbc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding commit at height %d: %w", block.Height, err)
+	}
+	return block, commit, nil
+}
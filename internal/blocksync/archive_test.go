@@ -0,0 +1,119 @@
+package blocksync
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/internal/test/factory"
+	"github.com/bhojpur/state/pkg/config"
+)
+
+func TestArchive_ExportAndRead(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := config.ResetTestRoot(t.TempDir(), "block_sync_archive_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 1, 30)
+	genDoc := factory.GenesisDoc(cfg, time.Now(), valSet.Validators, factory.ConsensusParams())
+	maxBlockHeight := int64(20)
+
+	rts := setup(ctx, t, genDoc, privVals[0], []int64{maxBlockHeight})
+	bs := rts.reactors[rts.nodes[0]].store
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	manifest, err := ExportArchive(bs, genDoc.ChainID, dir, 0, 0, 7)
+	require.NoError(t, err)
+
+	// the seen commit of the latest block is not stored, so it is left out
+	require.EqualValues(t, 1, manifest.StartHeight)
+	require.Equal(t, maxBlockHeight-1, manifest.EndHeight)
+	require.Len(t, manifest.Chunks, 3)
+
+	// exporting into an existing archive must fail
+	_, err = ExportArchive(bs, genDoc.ChainID, dir, 0, 0, 7)
+	require.Error(t, err)
+
+	reader, err := NewArchiveReader(dir, 5)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	for height := int64(5); height <= manifest.EndHeight; height++ {
+		block, commit, err := reader.Next()
+		require.NoError(t, err)
+		require.Equal(t, height, block.Height)
+		require.Equal(t, bs.LoadBlock(height).Hash(), block.Hash())
+		require.Equal(t, height, commit.Height)
+	}
+	_, _, err = reader.Next()
+	require.True(t, errors.Is(err, io.EOF))
+
+	_, err = NewArchiveReader(dir, maxBlockHeight)
+	require.Error(t, err)
+}
+
+func TestArchive_CorruptChunk(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := config.ResetTestRoot(t.TempDir(), "block_sync_archive_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 1, 30)
+	genDoc := factory.GenesisDoc(cfg, time.Now(), valSet.Validators, factory.ConsensusParams())
+
+	rts := setup(ctx, t, genDoc, privVals[0], []int64{10})
+	bs := rts.reactors[rts.nodes[0]].store
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	manifest, err := ExportArchive(bs, genDoc.ChainID, dir, 0, 0, 5)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, manifest.Chunks[1].File)
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	bz[len(bz)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, bz, 0600))
+
+	reader, err := NewArchiveReader(dir, 1)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	for height := int64(1); height <= manifest.Chunks[0].EndHeight; height++ {
+		_, _, err := reader.Next()
+		require.NoError(t, err)
+	}
+	_, _, err = reader.Next()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hash mismatch")
+}
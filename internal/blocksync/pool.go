@@ -136,6 +136,16 @@ func NewBlockPool(
 	return bp
 }
 
+// SetStartHeight sets the height the pool starts requesting blocks from. It
+// must be called before the pool is started.
+func (pool *BlockPool) SetStartHeight(height int64) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	pool.height = height
+	pool.startHeight = height
+}
+
 // OnStart implements service.Service by spawning requesters routine and recording
 // pool's start time.
 func (pool *BlockPool) OnStart(ctx context.Context) error {
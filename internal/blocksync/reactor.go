@@ -24,6 +24,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"runtime/debug"
 	"sync/atomic"
 	"time"
@@ -110,9 +112,21 @@ type Reactor struct {
 	metrics  *consensus.Metrics
 	eventBus *eventbus.EventBus
 
+	// directory of a block archive to replay before syncing from peers
+	archiveDir string
+
 	syncStartTime time.Time
 }
 
+// ReactorOption sets an optional parameter on the Reactor.
+type ReactorOption func(*Reactor)
+
+// WithArchive makes the reactor replay blocks from the archive in dir, if
+// one exists, before it starts requesting blocks from peers.
+func WithArchive(dir string) ReactorOption {
+	return func(r *Reactor) { r.archiveDir = dir }
+}
+
 // NewReactor returns new reactor instance.
 func NewReactor(
 	logger log.Logger,
@@ -125,6 +139,7 @@ func NewReactor(
 	blockSync bool,
 	metrics *consensus.Metrics,
 	eventBus *eventbus.EventBus,
+	options ...ReactorOption,
 ) *Reactor {
 	r := &Reactor{
 		logger:      logger,
@@ -139,6 +154,10 @@ func NewReactor(
 		eventBus:    eventBus,
 	}
 
+	for _, opt := range options {
+		opt(r)
+	}
+
 	r.BaseService = *service.NewBaseService(logger, "BlockSync", r)
 	return r
 }
@@ -179,12 +198,16 @@ func (r *Reactor) OnStart(ctx context.Context) error {
 	r.errorsCh = errorsCh
 
 	if r.blockSync.IsSet() {
-		if err := r.pool.Start(ctx); err != nil {
-			return err
-		}
-		go r.requestRoutine(ctx, blockSyncCh)
+		if r.archiveDir != "" {
+			go r.archiveRoutine(ctx, blockSyncCh)
+		} else {
+			if err := r.pool.Start(ctx); err != nil {
+				return err
+			}
+			go r.requestRoutine(ctx, blockSyncCh)
 
-		go r.poolRoutine(ctx, false, blockSyncCh)
+			go r.poolRoutine(ctx, false, blockSyncCh)
+		}
 	}
 
 	go r.processBlockSyncCh(ctx, blockSyncCh)
@@ -389,6 +412,111 @@ func (r *Reactor) SwitchToBlockSync(ctx context.Context, state sm.State) error {
 	return nil
 }
 
+// archiveRoutine replays blocks from the configured block archive and then
+// hands over to the pool to continue syncing from peers.
+func (r *Reactor) archiveRoutine(ctx context.Context, blockSyncCh *p2p.Channel) {
+	state, err := r.syncFromArchive(ctx, r.initialState)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.As(err, &sm.ErrUpgradeHalt{}) {
+			r.logger.Info("halting for upgrade; restart the node with the upgraded binary", "err", err)
+			return
+		}
+		// Blocks applied before the error are kept, so syncing from peers
+		// resumes from the last verified height.
+		r.logger.Error("failed to sync from block archive", "dir", r.archiveDir, "err", err)
+	}
+
+	r.initialState = state
+	r.pool.SetStartHeight(state.LastBlockHeight + 1)
+
+	if err := r.pool.Start(ctx); err != nil {
+		r.logger.Error("failed to start block pool", "err", err)
+		return
+	}
+
+	go r.requestRoutine(ctx, blockSyncCh)
+	go r.poolRoutine(ctx, false, blockSyncCh)
+}
+
+// syncFromArchive applies the blocks in the block archive that follow the
+// given state. Every commit is verified against the validator set of the
+// state it applies to before the block is saved and executed. It returns the
+// latest state reached, which is also valid when an error is returned.
+func (r *Reactor) syncFromArchive(ctx context.Context, state sm.State) (sm.State, error) {
+	manifest, err := LoadArchiveManifest(r.archiveDir)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+
+	if manifest.ChainID != state.ChainID {
+		return state, fmt.Errorf("archive is for chain %q, expected %q", manifest.ChainID, state.ChainID)
+	}
+
+	height := state.LastBlockHeight + 1
+	if state.LastBlockHeight == 0 {
+		height = state.InitialHeight
+	}
+	if height < manifest.StartHeight || height > manifest.EndHeight {
+		r.logger.Info("block archive does not cover the next height, skipping",
+			"height", height,
+			"archive_start", manifest.StartHeight,
+			"archive_end", manifest.EndHeight)
+		return state, nil
+	}
+
+	reader, err := NewArchiveReader(r.archiveDir, height)
+	if err != nil {
+		return state, err
+	}
+	defer reader.Close()
+
+	r.logger.Info("syncing from block archive", "dir", r.archiveDir, "from", height, "to", manifest.EndHeight)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		block, commit, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return state, err
+		}
+
+		parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+		if err != nil {
+			return state, fmt.Errorf("failed to make part set for block %d: %w", block.Height, err)
+		}
+		blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+
+		if err := state.Validators.VerifyCommitLight(state.ChainID, blockID, block.Height, commit); err != nil {
+			return state, fmt.Errorf("invalid commit for block %d: %w", block.Height, err)
+		}
+
+		r.store.SaveBlock(block, parts, commit)
+
+		state, err = r.blockExec.ApplyBlock(ctx, state, blockID, block)
+		if errors.As(err, &sm.ErrUpgradeHalt{}) {
+			return state, err
+		} else if err != nil {
+			// The block is saved but not applied; the handshake replays it
+			// on restart, as it does after a crash during block sync.
+			panic(fmt.Sprintf("failed to process committed block (%d:%X): %v", block.Height, block.Hash(), err))
+		}
+
+		r.metrics.RecordConsMetrics(block)
+	}
+
+	r.logger.Info("finished syncing from block archive", "height", state.LastBlockHeight)
+	return state, nil
+}
+
 func (r *Reactor) requestRoutine(ctx context.Context, blockSyncCh *p2p.Channel) {
 	statusUpdateTicker := time.NewTicker(statusUpdateIntervalSeconds * time.Second)
 	defer statusUpdateTicker.Stop()
@@ -576,7 +704,12 @@ func (r *Reactor) poolRoutine(ctx context.Context, stateSynced bool, blockSyncCh
 				// TODO: Same thing for app - but we would need a way to get the hash
 				// without persisting the state.
 				state, err = r.blockExec.ApplyBlock(ctx, state, firstID, first)
-				if err != nil {
+				if errors.As(err, &sm.ErrUpgradeHalt{}) {
+					// Neither keep syncing nor switch to consensus: the
+					// upgraded binary takes over from here.
+					r.logger.Info("halting for upgrade; restart the node with the upgraded binary", "err", err)
+					return
+				} else if err != nil {
 					// TODO: This is bad, are we zombie?
 					panic(fmt.Sprintf("failed to process committed block (%d:%X): %v", first.Height, first.Hash(), err))
 				}
@@ -23,6 +23,7 @@ package blocksync
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -70,6 +71,7 @@ func setup(
 	genDoc *types.GenesisDoc,
 	privVal types.PrivValidator,
 	maxBlockHeights []int64,
+	options ...ReactorOption,
 ) *reactorTestSuite {
 	t.Helper()
 
@@ -97,7 +99,7 @@ func setup(
 
 	i := 0
 	for nodeID := range rts.network.Nodes {
-		rts.addNode(ctx, t, nodeID, genDoc, privVal, maxBlockHeights[i])
+		rts.addNode(ctx, t, nodeID, genDoc, privVal, maxBlockHeights[i], options...)
 		i++
 	}
 
@@ -124,6 +126,7 @@ func (rts *reactorTestSuite) addNode(
 	genDoc *types.GenesisDoc,
 	privVal types.PrivValidator,
 	maxBlockHeight int64,
+	options ...ReactorOption,
 ) {
 	t.Helper()
 
@@ -220,6 +223,7 @@ func (rts *reactorTestSuite) addNode(
 		rts.blockSync,
 		consensus.NopMetrics(),
 		nil, // eventbus, can be nil
+		options...,
 	)
 
 	require.NoError(t, rts.reactors[nodeID].Start(ctx))
@@ -275,6 +279,142 @@ func TestReactor_AbruptDisconnect(t *testing.T) {
 	rts.network.Nodes[rts.nodes[1]].PeerManager.Disconnected(ctx, rts.nodes[0])
 }
 
+func TestReactor_SyncFromArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := config.ResetTestRoot(t.TempDir(), "block_sync_reactor_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 1, 30)
+	genDoc := factory.GenesisDoc(cfg, time.Now(), valSet.Validators, factory.ConsensusParams())
+	maxBlockHeight := int64(30)
+
+	rts := setup(ctx, t, genDoc, privVals[0], []int64{maxBlockHeight, 0})
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	manifest, err := ExportArchive(rts.reactors[rts.nodes[0]].store, genDoc.ChainID, dir, 0, 0, 8)
+	require.NoError(t, err)
+
+	r := rts.reactors[rts.nodes[1]]
+	r.archiveDir = dir
+
+	state, err := r.syncFromArchive(ctx, r.initialState)
+	require.NoError(t, err)
+	require.Equal(t, manifest.EndHeight, state.LastBlockHeight)
+	require.Equal(t, manifest.EndHeight, r.store.Height())
+
+	// replaying again is a no-op since the archive ends at the current height
+	state, err = r.syncFromArchive(ctx, state)
+	require.NoError(t, err)
+	require.Equal(t, manifest.EndHeight, state.LastBlockHeight)
+}
+
+// tamperingBlockStore returns the block and commit at height as changed by
+// tamper.
+type tamperingBlockStore struct {
+	sm.BlockStore
+	height int64
+	tamper func(*types.Block, *types.Commit)
+}
+
+func (bs *tamperingBlockStore) LoadBlock(height int64) *types.Block {
+	block := bs.BlockStore.LoadBlock(height)
+	if height == bs.height {
+		bs.tamper(block, bs.BlockStore.LoadBlockCommit(height))
+	}
+	return block
+}
+
+func (bs *tamperingBlockStore) LoadBlockCommit(height int64) *types.Commit {
+	commit := bs.BlockStore.LoadBlockCommit(height)
+	if height == bs.height {
+		bs.tamper(bs.BlockStore.LoadBlock(height), commit)
+	}
+	return commit
+}
+
+func TestReactor_SyncFromTamperedArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := config.ResetTestRoot(t.TempDir(), "block_sync_reactor_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 1, 30)
+	genDoc := factory.GenesisDoc(cfg, time.Now(), valSet.Validators, factory.ConsensusParams())
+
+	const tamperedHeight = 5
+	testCases := map[string]func(*types.Block, *types.Commit){
+		"bad signature": func(_ *types.Block, commit *types.Commit) {
+			commit.Signatures[0].Signature[0] ^= 0xff
+		},
+		"tampered block": func(block *types.Block, commit *types.Commit) {
+			// the commit refers to the tampered block, but was not signed
+			// for it
+			block.AppHash = []byte("tampered")
+			parts, err := block.MakePartSet(types.BlockPartSizeBytes)
+			require.NoError(t, err)
+			commit.BlockID = types.BlockID{Hash: block.Hash(), PartSetHeader: parts.Header()}
+		},
+	}
+	for name, tamper := range testCases {
+		tamper := tamper
+		t.Run(name, func(t *testing.T) {
+			rts := setup(ctx, t, genDoc, privVals[0], []int64{20, 0})
+
+			bs := &tamperingBlockStore{
+				BlockStore: rts.reactors[rts.nodes[0]].store,
+				height:     tamperedHeight,
+				tamper:     tamper,
+			}
+			dir := filepath.Join(t.TempDir(), "archive")
+			_, err := ExportArchive(bs, genDoc.ChainID, dir, 0, 0, 8)
+			require.NoError(t, err)
+
+			r := rts.reactors[rts.nodes[1]]
+			r.archiveDir = dir
+
+			// the blocks preceding the tampered one are applied
+			state, err := r.syncFromArchive(ctx, r.initialState)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid commit for block 5")
+			require.EqualValues(t, tamperedHeight-1, state.LastBlockHeight)
+			require.EqualValues(t, tamperedHeight-1, r.store.Height())
+		})
+	}
+}
+
+func TestReactor_StartFromArchive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := config.ResetTestRoot(t.TempDir(), "block_sync_reactor_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	valSet, privVals := factory.ValidatorSet(ctx, t, 1, 30)
+	genDoc := factory.GenesisDoc(cfg, time.Now(), valSet.Validators, factory.ConsensusParams())
+
+	src := setup(ctx, t, genDoc, privVals[0], []int64{20})
+	dir := filepath.Join(t.TempDir(), "archive")
+	manifest, err := ExportArchive(src.reactors[src.nodes[0]].store, genDoc.ChainID, dir, 0, 0, 8)
+	require.NoError(t, err)
+
+	// a node started with the archive replays it, and then requests the
+	// following blocks from peers
+	rts := setup(ctx, t, genDoc, privVals[0], []int64{0}, WithArchive(dir))
+	r := rts.reactors[rts.nodes[0]]
+
+	require.Eventually(t, func() bool {
+		height, _, _ := r.pool.GetStatus()
+		return r.pool.IsRunning() && height == manifest.EndHeight+1
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, manifest.EndHeight, r.store.Height())
+}
+
 func TestReactor_SyncTime(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
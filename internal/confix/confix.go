@@ -0,0 +1,182 @@
+package confix
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// It applies fixes to a Bhojpur State TOML configuration file to update a file
+// created with an older version of Bhojpur State to a compatible format for a
+// newer version, and checks configuration files against the layout of the
+// current version.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/creachadair/tomledit"
+	"github.com/creachadair/tomledit/transform"
+	"github.com/spf13/viper"
+
+	"github.com/bhojpur/state/pkg/config"
+)
+
+// ApplyFixes transforms doc and reports whether it succeeded.
+func ApplyFixes(ctx context.Context, doc *tomledit.Document) error {
+	// Check what version of Bhojpur State might have created this config file, as
+	// a safety check for the updates we are about to make.
+	bhojpurVersion := GuessConfigVersion(doc)
+	if bhojpurVersion == vUnknown {
+		return errors.New("cannot tell what Bhojpur State version created this config")
+	} else if bhojpurVersion < v34 || bhojpurVersion > v36 {
+		// TODO: Add in rewrites for older versions.  This will
+		// require some digging to discover what the changes were.  The upgrade
+		// instructions do not give specifics.
+		return fmt.Errorf("unable to update version %s config", bhojpurVersion)
+	}
+	return plan.Apply(ctx, doc)
+}
+
+// Migrate applies the fixes to the config document in data, and returns the
+// updated document. Each applied step is logged to the log writer of ctx, if
+// any, as set by transform.WithLogWriter. The updated document is checked
+// with CheckValid.
+func Migrate(ctx context.Context, data []byte) ([]byte, error) {
+	doc, err := tomledit.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	if err := ApplyFixes(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tomledit.Format(&buf, doc); err != nil {
+		return nil, fmt.Errorf("formatting config: %w", err)
+	}
+
+	// Verify that Bhojpur State can parse the results after our edits.
+	if err := CheckValid(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("updated config is invalid: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CheckConfig checks the config document in data against schema, and returns
+// the problems it finds along with the document in the current layout. A
+// document with the layout of an older version is migrated in memory first,
+// as by Migrate, so that settings renamed or removed since that version are
+// not reported. Problems are reported at the lines of their keys in data.
+// Documents older than the earliest layout Migrate knows are checked as they
+// are, so settings renamed since their version are reported as unknown: there
+// are no schemas of earlier versions to check them against.
+func CheckConfig(ctx context.Context, schema *Schema, data []byte) ([]Problem, []byte, error) {
+	doc, err := tomledit.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing config: %w", err)
+	}
+	if version := GuessConfigVersion(doc); version != CurrentVersion && version != vUnknown {
+		// The fixes keep the entries of doc, and with them their lines.
+		if err := ApplyFixes(ctx, doc); err != nil {
+			return nil, nil, err
+		}
+		var buf bytes.Buffer
+		if err := tomledit.Format(&buf, doc); err != nil {
+			return nil, nil, fmt.Errorf("formatting config: %w", err)
+		}
+		data = buf.Bytes()
+	}
+	return schema.Check(doc), data, nil
+}
+
+// LoadConfig loads and parses the TOML document from path.
+func LoadConfig(path string) (*tomledit.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return tomledit.Parse(f)
+}
+
+const (
+	vUnknown = ""
+	v32      = "v0.32"
+	v33      = "v0.33"
+	v34      = "v0.34"
+	v35      = "v0.35"
+	v36      = "v0.36"
+)
+
+// CurrentVersion is the version of the config layout written by this version
+// of Bhojpur State, as reported by GuessConfigVersion.
+const CurrentVersion = v36
+
+// GuessConfigVersion attempts to figure out which version of Bhojpur State
+// created the specified config document. It returns "" if the creating version
+// cannot be determined, otherwise a string of the form "vX.YY".
+func GuessConfigVersion(doc *tomledit.Document) string {
+	hasDisableWS := doc.First("rpc", "experimental-disable-websocket") != nil
+	hasUseLegacy := doc.First("p2p", "use-legacy") != nil // v0.35 only
+	if hasDisableWS && !hasUseLegacy {
+		return v36
+	}
+
+	hasBlockSync := transform.FindTable(doc, "blocksync") != nil // add: v0.35
+	hasStateSync := transform.FindTable(doc, "statesync") != nil // add: v0.34
+	if hasBlockSync && hasStateSync {
+		return v35
+	} else if hasStateSync {
+		return v34
+	}
+
+	hasIndexKeys := doc.First("tx_index", "index_keys") != nil // add: v0.33
+	hasIndexTags := doc.First("tx_index", "index_tags") != nil // rem: v0.33
+	if hasIndexKeys && !hasIndexTags {
+		return v33
+	}
+
+	hasFastSync := transform.FindTable(doc, "fastsync") != nil // add: v0.32
+	if hasIndexTags && hasFastSync {
+		return v32
+	}
+
+	// Something older, probably.
+	return vUnknown
+}
+
+// CheckValid checks whether the specified config appears to be a valid
+// Bhojpur State config file. This emulates how the node loads the config.
+func CheckValid(data []byte) error {
+	v := viper.New()
+	v.SetConfigType("toml")
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	cfg := config.DefaultConfig()
+	if err := v.Unmarshal(cfg); err != nil {
+		return fmt.Errorf("decoding config: %w", err)
+	}
+
+	return cfg.ValidateBasic()
+}
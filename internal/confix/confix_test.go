@@ -1,4 +1,4 @@
-package main_test
+package confix_test
 
 // Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
 
@@ -29,7 +29,7 @@ import (
 	"github.com/creachadair/tomledit"
 	"github.com/google/go-cmp/cmp"
 
-	confix "github.com/bhojpur/state/scripts/confix"
+	"github.com/bhojpur/state/internal/confix"
 )
 
 func mustParseConfig(t *testing.T, path string) *tomledit.Document {
@@ -117,3 +117,39 @@ func TestApplyFixes(t *testing.T) {
 		})
 	})
 }
+
+func TestCheckConfig(t *testing.T) {
+	schema := mustSchema(t)
+
+	// A v0.34 config file, with settings renamed since and a misspelled one.
+	data := []byte(`moniker = "node"
+
+[p2p]
+laddr = "tcp://0.0.0.0:26656"
+persistent_peerz = ""
+
+[statesync]
+enable = false
+chunk_fetchers = "4"
+
+[tx_index]
+indexer = "kv"
+`)
+	problems, migrated, err := confix.CheckConfig(context.Background(), schema, data)
+	if err != nil {
+		t.Fatalf("CheckConfig: unexpected error: %v", err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`line 5: unknown setting "p2p.persistent-peerz", did you mean p2p.persistent-peers?`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong problems: (-want, +got)\n%s", diff)
+	}
+	if !strings.Contains(string(migrated), `fetchers = "4"`) {
+		t.Errorf("Config was not migrated:\n%s", migrated)
+	}
+}
@@ -0,0 +1,122 @@
+package confix
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/tomledit"
+	"github.com/creachadair/tomledit/parser"
+	"github.com/creachadair/tomledit/transform"
+)
+
+func allKeys(s *tomledit.Section) []string {
+	var keys []string
+	s.Scan(func(key parser.Key, _ *tomledit.Entry) bool {
+		keys = append(keys, key.String())
+		return true
+	})
+	return keys
+}
+
+const (
+	delSection = "-S"
+	delMapping = "-M"
+	addSection = "+S"
+	addMapping = "+M"
+
+	delMapSep = "\n" + delMapping + " "
+	addMapSep = "\n" + addMapping + " "
+)
+
+// DiffKeys writes a keyspace diff of the TOML documents lhs and rhs to w,
+// with one line per key that differs:
+//
+//	-S name    -- section exists in lhs but not rhs
+//	+S name    -- section exists in rhs but not lhs
+//	-M name    -- mapping exists in lhs but not rhs
+//	+M name    -- mapping exists in rhs but not lhs
+//
+// Comments, order, and values are ignored for comparison purposes. The
+// sections of both documents are sorted by name.
+func DiffKeys(w io.Writer, lhs, rhs *tomledit.Document) {
+	diffSections(w, lhs.Global, rhs.Global)
+	lsec, rsec := lhs.Sections, rhs.Sections
+	transform.SortSectionsByName(lsec)
+	transform.SortSectionsByName(rsec)
+
+	i, j := 0, 0
+	for i < len(lsec) && j < len(rsec) {
+		if lsec[i].Name.Before(rsec[j].Name) {
+			fmt.Fprintln(w, delSection, lsec[i].Name)
+			fmt.Fprintln(w, delMapping, strings.Join(allKeys(lsec[i]), delMapSep))
+			i++
+		} else if rsec[j].Name.Before(lsec[i].Name) {
+			fmt.Fprintln(w, addSection, rsec[j].Name)
+			fmt.Fprintln(w, addMapping, strings.Join(allKeys(rsec[j]), addMapSep))
+			j++
+		} else {
+			diffSections(w, lsec[i], rsec[j])
+			i++
+			j++
+		}
+	}
+	for ; i < len(lsec); i++ {
+		fmt.Fprintln(w, delSection, lsec[i].Name)
+		fmt.Fprintln(w, delMapping, strings.Join(allKeys(lsec[i]), delMapSep))
+	}
+	for ; j < len(rsec); j++ {
+		fmt.Fprintln(w, addSection, rsec[j].Name)
+		fmt.Fprintln(w, addMapping, strings.Join(allKeys(rsec[j]), addMapSep))
+	}
+}
+
+func diffSections(w io.Writer, lhs, rhs *tomledit.Section) {
+	diffKeys(w, allKeys(lhs), allKeys(rhs))
+}
+
+func diffKeys(w io.Writer, lhs, rhs []string) {
+	sort.Strings(lhs)
+	sort.Strings(rhs)
+
+	i, j := 0, 0
+	for i < len(lhs) && j < len(rhs) {
+		if lhs[i] < rhs[j] {
+			fmt.Fprintln(w, delMapping, lhs[i])
+			i++
+		} else if lhs[i] > rhs[j] {
+			fmt.Fprintln(w, addMapping, rhs[j])
+			j++
+		} else {
+			i++
+			j++
+		}
+	}
+	for ; i < len(lhs); i++ {
+		fmt.Fprintln(w, delMapping, lhs[i])
+	}
+	for ; j < len(rhs); j++ {
+		fmt.Fprintln(w, addMapping, rhs[j])
+	}
+}
@@ -0,0 +1,117 @@
+package confix
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/creachadair/tomledit"
+	"github.com/creachadair/tomledit/parser"
+	"github.com/creachadair/tomledit/transform"
+)
+
+// UnknownKeyError is returned for a key that is not part of a schema.
+type UnknownKeyError struct {
+	Key string
+
+	// Suggestions are the known keys that Key may be a misspelling of, best
+	// first.
+	Suggestions []string
+}
+
+func (e UnknownKeyError) Error() string {
+	msg := fmt.Sprintf("unknown setting %q", e.Key)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(e.Suggestions, " or "))
+	}
+	return msg
+}
+
+// Get returns the setting with the given key, and its value in doc in TOML
+// syntax. If doc does not set it, the value is the default and ok is false.
+func (s *Schema) Get(doc *tomledit.Document, key string) (setting *Setting, value string, ok bool, err error) {
+	setting, found := s.Lookup(key)
+	if !found {
+		return nil, "", false, UnknownKeyError{Key: key, Suggestions: s.Suggest(key)}
+	}
+	k, err := parser.ParseKey(key)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if e := doc.First(k...); e != nil && e.IsMapping() {
+		return setting, e.Value.String(), true, nil
+	}
+	return setting, setting.Default, false, nil
+}
+
+// Set sets the setting with the given key in doc to value, which is in TOML
+// syntax, except that the quotes of string settings may be omitted. If doc
+// does not set it yet, the setting is added with its description, creating
+// its table if need be. Set does not check whether the value is valid for the
+// setting, which CheckValid does for the whole document.
+func (s *Schema) Set(doc *tomledit.Document, key, value string) error {
+	setting, found := s.Lookup(key)
+	if !found {
+		return UnknownKeyError{Key: key, Suggestions: s.Suggest(key)}
+	}
+	k, err := parser.ParseKey(key)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(setting.Default, `"`) && !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+		value = strconv.Quote(value)
+	}
+	v, err := parser.ParseValue(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	if e := doc.First(k...); e != nil {
+		if !e.IsMapping() {
+			return fmt.Errorf("%s is a table", key)
+		}
+		v.Trailer = e.Value.Trailer
+		e.Value = v
+		return nil
+	}
+
+	table, name := k[:len(k)-1], k[len(k)-1:]
+	tab := transform.FindTable(doc, table...)
+	if tab == nil {
+		sec := &tomledit.Section{}
+		if len(table) == 0 {
+			doc.Global = sec
+		} else {
+			sec.Heading = &parser.Heading{Name: table}
+			doc.Sections = append(doc.Sections, sec)
+		}
+		tab = &tomledit.Entry{Section: sec}
+	}
+	var block parser.Comments
+	if setting.Doc != "" {
+		block = parser.Comments{setting.Doc}
+	}
+	transform.InsertMapping(tab.Section, &parser.KeyValue{Block: block, Name: name, Value: v}, false)
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package confix
 
 // Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
 
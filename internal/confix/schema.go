@@ -0,0 +1,328 @@
+package confix
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/creachadair/tomledit"
+	"github.com/creachadair/tomledit/parser"
+
+	"github.com/bhojpur/state/pkg/config"
+)
+
+// maxSuggestions is the max number of suggestions for an unknown key.
+const maxSuggestions = 3
+
+// familyTable is the table of the rocksdb settings of individual databases,
+// which has a table of the same settings per database.
+var familyTable = parser.Key{"rocksdb", "families"}
+
+// familyPlaceholder stands for the database name in the keys of familyTable.
+const familyPlaceholder = "<db>"
+
+// A Setting is a key of the config file layout of a schema.
+type Setting struct {
+	Key     string // the full key, such as "mempool.size"
+	Default string // the default value, in TOML syntax
+	Doc     string // the description of the setting in the default config file
+}
+
+// A Schema lists the settings of a config file layout.
+type Schema struct {
+	settings map[string]*Setting
+	keys     []string        // sorted keys of settings
+	tables   map[string]bool // table names
+	hidden   map[string]bool // keys accepted by the node, but not documented
+}
+
+// CurrentSchema returns the schema of the config file layout of the current
+// version, as written by "statectl init". Settings the node still reads but
+// which are not in the default config file, such as deprecated ones, are
+// accepted by Check but are not listed.
+//
+// There is no schema of the layouts of earlier versions. A config file of an
+// earlier version can only be checked against this schema once migrated, see
+// CheckConfig, so settings that its migration drops are not reported, and
+// files older than the earliest version Migrate knows are checked unmigrated.
+func CurrentSchema() (*Schema, error) {
+	cfg := config.DefaultConfig()
+	cfg.RocksDB.Families = map[string]*config.RocksDBFamilyConfig{
+		familyPlaceholder: {
+			Compression:     "snappy",
+			BloomFilterBits: 0,
+			BlockCacheShare: 0,
+		},
+	}
+	doc, err := renderConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSchema(doc)
+	for _, key := range structKeys(reflect.TypeOf(config.Config{}), "") {
+		if _, ok := s.settings[key]; !ok {
+			s.hidden[key] = true
+		}
+	}
+	return s, nil
+}
+
+// DefaultConfig returns the default config file of the current version.
+func DefaultConfig() (*tomledit.Document, error) {
+	return renderConfig(config.DefaultConfig())
+}
+
+func renderConfig(cfg *config.Config) (*tomledit.Document, error) {
+	var buf bytes.Buffer
+	if err := cfg.RenderTemplate(&buf); err != nil {
+		return nil, err
+	}
+	// The placeholder is not a valid bare key, so quote it.
+	rendered := strings.ReplaceAll(buf.String(), "."+familyPlaceholder+"]", `."`+familyPlaceholder+`"]`)
+	doc, err := tomledit.Parse(strings.NewReader(rendered))
+	if err != nil {
+		return nil, fmt.Errorf("parsing default config: %w", err)
+	}
+	return doc, nil
+}
+
+// NewSchema returns the schema of the settings in doc, which is expected to
+// contain every setting with its default value.
+func NewSchema(doc *tomledit.Document) *Schema {
+	s := &Schema{
+		settings: make(map[string]*Setting),
+		tables:   make(map[string]bool),
+		hidden:   make(map[string]bool),
+	}
+	doc.Scan(func(key parser.Key, e *tomledit.Entry) bool {
+		if e.IsSection() {
+			s.tables[key.String()] = true
+			return true
+		}
+		s.settings[key.String()] = &Setting{
+			Key:     key.String(),
+			Default: e.Value.String(),
+			Doc:     commentText(e.Block),
+		}
+		s.keys = append(s.keys, key.String())
+		return true
+	})
+	sort.Strings(s.keys)
+	return s
+}
+
+// Keys returns the keys of the settings of s, in order.
+func (s *Schema) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Lookup returns the setting with the given key. Keys of the settings of
+// individual rocksdb databases, such as "rocksdb.families.blockstore.compression",
+// return the setting that applies to every database.
+func (s *Schema) Lookup(key string) (*Setting, bool) {
+	k, err := parser.ParseKey(key)
+	if err != nil {
+		return nil, false
+	}
+	setting, ok := s.settings[s.normalize(k).String()]
+	return setting, ok
+}
+
+// normalize replaces the database name in the keys of familyTable with the
+// placeholder used by the schema.
+func (s *Schema) normalize(key parser.Key) parser.Key {
+	if len(key) > len(familyTable) && familyTable.IsPrefixOf(key) {
+		norm := append(parser.Key{}, key...)
+		norm[len(familyTable)] = familyPlaceholder
+		return norm
+	}
+	return key
+}
+
+// A Problem is a key of a config file that is not part of its schema.
+type Problem struct {
+	Key  string
+	Line int // the line of the key in the config file, 1-based
+
+	// Table is true if Key is the name of a table.
+	Table bool
+
+	// Suggestions are the known keys that Key may be a misspelling of, best
+	// first.
+	Suggestions []string
+}
+
+func (p Problem) String() string {
+	kind := "setting"
+	if p.Table {
+		kind = "table"
+	}
+	msg := fmt.Sprintf("line %d: unknown %s %q", p.Line, kind, p.Key)
+	if len(p.Suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(p.Suggestions, " or "))
+	}
+	return msg
+}
+
+// Check returns the keys and tables of doc that are not part of s, in the
+// order they appear in doc.
+func (s *Schema) Check(doc *tomledit.Document) []Problem {
+	var problems []Problem
+	doc.Scan(func(key parser.Key, e *tomledit.Entry) bool {
+		norm := s.normalize(key).String()
+		if e.IsSection() {
+			if !s.tables[norm] && !familyTable.Equals(key) {
+				problems = append(problems, Problem{
+					Key:         key.String(),
+					Line:        e.Heading.Line,
+					Table:       true,
+					Suggestions: s.suggest(key.String(), s.tableNames()),
+				})
+			}
+			return true
+		}
+		if _, ok := s.settings[norm]; ok || s.hidden[norm] {
+			return true
+		}
+		problems = append(problems, Problem{
+			Key:         key.String(),
+			Line:        e.KeyValue.Line,
+			Suggestions: s.Suggest(key.String()),
+		})
+		return true
+	})
+	return problems
+}
+
+// Suggest returns the keys of s that key may be a misspelling of, best
+// first. Keys in snake_case are matched to their kebab-case equivalents, and
+// settings placed in the wrong table are matched by their name.
+func (s *Schema) Suggest(key string) []string {
+	return s.suggest(key, s.keys)
+}
+
+func (s *Schema) tableNames() []string {
+	names := make([]string, 0, len(s.tables))
+	for name := range s.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Schema) suggest(key string, candidates []string) []string {
+	type suggestion struct {
+		key  string
+		dist int
+	}
+
+	norm := strings.ReplaceAll(key, "_", "-")
+	name := lastComponent(norm)
+	maxDist := len(norm)/4 + 1
+
+	var found []suggestion
+	for _, candidate := range candidates {
+		dist := editDistance(norm, candidate)
+		if dist > maxDist && lastComponent(candidate) != name {
+			continue
+		}
+		found = append(found, suggestion{candidate, dist})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+
+	var keys []string
+	for i := 0; i < len(found) && i < maxSuggestions; i++ {
+		keys = append(keys, found[i].key)
+	}
+	return keys
+}
+
+func lastComponent(key string) string {
+	return key[strings.LastIndexByte(key, '.')+1:]
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// commentText returns the text of a block comment, without comment markers.
+func commentText(block parser.Comments) string {
+	lines := block.Clean()
+	for i, line := range lines {
+		line = strings.TrimPrefix(line, "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// structKeys returns the keys of the settings the node decodes into a value
+// of type t, which must be a struct, by their mapstructure tags.
+func structKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		name := strings.Split(tag, ",")[0]
+		switch {
+		case strings.Contains(tag, ",squash"):
+			keys = append(keys, structKeys(field.Type, prefix)...)
+		case name == "" || name == "-":
+			// Skip untagged fields and the remainder of unknown settings.
+		case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct:
+			keys = append(keys, structKeys(field.Type.Elem(), prefix+name+".")...)
+		default:
+			keys = append(keys, prefix+name)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,140 @@
+package confix_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/tomledit"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bhojpur/state/internal/confix"
+)
+
+func mustParse(t *testing.T, text string) *tomledit.Document {
+	t.Helper()
+	doc, err := tomledit.Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Parsing config: %v", err)
+	}
+	return doc
+}
+
+func mustSchema(t *testing.T) *confix.Schema {
+	t.Helper()
+	schema, err := confix.CurrentSchema()
+	if err != nil {
+		t.Fatalf("CurrentSchema: %v", err)
+	}
+	return schema
+}
+
+func TestSchemaCheck(t *testing.T) {
+	schema := mustSchema(t)
+
+	doc := mustParse(t, `moniker = "node"
+size = 10
+
+[mempool]
+sise = 10
+
+[p2p]
+max_connections = 10
+
+[consensus]
+timeout-propose = "3s"
+
+[rocksdb.families.tx_index]
+compression = "zstd"
+bloom-filter-bits = 10
+
+[mempol]
+`)
+	var got []string
+	for _, p := range schema.Check(doc) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`line 2: unknown setting "size", did you mean mempool.size?`,
+		`line 5: unknown setting "mempool.sise", did you mean mempool.size?`,
+		`line 8: unknown setting "p2p.max_connections", did you mean p2p.max-connections?`,
+		`line 17: unknown table "mempol", did you mean mempool?`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Wrong problems: (-want, +got)\n%s", diff)
+	}
+}
+
+func TestSchemaGetSet(t *testing.T) {
+	schema := mustSchema(t)
+	doc := mustParse(t, `moniker = "node"
+
+[mempool]
+# the mempool size
+size = 10 # transactions
+`)
+
+	setting, value, ok, err := schema.Get(doc, "mempool.size")
+	if err != nil || !ok || value != "10" || setting.Doc == "" {
+		t.Errorf("Get mempool.size: got %q, %v, %v", value, ok, err)
+	}
+	setting, value, ok, err = schema.Get(doc, "mempool.recheck")
+	if err != nil || ok || value != setting.Default {
+		t.Errorf("Get mempool.recheck: got %q, %v, %v", value, ok, err)
+	}
+	if _, _, _, err := schema.Get(doc, "mempool.sise"); err == nil {
+		t.Error("Get mempool.sise: got no error for an unknown key")
+	}
+
+	for _, kv := range [][2]string{
+		{"moniker", "other"},
+		{"mempool.size", "20"},
+		{"p2p.max-connections", "32"},
+		{"rocksdb.families.tx_index.compression", `"zstd"`},
+	} {
+		if err := schema.Set(doc, kv[0], kv[1]); err != nil {
+			t.Fatalf("Set %s: %v", kv[0], err)
+		}
+	}
+	err = schema.Set(doc, "p2p.max-conections", "32")
+	if ukerr, ok := err.(confix.UnknownKeyError); !ok || len(ukerr.Suggestions) == 0 {
+		t.Errorf("Set p2p.max-conections: got %v, want an unknown key error with suggestions", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tomledit.Format(&buf, doc); err != nil {
+		t.Fatalf("Formatting document: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`moniker = "other"`,
+		"# the mempool size\nsize = 20  # transactions",
+		"[p2p]",
+		"max-connections = 32",
+		"[rocksdb.families.tx_index]\ncompression = \"zstd\"",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -237,6 +238,113 @@ LOOP:
 	}
 }
 
+// voteExtensionsApp is a kvstore application that enables vote extensions
+// through a consensus param update returned by FinalizeBlock at height 1. It
+// records the heights at which it is asked to extend a vote.
+type voteExtensionsApp struct {
+	*kvstore.Application
+	enableHeight int64
+
+	mtx             sync.Mutex
+	extendedHeights []int64
+}
+
+func (app *voteExtensionsApp) FinalizeBlock(ctx context.Context, req *abci.RequestFinalizeBlock) (*abci.ResponseFinalizeBlock, error) {
+	res, err := app.Application.FinalizeBlock(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Height == 1 {
+		res.ConsensusParamUpdates = &v1.ConsensusParams{
+			Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: app.enableHeight},
+		}
+	}
+	return res, nil
+}
+
+func (app *voteExtensionsApp) ExtendVote(_ context.Context, req *abci.RequestExtendVote) (*abci.ResponseExtendVote, error) {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	app.extendedHeights = append(app.extendedHeights, req.Height)
+	return &abci.ResponseExtendVote{VoteExtension: []byte("extension")}, nil
+}
+
+func (app *voteExtensionsApp) VerifyVoteExtension(_ context.Context, req *abci.RequestVerifyVoteExtension) (*abci.ResponseVerifyVoteExtension, error) {
+	if req.Height < app.enableHeight || !bytes.Equal(req.VoteExtension, []byte("extension")) {
+		return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_REJECT}, nil
+	}
+	return &abci.ResponseVerifyVoteExtension{Status: abci.ResponseVerifyVoteExtension_ACCEPT}, nil
+}
+
+func (app *voteExtensionsApp) ExtendedHeights() []int64 {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	return append([]int64{}, app.extendedHeights...)
+}
+
+// TestWALReplayAcrossVoteExtensionsEnableHeight commits blocks across the
+// height at which vote extensions get enabled by a FinalizeBlock consensus
+// param update, restarts the node from its stores and WAL, and checks that it
+// keeps committing blocks with vote extensions enabled.
+func TestWALReplayAcrossVoteExtensionsEnableHeight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const enableHeight = 3
+
+	cfg, err := ResetConfig(t.TempDir(), "vote_extensions_replay")
+	require.NoError(t, err)
+	logger := log.NewNopLogger()
+
+	app := &voteExtensionsApp{Application: kvstore.NewApplication(), enableHeight: enableHeight}
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+
+	runUntil := func(state sm.State, height int64) sm.State {
+		cs := newStateWithConfigAndBlockStore(ctx, t, logger, cfg, state, loadPrivValidator(t, cfg), app, blockStore)
+		newBlockSub, err := cs.eventBus.SubscribeWithArgs(ctx, pubsub.SubscribeArgs{
+			ClientID: testSubscriber,
+			Query:    types.EventQueryNewBlock,
+		})
+		require.NoError(t, err)
+		require.NoError(t, cs.Start(ctx))
+
+		ctxto, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+		for {
+			msg, err := newBlockSub.Next(ctxto)
+			require.NoError(t, err, "waiting for block %d", height)
+			if msg.Data().(types.EventDataNewBlock).Block.Height >= height {
+				break
+			}
+		}
+		cs.Stop()
+		cs.Wait()
+		return cs.GetState()
+	}
+
+	genesisState, err := sm.MakeGenesisStateFromFile(cfg.GenesisFile())
+	require.NoError(t, err)
+	require.False(t, genesisState.ConsensusParams.ABCI.VoteExtensionsEnabled(genesisState.InitialHeight))
+
+	state := runUntil(genesisState, enableHeight+1)
+	require.EqualValues(t, enableHeight, state.ConsensusParams.ABCI.VoteExtensionsEnableHeight)
+
+	extended := app.ExtendedHeights()
+	require.NotEmpty(t, extended)
+	require.Contains(t, extended, int64(enableHeight))
+	for _, h := range extended {
+		require.GreaterOrEqual(t, h, int64(enableHeight), "vote extended before the enable height")
+	}
+
+	// The WAL holds the extended precommits of the height in progress. Replaying
+	// it on restart must feed them into a vote set that expects extensions.
+	lastHeight := state.LastBlockHeight
+	state = runUntil(state, lastHeight+2)
+	require.GreaterOrEqual(t, state.LastBlockHeight, lastHeight+2)
+	require.EqualValues(t, enableHeight, state.ConsensusParams.ABCI.VoteExtensionsEnableHeight)
+	require.Contains(t, app.ExtendedHeights(), lastHeight+2)
+}
+
 // crashingWAL is a WAL which crashes or rather simulates a crash during Save
 // (before and after). It remembers a message for which we last panicked
 // (lastPanickedForMsgIndex), so we don't panic for it in subsequent iterations.
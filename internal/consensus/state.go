@@ -30,6 +30,7 @@ import (
 	"os"
 	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -49,6 +50,7 @@ import (
 	bos "github.com/bhojpur/state/pkg/libs/os"
 	"github.com/bhojpur/state/pkg/libs/service"
 	libtime "github.com/bhojpur/state/pkg/libs/time"
+	"github.com/bhojpur/state/pkg/libs/trace"
 	"github.com/bhojpur/state/pkg/privval"
 	privrpc "github.com/bhojpur/state/pkg/privval/grpc"
 	"github.com/bhojpur/state/pkg/types"
@@ -199,8 +201,17 @@ type State struct {
 	// for reporting metrics
 	metrics *Metrics
 
+	// for tracing heights and their steps; spans are only kept when a
+	// tracer is set
+	tracer     *trace.Tracer
+	heightSpan *trace.Span
+	stepSpan   *trace.Span
+
 	// wait the channel event happening for shutting down the state gracefully
 	onStopCh chan *cstypes.RoundState
+
+	// set once the block at the height of a scheduled upgrade is committed
+	upgradeHalt *sm.ErrUpgradeHalt
 }
 
 // StateOption sets an optional parameter on the State.
@@ -295,6 +306,12 @@ func StateMetrics(metrics *Metrics) StateOption {
 	return func(cs *State) { cs.metrics = metrics }
 }
 
+// StateTracer sets the tracer used to record a span for each height and
+// each consensus step within it.
+func StateTracer(tracer *trace.Tracer) StateOption {
+	return func(cs *State) { cs.tracer = tracer }
+}
+
 // String returns a string.
 func (cs *State) String() string {
 	// better not to access shared variables
@@ -673,11 +690,39 @@ func (cs *State) updateRoundStep(round int32, step cstypes.RoundStepType) {
 		if cs.Step != step {
 			cs.metrics.MarkStep(cs.Step)
 		}
+		if cs.Step != step || cs.Round != round {
+			cs.traceStep(round, step)
+		}
 	}
 	cs.Round = round
 	cs.Step = step
 }
 
+// traceStep ends the span of the current step and starts one for the next,
+// as a child of the span covering the whole height. A new height span is
+// started when the step moves to RoundStepNewHeight.
+func (cs *State) traceStep(round int32, step cstypes.RoundStepType) {
+	if cs.tracer == nil {
+		return
+	}
+	cs.stepSpan.End()
+	if step == cstypes.RoundStepNewHeight || cs.heightSpan == nil {
+		cs.heightSpan.End()
+		_, cs.heightSpan = cs.tracer.Start(context.Background(), "consensus.height", "height", cs.Height)
+	}
+	_, cs.stepSpan = trace.StartSpan(trace.ContextWithSpan(context.Background(), cs.heightSpan),
+		"consensus."+strings.TrimPrefix(step.String(), "RoundStep"), "height", cs.Height, "round", round)
+}
+
+// traceContext returns ctx carrying the span of the current step, so calls
+// made to the application during the step are recorded as its children.
+func (cs *State) traceContext(ctx context.Context) context.Context {
+	if cs.stepSpan == nil {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, cs.stepSpan)
+}
+
 // enterNewRound(height, 0) at cs.StartTime.
 func (cs *State) scheduleRound0(rs *cstypes.RoundState) {
 	// cs.logger.Info("scheduleRound0", "now", libtime.Now(), "startTime", cs.StartTime)
@@ -832,7 +877,11 @@ func (cs *State) updateToState(state sm.State) {
 	cs.ValidRound = -1
 	cs.ValidBlock = nil
 	cs.ValidBlockParts = nil
-	cs.Votes = cstypes.NewHeightVoteSet(state.ChainID, height, validators)
+	if state.ConsensusParams.ABCI.VoteExtensionsEnabled(height) {
+		cs.Votes = cstypes.NewExtendedHeightVoteSet(state.ChainID, height, validators)
+	} else {
+		cs.Votes = cstypes.NewHeightVoteSet(state.ChainID, height, validators)
+	}
 	cs.CommitRound = -1
 	cs.LastValidators = state.LastValidators
 	cs.TriggeredTimeoutPrecommit = false
@@ -931,6 +980,12 @@ func (cs *State) receiveRoutine(ctx context.Context, maxSteps int) {
 			}
 		}
 
+		if cs.upgradeHalt != nil {
+			// Nothing may be executed past the upgrade height by this binary.
+			onExit(cs)
+			return
+		}
+
 		select {
 		case <-cs.txNotifier.TxsAvailable():
 			cs.handleTxsAvailable(ctx)
@@ -1440,7 +1495,7 @@ func (cs *State) createProposalBlock(ctx context.Context) (*types.Block, error)
 
 	proposerAddr := cs.privValidatorPubKey.Address()
 
-	ret, err := cs.blockExec.CreateProposalBlock(ctx, cs.Height, cs.state, commit, proposerAddr, cs.LastCommit.GetVotes())
+	ret, err := cs.blockExec.CreateProposalBlock(cs.traceContext(ctx), cs.Height, cs.state, commit, proposerAddr, cs.LastCommit.GetVotes())
 	if err != nil {
 		panic(err)
 	}
@@ -1542,7 +1597,7 @@ func (cs *State) defaultDoPrevote(ctx context.Context, height int64, round int32
 		liveness properties. Please see PrepareProposal-ProcessProposal coherence and determinism
 		properties in the ABCI++ specification.
 	*/
-	isAppValid, err := cs.blockExec.ProcessProposal(ctx, cs.ProposalBlock, cs.state)
+	isAppValid, err := cs.blockExec.ProcessProposal(cs.traceContext(ctx), cs.ProposalBlock, cs.state)
 	if err != nil {
 		panic(fmt.Sprintf("ProcessProposal: %v", err))
 	}
@@ -1971,7 +2026,7 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 
 	// Execute and commit the block, update and save the state, and update the mempool.
 	// NOTE The block.AppHash wont reflect these txs until the next block.
-	stateCopy, err := cs.blockExec.ApplyBlock(ctx,
+	stateCopy, err := cs.blockExec.ApplyBlock(cs.traceContext(ctx),
 		stateCopy,
 		types.BlockID{
 			Hash:          block.Hash(),
@@ -1979,7 +2034,17 @@ func (cs *State) finalizeCommit(ctx context.Context, height int64) {
 		},
 		block,
 	)
-	if err != nil {
+	var upgradeHalt sm.ErrUpgradeHalt
+	if errors.As(err, &upgradeHalt) {
+		// The block is committed; move to the next height so that peers and
+		// RPC clients see the final state, but don't start a new round.
+		cs.RecordMetrics(height, block)
+		cs.updateToState(stateCopy)
+		cs.upgradeHalt = &upgradeHalt
+		logger.Info("halting for upgrade; restart the node with the upgraded binary",
+			"upgrade", upgradeHalt.Name)
+		return
+	} else if err != nil {
 		logger.Error("failed to apply block", "err", err)
 		return
 	}
@@ -2347,9 +2412,9 @@ func (cs *State) addVote(
 		return
 	}
 
-	// Verify VoteExtension if precommit
-	if vote.Type == v1.PrecommitType {
-		if err = cs.blockExec.VerifyVoteExtension(ctx, vote); err != nil {
+	// Verify VoteExtension if precommit and vote extensions are enabled at this height.
+	if vote.Type == v1.PrecommitType && cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(vote.Height) {
+		if err = cs.blockExec.VerifyVoteExtension(cs.traceContext(ctx), vote); err != nil {
 			return false, err
 		}
 	}
@@ -2496,14 +2561,18 @@ func (cs *State) signVote(
 	// use our local precommit Timeout as the max wait time for getting a singed commit. The same goes for prevote.
 	timeout := cs.voteTimeout(cs.Round)
 
+	extEnabled := cs.state.ConsensusParams.ABCI.VoteExtensionsEnabled(vote.Height)
 	switch msgType {
 	case v1.PrecommitType:
-		// if the signedMessage type is for a precommit, add VoteExtension
-		ext, err := cs.blockExec.ExtendVote(ctx, vote)
-		if err != nil {
-			return nil, err
+		// if the signedMessage type is for a precommit and vote extensions
+		// are enabled at this height, add VoteExtension
+		if extEnabled {
+			ext, err := cs.blockExec.ExtendVote(cs.traceContext(ctx), vote)
+			if err != nil {
+				return nil, err
+			}
+			vote.Extension = ext
 		}
-		vote.Extension = ext
 	default:
 		timeout = time.Second
 	}
@@ -2515,7 +2584,9 @@ func (cs *State) signVote(
 
 	err := cs.privValidator.SignVote(ctxto, cs.state.ChainID, v)
 	vote.Signature = v.Signature
-	vote.ExtensionSignature = v.ExtensionSignature
+	if extEnabled {
+		vote.ExtensionSignature = v.ExtensionSignature
+	}
 	vote.Timestamp = v.Timestamp
 
 	return vote, err
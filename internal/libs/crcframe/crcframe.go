@@ -0,0 +1,78 @@
+package crcframe
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Write writes value to w as a frame, in a single write. It fails if value is
+// longer than maxSize.
+//
+// Frames use the format of the consensus WAL: 4 bytes CRC32c sum of the value
+// + 4 bytes length of the value + value, so that a frame cut short or
+// corrupted by a crash is detected when it's read back.
+func Write(w io.Writer, value []byte, maxSize int) error {
+	if len(value) > maxSize {
+		return fmt.Errorf("value is too big: %d bytes, max: %d bytes", len(value), maxSize)
+	}
+	frame := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint32(frame[0:4], crc32.Checksum(value, crc32c))
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(value)))
+	copy(frame[8:], value)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// Read reads the value of the next frame from r, and checks its checksum.
+// Values shorter than minSize or longer than maxSize are rejected. It returns
+// io.EOF at the end of the stream, and io.ErrUnexpectedEOF if the stream ends
+// within a frame.
+func Read(r io.Reader, minSize, maxSize int) ([]byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	crc := binary.BigEndian.Uint32(header[0:4])
+	length := binary.BigEndian.Uint32(header[4:8])
+	if uint64(length) > uint64(maxSize) || uint64(length) < uint64(minSize) {
+		return nil, fmt.Errorf("invalid value length %d", length)
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if actual := crc32.Checksum(value, crc32c); actual != crc {
+		return nil, fmt.Errorf("checksums do not match: read: %v, actual: %v", crc, actual)
+	}
+	return value, nil
+}
@@ -0,0 +1,72 @@
+package crcframe
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	values := [][]byte{[]byte("first"), {}, []byte("third")}
+	for _, value := range values {
+		require.NoError(t, Write(&buf, value, 16))
+	}
+	require.Error(t, Write(&buf, make([]byte, 17), 16))
+	full := buf.Len()
+
+	r := bytes.NewReader(buf.Bytes())
+	for _, want := range values {
+		value, err := Read(r, 0, 16)
+		require.NoError(t, err)
+		assert.Equal(t, want, value)
+	}
+	_, err := Read(r, 0, 16)
+	assert.Equal(t, io.EOF, err)
+
+	// values outside the size limits
+	r = bytes.NewReader(buf.Bytes())
+	_, err = Read(r, 0, 4)
+	assert.Error(t, err)
+	r = bytes.NewReader(buf.Bytes())
+	_, err = Read(r, 6, 16)
+	assert.Error(t, err)
+
+	// a frame cut short by a crash
+	r = bytes.NewReader(buf.Bytes()[:full-2])
+	for i := 0; i < 2; i++ {
+		_, err = Read(r, 0, 16)
+		require.NoError(t, err)
+	}
+	_, err = Read(r, 0, 16)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// a corrupted frame
+	corrupted := append([]byte{}, buf.Bytes()...)
+	corrupted[9] ^= 0xff
+	_, err = Read(bytes.NewReader(corrupted), 0, 16)
+	assert.Error(t, err)
+}
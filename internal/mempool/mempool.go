@@ -176,6 +176,16 @@ func (txmp *TxMempool) Unlock() {
 	txmp.mtx.Unlock()
 }
 
+// SetConfig replaces the configuration of the mempool with cfg. Lowering the
+// limits does not remove transactions already in the mempool, they only apply
+// to transactions added afterwards. The size of the cache is not changed. It
+// is thread-safe.
+func (txmp *TxMempool) SetConfig(cfg *config.MempoolConfig) {
+	txmp.mtx.Lock()
+	defer txmp.mtx.Unlock()
+	txmp.config = cfg
+}
+
 // Size returns the number of valid transactions in the mempool. It is
 // thread-safe.
 func (txmp *TxMempool) Size() int {
@@ -411,6 +411,15 @@ func TestTxMempool_CheckTxExceedsMaxSize(t *testing.T) {
 	require.NoError(t, err)
 
 	require.NoError(t, txmp.CheckTx(ctx, tx, nil, TxInfo{SenderID: 0}))
+
+	// Lowering the limit applies to transactions checked afterwards.
+	cfg := *txmp.config
+	cfg.MaxTxBytes = len(tx) - 1
+	txmp.SetConfig(&cfg)
+
+	_, err = rng.Read(tx)
+	require.NoError(t, err)
+	require.Error(t, txmp.CheckTx(ctx, tx, nil, TxInfo{SenderID: 0}))
 }
 
 func TestTxMempool_CheckTxSamePeer(t *testing.T) {
@@ -0,0 +1,197 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	p2pproto "github.com/bhojpur/state/pkg/api/v1/p2p"
+	"github.com/bhojpur/state/pkg/crypto"
+	"github.com/bhojpur/state/pkg/crypto/encoding"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+const (
+	// maxRecordAddresses is the maximum number of addresses in a record.
+	maxRecordAddresses = 16
+
+	// addressRecordDomain separates the sign bytes of address records from
+	// other messages signed with node keys.
+	addressRecordDomain = "bhojpur/state/p2p/address-record"
+)
+
+// AddressRecord is a record of the addresses of a node, signed with its node
+// key, so that addresses gossiped by peers can't be forged. A node replaces
+// its record by signing one with a higher sequence number, and a record is
+// only valid until it expires.
+type AddressRecord struct {
+	NodeID    types.NodeID
+	PubKey    crypto.PubKey
+	Addresses []NodeAddress
+	Sequence  uint64
+	Expires   time.Time
+	Signature []byte
+}
+
+// NewAddressRecord signs a record of the given addresses of the node with
+// privKey.
+func NewAddressRecord(
+	privKey crypto.PrivKey,
+	addresses []NodeAddress,
+	sequence uint64,
+	expires time.Time,
+) (*AddressRecord, error) {
+	r := &AddressRecord{
+		NodeID:    types.NodeIDFromPubKey(privKey.PubKey()),
+		PubKey:    privKey.PubKey(),
+		Addresses: addresses,
+		Sequence:  sequence,
+		Expires:   expires.UTC().Round(0),
+	}
+	sig, err := privKey.Sign(r.SignBytes())
+	if err != nil {
+		return nil, err
+	}
+	r.Signature = sig
+	return r, nil
+}
+
+// SignBytes returns the bytes signed by the node.
+func (r *AddressRecord) SignBytes() []byte {
+	buf := new(bytes.Buffer)
+	writeBytes := func(bz []byte) {
+		var n [binary.MaxVarintLen64]byte
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(len(bz)))])
+		buf.Write(bz)
+	}
+	writeUint := func(v uint64) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], v)
+		buf.Write(n[:])
+	}
+
+	writeBytes([]byte(addressRecordDomain))
+	writeBytes([]byte(r.NodeID))
+	writeUint(uint64(len(r.Addresses)))
+	for _, addr := range r.Addresses {
+		writeBytes([]byte(addr.String()))
+	}
+	writeUint(r.Sequence)
+	writeUint(uint64(r.Expires.UnixNano()))
+	return buf.Bytes()
+}
+
+// Verify checks that the record is well-formed, signed by the node it
+// describes, and not expired at now.
+func (r *AddressRecord) Verify(now time.Time) error {
+	if err := r.NodeID.Validate(); err != nil {
+		return fmt.Errorf("invalid node ID: %w", err)
+	}
+	if r.PubKey == nil {
+		return errors.New("no public key")
+	}
+	if id := types.NodeIDFromPubKey(r.PubKey); id != r.NodeID {
+		return fmt.Errorf("public key of node %v does not match node ID %v", id, r.NodeID)
+	}
+	if len(r.Addresses) == 0 || len(r.Addresses) > maxRecordAddresses {
+		return fmt.Errorf("record must have between 1 and %d addresses, got %d",
+			maxRecordAddresses, len(r.Addresses))
+	}
+	for _, addr := range r.Addresses {
+		if err := addr.Validate(); err != nil {
+			return fmt.Errorf("invalid address %v: %w", addr, err)
+		}
+		if addr.NodeID != r.NodeID {
+			return fmt.Errorf("address %v is not an address of node %v", addr, r.NodeID)
+		}
+	}
+	if !now.Before(r.Expires) {
+		return fmt.Errorf("record of node %v expired at %v", r.NodeID, r.Expires)
+	}
+	if !r.PubKey.VerifySignature(r.SignBytes(), r.Signature) {
+		return fmt.Errorf("invalid signature on record of node %v", r.NodeID)
+	}
+	return nil
+}
+
+// Expired returns true if the record has expired at now.
+func (r *AddressRecord) Expired(now time.Time) bool {
+	return !now.Before(r.Expires)
+}
+
+// ToProto converts the record to its Protobuf representation.
+func (r *AddressRecord) ToProto() (*p2pproto.AddressRecord, error) {
+	pk, err := encoding.PubKeyToProto(r.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	pb := &p2pproto.AddressRecord{
+		NodeId:    string(r.NodeID),
+		PubKey:    &pk,
+		Sequence:  r.Sequence,
+		Expires:   timestamppb.New(r.Expires),
+		Signature: r.Signature,
+	}
+	for _, addr := range r.Addresses {
+		pb.Addresses = append(pb.Addresses, addr.String())
+	}
+	return pb, nil
+}
+
+// AddressRecordFromProto converts a Protobuf address record. It does not
+// verify the record.
+func AddressRecordFromProto(pb *p2pproto.AddressRecord) (*AddressRecord, error) {
+	if pb == nil {
+		return nil, errors.New("nil address record")
+	}
+	if pb.PubKey == nil {
+		return nil, errors.New("address record has no public key")
+	}
+	pk, err := encoding.PubKeyFromProto(*pb.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(pb.Addresses) > maxRecordAddresses {
+		return nil, fmt.Errorf("address record has too many addresses (%d > maximum %d)",
+			len(pb.Addresses), maxRecordAddresses)
+	}
+	r := &AddressRecord{
+		NodeID:    types.NodeID(pb.NodeId),
+		PubKey:    pk,
+		Sequence:  pb.Sequence,
+		Expires:   pb.Expires.AsTime(),
+		Signature: pb.Signature,
+	}
+	for _, s := range pb.Addresses {
+		addr, err := ParseNodeAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", s, err)
+		}
+		r.Addresses = append(r.Addresses, addr)
+	}
+	return r, nil
+}
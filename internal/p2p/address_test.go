@@ -25,6 +25,7 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -406,3 +407,40 @@ func TestNodeAddress_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressRecord(t *testing.T) {
+	privKey := ed25519.GenPrivKey()
+	id := types.NodeIDFromPubKey(privKey.PubKey())
+	address := p2p.NodeAddress{Protocol: "tcp", NodeID: id, Hostname: "127.0.0.1", Port: 26656}
+	now := time.Now()
+
+	record, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{address}, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, record.Verify(now))
+
+	pb, err := record.ToProto()
+	require.NoError(t, err)
+	decoded, err := p2p.AddressRecordFromProto(pb)
+	require.NoError(t, err)
+	require.NoError(t, decoded.Verify(now))
+	require.Equal(t, record.Addresses, decoded.Addresses)
+
+	// expired
+	require.Error(t, record.Verify(now.Add(2*time.Hour)))
+
+	// addresses of another node
+	other := p2p.NodeAddress{Protocol: "tcp", NodeID: types.NodeID(strings.Repeat("a", 40)), Hostname: "127.0.0.1"}
+	foreign, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{other}, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Error(t, foreign.Verify(now))
+
+	// signed by another node
+	forged, err := p2p.NewAddressRecord(ed25519.GenPrivKey(), []p2p.NodeAddress{address}, 1, now.Add(time.Hour))
+	require.NoError(t, err)
+	forged.NodeID = id
+	require.Error(t, forged.Verify(now))
+
+	// tampered
+	decoded.Sequence++
+	require.Error(t, decoded.Verify(now))
+}
@@ -0,0 +1,325 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	auto "github.com/bhojpur/state/internal/libs/autofile"
+	"github.com/bhojpur/state/internal/libs/crcframe"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// maxCaptureRecordSize is the max size of an encoded capture record: a
+// message of the max size of the MConnection protocol, and its metadata.
+const maxCaptureRecordSize = 64*1024*1024 + 1024
+
+// CaptureRecord is an envelope captured by the router.
+type CaptureRecord struct {
+	Time      time.Time
+	ChannelID ChannelID
+	// Outbound is true for envelopes sent to the peer, false for those
+	// received from it.
+	Outbound bool
+	PeerID   types.NodeID
+
+	// WireType is the full protobuf name of the message type of the
+	// channel, which Message decodes into. MessageType is the name of the
+	// message it wraps, if the channel uses a Wrapper, and WireType
+	// otherwise.
+	WireType    string
+	MessageType string
+
+	// Message is the message as sent on the wire.
+	Message []byte
+}
+
+// envelopeCapture records the envelopes routed by the router into an
+// autofile group.
+type envelopeCapture struct {
+	logger log.Logger
+	cancel context.CancelFunc
+
+	mtx   sync.Mutex
+	timer *time.Timer
+	group *auto.Group
+	enc   *CaptureEncoder
+}
+
+// newEnvelopeCapture opens the autofile group with head at path, and starts
+// it. The group is rotated and pruned with the default autofile limits.
+func newEnvelopeCapture(logger log.Logger, path string) (*envelopeCapture, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group, err := auto.OpenGroup(ctx, logger, path)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := group.Start(ctx); err != nil {
+		cancel()
+		group.Close()
+		return nil, err
+	}
+	return &envelopeCapture{
+		logger: logger,
+		cancel: cancel,
+		group:  group,
+		enc:    NewCaptureEncoder(group),
+	}, nil
+}
+
+func (c *envelopeCapture) write(rec *CaptureRecord) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.group == nil {
+		return // closed
+	}
+	if err := c.enc.Encode(rec); err != nil {
+		c.logger.Error("failed to capture envelope", "peer", rec.PeerID, "channel", rec.ChannelID, "err", err)
+	}
+}
+
+// close flushes the captured envelopes to disk, and closes the group.
+func (c *envelopeCapture) close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.group == nil {
+		return nil
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	err := c.group.FlushAndSync()
+	c.group.Stop()
+	c.group.Close()
+	c.cancel()
+	c.group = nil
+	return err
+}
+
+// StartCapture starts recording the envelopes routed to and from peers into
+// the autofile group with head at path, replacing any running capture. The
+// capture stops after the given duration, or once StopCapture is called if
+// the duration is 0. Read the records back with a CaptureDecoder.
+func (r *Router) StartCapture(path string, duration time.Duration) error {
+	if duration < 0 {
+		return fmt.Errorf("capture duration can't be negative [%s]", duration)
+	}
+	capture, err := newEnvelopeCapture(r.logger, path)
+	if err != nil {
+		return err
+	}
+
+	// The capture is installed before the stop timer is armed, so that the
+	// timer always finds it.
+	r.captureMtx.Lock()
+	previous := r.capture
+	r.capture = capture
+	if duration > 0 {
+		capture.mtx.Lock()
+		capture.timer = time.AfterFunc(duration, func() {
+			if r.swapCapture(capture, nil) {
+				if err := capture.close(); err != nil {
+					r.logger.Error("failed to close envelope capture", "path", path, "err", err)
+				}
+				r.logger.Info("stopped capturing envelopes", "path", path)
+			}
+		})
+		capture.mtx.Unlock()
+	}
+	r.captureMtx.Unlock()
+
+	r.logger.Info("capturing envelopes", "path", path, "duration", duration)
+	if previous != nil {
+		return previous.close()
+	}
+	return nil
+}
+
+// StopCapture stops the running capture, if any.
+func (r *Router) StopCapture() error {
+	r.captureMtx.Lock()
+	capture := r.capture
+	r.capture = nil
+	r.captureMtx.Unlock()
+
+	if capture == nil {
+		return nil
+	}
+	r.logger.Info("stopped capturing envelopes")
+	return capture.close()
+}
+
+// swapCapture replaces the running capture with next if it is old, and
+// returns whether it did.
+func (r *Router) swapCapture(old, next *envelopeCapture) bool {
+	r.captureMtx.Lock()
+	defer r.captureMtx.Unlock()
+
+	if r.capture != old {
+		return false
+	}
+	r.capture = next
+	return true
+}
+
+// captureEnvelope records an envelope if a capture is running. wire is the
+// message as sent on the wire, and msg the message it wraps, or nil to
+// unwrap it as needed.
+func (r *Router) captureEnvelope(
+	outbound bool,
+	peerID types.NodeID,
+	chID ChannelID,
+	wire proto.Message,
+	msg proto.Message,
+	bz []byte,
+) {
+	r.captureMtx.RLock()
+	capture := r.capture
+	r.captureMtx.RUnlock()
+	if capture == nil {
+		return
+	}
+
+	if msg == nil {
+		msg = wire
+		if wrapper, ok := wire.(Wrapper); ok {
+			if inner, err := wrapper.Unwrap(); err == nil {
+				msg = inner
+			}
+		}
+	}
+	capture.write(&CaptureRecord{
+		Time:        time.Now(),
+		ChannelID:   chID,
+		Outbound:    outbound,
+		PeerID:      peerID,
+		WireType:    messageName(wire),
+		MessageType: messageName(msg),
+		Message:     bz,
+	})
+}
+
+// messageName returns the full protobuf name of a message, or its Go type if
+// it has no protobuf descriptor.
+func messageName(msg proto.Message) string {
+	if m, ok := msg.(protoreflect.ProtoMessage); ok {
+		return string(m.ProtoReflect().Descriptor().FullName())
+	}
+	return fmt.Sprintf("%T", msg)
+}
+
+// A CaptureEncoder writes capture records to an output stream.
+//
+// Each record is written as a checksummed frame (see crcframe.Write), whose
+// value is the time in Unix nanoseconds (8 bytes), the channel ID (2 bytes),
+// the direction (1 byte, 1 for outbound), the length-prefixed peer ID, wire
+// type and message type, and the message.
+type CaptureEncoder struct {
+	wr io.Writer
+}
+
+// NewCaptureEncoder returns a new encoder that writes to wr.
+func NewCaptureEncoder(wr io.Writer) *CaptureEncoder {
+	return &CaptureEncoder{wr}
+}
+
+// Encode writes rec to the stream in a single write.
+func (enc *CaptureEncoder) Encode(rec *CaptureRecord) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 11))
+	for _, s := range []string{string(rec.PeerID), rec.WireType, rec.MessageType} {
+		writeCaptureString(&buf, s)
+	}
+	buf.Write(rec.Message)
+
+	data := buf.Bytes()
+	binary.BigEndian.PutUint64(data[0:8], uint64(rec.Time.UnixNano()))
+	binary.BigEndian.PutUint16(data[8:10], uint16(rec.ChannelID))
+	if rec.Outbound {
+		data[10] = 1
+	}
+	return crcframe.Write(enc.wr, data, maxCaptureRecordSize)
+}
+
+func writeCaptureString(buf *bytes.Buffer, s string) {
+	var length [binary.MaxVarintLen64]byte
+	buf.Write(length[:binary.PutUvarint(length[:], uint64(len(s)))])
+	buf.WriteString(s)
+}
+
+// A CaptureDecoder reads capture records written by a CaptureEncoder from an
+// input stream, checking their checksums.
+type CaptureDecoder struct {
+	rd io.Reader
+}
+
+// NewCaptureDecoder returns a new decoder that reads from rd.
+func NewCaptureDecoder(rd io.Reader) *CaptureDecoder {
+	return &CaptureDecoder{rd}
+}
+
+// Decode reads the next record. It returns io.EOF at the end of the stream,
+// and io.ErrUnexpectedEOF if the stream ends within a record, as it may if
+// the capturing process crashed.
+func (dec *CaptureDecoder) Decode() (*CaptureRecord, error) {
+	data, err := crcframe.Read(dec.rd, 11, maxCaptureRecordSize)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &CaptureRecord{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(data[0:8]))),
+		ChannelID: ChannelID(binary.BigEndian.Uint16(data[8:10])),
+		Outbound:  data[10] == 1,
+	}
+	r := bytes.NewReader(data[11:])
+	var fields [3]string
+	for i := range fields {
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size > uint64(r.Len()) {
+			return nil, errors.New("invalid record field length")
+		}
+		field := make([]byte, size)
+		if _, err := io.ReadFull(r, field); err != nil {
+			return nil, err
+		}
+		fields[i] = string(field)
+	}
+	rec.PeerID = types.NodeID(fields[0])
+	rec.WireType = fields[1]
+	rec.MessageType = fields[2]
+	rec.Message = data[len(data)-r.Len():]
+	return rec, nil
+}
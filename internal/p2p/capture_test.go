@@ -0,0 +1,89 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureEncoderDecoder(t *testing.T) {
+	records := []*CaptureRecord{
+		{
+			Time:        time.Unix(0, 1600000000000000000),
+			ChannelID:   0x20,
+			Outbound:    true,
+			PeerID:      "00ff00ff00ff00ff00ff00ff00ff00ff00ff00ff",
+			WireType:    "v1.consensus.Message",
+			MessageType: "v1.consensus.NewRoundStep",
+			Message:     []byte{0x0a, 0x02, 0x08, 0x01},
+		},
+		{
+			Time:        time.Unix(0, 1600000000100000000),
+			ChannelID:   0x30,
+			PeerID:      "ff00ff00ff00ff00ff00ff00ff00ff00ff00ff00",
+			WireType:    "v1.mempool.Message",
+			MessageType: "v1.mempool.Txs",
+			Message:     []byte{},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewCaptureEncoder(&buf)
+	for _, rec := range records {
+		require.NoError(t, enc.Encode(rec))
+	}
+	full := buf.Len()
+
+	dec := NewCaptureDecoder(bytes.NewReader(buf.Bytes()))
+	for _, want := range records {
+		rec, err := dec.Decode()
+		require.NoError(t, err)
+		assert.True(t, want.Time.Equal(rec.Time))
+		assert.Equal(t, want.ChannelID, rec.ChannelID)
+		assert.Equal(t, want.Outbound, rec.Outbound)
+		assert.Equal(t, want.PeerID, rec.PeerID)
+		assert.Equal(t, want.WireType, rec.WireType)
+		assert.Equal(t, want.MessageType, rec.MessageType)
+		assert.Equal(t, want.Message, rec.Message)
+	}
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// a record cut short by a crash
+	dec = NewCaptureDecoder(bytes.NewReader(buf.Bytes()[:full-2]))
+	_, err = dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// a corrupted record
+	corrupted := append([]byte{}, buf.Bytes()...)
+	corrupted[10] ^= 0xff
+	dec = NewCaptureDecoder(bytes.NewReader(corrupted))
+	_, err = dec.Decode()
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package dht
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+/*
+Package dht discovers peers through a Kademlia-style distributed hash table
+keyed by node ID, as an optional complement to PEX.
+
+PEX spreads random samples of addresses, which converge slowly in large
+networks and can't find a particular node. The DHT reactor instead keeps a
+routing table of nodes sorted into buckets by the length of the prefix their
+ID shares with the local node ID. Every node thus knows many nodes close to
+it in the XOR metric and a few far away, and a FIND_NODE lookup gets closer to
+its target with each round of requests, finding any node in a logarithmic
+number of hops.
+
+Messages can only be sent to connected peers, so nodes discovered by a lookup
+are added to the peer manager, and the lookup goes on through them once the
+router has dialed them. Signed address records are shared along with the
+nodes when known, and take precedence over plain addresses as in PEX.
+*/
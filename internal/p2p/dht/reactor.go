@@ -0,0 +1,568 @@
+package dht
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/internal/p2p/conn"
+	protop2p "github.com/bhojpur/state/pkg/api/v1/p2p"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/libs/service"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+var (
+	_ service.Service = (*Reactor)(nil)
+	_ p2p.Wrapper     = (*protop2p.DhtMessage)(nil)
+
+	// ErrNodeNotFound is returned by FindNode when a lookup ends without
+	// finding the addresses of the node.
+	ErrNodeNotFound = errors.New("node not found")
+)
+
+const (
+	// DHTChannel is a channel for DHT messages
+	DHTChannel = 0x01
+
+	// over-estimate of the size of a peer in a response, with its address
+	// record or plain addresses
+	maxPeerSize = 4096
+
+	maxMsgSize = maxPeerSize * bucketSize
+
+	// the maximum number of addresses of a peer without a record in a
+	// response
+	maxPeerAddresses = 8
+
+	// the number of peers queried concurrently in each round of a lookup
+	// (alpha in Kademlia)
+	lookupConcurrency = 3
+
+	// how long to wait for a peer to respond to a request
+	requestTimeout = 5 * time.Second
+
+	// how long a lookup waits for the router to connect to discovered nodes
+	// closer to the target, when there are no more connected ones to query
+	lookupDialWait = 3 * time.Second
+
+	// the maximum number of requests a peer may send per second
+	maxRequestsPerSecond = 20
+
+	// how often the routing table is refreshed by a lookup of a random node
+	refreshInterval = 10 * time.Minute
+)
+
+// ChannelDescriptor returns the descriptor of the DHT channel.
+func ChannelDescriptor() *conn.ChannelDescriptor {
+	return &conn.ChannelDescriptor{
+		ID:                  DHTChannel,
+		MessageType:         new(protop2p.DhtMessage),
+		Priority:            1,
+		SendQueueCapacity:   10,
+		RecvMessageCapacity: maxMsgSize,
+		RecvBufferCapacity:  128,
+		Name:                "dht",
+	}
+}
+
+// The DHT reactor discovers peers through a Kademlia-style distributed hash
+// table over node IDs, alongside PEX. It keeps a routing table of known nodes
+// sorted by XOR distance to the local node ID, answers FIND_NODE requests
+// from it, and iteratively looks up nodes by querying the connected peers
+// closest to them. Discovered nodes are added to the peer manager, which lets
+// the router dial them, so that lookups can go on through them.
+//
+// Lookups of the local node ID when connecting to the first peer, and of
+// random IDs periodically, keep the routing table populated. FindNode finds a
+// particular node, e.g. to reconnect to a known validator.
+type Reactor struct {
+	service.BaseService
+	logger log.Logger
+
+	peerManager *p2p.PeerManager
+	chCreator   p2p.ChannelCreator
+	peerEvents  p2p.PeerEventSubscriber
+	table       *routingTable
+
+	mtx sync.Mutex
+
+	// channel is the DHT channel, set on start.
+	channel *p2p.Channel
+
+	// pending tracks the requests sent by lookups, by request ID, so that
+	// responses are delivered to the lookups waiting for them.
+	pending       map[uint64]*pendingRequest
+	nextRequestID uint64
+
+	// connected is closed and replaced whenever a peer connects, waking up
+	// the lookups waiting for discovered nodes to be dialed.
+	connected chan struct{}
+
+	// bootstrapped is set once the first lookup of the local node ID ran.
+	bootstrapped bool
+
+	// receivedRequests counts the requests received from each peer in the
+	// current second, to rate limit them (as defined by
+	// maxRequestsPerSecond).
+	receivedRequests map[types.NodeID]*requestCount
+}
+
+type pendingRequest struct {
+	peerID   types.NodeID
+	response chan *protop2p.DhtFindNodeResponse
+}
+
+type requestCount struct {
+	since time.Time
+	count int
+}
+
+// NewReactor returns a reference to a new reactor.
+func NewReactor(
+	logger log.Logger,
+	peerManager *p2p.PeerManager,
+	channelCreator p2p.ChannelCreator,
+	peerEvents p2p.PeerEventSubscriber,
+) (*Reactor, error) {
+	table, err := newRoutingTable(peerManager.SelfID())
+	if err != nil {
+		return nil, fmt.Errorf("invalid node ID: %w", err)
+	}
+
+	r := &Reactor{
+		logger:           logger,
+		peerManager:      peerManager,
+		chCreator:        channelCreator,
+		peerEvents:       peerEvents,
+		table:            table,
+		pending:          make(map[uint64]*pendingRequest),
+		connected:        make(chan struct{}),
+		receivedRequests: make(map[types.NodeID]*requestCount),
+	}
+
+	r.BaseService = *service.NewBaseService(logger, "DHT", r)
+	return r, nil
+}
+
+// OnStart opens the DHT channel and starts go routines listening for
+// envelopes on it and for peer updates, and refreshing the routing table.
+func (r *Reactor) OnStart(ctx context.Context) error {
+	channel, err := r.chCreator(ctx, ChannelDescriptor())
+	if err != nil {
+		return err
+	}
+	r.mtx.Lock()
+	r.channel = channel
+	r.mtx.Unlock()
+
+	peerUpdates := r.peerEvents(ctx)
+	go r.processDHTCh(ctx, channel)
+	go r.processPeerUpdates(ctx, peerUpdates)
+	go r.refreshRoutine(ctx)
+	return nil
+}
+
+// OnStop stops the reactor. The go routines exit with the start context.
+func (r *Reactor) OnStop() {}
+
+// FindNode looks up a node by ID, and returns its addresses. The addresses
+// are also added to the peer manager, so the router dials the node when it
+// has room for it.
+func (r *Reactor) FindNode(ctx context.Context, target types.NodeID) ([]p2p.NodeAddress, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	if target == r.peerManager.SelfID() {
+		return nil, errors.New("can't look up the local node")
+	}
+
+	if err := r.lookup(ctx, target); err != nil {
+		return nil, err
+	}
+	if entry, ok := r.table.Get(target); ok && len(entry.Addresses) > 0 {
+		return entry.Addresses, nil
+	}
+	return nil, ErrNodeNotFound
+}
+
+// processDHTCh implements a blocking event loop where we listen for p2p
+// Envelope messages from the DHT channel.
+func (r *Reactor) processDHTCh(ctx context.Context, dhtCh *p2p.Channel) {
+	iter := dhtCh.Receive(ctx)
+	for iter.Next(ctx) {
+		envelope := iter.Envelope()
+		if err := r.handleMessage(ctx, envelope, dhtCh); err != nil {
+			r.logger.Error("failed to process message",
+				"ch_id", envelope.ChannelID, "envelope", envelope, "err", err)
+			if serr := dhtCh.SendError(ctx, p2p.PeerError{
+				NodeID: envelope.From,
+				Err:    err,
+			}); serr != nil {
+				return
+			}
+		}
+	}
+}
+
+// processPeerUpdates initiates a blocking process where we listen for and
+// handle PeerUpdate messages, until the context is canceled.
+func (r *Reactor) processPeerUpdates(ctx context.Context, peerUpdates *p2p.PeerUpdates) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case peerUpdate := <-peerUpdates.Updates():
+			r.processPeerUpdate(ctx, peerUpdate)
+		}
+	}
+}
+
+// processPeerUpdate processes a PeerUpdate. Connected peers are added to the
+// routing table, and the first one bootstraps it with a lookup of the local
+// node ID.
+func (r *Reactor) processPeerUpdate(ctx context.Context, peerUpdate p2p.PeerUpdate) {
+	r.logger.Debug("received DHT peer update", "peer", peerUpdate.NodeID, "status", peerUpdate.Status)
+
+	switch peerUpdate.Status {
+	case p2p.PeerStatusUp:
+		entry := routingEntry{ID: peerUpdate.NodeID, Connected: true, LastSeen: time.Now()}
+		if addresses, record, ok := r.peerManager.Discoverable(peerUpdate.NodeID); ok {
+			entry.Addresses = addresses
+			entry.Record = record
+		}
+		r.table.Update(entry)
+
+		r.mtx.Lock()
+		close(r.connected)
+		r.connected = make(chan struct{})
+		bootstrap := !r.bootstrapped
+		r.bootstrapped = true
+		r.mtx.Unlock()
+
+		if bootstrap {
+			go func() {
+				if err := r.lookup(ctx, r.peerManager.SelfID()); err != nil {
+					r.logger.Debug("failed to bootstrap DHT routing table", "err", err)
+				}
+			}()
+		}
+
+	case p2p.PeerStatusDown:
+		r.table.SetConnected(peerUpdate.NodeID, false)
+		r.mtx.Lock()
+		delete(r.receivedRequests, peerUpdate.NodeID)
+		r.mtx.Unlock()
+	}
+}
+
+// refreshRoutine periodically looks up a random node ID, to learn about nodes
+// across the whole ID space.
+func (r *Reactor) refreshRoutine(ctx context.Context) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			target := make([]byte, types.NodeIDByteLength)
+			if _, err := rand.Read(target); err != nil {
+				r.logger.Error("failed to generate DHT refresh target", "err", err)
+				continue
+			}
+			if err := r.lookup(ctx, types.NodeID(hex.EncodeToString(target))); err != nil {
+				r.logger.Debug("failed to refresh DHT routing table", "err", err)
+			}
+		}
+	}
+}
+
+// handleMessage handles envelopes sent from peers on the DHT channel.
+func (r *Reactor) handleMessage(ctx context.Context, envelope *p2p.Envelope, dhtCh *p2p.Channel) error {
+	switch msg := envelope.Message.(type) {
+	case *protop2p.DhtFindNodeRequest:
+		if err := r.markPeerRequest(envelope.From); err != nil {
+			return err
+		}
+		target, err := types.NewNodeID(msg.Target)
+		if err != nil {
+			return fmt.Errorf("invalid FIND_NODE target: %w", err)
+		}
+		return dhtCh.Send(ctx, p2p.Envelope{
+			To: envelope.From,
+			Message: &protop2p.DhtFindNodeResponse{
+				RequestId: msg.RequestId,
+				Peers:     r.closestPeers(target, envelope.From),
+			},
+		})
+
+	case *protop2p.DhtFindNodeResponse:
+		if len(msg.Peers) > bucketSize {
+			return fmt.Errorf("peer sent too many DHT peers (%d > maximum %d)",
+				len(msg.Peers), bucketSize)
+		}
+
+		r.mtx.Lock()
+		request, ok := r.pending[msg.RequestId]
+		if ok && request.peerID == envelope.From {
+			delete(r.pending, msg.RequestId)
+		}
+		r.mtx.Unlock()
+
+		// responses arriving after the request timed out are dropped
+		if !ok || request.peerID != envelope.From {
+			r.logger.Debug("dropped unexpected FIND_NODE response",
+				"peer", envelope.From, "request_id", msg.RequestId)
+			return nil
+		}
+		request.response <- msg
+		return nil
+
+	default:
+		return fmt.Errorf("received unknown message: %T", msg)
+	}
+}
+
+// closestPeers returns the peers of the routing table closest to the target
+// with known addresses, except for the requesting peer, for a FIND_NODE
+// response.
+func (r *Reactor) closestPeers(target, requester types.NodeID) []*protop2p.DhtPeer {
+	key, _ := target.Bytes()
+	entries := r.table.Closest(key, bucketSize, func(entry routingEntry) bool {
+		return entry.ID != requester && len(entry.Addresses) > 0
+	})
+
+	peers := make([]*protop2p.DhtPeer, 0, len(entries))
+	for _, entry := range entries {
+		peer := &protop2p.DhtPeer{NodeId: string(entry.ID)}
+		if entry.Record != nil && !entry.Record.Expired(time.Now()) {
+			record, err := entry.Record.ToProto()
+			if err == nil {
+				peer.Record = record
+				peers = append(peers, peer)
+				continue
+			}
+		}
+		for i, addr := range entry.Addresses {
+			if i >= maxPeerAddresses {
+				break
+			}
+			peer.Addresses = append(peer.Addresses, addr.String())
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// lookup iteratively looks up the nodes closest to the target. In each
+// round, it queries the closest connected peers not queried yet, and adds the
+// nodes they return to the routing table and the peer manager. When the
+// closest nodes left to query aren't connected yet, it waits for the router
+// to dial them. The lookup ends once the target is known, or there is no one
+// left to query.
+func (r *Reactor) lookup(ctx context.Context, target types.NodeID) error {
+	key, err := target.Bytes()
+	if err != nil {
+		return err
+	}
+	queried := map[types.NodeID]bool{}
+
+	for {
+		if entry, ok := r.table.Get(target); ok && len(entry.Addresses) > 0 {
+			return nil
+		}
+
+		r.mtx.Lock()
+		connected := r.connected
+		r.mtx.Unlock()
+
+		closest := r.table.Closest(key, bucketSize, func(entry routingEntry) bool {
+			return !queried[entry.ID]
+		})
+		candidates := make([]types.NodeID, 0, lookupConcurrency)
+		dialing := false
+		for _, entry := range closest {
+			switch {
+			case entry.Connected && len(candidates) < lookupConcurrency:
+				candidates = append(candidates, entry.ID)
+			case !entry.Connected && len(entry.Addresses) > 0:
+				dialing = true
+			}
+		}
+
+		if len(candidates) == 0 {
+			if !dialing {
+				return nil
+			}
+			timer := time.NewTimer(lookupDialWait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+				return nil
+			case <-connected:
+				timer.Stop()
+				continue
+			}
+		}
+
+		var wg sync.WaitGroup
+		for _, peerID := range candidates {
+			queried[peerID] = true
+			wg.Add(1)
+			go func(peerID types.NodeID) {
+				defer wg.Done()
+				peers, err := r.requestFindNode(ctx, peerID, target)
+				if err != nil {
+					r.logger.Debug("FIND_NODE request failed", "peer", peerID, "err", err)
+					return
+				}
+				r.addPeers(peerID, peers)
+			}(peerID)
+		}
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// requestFindNode sends a FIND_NODE request to a peer and waits for its
+// response.
+func (r *Reactor) requestFindNode(ctx context.Context, peerID, target types.NodeID) ([]*protop2p.DhtPeer, error) {
+	r.mtx.Lock()
+	if r.channel == nil {
+		r.mtx.Unlock()
+		return nil, errors.New("DHT reactor is not running")
+	}
+	channel := r.channel
+	r.nextRequestID++
+	requestID := r.nextRequestID
+	request := &pendingRequest{
+		peerID:   peerID,
+		response: make(chan *protop2p.DhtFindNodeResponse, 1),
+	}
+	r.pending[requestID] = request
+	r.mtx.Unlock()
+
+	defer func() {
+		r.mtx.Lock()
+		delete(r.pending, requestID)
+		r.mtx.Unlock()
+	}()
+
+	if err := channel.Send(ctx, p2p.Envelope{
+		To:      peerID,
+		Message: &protop2p.DhtFindNodeRequest{RequestId: requestID, Target: string(target)},
+	}); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(requestTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, errors.New("FIND_NODE request timed out")
+	case response := <-request.response:
+		return response.Peers, nil
+	}
+}
+
+// addPeers adds the peers returned by a FIND_NODE response to the routing
+// table and the peer manager. Signed address records are preferred over plain
+// addresses, which must match the node ID they're given for.
+func (r *Reactor) addPeers(from types.NodeID, peers []*protop2p.DhtPeer) {
+	logger := r.logger.With("peer", from)
+	selfID := r.peerManager.SelfID()
+
+	for _, pb := range peers {
+		nodeID := types.NodeID(pb.NodeId)
+		if nodeID == selfID || nodeID.Validate() != nil {
+			continue
+		}
+		entry := routingEntry{ID: nodeID, LastSeen: time.Now()}
+
+		if pb.Record != nil {
+			record, err := p2p.AddressRecordFromProto(pb.Record)
+			if err != nil || record.NodeID != nodeID {
+				logger.Debug("invalid DHT address record", "node", nodeID, "err", err)
+				continue
+			}
+			if _, err := r.peerManager.AddRecord(record); err != nil {
+				logger.Debug("rejected DHT address record", "node", nodeID, "err", err)
+				continue
+			}
+			entry.Addresses = record.Addresses
+			entry.Record = record
+		} else {
+			for i, url := range pb.Addresses {
+				if i >= maxPeerAddresses {
+					break
+				}
+				address, err := p2p.ParseNodeAddress(url)
+				if err != nil || address.NodeID != nodeID {
+					continue
+				}
+				if _, err := r.peerManager.Add(address); err != nil {
+					logger.Debug("failed to add DHT address", "address", address, "err", err)
+					continue
+				}
+				entry.Addresses = append(entry.Addresses, address)
+			}
+		}
+
+		if len(entry.Addresses) > 0 {
+			r.table.Update(entry)
+		}
+	}
+}
+
+func (r *Reactor) markPeerRequest(peer types.NodeID) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := time.Now()
+	requests, ok := r.receivedRequests[peer]
+	if !ok || now.Sub(requests.since) >= time.Second {
+		requests = &requestCount{since: now}
+		r.receivedRequests[peer] = requests
+	}
+	requests.count++
+	if requests.count > maxRequestsPerSecond {
+		return fmt.Errorf("peer %v sent too many DHT requests (> %d per second)",
+			peer, maxRequestsPerSecond)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package dht
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/internal/p2p/p2ptest"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+func TestRoutingTable(t *testing.T) {
+	self := newNodeID(t, "00")
+	table, err := newRoutingTable(self)
+	require.NoError(t, err)
+
+	// the local node is never added
+	require.False(t, table.Update(routingEntry{ID: self}))
+
+	// nodes sharing longer prefixes with the target are closer
+	far := newNodeID(t, "ff")
+	near := newNodeID(t, "01")
+	nearer := types.NodeID(strings.Repeat("00", types.NodeIDByteLength-1) + "ff")
+	for _, id := range []types.NodeID{far, near, nearer} {
+		require.True(t, table.Update(routingEntry{ID: id, Connected: true}))
+	}
+	require.Equal(t, 3, table.Size())
+
+	key, err := self.Bytes()
+	require.NoError(t, err)
+	closest := table.Closest(key, 2, nil)
+	require.Len(t, closest, 2)
+	require.Equal(t, nearer, closest[0].ID)
+	require.Equal(t, near, closest[1].ID)
+
+	closest = table.Closest(key, bucketSize, func(entry routingEntry) bool {
+		return entry.ID != nearer
+	})
+	require.Len(t, closest, 2)
+	require.Equal(t, near, closest[0].ID)
+
+	// a full bucket evicts disconnected entries for new nodes, but keeps
+	// connected ones
+	for i := 0; i < bucketSize; i++ {
+		id := types.NodeID("4" + strings.Repeat("0", 2*types.NodeIDByteLength-3) + hexByte(i))
+		require.True(t, table.Update(routingEntry{ID: id, Connected: true}))
+	}
+	overflow := newNodeID(t, "44")
+	require.False(t, table.Update(routingEntry{ID: overflow}))
+
+	evicted := types.NodeID("4" + strings.Repeat("0", 2*types.NodeIDByteLength-3) + hexByte(0))
+	table.SetConnected(evicted, false)
+	require.True(t, table.Update(routingEntry{ID: overflow}))
+	_, ok := table.Get(evicted)
+	require.False(t, ok)
+	_, ok = table.Get(overflow)
+	require.True(t, ok)
+
+	table.Remove(overflow)
+	_, ok = table.Get(overflow)
+	require.False(t, ok)
+}
+
+func TestReactorFindNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	network := p2ptest.MakeNetwork(ctx, t, p2ptest.NetworkOptions{NumNodes: 4})
+	nodes := make([]*p2ptest.Node, 0, len(network.Nodes))
+	for _, node := range network.Nodes {
+		nodes = append(nodes, node)
+	}
+
+	reactors := make([]*Reactor, len(nodes))
+	channels := network.MakeChannelsNoCleanup(ctx, t, ChannelDescriptor())
+	for i, node := range nodes {
+		channel := channels[node.NodeID]
+		reactor, err := NewReactor(
+			log.NewNopLogger().With("nodeID", node.NodeID),
+			node.PeerManager,
+			func(context.Context, *p2p.ChannelDescriptor) (*p2p.Channel, error) { return channel, nil },
+			node.PeerManager.Subscribe,
+		)
+		require.NoError(t, err)
+		require.NoError(t, reactor.Start(ctx))
+		reactors[i] = reactor
+	}
+	t.Cleanup(func() {
+		cancel()
+		for _, reactor := range reactors {
+			reactor.Wait()
+		}
+	})
+
+	// connect the nodes in a chain, so that the first node only finds the
+	// last one through the ones in between
+	for i := 0; i+1 < len(nodes); i++ {
+		connectPeers(ctx, t, nodes[i], nodes[i+1])
+	}
+	require.NotContains(t, nodes[0].PeerManager.Peers(), nodes[3].NodeID)
+
+	lookupCtx, lookupCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer lookupCancel()
+
+	addresses, err := reactors[0].FindNode(lookupCtx, nodes[3].NodeID)
+	require.NoError(t, err)
+	require.Equal(t, []p2p.NodeAddress{nodes[3].NodeAddress}, addresses)
+	require.Contains(t, nodes[0].PeerManager.Peers(), nodes[3].NodeID)
+
+	_, err = reactors[0].FindNode(lookupCtx, newNodeID(t, "ab"))
+	require.True(t, errors.Is(err, ErrNodeNotFound), "unexpected error %v", err)
+
+	_, err = reactors[0].FindNode(lookupCtx, nodes[0].NodeID)
+	require.Error(t, err)
+}
+
+// connectPeers dials the target node from the source node, and waits for the
+// connection on both sides.
+func connectPeers(ctx context.Context, t *testing.T, source, target *p2ptest.Node) {
+	t.Helper()
+
+	sourceSub := source.PeerManager.Subscribe(ctx)
+	targetSub := target.PeerManager.Subscribe(ctx)
+
+	added, err := source.PeerManager.Add(target.NodeAddress)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	for _, sub := range []*p2p.PeerUpdates{sourceSub, targetSub} {
+		select {
+		case peerUpdate := <-sub.Updates():
+			require.Equal(t, p2p.PeerStatusUp, peerUpdate.Status)
+		case <-time.After(2 * time.Second):
+			require.Fail(t, "timed out waiting for peer", "%v dialing %v",
+				source.NodeID, target.NodeID)
+		}
+	}
+}
+
+func newNodeID(t *testing.T, id string) types.NodeID {
+	nodeID, err := types.NewNodeID(strings.Repeat(id, types.NodeIDByteLength))
+	require.NoError(t, err)
+	return nodeID
+}
+
+func hexByte(i int) string {
+	return string("0123456789abcdef"[i>>4]) + string("0123456789abcdef"[i&0xf])
+}
@@ -0,0 +1,251 @@
+package dht
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+const (
+	// bucketSize is the maximum number of entries in a bucket of the routing
+	// table, and the number of peers returned for a lookup (k in Kademlia).
+	bucketSize = 20
+
+	// keyBits is the number of bits in a node ID, and thus of buckets.
+	keyBits = types.NodeIDByteLength * 8
+)
+
+// routingEntry is a node known to the routing table.
+type routingEntry struct {
+	ID        types.NodeID
+	Addresses []p2p.NodeAddress
+	Record    *p2p.AddressRecord
+	Connected bool
+	LastSeen  time.Time
+
+	key []byte
+}
+
+// routingTable is a Kademlia routing table keyed by node ID. Nodes are sorted
+// into buckets by the length of the prefix they share with the local node ID,
+// so that the table knows many nodes close to it and a few far away from it.
+// Within a bucket, entries are ordered from least to most recently seen.
+type routingTable struct {
+	mtx     sync.Mutex
+	self    []byte
+	buckets [keyBits][]*routingEntry
+}
+
+// newRoutingTable creates an empty routing table for the given local node.
+func newRoutingTable(selfID types.NodeID) (*routingTable, error) {
+	self, err := selfID.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &routingTable{self: self}, nil
+}
+
+// bucketIndex returns the bucket of a key, which is the number of leading
+// bits it shares with the local node ID. The local node itself has no bucket
+// and returns -1.
+func (t *routingTable) bucketIndex(key []byte) int {
+	for i := range key {
+		if x := key[i] ^ t.self[i]; x != 0 {
+			return i*8 + bits.LeadingZeros8(x)
+		}
+	}
+	return -1
+}
+
+// Update inserts or refreshes a node. Addresses and records replace the known
+// ones when given. When the bucket of a new node is full, the least recently
+// seen disconnected entry is evicted for it; if all entries are connected,
+// the new node is dropped, as long-lived nodes are the most likely to stay.
+// It returns whether the node is in the table afterwards.
+func (t *routingTable) Update(entry routingEntry) bool {
+	key, err := entry.ID.Bytes()
+	if err != nil || len(key) != len(t.self) {
+		return false
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	index := t.bucketIndex(key)
+	if index < 0 {
+		return false
+	}
+	bucket := t.buckets[index]
+
+	for i, existing := range bucket {
+		if existing.ID != entry.ID {
+			continue
+		}
+		if len(entry.Addresses) > 0 {
+			existing.Addresses = entry.Addresses
+		}
+		if entry.Record != nil {
+			existing.Record = entry.Record
+		}
+		existing.Connected = existing.Connected || entry.Connected
+		existing.LastSeen = entry.LastSeen
+		// move it to the tail, being the most recently seen
+		t.buckets[index] = append(append(bucket[:i:i], bucket[i+1:]...), existing)
+		return true
+	}
+
+	if len(bucket) >= bucketSize {
+		evicted := -1
+		for i, existing := range bucket {
+			if !existing.Connected {
+				evicted = i
+				break
+			}
+		}
+		if evicted < 0 {
+			return false
+		}
+		bucket = append(bucket[:evicted:evicted], bucket[evicted+1:]...)
+	}
+
+	entry.key = key
+	t.buckets[index] = append(bucket, &entry)
+	return true
+}
+
+// SetConnected marks a node in the table as connected or disconnected.
+func (t *routingTable) SetConnected(id types.NodeID, connected bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if entry := t.get(id); entry != nil {
+		entry.Connected = connected
+	}
+}
+
+// Remove removes a node from the table.
+func (t *routingTable) Remove(id types.NodeID) {
+	key, err := id.Bytes()
+	if err != nil || len(key) != len(t.self) {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	index := t.bucketIndex(key)
+	if index < 0 {
+		return
+	}
+	bucket := t.buckets[index]
+	for i, entry := range bucket {
+		if entry.ID == id {
+			t.buckets[index] = append(bucket[:i:i], bucket[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get returns a copy of the entry of a node, if the table knows it.
+func (t *routingTable) Get(id types.NodeID) (routingEntry, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if entry := t.get(id); entry != nil {
+		return *entry, true
+	}
+	return routingEntry{}, false
+}
+
+// get returns the entry of a node. The caller must hold the mutex lock.
+func (t *routingTable) get(id types.NodeID) *routingEntry {
+	key, err := id.Bytes()
+	if err != nil || len(key) != len(t.self) {
+		return nil
+	}
+	index := t.bucketIndex(key)
+	if index < 0 {
+		return nil
+	}
+	for _, entry := range t.buckets[index] {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Closest returns copies of up to limit entries closest to the target by XOR
+// distance, closest first, skipping those the filter rejects.
+func (t *routingTable) Closest(target []byte, limit int, filter func(routingEntry) bool) []routingEntry {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	entries := []routingEntry{}
+	for _, bucket := range t.buckets {
+		for _, entry := range bucket {
+			if filter == nil || filter(*entry) {
+				entries = append(entries, *entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return closer(target, entries[i].key, entries[j].key)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// Size returns the number of nodes in the table.
+func (t *routingTable) Size() int {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	size := 0
+	for _, bucket := range t.buckets {
+		size += len(bucket)
+	}
+	return size
+}
+
+// closer returns whether key a is closer to the target than key b by XOR
+// distance.
+func closer(target, a, b []byte) bool {
+	return bytes.Compare(distance(target, a), distance(target, b)) < 0
+}
+
+// distance returns the XOR distance between two keys.
+func distance(a, b []byte) []byte {
+	d := make([]byte, len(a))
+	for i := range a {
+		d[i] = a[i] ^ b[i]
+	}
+	return d
+}
@@ -76,6 +76,10 @@ type Metrics struct {
 	// queue for a specific flow (i.e. Channel).
 	PeerQueueMsgSize metrics.Gauge
 
+	// RouterSendThrottle defines the time messages waited for the send rate
+	// limits of their p2p Channel and peer.
+	RouterSendThrottle metrics.Histogram
+
 	mtx               *sync.RWMutex
 	messageLabelNames map[reflect.Type]string
 }
@@ -152,6 +156,13 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Help:      "The size of messages sent over a peer's queue for a specific p2p Channel.",
 		}, append(labels, "ch_id")).With(labelsAndValues...),
 
+		RouterSendThrottle: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "router_send_throttle",
+			Help:      "The time messages waited for the send rate limits of their p2p Channel and peer.",
+		}, append(labels, "ch_id")).With(labelsAndValues...),
+
 		mtx:               &sync.RWMutex{},
 		messageLabelNames: map[reflect.Type]string{},
 	}
@@ -169,6 +180,7 @@ func NopMetrics() *Metrics {
 		RouterChannelQueueSend: discard.NewHistogram(),
 		PeerQueueDroppedMsgs:   discard.NewCounter(),
 		PeerQueueMsgSize:       discard.NewGauge(),
+		RouterSendThrottle:     discard.NewHistogram(),
 		mtx:                    &sync.RWMutex{},
 		messageLabelNames:      map[reflect.Type]string{},
 	}
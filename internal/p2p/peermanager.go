@@ -36,12 +36,21 @@ import (
 
 	tmsync "github.com/bhojpur/state/internal/libs/sync"
 	p2pproto "github.com/bhojpur/state/pkg/api/v1/p2p"
+	"github.com/bhojpur/state/pkg/crypto"
 	"github.com/bhojpur/state/pkg/types"
 )
 
 const (
 	// retryNever is returned by retryDelay() when retries are disabled.
 	retryNever time.Duration = math.MaxInt64
+
+	// defaultAddressRecordTTL is the default AddressRecordTTL.
+	defaultAddressRecordTTL = 24 * time.Hour
+
+	// signedPeerScore is added to the score of peers whose addresses are
+	// known from a signed address record, so that they are preferred over
+	// peers only known from unsigned, possibly forged, addresses.
+	signedPeerScore = 1
 )
 
 // PeerStatus is a peer status.
@@ -162,6 +171,15 @@ type PeerManagerOptions struct {
 	// If Hostname and Port are unset, Advertise() will include no self-announcement
 	SelfAddress NodeAddress
 
+	// PrivKey is the node key, used to sign the address record of
+	// SelfAddress advertised to peers. If nil, no record is advertised.
+	PrivKey crypto.PrivKey
+
+	// AddressRecordTTL is how long the address record of this node is valid
+	// for. It is signed again once half of it has elapsed. 0 uses a default
+	// of 24 hours.
+	AddressRecordTTL time.Duration
+
 	// persistentPeers provides fast PersistentPeers lookups. It is built
 	// by optimize().
 	persistentPeers map[types.NodeID]bool
@@ -294,6 +312,7 @@ type PeerManager struct {
 	ready         map[types.NodeID]bool         // ready peers (Ready → Disconnected)
 	evict         map[types.NodeID]bool         // peers scheduled for eviction (Connected → EvictNext)
 	evicting      map[types.NodeID]bool         // peers being evicted (EvictNext → Disconnected)
+	selfRecord    *AddressRecord                // signed record of SelfAddress, see AdvertiseRecords
 }
 
 // NewPeerManager creates a new peer manager.
@@ -337,6 +356,11 @@ func NewPeerManager(selfID types.NodeID, peerDB dbm.DB, options PeerManagerOptio
 	return peerManager, nil
 }
 
+// SelfID returns the node ID of the local node.
+func (m *PeerManager) SelfID() types.NodeID {
+	return m.selfID
+}
+
 // configurePeers configures peers in the peer store with ephemeral runtime
 // configuration, e.g. PersistentPeers. It also removes ourself, if we're in the
 // peer store. The caller must hold the mutex lock.
@@ -402,9 +426,32 @@ func (m *PeerManager) prunePeers() error {
 	return nil
 }
 
+// SetMaxConnected changes the maximum number of connected peers. If it is
+// lowered below the number of connected peers, the lowest-scored ones are
+// evicted.
+func (m *PeerManager) SetMaxConnected(maxConnected uint16) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	options := m.options
+	options.MaxConnected = maxConnected
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	m.options.MaxConnected = maxConnected
+
+	m.dialWaker.Wake()
+	m.evictWaker.Wake()
+	return nil
+}
+
 // Add adds a peer to the manager, given as an address. If the peer already
 // exists, the address is added to it if it isn't already present. This will push
 // low scoring peers out of the address book if it exceeds the maximum size.
+//
+// The address is unsigned, so unless the peer is persistent, it is ignored if
+// the peer has an unexpired signed address record, and the peer is scored
+// lower than peers with records. See AddRecord.
 func (m *PeerManager) Add(address NodeAddress) (bool, error) {
 	if err := address.Validate(); err != nil {
 		return false, err
@@ -420,6 +467,9 @@ func (m *PeerManager) Add(address NodeAddress) (bool, error) {
 	if !ok {
 		peer = m.newPeerInfo(address.NodeID)
 	}
+	if peer.Record != nil && !peer.Record.Expired(time.Now()) && !m.options.isPersistent(peer.ID) {
+		return false, nil
+	}
 	_, ok = peer.AddressInfo[address]
 	// if we already have the peer address, there's no need to continue
 	if ok {
@@ -438,6 +488,56 @@ func (m *PeerManager) Add(address NodeAddress) (bool, error) {
 	return true, nil
 }
 
+// AddRecord adds or updates a peer given its signed address record. The
+// record is verified, and only replaces the peer's current record if it has a
+// higher sequence number. The addresses of the peer are then replaced by the
+// ones in the record, except for persistent peers whose configured addresses
+// are kept. It returns true if the record was accepted.
+func (m *PeerManager) AddRecord(record *AddressRecord) (bool, error) {
+	if err := record.Verify(time.Now()); err != nil {
+		return false, err
+	}
+	if record.NodeID == m.selfID {
+		return false, fmt.Errorf("can't add self (%v) to peer store", m.selfID)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	peer, ok := m.store.Get(record.NodeID)
+	if !ok {
+		peer = m.newPeerInfo(record.NodeID)
+	}
+	if peer.Record != nil && peer.Record.Sequence >= record.Sequence {
+		return false, nil
+	}
+
+	addressInfo := make(map[NodeAddress]*peerAddressInfo, len(record.Addresses))
+	if peer.Persistent {
+		for addr, info := range peer.AddressInfo {
+			addressInfo[addr] = info
+		}
+	}
+	for _, addr := range record.Addresses {
+		if info, ok := peer.AddressInfo[addr]; ok {
+			addressInfo[addr] = info
+		} else {
+			addressInfo[addr] = &peerAddressInfo{Address: addr}
+		}
+	}
+	peer.AddressInfo = addressInfo
+	peer.Record = record
+
+	if err := m.store.Set(peer); err != nil {
+		return false, err
+	}
+	if err := m.prunePeers(); err != nil {
+		return true, err
+	}
+	m.dialWaker.Wake()
+	return true, nil
+}
+
 // PeerRatio returns the ratio of peer addresses stored to the maximum size.
 func (m *PeerManager) PeerRatio() float64 {
 	m.mtx.Lock()
@@ -848,6 +948,84 @@ func (m *PeerManager) Advertise(peerID types.NodeID, limit uint16) []NodeAddress
 	return addresses
 }
 
+// AdvertiseRecords returns a list of signed address records to advertise to
+// a peer: the record of this node, if it has a self address and a node key,
+// followed by the unexpired records of the highest-ranked peers.
+func (m *PeerManager) AdvertiseRecords(peerID types.NodeID, limit uint16) []*AddressRecord {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := time.Now()
+	records := make([]*AddressRecord, 0, limit)
+	// signing only fails for keys not supporting it, then there's no record
+	if self, err := m.signSelfRecord(now); err == nil && self != nil && limit > 0 {
+		records = append(records, self)
+	}
+
+	for _, peer := range m.store.Ranked() {
+		if len(records) >= int(limit) {
+			break
+		}
+		if peer.ID == peerID || peer.Record == nil || peer.Record.Expired(now) {
+			continue
+		}
+		if _, ok := m.options.PrivatePeers[peer.ID]; ok {
+			continue
+		}
+		records = append(records, peer.Record)
+	}
+	return records
+}
+
+// Discoverable returns the addresses of a peer along with its unexpired
+// address record, if any, for sharing with other peers. It returns false for
+// unknown and private peers.
+func (m *PeerManager) Discoverable(peerID types.NodeID) ([]NodeAddress, *AddressRecord, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.options.PrivatePeers[peerID]; ok {
+		return nil, nil, false
+	}
+	peer, ok := m.store.Get(peerID)
+	if !ok {
+		return nil, nil, false
+	}
+	addresses := make([]NodeAddress, 0, len(peer.AddressInfo))
+	for _, addressInfo := range peer.AddressInfo {
+		addresses = append(addresses, addressInfo.Address)
+	}
+	record := peer.Record
+	if record != nil && record.Expired(time.Now()) {
+		record = nil
+	}
+	return addresses, record, true
+}
+
+// signSelfRecord returns the signed address record of this node, signing a
+// new one if there is none yet or half of its time to live has elapsed. The
+// sequence number is the signing time, so that it increases across restarts
+// without being persisted. The caller must hold the mutex lock.
+func (m *PeerManager) signSelfRecord(now time.Time) (*AddressRecord, error) {
+	if m.options.PrivKey == nil || m.options.SelfAddress.Hostname == "" || m.options.SelfAddress.Port == 0 {
+		return nil, nil
+	}
+	ttl := m.options.AddressRecordTTL
+	if ttl == 0 {
+		ttl = defaultAddressRecordTTL
+	}
+	if m.selfRecord != nil && m.selfRecord.Expires.Sub(now) > ttl/2 {
+		return m.selfRecord, nil
+	}
+	record, err := NewAddressRecord(m.options.PrivKey,
+		[]NodeAddress{m.options.SelfAddress}, uint64(now.UnixNano()), now.Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	m.selfRecord = record
+	return record, nil
+}
+
 // PeerEventSubscriber describes the type of the subscription method, to assist
 // in isolating reactors specific construction and lifecycle from the
 // peer manager.
@@ -1244,6 +1422,9 @@ type peerInfo struct {
 	FixedScore PeerScore // mainly for tests
 
 	MutableScore int64 // updated by router
+
+	// Record is the latest signed address record of the peer, if any.
+	Record *AddressRecord
 }
 
 // peerInfoFromProto converts a Protobuf PeerInfo message to a peerInfo,
@@ -1264,6 +1445,13 @@ func peerInfoFromProto(msg *p2pproto.PeerInfo) (*peerInfo, error) {
 		p.AddressInfo[addressInfo.Address] = addressInfo
 
 	}
+	if msg.AddressRecord != nil {
+		record, err := AddressRecordFromProto(msg.AddressRecord)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address record: %w", err)
+		}
+		p.Record = record
+	}
 	return p, p.Validate()
 }
 
@@ -1279,6 +1467,10 @@ func (p *peerInfo) ToProto() *p2pproto.PeerInfo {
 	for _, addressInfo := range p.AddressInfo {
 		msg.AddressInfo = append(msg.AddressInfo, addressInfo.ToProto())
 	}
+	if p.Record != nil {
+		// the record was verified when added, so it converts
+		msg.AddressRecord, _ = p.Record.ToProto()
+	}
 	if msg.LastConnected.IsZero() {
 		msg.LastConnected = nil
 	}
@@ -1309,6 +1501,9 @@ func (p *peerInfo) Score() PeerScore {
 	}
 
 	score := p.MutableScore
+	if p.Record != nil {
+		score += signedPeerScore
+	}
 	if score > int64(MaxPeerScoreNotPersistent) {
 		score = int64(MaxPeerScoreNotPersistent)
 	}
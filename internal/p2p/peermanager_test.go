@@ -34,6 +34,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/pkg/crypto/ed25519"
 	"github.com/bhojpur/state/pkg/types"
 )
 
@@ -289,6 +290,93 @@ func TestPeerManager_Add(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestPeerManager_AddRecord(t *testing.T) {
+	privKey := ed25519.GenPrivKey()
+	aID := types.NodeIDFromPubKey(privKey.PubKey())
+	aAddress := p2p.NodeAddress{Protocol: "tcp", NodeID: aID, Hostname: "127.0.0.1", Port: 26656}
+	bAddress := p2p.NodeAddress{Protocol: "tcp", NodeID: aID, Hostname: "127.0.0.2", Port: 26656}
+	expires := time.Now().Add(time.Hour)
+
+	db := dbm.NewMemDB()
+	peerManager, err := p2p.NewPeerManager(selfID, db, p2p.PeerManagerOptions{})
+	require.NoError(t, err)
+
+	// An unsigned address is replaced by the addresses of a record.
+	forged := p2p.NodeAddress{Protocol: "tcp", NodeID: aID, Hostname: "10.0.0.1", Port: 26656}
+	added, err := peerManager.Add(forged)
+	require.NoError(t, err)
+	require.True(t, added)
+	unsignedScore := peerManager.Scores()[aID]
+
+	record, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{aAddress}, 2, expires)
+	require.NoError(t, err)
+	added, err = peerManager.AddRecord(record)
+	require.NoError(t, err)
+	require.True(t, added)
+	require.Equal(t, []p2p.NodeAddress{aAddress}, peerManager.Addresses(aID))
+	require.Greater(t, peerManager.Scores()[aID], unsignedScore)
+
+	// Unsigned addresses and older records are then ignored.
+	added, err = peerManager.Add(forged)
+	require.NoError(t, err)
+	require.False(t, added)
+	older, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{bAddress}, 1, expires)
+	require.NoError(t, err)
+	added, err = peerManager.AddRecord(older)
+	require.NoError(t, err)
+	require.False(t, added)
+	require.Equal(t, []p2p.NodeAddress{aAddress}, peerManager.Addresses(aID))
+
+	// A newer record replaces the addresses.
+	newer, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{bAddress}, 3, expires)
+	require.NoError(t, err)
+	added, err = peerManager.AddRecord(newer)
+	require.NoError(t, err)
+	require.True(t, added)
+	require.Equal(t, []p2p.NodeAddress{bAddress}, peerManager.Addresses(aID))
+
+	// Tampered records are rejected.
+	tampered, err := p2p.NewAddressRecord(privKey, []p2p.NodeAddress{aAddress}, 4, expires)
+	require.NoError(t, err)
+	tampered.Addresses = []p2p.NodeAddress{forged}
+	_, err = peerManager.AddRecord(tampered)
+	require.Error(t, err)
+	require.Equal(t, []p2p.NodeAddress{bAddress}, peerManager.Addresses(aID))
+
+	// The record is persisted, and advertised.
+	peerManager, err = p2p.NewPeerManager(selfID, db, p2p.PeerManagerOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []p2p.NodeAddress{bAddress}, peerManager.Addresses(aID))
+	records := peerManager.AdvertiseRecords(selfID, 10)
+	require.Len(t, records, 1)
+	require.EqualValues(t, 3, records[0].Sequence)
+	require.NoError(t, records[0].Verify(time.Now()))
+}
+
+func TestPeerManager_AdvertiseRecords_Self(t *testing.T) {
+	privKey := ed25519.GenPrivKey()
+	selfAddress := p2p.NodeAddress{
+		Protocol: "tcp",
+		NodeID:   types.NodeIDFromPubKey(privKey.PubKey()),
+		Hostname: "127.0.0.1",
+		Port:     26656,
+	}
+	peerManager, err := p2p.NewPeerManager(selfAddress.NodeID, dbm.NewMemDB(), p2p.PeerManagerOptions{
+		SelfAddress: selfAddress,
+		PrivKey:     privKey,
+	})
+	require.NoError(t, err)
+
+	records := peerManager.AdvertiseRecords(selfID, 10)
+	require.Len(t, records, 1)
+	require.NoError(t, records[0].Verify(time.Now()))
+	require.Equal(t, []p2p.NodeAddress{selfAddress}, records[0].Addresses)
+
+	// the record is reused until half of its time to live has elapsed
+	require.Equal(t, records, peerManager.AdvertiseRecords(selfID, 10))
+	require.Empty(t, peerManager.AdvertiseRecords(selfID, 0))
+}
+
 func TestPeerManager_DialNext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -1409,6 +1497,39 @@ func TestPeerManager_EvictNext(t *testing.T) {
 	require.Equal(t, context.DeadlineExceeded, err)
 }
 
+func TestPeerManager_SetMaxConnected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := p2p.NodeAddress{Protocol: "memory", NodeID: types.NodeID(strings.Repeat("a", 40))}
+	b := p2p.NodeAddress{Protocol: "memory", NodeID: types.NodeID(strings.Repeat("b", 40))}
+
+	peerManager, err := p2p.NewPeerManager(selfID, dbm.NewMemDB(), p2p.PeerManagerOptions{
+		MaxConnected: 2,
+		MaxPeers:     10,
+	})
+	require.NoError(t, err)
+
+	for _, addr := range []p2p.NodeAddress{a, b} {
+		added, err := peerManager.Add(addr)
+		require.NoError(t, err)
+		require.True(t, added)
+		require.NoError(t, peerManager.Accepted(addr.NodeID))
+		peerManager.Ready(ctx, addr.NodeID, nil)
+	}
+
+	// The limit can't exceed MaxPeers.
+	require.Error(t, peerManager.SetMaxConnected(20))
+
+	// Lowering the limit evicts a peer.
+	require.NoError(t, peerManager.SetMaxConnected(1))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	evict, err := peerManager.EvictNext(timeoutCtx)
+	require.NoError(t, err)
+	require.Contains(t, []types.NodeID{a.NodeID, b.NodeID}, evict)
+}
+
 func TestPeerManager_EvictNext_WakeOnError(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
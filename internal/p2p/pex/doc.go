@@ -36,6 +36,13 @@ different set of proto messages which include a list of
 endpoints that each peer uses. The V2 reactor has backwards compatibility with
 V1. It can also handle V1 messages.
 
+Alongside the plain addresses, responses carry signed address records. A
+record binds a node ID to its addresses with the node's own key, a sequence
+number and an expiry, so a peer cannot advertise addresses on behalf of
+another node. Records with a newer sequence replace the addresses the peer
+manager holds for that node, and plain addresses are only used for nodes
+without a valid record.
+
 The reactor is able to tweak the intensity of it's search by decreasing or
 increasing the interval between each request. It tracks connected peers via a
 linked list, sending a request to the node at the front of the list and adding
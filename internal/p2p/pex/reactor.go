@@ -256,7 +256,8 @@ func (r *Reactor) handlePexMessage(ctx context.Context, envelope *p2p.Envelope,
 		}
 
 		// Fetch peers from the peer manager, convert NodeAddresses into URL
-		// strings, and send them back to the caller.
+		// strings, and send them back to the caller. Signed records are sent
+		// along for peers understanding them, and take precedence there.
 		nodeAddresses := r.peerManager.Advertise(envelope.From, maxAddresses)
 		pexAddresses := make([]protop2p.PexAddress, len(nodeAddresses))
 		for idx, addr := range nodeAddresses {
@@ -264,9 +265,19 @@ func (r *Reactor) handlePexMessage(ctx context.Context, envelope *p2p.Envelope,
 				URL: addr.String(),
 			}
 		}
+		addressRecords := r.peerManager.AdvertiseRecords(envelope.From, maxAddresses)
+		records := make([]*protop2p.AddressRecord, 0, len(addressRecords))
+		for _, record := range addressRecords {
+			pb, err := record.ToProto()
+			if err != nil {
+				logger.Error("failed to encode address record", "node", record.NodeID, "err", err)
+				continue
+			}
+			records = append(records, pb)
+		}
 		return 0, pexCh.Send(ctx, p2p.Envelope{
 			To:      envelope.From,
-			Message: &protop2p.PexResponse{Addresses: pexAddresses},
+			Message: &protop2p.PexResponse{Addresses: pexAddresses, Records: records},
 		})
 
 	case *protop2p.PexResponse:
@@ -280,8 +291,30 @@ func (r *Reactor) handlePexMessage(ctx context.Context, envelope *p2p.Envelope,
 			return 0, fmt.Errorf("peer sent too many addresses (%d > maxiumum %d)",
 				len(msg.Addresses), maxAddresses)
 		}
+		if len(msg.Records) > maxAddresses {
+			return 0, fmt.Errorf("peer sent too many address records (%d > maxiumum %d)",
+				len(msg.Records), maxAddresses)
+		}
 
+		// Signed records are added first, so that the unsigned addresses of
+		// the same nodes are ignored.
 		var numAdded int
+		for _, pb := range msg.Records {
+			record, err := p2p.AddressRecordFromProto(pb)
+			if err != nil {
+				logger.Debug("invalid PEX address record", "err", err)
+				continue
+			}
+			added, err := r.peerManager.AddRecord(record)
+			if err != nil {
+				logger.Debug("rejected PEX address record", "node", record.NodeID, "err", err)
+				continue
+			}
+			if added {
+				numAdded++
+				logger.Debug("added PEX address record", "node", record.NodeID, "sequence", record.Sequence)
+			}
+		}
 		for _, pexAddress := range msg.Addresses {
 			peerAddress, err := p2p.ParseNodeAddress(pexAddress.URL)
 			if err != nil {
@@ -268,10 +268,6 @@ func (s *pqScheduler) process(ctx context.Context) {
 					s.sizes[uint(s.chDescs[i].Priority)] -= pqEnv.size
 				}
 
-				s.metrics.PeerSendBytesTotal.With(
-					"chID", chIDStr,
-					"peer_id", string(pqEnv.envelope.To),
-					"message_type", s.metrics.ValueToMetricLabel(pqEnv.envelope.Message)).Add(float64(pqEnv.size))
 				s.metrics.PeerPendingSendBytes.With(
 					"peer_id", string(pqEnv.envelope.To)).Add(float64(-pqEnv.size))
 				select {
@@ -92,6 +92,14 @@ type RouterOptions struct {
 	// are used to dial peers. This defaults to the value of
 	// runtime.NumCPU.
 	NumConcurrentDials func() int
+
+	// ChannelSendRates limits the number of bytes per second sent on
+	// channels, across all peers. Channels without a limit are unlimited.
+	ChannelSendRates map[ChannelID]int64
+
+	// PeerSendRate limits the number of bytes per second sent to each peer,
+	// across all channels. 0 means no limit.
+	PeerSendRate int64
 }
 
 const (
@@ -122,6 +130,15 @@ func (o *RouterOptions) Validate() error {
 		o.MaxIncomingConnectionAttempts = 100
 	}
 
+	for chID, rate := range o.ChannelSendRates {
+		if rate <= 0 {
+			return fmt.Errorf("send rate of channel %#x must be positive [%d]", chID, rate)
+		}
+	}
+	if o.PeerSendRate < 0 {
+		return fmt.Errorf("peer send rate can't be negative [%d]", o.PeerSendRate)
+	}
+
 	return nil
 }
 
@@ -190,6 +207,12 @@ type Router struct {
 	channelMtx      sync.RWMutex
 	channelQueues   map[ChannelID]queue // inbound messages from all peers to a single channel
 	channelMessages map[ChannelID]proto.Message
+
+	channelLimiters map[ChannelID]*rateLimiter // send rate limits shared by all peers
+	traffic         *trafficCounter
+
+	captureMtx sync.RWMutex
+	capture    *envelopeCapture // nil unless capturing envelopes
 }
 
 // NewRouter creates a new Router. The given Transports must already be
@@ -228,6 +251,11 @@ func NewRouter(
 		channelMessages: map[ChannelID]proto.Message{},
 		peerQueues:      map[types.NodeID]queue{},
 		peerChannels:    make(map[types.NodeID]ChannelIDSet),
+		channelLimiters: make(map[ChannelID]*rateLimiter, len(options.ChannelSendRates)),
+		traffic:         newTrafficCounter(),
+	}
+	for chID, rate := range options.ChannelSendRates {
+		router.channelLimiters[chID] = newRateLimiter(rate)
 	}
 
 	router.BaseService = service.NewBaseService(logger, "router", router)
@@ -761,6 +789,7 @@ func (r *Router) routePeer(ctx context.Context, peerID types.NodeID, conn Connec
 		delete(r.peerQueues, peerID)
 		delete(r.peerChannels, peerID)
 		r.peerMtx.Unlock()
+		r.traffic.removePeer(peerID)
 
 		sendQueue.close()
 
@@ -826,6 +855,7 @@ func (r *Router) receivePeer(ctx context.Context, peerID types.NodeID, conn Conn
 		if err != nil {
 			return err
 		}
+		r.traffic.add(peerID, chID, 0, len(bz))
 
 		r.channelMtx.RLock()
 		queue, ok := r.channelQueues[chID]
@@ -843,6 +873,7 @@ func (r *Router) receivePeer(ctx context.Context, peerID types.NodeID, conn Conn
 			continue
 		}
 
+		wire := msg
 		if wrapper, ok := msg.(Wrapper); ok {
 			msg, err = wrapper.Unwrap()
 			if err != nil {
@@ -850,6 +881,7 @@ func (r *Router) receivePeer(ctx context.Context, peerID types.NodeID, conn Conn
 				continue
 			}
 		}
+		r.captureEnvelope(false, peerID, chID, wire, msg, bz)
 
 		start := time.Now().UTC()
 
@@ -871,8 +903,28 @@ func (r *Router) receivePeer(ctx context.Context, peerID types.NodeID, conn Conn
 	}
 }
 
-// sendPeer sends queued messages to a peer.
+// outboundMessage is a marshaled message to send to a peer.
+type outboundMessage struct {
+	envelope Envelope
+	bz       []byte
+}
+
+// sendPeer sends queued messages to a peer. Messages on channels with a send
+// rate limit are handed to a goroutine per channel, which waits for the limit
+// so that they don't hold up the messages of other channels. Once such a
+// channel has a backlog of queueBufferDefault messages, its next message
+// blocks the peer queue, passing the back pressure on to the reactors.
 func (r *Router) sendPeer(ctx context.Context, peerID types.NodeID, conn Connection, peerQueue queue) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var peerLimiter *rateLimiter
+	if r.options.PeerSendRate > 0 {
+		peerLimiter = newRateLimiter(r.options.PeerSendRate)
+	}
+	throttled := make(map[ChannelID]chan outboundMessage)
+	errCh := make(chan error, 1)
+
 	for {
 		start := time.Now().UTC()
 
@@ -889,12 +941,35 @@ func (r *Router) sendPeer(ctx context.Context, peerID types.NodeID, conn Connect
 				r.logger.Error("failed to marshal message", "peer", peerID, "err", err)
 				continue
 			}
+			msg := outboundMessage{envelope: envelope, bz: bz}
+
+			channelLimiter, ok := r.channelLimiters[envelope.ChannelID]
+			if !ok {
+				if err := r.sendMessage(ctx, peerID, conn, msg, peerLimiter); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				continue
+			}
 
-			if err = conn.SendMessage(ctx, envelope.ChannelID, bz); err != nil {
+			ch, ok := throttled[envelope.ChannelID]
+			if !ok {
+				ch = make(chan outboundMessage, queueBufferDefault)
+				throttled[envelope.ChannelID] = ch
+				go r.sendThrottled(ctx, peerID, conn, ch, errCh, peerLimiter, channelLimiter)
+			}
+			select {
+			case ch <- msg:
+			case err := <-errCh:
 				return err
+			case <-ctx.Done():
+				return nil
 			}
 
-			r.logger.Debug("sent message", "peer", envelope.To, "message", envelope.Message)
+		case err := <-errCh:
+			return err
 
 		case <-peerQueue.closed():
 			return nil
@@ -905,6 +980,88 @@ func (r *Router) sendPeer(ctx context.Context, peerID types.NodeID, conn Connect
 	}
 }
 
+// sendThrottled sends the messages of a rate limited channel to a peer,
+// until the context is canceled. Send errors are reported on errCh.
+func (r *Router) sendThrottled(
+	ctx context.Context,
+	peerID types.NodeID,
+	conn Connection,
+	ch <-chan outboundMessage,
+	errCh chan<- error,
+	limiters ...*rateLimiter,
+) {
+	for {
+		select {
+		case msg := <-ch:
+			if err := r.sendMessage(ctx, peerID, conn, msg, limiters...); err != nil {
+				if ctx.Err() == nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendMessage waits for the given send rate limits, if any, and sends a
+// message to a peer.
+func (r *Router) sendMessage(
+	ctx context.Context,
+	peerID types.NodeID,
+	conn Connection,
+	msg outboundMessage,
+	limiters ...*rateLimiter,
+) error {
+	chID := msg.envelope.ChannelID
+	chIDStr := fmt.Sprint(chID)
+
+	start := time.Now()
+	throttled := false
+	for _, limiter := range limiters {
+		if limiter == nil {
+			continue
+		}
+		throttled = true
+		if err := limiter.wait(ctx, len(msg.bz)); err != nil {
+			return err
+		}
+	}
+	if throttled {
+		r.metrics.RouterSendThrottle.With("ch_id", chIDStr).Observe(time.Since(start).Seconds())
+	}
+
+	if err := conn.SendMessage(ctx, chID, msg.bz); err != nil {
+		return err
+	}
+
+	r.traffic.add(peerID, chID, len(msg.bz), 0)
+	r.captureEnvelope(true, peerID, chID, msg.envelope.Message, nil, msg.bz)
+	r.metrics.PeerSendBytesTotal.With(
+		"chID", chIDStr,
+		"peer_id", string(peerID),
+		"message_type", r.metrics.ValueToMetricLabel(msg.envelope.Message)).Add(float64(len(msg.bz)))
+	r.logger.Debug("sent message", "peer", msg.envelope.To, "message", msg.envelope.Message)
+	return nil
+}
+
+// ChannelTraffic returns the number of bytes sent and received on each
+// channel since the router started.
+func (r *Router) ChannelTraffic() map[ChannelID]TrafficStats {
+	return r.traffic.channelStats()
+}
+
+// PeerTraffic returns the number of bytes sent to and received from a
+// connected peer on each channel, since it connected.
+func (r *Router) PeerTraffic(peerID types.NodeID) map[ChannelID]TrafficStats {
+	return r.traffic.peerStats(peerID)
+}
+
 // evictPeers evicts connected peers as requested by the peer manager.
 func (r *Router) evictPeers(ctx context.Context) {
 	for {
@@ -969,6 +1126,10 @@ func (r *Router) OnStop() {
 		r.logger.Error("failed to close transport", "err", err)
 	}
 
+	if err := r.StopCapture(); err != nil {
+		r.logger.Error("failed to close envelope capture", "err", err)
+	}
+
 	// Collect all remaining queues, and wait for them to close.
 	queues := []queue{}
 
@@ -21,10 +21,13 @@ package p2p_test
 // THE SOFTWARE.
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
@@ -243,6 +246,58 @@ func TestRouter_Channel_SendReceive(t *testing.T) {
 	}
 }
 
+func TestRouter_Capture(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Cleanup(leaktest.Check(t))
+
+	network := p2ptest.MakeNetwork(ctx, t, p2ptest.NetworkOptions{NumNodes: 2})
+
+	ids := network.NodeIDs()
+	aID, bID := ids[0], ids[1]
+	channels := network.MakeChannels(ctx, t, chDesc)
+	a, b := channels[aID], channels[bID]
+
+	network.Start(ctx, t)
+
+	path := filepath.Join(t.TempDir(), "capture")
+	router := network.Nodes[aID].Router
+	require.NoError(t, router.StartCapture(path, 0))
+
+	// Both the envelope sent by a and the reply it receives are captured.
+	p2ptest.RequireSend(ctx, t, a, p2p.Envelope{To: bID, Message: &p2ptest.Message{Value: "foo"}})
+	p2ptest.RequireReceive(ctx, t, b, p2p.Envelope{From: aID, Message: &p2ptest.Message{Value: "foo"}})
+	p2ptest.RequireSend(ctx, t, b, p2p.Envelope{To: aID, Message: &p2ptest.Message{Value: "bar"}})
+	p2ptest.RequireReceive(ctx, t, a, p2p.Envelope{From: bID, Message: &p2ptest.Message{Value: "bar"}})
+
+	require.NoError(t, router.StopCapture())
+
+	// Envelopes routed after the capture stopped are not recorded.
+	p2ptest.RequireSend(ctx, t, a, p2p.Envelope{To: bID, Message: &p2ptest.Message{Value: "baz"}})
+	p2ptest.RequireReceive(ctx, t, b, p2p.Envelope{From: aID, Message: &p2ptest.Message{Value: "baz"}})
+
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	dec := p2p.NewCaptureDecoder(bytes.NewReader(bz))
+	values := map[bool]string{}
+	for {
+		rec, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, chDesc.ID, rec.ChannelID)
+		require.Equal(t, bID, rec.PeerID)
+		require.NotEmpty(t, rec.MessageType)
+
+		msg := &p2ptest.Message{}
+		require.NoError(t, proto.Unmarshal(rec.Message, msg))
+		values[rec.Outbound] = msg.Value
+	}
+	require.Equal(t, map[bool]string{true: "foo", false: "bar"}, values)
+}
+
 func TestRouter_Channel_Broadcast(t *testing.T) {
 	t.Cleanup(leaktest.Check(t))
 
@@ -0,0 +1,156 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// TrafficStats are counters of the bytes of the messages sent and received.
+type TrafficStats struct {
+	SendBytes int64
+	RecvBytes int64
+}
+
+// rateLimiter is a token bucket limiting the number of bytes sent per second,
+// with a burst of one second worth of bytes. A message is let through as soon
+// as the bucket isn't in debt, and its size is then taken from the bucket, so
+// that messages larger than the burst are sent whole and only delay the
+// following ones.
+type rateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a message of the given size can be sent, and takes its
+// size from the bucket.
+func (l *rateLimiter) wait(ctx context.Context, size int) error {
+	for {
+		l.mtx.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+		l.last = now
+
+		if l.tokens >= 0 {
+			l.tokens -= float64(size)
+			l.mtx.Unlock()
+			return nil
+		}
+		delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+		l.mtx.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// trafficCounter counts the bytes sent and received by the router, per
+// channel since it started, and per channel of each connected peer.
+type trafficCounter struct {
+	mtx      sync.Mutex
+	channels map[ChannelID]*TrafficStats
+	peers    map[types.NodeID]map[ChannelID]*TrafficStats
+}
+
+func newTrafficCounter() *trafficCounter {
+	return &trafficCounter{
+		channels: make(map[ChannelID]*TrafficStats),
+		peers:    make(map[types.NodeID]map[ChannelID]*TrafficStats),
+	}
+}
+
+func (c *trafficCounter) add(peerID types.NodeID, chID ChannelID, send, recv int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	channel, ok := c.channels[chID]
+	if !ok {
+		channel = &TrafficStats{}
+		c.channels[chID] = channel
+	}
+	channel.SendBytes += int64(send)
+	channel.RecvBytes += int64(recv)
+
+	peer, ok := c.peers[peerID]
+	if !ok {
+		peer = make(map[ChannelID]*TrafficStats)
+		c.peers[peerID] = peer
+	}
+	peerChannel, ok := peer[chID]
+	if !ok {
+		peerChannel = &TrafficStats{}
+		peer[chID] = peerChannel
+	}
+	peerChannel.SendBytes += int64(send)
+	peerChannel.RecvBytes += int64(recv)
+}
+
+// removePeer drops the counters of a disconnected peer.
+func (c *trafficCounter) removePeer(peerID types.NodeID) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.peers, peerID)
+}
+
+func (c *trafficCounter) channelStats() map[ChannelID]TrafficStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := make(map[ChannelID]TrafficStats, len(c.channels))
+	for chID, channel := range c.channels {
+		stats[chID] = *channel
+	}
+	return stats
+}
+
+func (c *trafficCounter) peerStats(peerID types.NodeID) map[ChannelID]TrafficStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := make(map[ChannelID]TrafficStats, len(c.peers[peerID]))
+	for chID, channel := range c.peers[peerID] {
+		stats[chID] = *channel
+	}
+	return stats
+}
@@ -0,0 +1,80 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/pkg/types"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a burst of one second worth of bytes goes through at once, and a
+	// message larger than the remaining tokens still goes through whole
+	limiter := newRateLimiter(1000)
+	start := time.Now()
+	require.NoError(t, limiter.wait(ctx, 600))
+	require.NoError(t, limiter.wait(ctx, 600))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// the bucket is 200 bytes in debt now, so the next message waits for it
+	start = time.Now()
+	require.NoError(t, limiter.wait(ctx, 100))
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	// waiting is interrupted by the context
+	require.NoError(t, limiter.wait(ctx, 5000))
+	cancel()
+	require.ErrorIs(t, limiter.wait(ctx, 1), context.Canceled)
+}
+
+func TestTrafficCounter(t *testing.T) {
+	peerA := types.NodeID("aa")
+	peerB := types.NodeID("bb")
+
+	counter := newTrafficCounter()
+	counter.add(peerA, 0x20, 10, 0)
+	counter.add(peerA, 0x20, 0, 5)
+	counter.add(peerA, 0x40, 100, 0)
+	counter.add(peerB, 0x20, 1, 2)
+
+	require.Equal(t, map[ChannelID]TrafficStats{
+		0x20: {SendBytes: 11, RecvBytes: 7},
+		0x40: {SendBytes: 100},
+	}, counter.channelStats())
+	require.Equal(t, map[ChannelID]TrafficStats{
+		0x20: {SendBytes: 10, RecvBytes: 5},
+		0x40: {SendBytes: 100},
+	}, counter.peerStats(peerA))
+
+	// the counters of disconnected peers are dropped, but the channel totals
+	// are kept
+	counter.removePeer(peerA)
+	require.Empty(t, counter.peerStats(peerA))
+	require.Equal(t, TrafficStats{SendBytes: 11, RecvBytes: 7}, counter.channelStats()[0x20])
+}
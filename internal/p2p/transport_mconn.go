@@ -30,8 +30,6 @@ import (
 	"strconv"
 	"sync"
 
-	"golang.org/x/net/netutil"
-
 	"github.com/bhojpur/state/internal/libs/protoio"
 	"github.com/bhojpur/state/internal/p2p/conn"
 	p2pproto "github.com/bhojpur/state/pkg/api/v1/p2p"
@@ -68,6 +66,7 @@ type MConnTransport struct {
 	closeOnce sync.Once
 	doneCh    chan struct{}
 	listener  net.Listener
+	limiter   *limitListener
 }
 
 // NewMConnTransport sets up a new MConnection transport. This uses the
@@ -85,6 +84,7 @@ func NewMConnTransport(
 		mConnConfig:  mConnConfig,
 		doneCh:       make(chan struct{}),
 		channelDescs: channelDescs,
+		limiter:      newLimitListener(int(options.MaxAcceptedConnections)),
 	}
 }
 
@@ -139,15 +139,13 @@ func (m *MConnTransport) Listen(endpoint *Endpoint) error {
 	if err != nil {
 		return err
 	}
-	if m.options.MaxAcceptedConnections > 0 {
-		// FIXME: This will establish the inbound connection but simply hang it
-		// until another connection is released. It would probably be better to
-		// return an error to the remote peer or close the connection. This is
-		// also a DoS vector since the connection will take up kernel resources.
-		// This was just carried over from the legacy P2P stack.
-		listener = netutil.LimitListener(listener, int(m.options.MaxAcceptedConnections))
-	}
-	m.listener = listener
+	// FIXME: Beyond MaxAcceptedConnections, this will establish the inbound
+	// connection but simply hang it until another connection is released. It
+	// would probably be better to return an error to the remote peer or close
+	// the connection. This is also a DoS vector since the connection will take
+	// up kernel resources. This was just carried over from the legacy P2P stack.
+	m.limiter.Listener = listener
+	m.listener = m.limiter
 
 	return nil
 }
@@ -225,6 +223,13 @@ func (m *MConnTransport) Close() error {
 	return err
 }
 
+// SetMaxAcceptedConnections changes the maximum number of simultaneous accepted
+// connections, 0 meaning unlimited. Connections accepted beyond a lowered
+// limit are not closed.
+func (m *MConnTransport) SetMaxAcceptedConnections(max uint32) {
+	m.limiter.setMax(int(max))
+}
+
 // SetChannels sets the channel descriptors to be used when
 // establishing a connection.
 //
@@ -508,3 +513,85 @@ func (c *mConnConnection) Close() error {
 	})
 	return err
 }
+
+// limitListener is a net.Listener that accepts at most max simultaneous
+// connections, like netutil.LimitListener, but whose limit can be changed
+// while it is in use. A limit of 0 means unlimited.
+type limitListener struct {
+	net.Listener
+
+	mtx    sync.Mutex
+	cond   *sync.Cond
+	max    int
+	active int
+	closed bool
+}
+
+func newLimitListener(max int) *limitListener {
+	l := &limitListener{max: max}
+	l.cond = sync.NewCond(&l.mtx)
+	return l
+}
+
+func (l *limitListener) setMax(max int) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.max = max
+	l.cond.Broadcast()
+}
+
+// acquire waits for a free connection slot, and reports whether one was taken
+// before the listener was closed.
+func (l *limitListener) acquire() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for !l.closed && l.max > 0 && l.active >= l.max {
+		l.cond.Wait()
+	}
+	if l.closed {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *limitListener) release() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.active--
+	l.cond.Broadcast()
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	if !l.acquire() {
+		return nil, net.ErrClosed
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	return &limitListenerConn{Conn: c, release: l.release}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.mtx.Lock()
+	l.closed = true
+	l.cond.Broadcast()
+	l.mtx.Unlock()
+	return err
+}
+
+// limitListenerConn releases its slot in a limitListener when closed.
+type limitListenerConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
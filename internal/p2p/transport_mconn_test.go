@@ -146,6 +146,20 @@ func TestMConnTransport_AcceptMaxAcceptedConnections(t *testing.T) {
 	accept3 := <-acceptCh
 	defer accept3.Close()
 	require.Equal(t, dial3.LocalEndpoint(), accept3.RemoteEndpoint())
+
+	// Raising the limit lets a further connection through.
+	dial4, err := transport.Dial(ctx, endpoint)
+	require.NoError(t, err)
+	defer dial4.Close()
+	select {
+	case <-acceptCh:
+		require.Fail(t, "unexpected accept")
+	case <-time.After(time.Second):
+	}
+	transport.SetMaxAcceptedConnections(3)
+	accept4 := <-acceptCh
+	defer accept4.Close()
+	require.Equal(t, dial4.LocalEndpoint(), accept4.RemoteEndpoint())
 }
 
 func TestMConnTransport_Listen(t *testing.T) {
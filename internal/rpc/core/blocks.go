@@ -221,7 +221,7 @@ func (env *Environment) BlockResults(ctx context.Context, req *coretypes.Request
 
 // BlockSearch searches for a paginated set of blocks matching the provided query.
 func (env *Environment) BlockSearch(ctx context.Context, req *coretypes.RequestBlockSearch) (*coretypes.ResultBlockSearch, error) {
-	if !indexer.KVSinkEnabled(env.EventSinks) {
+	if !indexer.KVSinkEnabled(env.eventSinks()) {
 		return nil, fmt.Errorf("block searching is disabled due to no kvEventSink")
 	}
 
@@ -231,7 +231,7 @@ func (env *Environment) BlockSearch(ctx context.Context, req *coretypes.RequestB
 	}
 
 	var kvsink indexer.EventSink
-	for _, sink := range env.EventSinks {
+	for _, sink := range env.eventSinks() {
 		if sink.Type() == indexer.KV {
 			kvsink = sink
 		}
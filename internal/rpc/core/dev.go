@@ -22,7 +22,12 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
 	"github.com/bhojpur/state/pkg/rpc/coretypes"
 )
 
@@ -31,3 +36,85 @@ func (env *Environment) UnsafeFlushMempool(ctx context.Context) (*coretypes.Resu
 	env.Mempool.Flush()
 	return &coretypes.ResultUnsafeFlushMempool{}, nil
 }
+
+// UnsafeSetLogLevel changes the log levels of the node at runtime. The level
+// is either a single level, such as "debug", or a level followed by
+// per-module overrides, such as "info,consensus=debug,p2p=error".
+func (env *Environment) UnsafeSetLogLevel(ctx context.Context, req *coretypes.RequestSetLogLevel) (*coretypes.ResultUnsafeSetLogLevel, error) {
+	if err := log.SetLevel(env.Logger, req.Level); err != nil {
+		return nil, err
+	}
+	level, err := log.GetLevel(env.Logger)
+	if err != nil {
+		return nil, err
+	}
+	env.Logger.Info("changed log level", "level", level)
+	return &coretypes.ResultUnsafeSetLogLevel{Level: level}, nil
+}
+
+// UnsafeReloadConfig re-reads the config file of the node, and applies the
+// changed settings that can take effect without a restart. Changed settings
+// that require a restart are reported as rejected.
+func (env *Environment) UnsafeReloadConfig(ctx context.Context) (*coretypes.ResultUnsafeReloadConfig, error) {
+	if env.ReloadConfig == nil {
+		return nil, errors.New("the node does not support reloading its configuration")
+	}
+	report, err := env.ReloadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &coretypes.ResultUnsafeReloadConfig{
+		Applied:  configChanges(report.Applied),
+		Rejected: configChanges(report.Rejected),
+	}, nil
+}
+
+// UnsafeBackup writes a consistent copy of the databases of the node into a
+// directory on the node's host. The directory must not contain databases yet.
+func (env *Environment) UnsafeBackup(ctx context.Context, req *coretypes.RequestBackup) (*coretypes.ResultUnsafeBackup, error) {
+	if env.Backup == nil {
+		return nil, errors.New("the node does not support backups")
+	}
+	if req.Dir == "" {
+		return nil, errors.New("a backup directory is required")
+	}
+	ids, err := env.Backup(ctx, req.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &coretypes.ResultUnsafeBackup{Dir: req.Dir, Databases: ids}, nil
+}
+
+// UnsafeP2PCapture starts recording the envelopes the node routes to and
+// from its peers into an autofile group on the node's host, or stops the
+// running capture if no path is given.
+func (env *Environment) UnsafeP2PCapture(ctx context.Context, req *coretypes.RequestP2PCapture) (*coretypes.ResultUnsafeP2PCapture, error) {
+	if env.P2PCapture == nil {
+		return nil, errors.New("the node does not support capturing p2p messages")
+	}
+	if req.Path == "" {
+		if err := env.P2PCapture.StopCapture(); err != nil {
+			return nil, err
+		}
+		return &coretypes.ResultUnsafeP2PCapture{}, nil
+	}
+	if req.Duration < 0 {
+		return nil, fmt.Errorf("capture duration can't be negative [%s]", req.Duration)
+	}
+	if err := env.P2PCapture.StartCapture(req.Path, req.Duration); err != nil {
+		return nil, err
+	}
+	res := &coretypes.ResultUnsafeP2PCapture{Path: req.Path}
+	if req.Duration > 0 {
+		res.Until = time.Now().Add(req.Duration)
+	}
+	return res, nil
+}
+
+func configChanges(changes []config.FieldChange) []coretypes.ConfigChange {
+	out := make([]coretypes.ConfigChange, len(changes))
+	for i, c := range changes {
+		out[i] = coretypes.ConfigChange{Key: c.Key, Old: c.Old, New: c.New}
+	}
+	return out
+}
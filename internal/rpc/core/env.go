@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/rs/cors"
@@ -47,6 +48,7 @@ import (
 	"github.com/bhojpur/state/pkg/crypto"
 	"github.com/bhojpur/state/pkg/libs/log"
 	"github.com/bhojpur/state/pkg/libs/strings"
+	"github.com/bhojpur/state/pkg/libs/trace"
 	"github.com/bhojpur/state/pkg/rpc/coretypes"
 	rpcserver "github.com/bhojpur/state/pkg/rpc/jsonrpc/server"
 	"github.com/bhojpur/state/pkg/types"
@@ -81,6 +83,16 @@ type peerManager interface {
 	Addresses(types.NodeID) []p2p.NodeAddress
 }
 
+type trafficCounter interface {
+	ChannelTraffic() map[p2p.ChannelID]p2p.TrafficStats
+	PeerTraffic(types.NodeID) map[p2p.ChannelID]p2p.TrafficStats
+}
+
+type envelopeCapturer interface {
+	StartCapture(path string, duration time.Duration) error
+	StopCapture() error
+}
+
 // Environment contains objects and interfaces used by the RPC. It is expected
 // to be setup once during startup.
 type Environment struct {
@@ -101,6 +113,8 @@ type Environment struct {
 
 	// interfaces for new p2p interfaces
 	PeerManager peerManager
+	Traffic     trafficCounter   // optional
+	P2PCapture  envelopeCapturer // optional
 
 	// objects
 	PubKey            crypto.PubKey
@@ -115,8 +129,73 @@ type Environment struct {
 
 	Config config.RPCConfig
 
+	// Tracer, if set, records a span for each RPC request.
+	Tracer *trace.Tracer
+
+	// ReloadConfig, if set, re-reads the config file of the node and applies
+	// the settings that can be changed while it runs.
+	ReloadConfig func(context.Context) (*config.ReloadReport, error)
+
+	// Backup, if set, checkpoints the databases of the node into a directory
+	// and returns the IDs of the databases written.
+	Backup func(ctx context.Context, dir string) ([]string, error)
+
+	// settings that can be replaced while serving.
+	settingsMtx sync.RWMutex
+	cors        *cors.Cors // nil if CORS is disabled
+
 	// cache of chunked genesis data.
 	genChunks []string
+
+	// cache of responses for immutable heights, and the latest height it saw.
+	responseCache *rpcserver.ResponseCache
+	cacheMtx      sync.Mutex
+	cacheHeight   int64
+}
+
+// SetEventSinks replaces the event sinks queried by the RPC service.
+func (env *Environment) SetEventSinks(sinks []indexer.EventSink) {
+	env.settingsMtx.Lock()
+	defer env.settingsMtx.Unlock()
+	env.EventSinks = sinks
+}
+
+func (env *Environment) eventSinks() []indexer.EventSink {
+	env.settingsMtx.RLock()
+	defer env.settingsMtx.RUnlock()
+	return env.EventSinks
+}
+
+// SetCORS applies the CORS settings of cfg to the RPC service.
+func (env *Environment) SetCORS(cfg *config.RPCConfig) {
+	var c *cors.Cors
+	if cfg.IsCorsEnabled() {
+		c = cors.New(cors.Options{
+			AllowedOrigins: cfg.CORSAllowedOrigins,
+			AllowedMethods: cfg.CORSAllowedMethods,
+			AllowedHeaders: cfg.CORSAllowedHeaders,
+		})
+	}
+
+	env.settingsMtx.Lock()
+	defer env.settingsMtx.Unlock()
+	env.cors = c
+}
+
+// corsHandler applies the current CORS settings to requests before passing
+// them on to next.
+func (env *Environment) corsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env.settingsMtx.RLock()
+		c := env.cors
+		env.settingsMtx.RUnlock()
+
+		if c == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c.ServeHTTP(w, r, next.ServeHTTP)
+	})
 }
 
 func validatePage(pagePtr *int, perPage, totalCount int) (int, error) {
@@ -215,6 +294,26 @@ func (env *Environment) getHeight(latestHeight int64, heightPtr *int64) (int64,
 	return latestHeight, nil
 }
 
+// IsImmutableHeight implements RPCCacheable. Results are immutable for heights
+// from the block store base up to, but excluding, the latest committed height,
+// whose commit may still change. Pruned heights are thus never served from the
+// cache. If the latest height decreases due to a rollback, the response cache
+// is purged, since the rolled back heights may be committed differently.
+func (env *Environment) IsImmutableHeight(height *coretypes.Int64) bool {
+	latest := env.BlockStore.Height()
+
+	env.cacheMtx.Lock()
+	if latest < env.cacheHeight && env.responseCache != nil {
+		env.Logger.Info("Purging RPC response cache after rollback",
+			"height", latest, "previous", env.cacheHeight)
+		env.responseCache.Purge()
+	}
+	env.cacheHeight = latest
+	env.cacheMtx.Unlock()
+
+	return height != nil && int64(*height) >= env.BlockStore.Base() && int64(*height) < latest
+}
+
 func (env *Environment) latestUncommittedHeight() int64 {
 	if env.ConsensusReactor != nil {
 		// consensus reactor can be nil in inspect mode.
@@ -240,9 +339,15 @@ func (env *Environment) StartService(ctx context.Context, conf *config.Config) (
 		fmt.Sprintf("Listener(@%v)", conf.P2P.ExternalAddress),
 	}
 
+	env.SetCORS(conf.RPC)
+
 	listenAddrs := strings.SplitAndTrimEmpty(conf.RPC.ListenAddress, ",", " ")
+	if conf.RPC.ResponseCacheSize > 0 {
+		env.responseCache = rpcserver.NewResponseCache(conf.RPC.ResponseCacheSize)
+	}
 	routes := NewRoutesMap(env, &RouteOptions{
 		Unsafe: conf.RPC.Unsafe,
+		Cache:  env.responseCache,
 	})
 
 	cfg := rpcserver.DefaultConfig()
@@ -255,6 +360,25 @@ func (env *Environment) StartService(ctx context.Context, conf *config.Config) (
 		cfg.WriteTimeout = conf.RPC.TimeoutBroadcastTxCommit + 1*time.Second
 	}
 
+	methodCosts, err := conf.RPC.MethodCostWeights()
+	if err != nil {
+		return nil, err
+	}
+	for method := range methodCosts {
+		if _, ok := routes[method]; !ok {
+			return nil, fmt.Errorf("method-costs: unknown RPC method %q", method)
+		}
+	}
+	rateLimiter := rpcserver.NewRateLimiter(rpcserver.RateLimitConfig{
+		Rate:         conf.RPC.RateLimit,
+		Burst:        conf.RPC.RateLimitBurst,
+		APIKeyHeader: conf.RPC.APIKeyHeader,
+		APIKeys:      conf.RPC.APIKeys,
+		KeyRate:      conf.RPC.APIKeyRateLimit,
+		KeyBurst:     conf.RPC.APIKeyRateLimitBurst,
+		MethodCosts:  methodCosts,
+	})
+
 	// If the event log is enabled, subscribe to all events published to the
 	// event bus, and forward them to the event log.
 	if lg := env.EventLog; lg != nil {
@@ -323,15 +447,11 @@ func (env *Environment) StartService(ctx context.Context, conf *config.Config) (
 			return nil, err
 		}
 
-		var rootHandler http.Handler = mux
-		if conf.RPC.IsCorsEnabled() {
-			corsMiddleware := cors.New(cors.Options{
-				AllowedOrigins: conf.RPC.CORSAllowedOrigins,
-				AllowedMethods: conf.RPC.CORSAllowedMethods,
-				AllowedHeaders: conf.RPC.CORSAllowedHeaders,
-			})
-			rootHandler = corsMiddleware.Handler(mux)
+		var rootHandler = rateLimiter.Handler(mux)
+		if env.Tracer != nil {
+			rootHandler = env.Tracer.Handler(rootHandler)
 		}
+		rootHandler = env.corsHandler(rootHandler)
 		if conf.RPC.IsTLSEnabled() {
 			go func() {
 				if err := rpcserver.ServeTLS(
@@ -32,6 +32,7 @@ import (
 	pbsb "github.com/bhojpur/state/internal/pubsub"
 	tmquery "github.com/bhojpur/state/internal/pubsub/query"
 	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	rpcserver "github.com/bhojpur/state/pkg/rpc/jsonrpc/server"
 	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
 )
 
@@ -53,6 +54,8 @@ func (env *Environment) Subscribe(ctx context.Context, req *coretypes.RequestSub
 		return nil, fmt.Errorf("max_subscription_clients %d reached", env.Config.MaxSubscriptionClients)
 	} else if env.EventBus.NumClientSubscriptions(addr) >= env.Config.MaxSubscriptionsPerClient {
 		return nil, fmt.Errorf("max_subscriptions_per_client %d reached", env.Config.MaxSubscriptionsPerClient)
+	} else if limit := env.Config.MaxClientSubscriptions; limit > 0 && env.numClientSubscriptions(ctx) >= limit {
+		return nil, fmt.Errorf("max_client_subscriptions %d reached", limit)
 	} else if len(req.Query) > maxQueryLength {
 		return nil, errors.New("maximum query length exceeded")
 	}
@@ -149,6 +152,16 @@ func (env *Environment) UnsubscribeAll(ctx context.Context) (*coretypes.ResultUn
 	return &coretypes.ResultUnsubscribe{}, nil
 }
 
+// numClientSubscriptions returns the number of subscriptions held by the
+// client making the call in ctx, across all of its websocket connections.
+func (env *Environment) numClientSubscriptions(ctx context.Context) int {
+	n := 0
+	for _, addr := range rpcserver.ClientConnections(ctx) {
+		n += env.EventBus.NumClientSubscriptions(addr)
+	}
+	return n
+}
+
 // Events applies a query to the event log. If an event log is not enabled,
 // Events reports an error. Otherwise, it filters the current contents of the
 // log to return matching events.
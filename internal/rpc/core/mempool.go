@@ -110,7 +110,7 @@ func (env *Environment) BroadcastTxCommit(ctx context.Context, req *coretypes.Re
 			}, fmt.Errorf("transaction encountered error (%s)", r.MempoolError)
 		}
 
-		if !indexer.KVSinkEnabled(env.EventSinks) {
+		if !indexer.KVSinkEnabled(env.eventSinks()) {
 			return &coretypes.ResultBroadcastTxCommit{
 					CheckTx: *r,
 					Hash:    req.Tx.Hash(),
@@ -24,7 +24,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
+	"github.com/bhojpur/state/internal/p2p"
 	"github.com/bhojpur/state/pkg/rpc/coretypes"
 )
 
@@ -39,10 +41,23 @@ func (env *Environment) NetInfo(ctx context.Context) (*coretypes.ResultNetInfo,
 			continue
 		}
 
-		peers = append(peers, coretypes.Peer{
+		info := coretypes.Peer{
 			ID:  peer,
 			URL: addrs[0].String(),
-		})
+		}
+		if env.Traffic != nil {
+			info.Channels = channelTraffic(env.Traffic.PeerTraffic(peer))
+			for _, channel := range info.Channels {
+				info.SendBytes += channel.SendBytes
+				info.RecvBytes += channel.RecvBytes
+			}
+		}
+		peers = append(peers, info)
+	}
+
+	var channels []coretypes.ChannelTraffic
+	if env.Traffic != nil {
+		channels = channelTraffic(env.Traffic.ChannelTraffic())
 	}
 
 	return &coretypes.ResultNetInfo{
@@ -50,9 +65,25 @@ func (env *Environment) NetInfo(ctx context.Context) (*coretypes.ResultNetInfo,
 		Listeners: env.Listeners,
 		NPeers:    len(peers),
 		Peers:     peers,
+		Channels:  channels,
 	}, nil
 }
 
+// channelTraffic converts traffic stats by channel into a list sorted by
+// channel ID.
+func channelTraffic(stats map[p2p.ChannelID]p2p.TrafficStats) []coretypes.ChannelTraffic {
+	channels := make([]coretypes.ChannelTraffic, 0, len(stats))
+	for chID, traffic := range stats {
+		channels = append(channels, coretypes.ChannelTraffic{
+			ID:        uint16(chID),
+			SendBytes: traffic.SendBytes,
+			RecvBytes: traffic.RecvBytes,
+		})
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].ID < channels[j].ID })
+	return channels
+}
+
 // Genesis returns genesis file.
 func (env *Environment) Genesis(ctx context.Context) (*coretypes.ResultGenesis, error) {
 	if len(env.genChunks) > 1 {
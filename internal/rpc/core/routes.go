@@ -35,6 +35,10 @@ type RoutesMap map[string]*rpc.RPCFunc
 // is ready for use and provides defaults as specified.
 type RouteOptions struct {
 	Unsafe bool // include "unsafe" methods (default false)
+
+	// If set and svc implements RPCCacheable, results of block, block_results,
+	// commit, header and validators for immutable heights are cached here.
+	Cache *rpc.ResponseCache
 }
 
 // NewRoutesMap constructs an RPC routing map for the given service
@@ -92,6 +96,21 @@ func NewRoutesMap(svc RPCService, opts *RouteOptions) RoutesMap {
 	}
 	if u, ok := svc.(RPCUnsafe); ok && opts.Unsafe {
 		out["unsafe_flush_mempool"] = rpc.NewRPCFunc(u.UnsafeFlushMempool)
+		out["unsafe_set_log_level"] = rpc.NewRPCFunc(u.UnsafeSetLogLevel)
+		out["unsafe_reload_config"] = rpc.NewRPCFunc(u.UnsafeReloadConfig)
+		out["unsafe_backup"] = rpc.NewRPCFunc(u.UnsafeBackup)
+		out["unsafe_p2p_capture"] = rpc.NewRPCFunc(u.UnsafeP2PCapture)
+	}
+	if c, ok := svc.(RPCCacheable); ok && opts.Cache != nil {
+		blockInfoPolicy := func(ctx context.Context, params interface{}) bool {
+			return c.IsImmutableHeight(params.(*coretypes.RequestBlockInfo).Height)
+		}
+		for _, name := range []string{"block", "block_results", "commit", "header"} {
+			out[name].WithCache(opts.Cache, blockInfoPolicy)
+		}
+		out["validators"].WithCache(opts.Cache, func(ctx context.Context, params interface{}) bool {
+			return c.IsImmutableHeight(params.(*coretypes.RequestValidators).Height)
+		})
 	}
 	return out
 }
@@ -138,4 +157,16 @@ type RPCService interface {
 // exported by the RPC service.
 type RPCUnsafe interface {
 	UnsafeFlushMempool(ctx context.Context) (*coretypes.ResultUnsafeFlushMempool, error)
+	UnsafeSetLogLevel(ctx context.Context, req *coretypes.RequestSetLogLevel) (*coretypes.ResultUnsafeSetLogLevel, error)
+	UnsafeReloadConfig(ctx context.Context) (*coretypes.ResultUnsafeReloadConfig, error)
+	UnsafeBackup(ctx context.Context, req *coretypes.RequestBackup) (*coretypes.ResultUnsafeBackup, error)
+	UnsafeP2PCapture(ctx context.Context, req *coretypes.RequestP2PCapture) (*coretypes.ResultUnsafeP2PCapture, error)
+}
+
+// RPCCacheable defines the method an RPC service implements to allow caching
+// of results for past heights.
+type RPCCacheable interface {
+	// IsImmutableHeight reports whether results for the given height, which is
+	// nil for the latest height, will never change.
+	IsImmutableHeight(height *coretypes.Int64) bool
 }
@@ -38,11 +38,11 @@ import (
 // place.
 func (env *Environment) Tx(ctx context.Context, req *coretypes.RequestTx) (*coretypes.ResultTx, error) {
 	// if index is disabled, return error
-	if !indexer.KVSinkEnabled(env.EventSinks) {
+	if !indexer.KVSinkEnabled(env.eventSinks()) {
 		return nil, errors.New("transaction querying is disabled due to no kvEventSink")
 	}
 
-	for _, sink := range env.EventSinks {
+	for _, sink := range env.eventSinks() {
 		if sink.Type() == indexer.KV {
 			r, err := sink.GetTxByHash(req.Hash)
 			if r == nil {
@@ -72,7 +72,7 @@ func (env *Environment) Tx(ctx context.Context, req *coretypes.RequestTx) (*core
 // TxSearch allows you to query for multiple transactions results. It returns a
 // list of transactions (maximum ?per_page entries) and the total count.
 func (env *Environment) TxSearch(ctx context.Context, req *coretypes.RequestTxSearch) (*coretypes.ResultTxSearch, error) {
-	if !indexer.KVSinkEnabled(env.EventSinks) {
+	if !indexer.KVSinkEnabled(env.eventSinks()) {
 		return nil, fmt.Errorf("transaction searching is disabled due to no kvEventSink")
 	} else if len(req.Query) > maxQueryLength {
 		return nil, errors.New("maximum query length exceeded")
@@ -83,7 +83,7 @@ func (env *Environment) TxSearch(ctx context.Context, req *coretypes.RequestTxSe
 		return nil, err
 	}
 
-	for _, sink := range env.EventSinks {
+	for _, sink := range env.eventSinks() {
 		if sink.Type() == indexer.KV {
 			results, err := sink.SearchTxEvents(ctx, q)
 			if err != nil {
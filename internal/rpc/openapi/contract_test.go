@@ -0,0 +1,383 @@
+package openapi_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/example/kvstore"
+	"github.com/bhojpur/state/internal/rpc/core"
+	"github.com/bhojpur/state/internal/rpc/openapi"
+	typespb "github.com/bhojpur/state/pkg/api/v1/types"
+	"github.com/bhojpur/state/pkg/crypto"
+	"github.com/bhojpur/state/pkg/privval"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	rpcclient "github.com/bhojpur/state/pkg/rpc/jsonrpc/client"
+	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
+	rpctest "github.com/bhojpur/state/pkg/rpc/test"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// contractCase describes how to exercise a single route.
+type contractCase struct {
+	params interface{} // JSON-RPC params, or nil
+
+	// query holds the URI parameters of a GET request for the route. Routes
+	// without parameters are always exercised via GET as well.
+	query url.Values
+
+	// If set, the route may report a well-formed JSON-RPC error instead of a
+	// result, e.g. because the state it operates on has already changed.
+	allowError bool
+}
+
+// TestContract checks the result of every route of a running node against
+// the OpenAPI document generated for the routes.
+func TestContract(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conf, err := rpctest.CreateConfig(t, t.Name())
+	require.NoError(t, err)
+	app := kvstore.NewApplication()
+	node, closer, err := rpctest.StartBhojpurState(ctx, conf, app, rpctest.SuppressStdout)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, closer(ctx))
+		assert.NoError(t, app.Close())
+		node.Wait()
+	})
+
+	routes := core.NewRoutesMap(&core.Environment{}, &core.RouteOptions{Unsafe: conf.RPC.Unsafe})
+	doc := openapi.Generate(routes)
+	c := &contractClient{
+		addr: strings.Replace(conf.RPC.ListenAddress, "tcp://", "http://", 1),
+	}
+
+	// Produce a committed transaction and a couple of blocks to query.
+	var committed coretypes.ResultBroadcastTxCommit
+	c.mustCall(ctx, t, "broadcast_tx_commit", map[string]interface{}{"tx": types.Tx("contract=commit")}, &committed)
+	require.Zero(t, committed.TxResult.Code)
+	c.waitForHeight(ctx, t, committed.Height+1)
+
+	var block coretypes.ResultBlock
+	c.mustCall(ctx, t, "block", map[string]interface{}{"height": "1"}, &block)
+	blockHash := block.BlockID.Hash.String()
+
+	pv, err := privval.LoadFilePV(conf.PrivValidator.KeyFile(), conf.PrivValidator.StateFile())
+	require.NoError(t, err)
+	evidence := newDuplicateVoteEvidence(t, pv, conf.ChainID(), block.Block.Time)
+
+	pending := types.Tx("contract=pending")
+	heightOne := url.Values{"height": {"1"}}
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	cases := map[string]contractCase{
+		"events": {params: map[string]interface{}{
+			"filter":   map[string]interface{}{"query": "tm.event = 'NewBlock'"},
+			"maxItems": 1,
+		}},
+		"subscribe":       {params: map[string]interface{}{"query": "tm.event = 'NewBlockHeader'"}},
+		"unsubscribe":     {params: map[string]interface{}{"query": "tm.event = 'NewBlockHeader'"}},
+		"unsubscribe_all": {allowError: true}, // no subscriptions are left
+
+		"health":   {},
+		"status":   {},
+		"net_info": {},
+		"blockchain": {
+			params: map[string]interface{}{"minHeight": "1", "maxHeight": "2"},
+			query:  url.Values{"minHeight": {"1"}, "maxHeight": {"2"}},
+		},
+		"genesis":         {},
+		"genesis_chunked": {params: map[string]interface{}{"chunk": "0"}, query: url.Values{"chunk": {"0"}}},
+		"header":          {params: map[string]interface{}{"height": "1"}, query: heightOne},
+		"header_by_hash":  {params: map[string]interface{}{"hash": blockHash}, query: url.Values{"hash": {"0x" + blockHash}}},
+		"block":           {params: map[string]interface{}{"height": "1"}, query: heightOne},
+		"block_by_hash":   {params: map[string]interface{}{"hash": blockHash}, query: url.Values{"hash": {"0x" + blockHash}}},
+		"block_results":   {params: map[string]interface{}{"height": fmt.Sprint(committed.Height)}},
+		"commit":          {params: map[string]interface{}{"height": "1"}, query: heightOne},
+		"check_tx":        {params: map[string]interface{}{"tx": types.Tx("contract=check")}},
+		"remove_tx": {
+			params:     map[string]interface{}{"txkey": pending.Key()},
+			allowError: true, // the transaction may already be committed
+		},
+		"tx": {params: map[string]interface{}{"hash": committed.Hash.String(), "prove": true}},
+		"tx_search": {
+			params: map[string]interface{}{"query": fmt.Sprintf("tx.height = %d", committed.Height), "prove": true},
+			query:  url.Values{"query": {fmt.Sprintf("%q", fmt.Sprintf("tx.height = %d", committed.Height))}},
+		},
+		"block_search": {
+			params: map[string]interface{}{"query": "block.height >= 1", "per_page": "2"},
+			query:  url.Values{"query": {`"block.height >= 1"`}},
+		},
+		"validators":           {params: map[string]interface{}{"height": "1"}, query: heightOne},
+		"dump_consensus_state": {},
+		"consensus_state":      {},
+		"consensus_params":     {params: map[string]interface{}{"height": "1"}, query: heightOne},
+		"unconfirmed_txs":      {params: map[string]interface{}{"page": "1", "per_page": "10"}},
+		"num_unconfirmed_txs":  {},
+
+		"broadcast_tx_commit": {params: map[string]interface{}{"tx": types.Tx("contract=commit2")}},
+		"broadcast_tx_sync":   {params: map[string]interface{}{"tx": types.Tx("contract=sync")}},
+		"broadcast_tx_async":  {params: map[string]interface{}{"tx": pending}},
+
+		"abci_query": {
+			params: map[string]interface{}{"path": "/key", "data": fmt.Sprintf("%X", "contract")},
+			query:  url.Values{"path": {`"/key"`}, "data": {"0x" + fmt.Sprintf("%X", "contract")}},
+		},
+		"abci_info": {},
+
+		"broadcast_evidence": {params: &coretypes.RequestBroadcastEvidence{Evidence: evidence}},
+
+		"unsafe_flush_mempool": {},
+		"unsafe_set_log_level": {
+			params: map[string]interface{}{"level": "info,consensus=debug"},
+			query:  url.Values{"level": {`"info,consensus=debug"`}},
+		},
+		"unsafe_reload_config": {},
+		"unsafe_backup": {
+			params:     map[string]interface{}{"dir": backupDir},
+			query:      url.Values{"dir": {fmt.Sprintf("%q", backupDir)}},
+			allowError: true, // in-memory databases cannot be checkpointed
+		},
+	}
+
+	ws, err := rpcclient.NewWS(c.addr, "/websocket")
+	require.NoError(t, err)
+	require.NoError(t, ws.Start(ctx))
+	t.Cleanup(func() { _ = ws.Stop() })
+
+	// Routes are exercised in a fixed order, so that e.g. unsubscribe follows
+	// subscribe, and broadcast_tx_async precedes remove_tx.
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := contractOrder[names[i]], contractOrder[names[j]]
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	for i, name := range names {
+		tc, ok := cases[name]
+		require.True(t, ok, "no contract case for route %q", name)
+		fn := routes[name]
+
+		t.Run(name, func(t *testing.T) {
+			var resp rpctypes.RPCResponse
+			if fn.IsWebsocket() {
+				resp = wsCall(ctx, t, ws, 1000+i, name, tc.params)
+			} else {
+				resp = c.call(ctx, t, name, tc.params)
+			}
+			checkResponse(t, doc, name, resp, tc.allowError)
+
+			if fn.IsWebsocket() || (tc.query == nil && len(fn.ArgNames()) > 0) {
+				return
+			}
+			status, body := c.get(ctx, t, name, tc.query)
+			if status != http.StatusOK {
+				var resp rpctypes.RPCResponse
+				require.NoError(t, json.Unmarshal(body, &resp), "GET /%s: %s", name, body)
+				require.NotNil(t, resp.Error, "GET /%s: status %d without error", name, status)
+				require.True(t, tc.allowError, "GET /%s: %v", name, resp.Error)
+				return
+			}
+			require.NoError(t, doc.ValidateResult(name, body), "GET /%s", name)
+		})
+	}
+}
+
+// contractOrder ranks routes that must be exercised before or after others.
+// Routes not listed have rank zero.
+var contractOrder = map[string]int{
+	"broadcast_tx_async":   -1,
+	"subscribe":            1,
+	"unsubscribe":          2,
+	"unsubscribe_all":      3,
+	"unsafe_flush_mempool": 4,
+}
+
+func checkResponse(t *testing.T, doc *openapi.Document, method string, resp rpctypes.RPCResponse, allowError bool) {
+	t.Helper()
+
+	if resp.Error != nil {
+		require.True(t, allowError, "%s: %v", method, resp.Error)
+		require.NotZero(t, resp.Error.Code, "%s: error without code", method)
+		require.NotEmpty(t, resp.Error.Message, "%s: error without message", method)
+		return
+	}
+	require.NoError(t, doc.ValidateResult(method, resp.Result), "%s", method)
+}
+
+// contractClient calls routes of a node via HTTP, exposing the complete
+// responses.
+type contractClient struct {
+	addr string
+	id   int
+}
+
+func (c *contractClient) call(ctx context.Context, t *testing.T, method string, params interface{}) rpctypes.RPCResponse {
+	t.Helper()
+
+	c.id++
+	req := rpctypes.NewRequest(c.id)
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	require.NoError(t, req.SetMethodAndParams(method, params))
+	bz, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.addr, bytes.NewReader(bz))
+	require.NoError(t, err)
+	hreq.Header.Set("Content-Type", "application/json")
+	hresp, err := http.DefaultClient.Do(hreq)
+	require.NoError(t, err)
+	defer hresp.Body.Close()
+	body, err := io.ReadAll(hresp.Body)
+	require.NoError(t, err)
+
+	var resp rpctypes.RPCResponse
+	require.NoError(t, json.Unmarshal(body, &resp), "%s: %s", method, body)
+	require.Equal(t, req.ID(), resp.ID())
+	return resp
+}
+
+func (c *contractClient) mustCall(ctx context.Context, t *testing.T, method string, params, result interface{}) {
+	t.Helper()
+
+	resp := c.call(ctx, t, method, params)
+	require.Nil(t, resp.Error, "%s: %v", method, resp.Error)
+	require.NoError(t, json.Unmarshal(resp.Result, result))
+}
+
+func (c *contractClient) get(ctx context.Context, t *testing.T, method string, query url.Values) (int, []byte) {
+	t.Helper()
+
+	u := c.addr + "/" + method
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	require.NoError(t, err)
+	hresp, err := http.DefaultClient.Do(hreq)
+	require.NoError(t, err)
+	defer hresp.Body.Close()
+	body, err := io.ReadAll(hresp.Body)
+	require.NoError(t, err)
+	return hresp.StatusCode, body
+}
+
+func (c *contractClient) waitForHeight(ctx context.Context, t *testing.T, height int64) {
+	t.Helper()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var status coretypes.ResultStatus
+		c.mustCall(ctx, t, "status", nil, &status)
+		if status.SyncInfo.LatestBlockHeight >= height {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// wsCall calls a route via the websocket endpoint, skipping any event
+// notifications received before the response.
+func wsCall(ctx context.Context, t *testing.T, ws *rpcclient.WSClient, id int, method string, params interface{}) rpctypes.RPCResponse {
+	t.Helper()
+
+	req := rpctypes.NewRequest(id)
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	require.NoError(t, req.SetMethodAndParams(method, params))
+	require.NoError(t, ws.Send(ctx, req))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for {
+		select {
+		case resp := <-ws.ResponsesCh:
+			if resp.ID() == req.ID() {
+				return resp
+			}
+		case <-ctx.Done():
+			t.Fatalf("%s: no response: %v", method, ctx.Err())
+		}
+	}
+}
+
+func newDuplicateVoteEvidence(t *testing.T, pv *privval.FilePV, chainID string, timestamp time.Time) *types.DuplicateVoteEvidence {
+	t.Helper()
+
+	vote := types.Vote{
+		ValidatorAddress: pv.Key.Address,
+		ValidatorIndex:   0,
+		Height:           1,
+		Round:            0,
+		Type:             typespb.PrevoteType,
+		Timestamp:        timestamp,
+		BlockID: types.BlockID{
+			Hash: crypto.Checksum([]byte("blockhash1")),
+			PartSetHeader: types.PartSetHeader{
+				Total: 1000,
+				Hash:  crypto.Checksum([]byte("partset")),
+			},
+		},
+	}
+	vote2 := vote
+	vote2.BlockID.Hash = crypto.Checksum([]byte("blockhash2"))
+
+	var err error
+	vote.Signature, err = pv.Key.PrivKey.Sign(types.VoteSignBytes(chainID, vote.ToProto()))
+	require.NoError(t, err)
+	vote2.Signature, err = pv.Key.PrivKey.Sign(types.VoteSignBytes(chainID, vote2.ToProto()))
+	require.NoError(t, err)
+
+	valSet := types.NewValidatorSet([]*types.Validator{types.NewValidator(pv.Key.PubKey, 10)})
+	ev, err := types.NewDuplicateVoteEvidence(&vote, &vote2, timestamp, valSet)
+	require.NoError(t, err)
+	return ev
+}
@@ -0,0 +1,249 @@
+package openapi
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package openapi describes the RPC service of a node as an OpenAPI document,
+// derived from the routes of the service and the types of their parameters
+// and results.
+//
+// Each route that is not restricted to the websocket endpoint is described as
+// a GET operation on its URI path, with one query parameter per request field.
+// Since every route is also callable via JSON-RPC, the document additionally
+// lists all routes, including the websocket-only ones, under the
+// "x-jsonrpc-methods" extension, with the schemas of their params and result.
+
+import (
+	"sort"
+
+	"github.com/bhojpur/state/internal/rpc/core"
+	"github.com/bhojpur/state/pkg/version"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification to which
+// generated documents conform.
+const OpenAPIVersion = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// Methods describes each route as a JSON-RPC method.
+	Methods map[string]*Method `json:"x-jsonrpc-methods"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Components holds the schemas referenced from the rest of the document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a single query parameter of an operation.
+type Parameter struct {
+	Name   string  `json:"name"`
+	In     string  `json:"in"`
+	Schema *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the content of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Method describes a route as a JSON-RPC method.
+type Method struct {
+	Params    *Schema `json:"params,omitempty"`
+	Result    *Schema `json:"result,omitempty"`
+	Websocket bool    `json:"websocket,omitempty"`
+}
+
+const jsonContent = "application/json"
+
+// Generate returns an OpenAPI document describing the given routes.
+func Generate(routes core.RoutesMap) *Document {
+	doc := &Document{
+		OpenAPI: OpenAPIVersion,
+		Info: Info{
+			Title:       "Bhojpur State RPC",
+			Description: "RPC interface of a Bhojpur State node.",
+			Version:     version.Version,
+		},
+		Paths:   make(map[string]*PathItem),
+		Methods: make(map[string]*Method),
+	}
+
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := newSchemaBuilder()
+	for _, name := range names {
+		fn := routes[name]
+
+		method := &Method{Websocket: fn.IsWebsocket()}
+		var params *Schema
+		if pt := fn.ParamType(); pt != nil {
+			params = b.schemaOf(pt)
+			method.Params = params
+		}
+		if rt := fn.ResultType(); rt != nil {
+			method.Result = b.schemaOf(rt)
+		}
+		doc.Methods[name] = method
+		if method.Websocket {
+			continue // not available via GET
+		}
+
+		op := &Operation{
+			OperationID: name,
+			Responses: map[string]*Response{
+				"200": {Description: "the result of " + name},
+				"500": {
+					Description: "a JSON-RPC error",
+					Content:     map[string]*MediaType{jsonContent: {Schema: errorResponseSchema()}},
+				},
+			},
+		}
+		if method.Result != nil {
+			op.Responses["200"].Content = map[string]*MediaType{
+				jsonContent: {Schema: method.Result},
+			}
+		}
+		for _, arg := range fn.ArgNames() {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:   arg,
+				In:     "query",
+				Schema: queryParamSchema(b, params, arg),
+			})
+		}
+		doc.Paths["/"+name] = &PathItem{Get: op}
+	}
+
+	doc.Paths["/"] = &PathItem{Post: &Operation{
+		OperationID: "jsonrpc",
+		Summary:     "JSON-RPC 2.0 endpoint for all methods listed in x-jsonrpc-methods",
+		RequestBody: &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{jsonContent: {Schema: requestSchema()}},
+		},
+		Responses: map[string]*Response{
+			"200": {
+				Description: "a JSON-RPC response",
+				Content:     map[string]*MediaType{jsonContent: {Schema: responseSchema()}},
+			},
+		},
+	}}
+	doc.Components.Schemas = b.components
+	return doc
+}
+
+// queryParamSchema returns the schema of the named field of the params
+// schema, as accepted in a URI query.
+func queryParamSchema(b *schemaBuilder, params *Schema, name string) *Schema {
+	if params != nil && params.Ref != "" {
+		params = b.resolve(params)
+	}
+	if params == nil || params.Properties[name] == nil {
+		return &Schema{Type: "string"}
+	}
+	return params.Properties[name]
+}
+
+func requestSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"jsonrpc": {Type: "string"},
+			"id":      {Description: "request identifier, a string or number"},
+			"method":  {Type: "string"},
+			"params":  {Description: "method parameters, an object or array"},
+		},
+		Required: []string{"jsonrpc", "method"},
+	}
+}
+
+func responseSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"jsonrpc": {Type: "string"},
+			"id":      {Description: "request identifier, a string or number"},
+			"result":  {Description: "method result, see x-jsonrpc-methods"},
+			"error":   errorSchema(),
+		},
+		Required: []string{"jsonrpc"},
+	}
+}
+
+func errorResponseSchema() *Schema {
+	s := responseSchema()
+	s.Required = append(s.Required, "error")
+	return s
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Format: "int32"},
+			"message": {Type: "string"},
+			"data":    {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+}
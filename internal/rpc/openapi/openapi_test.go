@@ -0,0 +1,110 @@
+package openapi
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/internal/rpc/core"
+	"github.com/bhojpur/state/pkg/crypto/ed25519"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+func TestGenerate_CoversRoutes(t *testing.T) {
+	routes := core.NewRoutesMap(&core.Environment{}, &core.RouteOptions{Unsafe: true})
+	doc := Generate(routes)
+
+	for name, fn := range routes {
+		require.Contains(t, doc.Methods, name)
+		if fn.IsWebsocket() {
+			require.NotContains(t, doc.Paths, "/"+name)
+			continue
+		}
+		require.Contains(t, doc.Paths, "/"+name)
+		op := doc.Paths["/"+name].Get
+		require.NotNil(t, op, name)
+		require.Len(t, op.Parameters, len(fn.ArgNames()), name)
+	}
+	require.NotNil(t, doc.Paths["/"].Post)
+
+	// Every reference must resolve to a component.
+	bz, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				require.Contains(t, doc.Components.Schemas, strings.TrimPrefix(ref, refPrefix))
+			}
+			for _, elem := range v {
+				walk(elem)
+			}
+		case []interface{}:
+			for _, elem := range v {
+				walk(elem)
+			}
+		}
+	}
+	var raw interface{}
+	require.NoError(t, json.Unmarshal(bz, &raw))
+	walk(raw)
+}
+
+func TestDocument_ValidateResult(t *testing.T) {
+	doc := Generate(core.NewRoutesMap(&core.Environment{}, nil))
+
+	val := types.NewValidator(ed25519.GenPrivKey().PubKey(), 10)
+	result, err := json.Marshal(&coretypes.ResultValidators{
+		BlockHeight: 1,
+		Validators:  []*types.Validator{val},
+		Count:       1,
+		Total:       1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, doc.ValidateResult("validators", result))
+
+	for _, tc := range []struct {
+		method string
+		result string
+		ok     bool
+	}{
+		{"health", `{}`, true},
+		{"health", `[]`, false},
+		{"num_unconfirmed_txs", `{"n_txs":"1","total":"2","total_bytes":"3","txs":null}`, true},
+		{"num_unconfirmed_txs", `{"n_txs":1}`, false},
+		{"validators", `{"block_height":"1","validators":[{"voting_power":10}]}`, false},
+		{"validators", `{"block_height":"1","unknown":true}`, true},
+		{"no_such_method", `{}`, false},
+	} {
+		err := doc.ValidateResult(tc.method, json.RawMessage(tc.result))
+		if tc.ok {
+			require.NoError(t, err, "%s %s", tc.method, tc.result)
+		} else {
+			require.Error(t, err, "%s %s", tc.method, tc.result)
+		}
+	}
+}
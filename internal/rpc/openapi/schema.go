@@ -0,0 +1,299 @@
+package openapi
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bhojpur/state/pkg/libs/bytes"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+// Schema is an OpenAPI schema object, restricted to the subset needed to
+// describe the JSON encoding of the RPC types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+const refPrefix = "#/components/schemas/"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+// taggedSchema describes a value encoded by the jsontypes package, which wraps
+// the value with its type tag.
+func taggedSchema(description string) *Schema {
+	return &Schema{
+		Type:        "object",
+		Description: description,
+		Nullable:    true,
+		Properties: map[string]*Schema{
+			"type":  {Type: "string", Description: "type tag of the value"},
+			"value": {Description: "the encoded value"},
+		},
+		Required: []string{"type", "value"},
+	}
+}
+
+// int64StringSchema describes an int64 encoded as a JSON string.
+func int64StringSchema() *Schema {
+	return &Schema{Type: "string", Format: "int64"}
+}
+
+// hexSchema describes a byte slice encoded as a hexadecimal string.
+func hexSchema() *Schema {
+	return &Schema{Type: "string", Format: "hex"}
+}
+
+// overrides describes the types whose custom JSON encoding is not derived
+// from their Go structure.
+var overrides = map[reflect.Type]func() *Schema{
+	reflect.TypeOf(coretypes.Int64(0)): func() *Schema {
+		return &Schema{Type: "integer", Format: "int64", Description: "also accepted as a string"}
+	},
+	reflect.TypeOf(bytes.HexBytes{}): hexSchema,
+	reflect.TypeOf(types.Validator{}): func() *Schema {
+		return &Schema{Type: "object", Properties: map[string]*Schema{
+			"address":           hexSchema(),
+			"pub_key":           taggedSchema("public key"),
+			"voting_power":      int64StringSchema(),
+			"proposer_priority": int64StringSchema(),
+		}}
+	},
+	reflect.TypeOf(types.GenesisValidator{}): func() *Schema {
+		return &Schema{Type: "object", Properties: map[string]*Schema{
+			"address": hexSchema(),
+			"pub_key": taggedSchema("public key"),
+			"power":   int64StringSchema(),
+			"name":    {Type: "string"},
+		}}
+	},
+	reflect.TypeOf(coretypes.ValidatorInfo{}): func() *Schema {
+		return &Schema{Type: "object", Properties: map[string]*Schema{
+			"address":      hexSchema(),
+			"pub_key":      taggedSchema("public key"),
+			"voting_power": int64StringSchema(),
+		}}
+	},
+	reflect.TypeOf(coretypes.ResultEvent{}): func() *Schema {
+		return &Schema{Type: "object", Properties: map[string]*Schema{
+			"subscription_id": {Type: "string"},
+			"query":           {Type: "string"},
+			"data":            taggedSchema("event data"),
+			"events":          {Type: "array", Nullable: true, Items: &Schema{Type: "object"}},
+		}}
+	},
+	reflect.TypeOf(coretypes.Evidence{}): func() *Schema {
+		return taggedSchema("evidence")
+	},
+	reflect.TypeOf(coretypes.RequestBroadcastEvidence{}): func() *Schema {
+		return &Schema{Type: "object", Properties: map[string]*Schema{
+			"evidence": taggedSchema("evidence"),
+		}, Required: []string{"evidence"}}
+	},
+	reflect.TypeOf(types.EvidenceList{}): func() *Schema {
+		return &Schema{Type: "array", Nullable: true, Items: taggedSchema("evidence")}
+	},
+}
+
+// schemaBuilder builds schemas for Go types, collecting the schemas of named
+// struct types as components.
+type schemaBuilder struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	taken      map[string]reflect.Type
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		taken:      make(map[string]reflect.Type),
+	}
+}
+
+// schemaOf returns the schema of the JSON encoding of values of type t.
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	if override, ok := overrides[t]; ok {
+		return override()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case t == rawMessageType:
+		return &Schema{Description: "arbitrary JSON value"}
+	case t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface &&
+		(t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType)):
+		return &Schema{Description: "custom JSON encoding of " + t.String()}
+	case t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface &&
+		(t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &Schema{Type: "array", Nullable: true, Items: b.schemaOf(t.Elem())}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", Nullable: true, AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.Ptr:
+		s := b.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s // references are resolved as nullable
+		}
+		s.Nullable = true
+		return s
+	case reflect.Interface:
+		return &Schema{Description: "dynamically typed value", Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return &Schema{Ref: refPrefix + b.component(t)}
+	}
+	return &Schema{Description: "unsupported type " + t.String()}
+}
+
+// resolve returns the schema referenced by s, or s itself if it is not a
+// reference.
+func (b *schemaBuilder) resolve(s *Schema) *Schema {
+	return resolveRef(b.components, s)
+}
+
+func resolveRef(components map[string]*Schema, s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = components[strings.TrimPrefix(s.Ref, refPrefix)]
+	}
+	return s
+}
+
+// component registers the schema of a named struct type as a component,
+// returning its name.
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	// Components are named by package and type, qualified with further path
+	// elements if the name is already taken by another type.
+	dir, name := path.Split(t.PkgPath())
+	name += "." + t.Name()
+	for {
+		if other, ok := b.taken[name]; !ok || other == t {
+			break
+		}
+		var elem string
+		dir, elem = path.Split(strings.TrimSuffix(dir, "/"))
+		if elem == "" {
+			name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + t.Name()
+			break
+		}
+		name = elem + "." + name
+	}
+
+	b.names[t] = name
+	b.taken[name] = t
+	b.components[name] = &Schema{} // placeholder for recursive types
+	*b.components[name] = *b.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of a struct type, following the field rules
+// of encoding/json.
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// Fields of embedded structs are promoted.
+				for pname, prop := range b.structSchema(ft).Properties {
+					if _, ok := s.Properties[pname]; !ok {
+						s.Properties[pname] = prop
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := b.schemaOf(field.Type)
+		for _, opt := range opts[1:] {
+			if opt == "string" {
+				prop = &Schema{Type: "string", Format: prop.Format}
+			}
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
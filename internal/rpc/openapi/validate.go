@@ -0,0 +1,143 @@
+package openapi
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ValidateResult reports whether result, the JSON encoding of a result of the
+// named method, conforms to the result schema of the method in d. Validation
+// is permissive: properties not described by the schema are ignored, and
+// described properties may be absent.
+func (d *Document) ValidateResult(method string, result json.RawMessage) error {
+	m, ok := d.Methods[method]
+	if !ok {
+		return fmt.Errorf("unknown method %q", method)
+	}
+	if m.Result == nil {
+		if len(bytes.TrimSpace(result)) == 0 || bytes.Equal(bytes.TrimSpace(result), []byte("null")) {
+			return nil
+		}
+		return fmt.Errorf("method %q has no result, got %s", method, result)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(result))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("decoding result of %q: %w", method, err)
+	}
+	return d.validate(m.Result, value, "result")
+}
+
+// validate reports whether value conforms to schema s. The location of value
+// in the result is given by at, for use in error messages.
+func (d *Document) validate(s *Schema, value interface{}, at string) error {
+	s = resolveRef(d.Components.Schemas, s)
+	if s == nil {
+		return fmt.Errorf("%s: unresolved schema reference", at)
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null value for non-nullable %s", at, s.Type)
+	}
+
+	switch s.Type {
+	case "":
+		return nil // any value
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(at, s.Type, value)
+		}
+
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return typeError(at, s.Type, value)
+		}
+		if _, err := n.Int64(); err != nil {
+			// Values above the range of int64 are permitted for uint64 fields.
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				return fmt.Errorf("%s: %s is not an integer", at, n)
+			}
+		}
+
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return typeError(at, s.Type, value)
+		}
+
+	case "string":
+		if _, ok := value.(string); !ok {
+			return typeError(at, s.Type, value)
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError(at, s.Type, value)
+		}
+		for i, item := range items {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(at, s.Type, value)
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop := s.Properties[key]
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := d.validate(prop, obj[key], at+"."+key); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("%s: unsupported schema type %q", at, s.Type)
+	}
+	return nil
+}
+
+func typeError(at, want string, value interface{}) error {
+	return fmt.Errorf("%s: got %T, want %s", at, value, want)
+}
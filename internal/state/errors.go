@@ -125,3 +125,15 @@ func (e ErrNoConsensusParamsForHeight) Error() string {
 func (e ErrNoABCIResponsesForHeight) Error() string {
 	return fmt.Sprintf("could not find results for height #%d", e.Height)
 }
+
+// ErrUpgradeHalt is returned by the BlockExecutor once it has committed the
+// block at the height of the scheduled upgrade plan. The node must not
+// execute any further blocks with the current binary.
+type ErrUpgradeHalt struct {
+	Name   string
+	Height int64
+}
+
+func (e ErrUpgradeHalt) Error() string {
+	return fmt.Sprintf("halted for upgrade %q after committing height %d", e.Name, e.Height)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/bhojpur/state/pkg/crypto/encoding"
 	"github.com/bhojpur/state/pkg/crypto/merkle"
 	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/libs/trace"
 	"github.com/bhojpur/state/pkg/types"
 )
 
@@ -66,6 +67,20 @@ type BlockExecutor struct {
 
 	// cache the verification results over a single height
 	cache map[string]struct{}
+
+	// halts block execution at the height of a scheduled upgrade
+	upgrader *Upgrader
+}
+
+// BlockExecutorOption sets an optional parameter on the BlockExecutor.
+type BlockExecutorOption func(*BlockExecutor)
+
+// BlockExecutorWithUpgrader makes the BlockExecutor record upgrade plans
+// returned by the application and halt at the scheduled upgrade height.
+func BlockExecutorWithUpgrader(u *Upgrader) BlockExecutorOption {
+	return func(blockExec *BlockExecutor) {
+		blockExec.upgrader = u
+	}
 }
 
 // NewBlockExecutor returns a new BlockExecutor with the passed-in EventBus.
@@ -78,8 +93,9 @@ func NewBlockExecutor(
 	blockStore BlockStore,
 	eventBus *eventbus.EventBus,
 	metrics *Metrics,
+	options ...BlockExecutorOption,
 ) *BlockExecutor {
-	return &BlockExecutor{
+	blockExec := &BlockExecutor{
 		eventBus:   eventBus,
 		store:      stateStore,
 		appClient:  appClient,
@@ -90,6 +106,10 @@ func NewBlockExecutor(
 		cache:      make(map[string]struct{}),
 		blockStore: blockStore,
 	}
+	for _, option := range options {
+		option(blockExec)
+	}
+	return blockExec
 }
 
 func (blockExec *BlockExecutor) Store() Store {
@@ -123,12 +143,13 @@ func (blockExec *BlockExecutor) CreateProposalBlock(
 	block := state.MakeBlock(height, txs, commit, evidence, proposerAddr)
 
 	localLastCommit := buildLastCommitInfo(block, blockExec.store, state.InitialHeight)
+	extensionsEnabled := state.ConsensusParams.ABCI.VoteExtensionsEnabled(height - 1)
 	rpp, err := blockExec.appClient.PrepareProposal(
 		ctx,
 		&abci.RequestPrepareProposal{
 			MaxTxBytes:          maxDataBytes,
 			Txs:                 block.Txs.ToSliceOfBytes(),
-			LocalLastCommit:     extendedCommitInfo(localLastCommit, votes),
+			LocalLastCommit:     extendedCommitInfo(localLastCommit, votes, extensionsEnabled),
 			ByzantineValidators: block.Evidence.ToABCI(),
 			Height:              block.Height,
 			Time:                block.Time,
@@ -217,7 +238,25 @@ func (blockExec *BlockExecutor) ValidateBlock(ctx context.Context, state State,
 // It's the only function that needs to be called
 // from outside this package to process and commit an entire block.
 // It takes a blockID to avoid recomputing the parts hash.
+//
+// If the block is the last one before a scheduled upgrade, it returns the
+// new state together with ErrUpgradeHalt.
+//
+// If ctx holds a span, the execution is recorded as a child span, with the
+// ABCI calls made to execute the block as its children.
 func (blockExec *BlockExecutor) ApplyBlock(
+	ctx context.Context,
+	state State,
+	blockID types.BlockID, block *types.Block) (State, error) {
+	ctx, span := trace.StartSpan(ctx, "state.ApplyBlock", "height", block.Height, "txs", len(block.Txs))
+	defer span.End()
+
+	state, err := blockExec.applyBlock(ctx, state, blockID, block)
+	span.RecordError(err)
+	return state, err
+}
+
+func (blockExec *BlockExecutor) applyBlock(
 	ctx context.Context,
 	state State,
 	blockID types.BlockID, block *types.Block) (State, error) {
@@ -311,6 +350,21 @@ func (blockExec *BlockExecutor) ApplyBlock(
 	// NOTE: if we crash between Commit and Save, events wont be fired during replay
 	fireEvents(blockExec.logger, blockExec.eventBus, block, blockID, finalizeBlockResponse, validatorUpdates)
 
+	if blockExec.upgrader != nil {
+		if plan := finalizeBlockResponse.UpgradePlan; plan != nil {
+			if err := blockExec.upgrader.Schedule(UpgradePlanFromProto(plan), block.Height); err != nil {
+				blockExec.logger.Error("failed to schedule upgrade", "height", block.Height, "err", err)
+			} else {
+				blockExec.logger.Info("upgrade scheduled", "name", plan.Name, "upgrade_height", plan.Height)
+			}
+		}
+		// The block is committed and the state saved, so the caller can
+		// stop cleanly when the upgrade height has been reached.
+		if err := blockExec.upgrader.checkHalt(state); err != nil {
+			return state, err
+		}
+	}
+
 	return state, nil
 }
 
@@ -437,8 +491,9 @@ func buildLastCommitInfo(block *types.Block, store Store, initialHeight int64) a
 
 // extendedCommitInfo expects a CommitInfo struct along with all of the
 // original votes relating to that commit, including their vote extensions. The
-// order of votes does not matter.
-func extendedCommitInfo(c abci.CommitInfo, votes []*types.Vote) abci.ExtendedCommitInfo {
+// order of votes does not matter. Vote extensions are only included if they
+// were enabled at the height of the commit.
+func extendedCommitInfo(c abci.CommitInfo, votes []*types.Vote, extensionsEnabled bool) abci.ExtendedCommitInfo {
 	if len(c.Votes) != len(votes) {
 		panic(fmt.Sprintf("extendedCommitInfo: number of votes from commit differ from the number of votes supplied (%d != %d)", len(c.Votes), len(votes)))
 	}
@@ -462,7 +517,9 @@ func extendedCommitInfo(c abci.CommitInfo, votes []*types.Vote) abci.ExtendedCom
 			if !ok || vote == nil {
 				panic(fmt.Sprintf("extendedCommitInfo: validator with address %s signed last block, but could not find vote for it", valAddr))
 			}
-			ext = vote.Extension
+			if extensionsEnabled {
+				ext = vote.Extension
+			}
 		}
 		vs[i] = abci.ExtendedVoteInfo{
 			Validator:       c.Votes[i].Validator,
@@ -538,6 +595,9 @@ func (state State) Update(
 		if err != nil {
 			return state, fmt.Errorf("error updating consensus params: %w", err)
 		}
+		if err := state.ConsensusParams.ValidateUpdate(consensusParamUpdates, header.Height); err != nil {
+			return state, fmt.Errorf("error updating consensus params: %w", err)
+		}
 
 		state.Version.Consensus.App = nextParams.Version.AppVersion
 
@@ -292,6 +292,7 @@ type testApp struct {
 	CommitVotes         []abci.VoteInfo
 	ByzantineValidators []abci.Misbehavior
 	ValidatorUpdates    []abci.ValidatorUpdate
+	UpgradePlan         *abci.UpgradePlan
 }
 
 var _ abci.Application = (*testApp)(nil)
@@ -320,8 +321,9 @@ func (app *testApp) FinalizeBlock(_ context.Context, req *abci.RequestFinalizeBl
 				AppVersion: 1,
 			},
 		},
-		Events:    []abci.Event{},
-		TxResults: resTxs,
+		Events:      []abci.Event{},
+		TxResults:   resTxs,
+		UpgradePlan: app.UpgradePlan,
 	}, nil
 }
 
@@ -22,6 +22,7 @@ package indexer
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/bhojpur/state/internal/eventbus"
@@ -37,6 +38,7 @@ type Service struct {
 	service.BaseService
 	logger log.Logger
 
+	mtx        sync.Mutex // guards eventSinks and currentBlock
 	eventSinks []EventSink
 	eventBus   *eventbus.EventBus
 	metrics    *Metrics
@@ -66,6 +68,14 @@ func NewService(args ServiceArgs) *Service {
 // publish publishes a pubsub message to the service. The service blocks until
 // the message has been fully processed.
 func (is *Service) publish(msg pubsub.Message) error {
+	is.mtx.Lock()
+	defer is.mtx.Unlock()
+
+	if !IndexingEnabled(is.eventSinks) {
+		is.currentBlock.batch = nil
+		return nil
+	}
+
 	// Indexing has three states. Initially, no block is in progress (WAIT) and
 	// we expect a block header. Upon seeing a header, we are waiting for zero
 	// or more transactions (GATHER). Once all the expected transactions have
@@ -73,8 +83,12 @@ func (is *Service) publish(msg pubsub.Message) error {
 	// block, we revert to the WAIT state for the next block.
 
 	if is.currentBlock.batch == nil {
-		// WAIT: Start a new block.
-		hdr := msg.Data().(types.EventDataNewBlockHeader)
+		// WAIT: Start a new block. Transactions of a block whose header was
+		// not seen, because indexing was disabled at the time, are skipped.
+		hdr, ok := msg.Data().(types.EventDataNewBlockHeader)
+		if !ok {
+			return nil
+		}
 		is.currentBlock.header = hdr
 		is.currentBlock.height = hdr.Header.Height
 		is.currentBlock.batch = NewBatch(hdr.NumTxs)
@@ -134,25 +148,36 @@ func (is *Service) publish(msg pubsub.Message) error {
 	return nil
 }
 
-// OnStart implements part of service.Service. It registers an observer for the
-// indexer if the underlying event sinks support indexing.
-//
-// TODO: Can we get rid of the "enabled" check?
+// OnStart implements part of service.Service. It registers an observer to
+// capture block header data for the indexer. Events are ignored while none of
+// the event sinks support indexing, which may change by ReplaceEventSinks.
 func (is *Service) OnStart(ctx context.Context) error {
-	// If the event sinks support indexing, register an observer to capture
-	// block header data for the indexer.
-	if IndexingEnabled(is.eventSinks) {
-		err := is.eventBus.Observe(ctx, is.publish,
-			types.EventQueryNewBlockHeader, types.EventQueryTx)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return is.eventBus.Observe(ctx, is.publish,
+		types.EventQueryNewBlockHeader, types.EventQueryTx)
+}
+
+// ReplaceEventSinks stops the event sinks of the service, and replaces them by
+// those returned by open. Indexing is paused until open returns, so that no
+// events are missed. The sinks returned by open are used even if it also
+// reports an error, which lets it fall back to other sinks.
+func (is *Service) ReplaceEventSinks(open func() ([]EventSink, error)) error {
+	is.mtx.Lock()
+	defer is.mtx.Unlock()
+
+	is.stopEventSinks()
+	sinks, err := open()
+	is.eventSinks = sinks
+	return err
 }
 
 // OnStop implements service.Service by closing the event sinks.
 func (is *Service) OnStop() {
+	is.mtx.Lock()
+	defer is.mtx.Unlock()
+	is.stopEventSinks()
+}
+
+func (is *Service) stopEventSinks() {
 	for _, sink := range is.eventSinks {
 		if err := sink.Stop(); err != nil {
 			is.logger.Error("failed to close eventsink", "eventsink", sink.Type(), "err", err)
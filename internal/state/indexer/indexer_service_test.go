@@ -38,6 +38,7 @@ import (
 	"github.com/bhojpur/state/internal/eventbus"
 	"github.com/bhojpur/state/internal/state/indexer"
 	"github.com/bhojpur/state/internal/state/indexer/sink/kv"
+	"github.com/bhojpur/state/internal/state/indexer/sink/null"
 	"github.com/bhojpur/state/internal/state/indexer/sink/psql"
 	abcipb "github.com/bhojpur/state/pkg/abci/types"
 	liblog "github.com/bhojpur/state/pkg/libs/log"
@@ -129,6 +130,55 @@ func TestIndexerServiceIndexesBlocks(t *testing.T) {
 	assert.Nil(t, teardown(t, pool))
 }
 
+func TestIndexerServiceReplaceEventSinks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := liblog.NewNopLogger()
+	eventBus := eventbus.NewDefault(logger)
+	require.NoError(t, eventBus.Start(ctx))
+	t.Cleanup(eventBus.Wait)
+
+	service := indexer.NewService(indexer.ServiceArgs{
+		Logger:   logger,
+		Sinks:    []indexer.EventSink{null.NewEventSink()},
+		EventBus: eventBus,
+	})
+	require.NoError(t, service.Start(ctx))
+	t.Cleanup(service.Wait)
+
+	publish := func(height int64, tx types.Tx) {
+		require.NoError(t, eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{
+			Header: types.Header{Height: height},
+			NumTxs: 1,
+		}))
+		require.NoError(t, eventBus.PublishEventTx(types.EventDataTx{TxResult: abcipb.TxResult{
+			Height: height,
+			Tx:     tx,
+		}}))
+	}
+
+	// Blocks are not indexed while indexing is disabled.
+	publish(1, types.Tx("foo"))
+
+	sink := kv.NewEventSink(dbm.NewMemDB())
+	require.NoError(t, service.ReplaceEventSinks(func() ([]indexer.EventSink, error) {
+		return []indexer.EventSink{sink}, nil
+	}))
+	publish(2, types.Tx("bar"))
+
+	ok, err := sink.HasBlock(1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = sink.HasBlock(2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	res, err := sink.GetTxByHash(types.Tx("bar").Hash())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res.Height)
+}
+
 func readSchema() ([]*schema.Migration, error) {
 	filename := "./sink/psql/schema.sql"
 	contents, err := os.ReadFile(filename)
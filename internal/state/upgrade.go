@@ -0,0 +1,215 @@
+package state
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bhojpur/state/internal/libs/tempfile"
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/version"
+)
+
+// UpgradePlan schedules a coordinated upgrade: every node stops after
+// committing the block at Height and only continues once an upgraded binary
+// has been installed. Plans are either configured by the operator or
+// returned by the application from FinalizeBlock.
+type UpgradePlan struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height,string"`
+	Info   string `json:"info,omitempty"`
+}
+
+// UpgradePlanFromProto converts an ABCI upgrade plan. A nil plan converts to
+// the zero plan.
+func UpgradePlanFromProto(pb *abci.UpgradePlan) UpgradePlan {
+	if pb == nil {
+		return UpgradePlan{}
+	}
+	return UpgradePlan{
+		Name:   pb.Name,
+		Height: pb.Height,
+		Info:   pb.Info,
+	}
+}
+
+// IsZero returns true if no upgrade is scheduled.
+func (p UpgradePlan) IsZero() bool {
+	return p.Height == 0
+}
+
+// ValidateBasic performs basic validation.
+func (p UpgradePlan) ValidateBasic() error {
+	if p.Height <= 0 {
+		return fmt.Errorf("upgrade height must be positive, got %d", p.Height)
+	}
+	if p.Name == "" {
+		return errors.New("upgrade name cannot be empty")
+	}
+	return nil
+}
+
+// UpgradeInfo is the content of the upgrade-info file. It is written when a
+// plan is scheduled, and again with Halted set once the node has stopped at
+// the upgrade height, recording the versions the old binary ran with.
+type UpgradeInfo struct {
+	UpgradePlan
+
+	Halted        bool   `json:"halted"`
+	Software      string `json:"software,omitempty"`
+	BlockProtocol uint64 `json:"block_protocol,string,omitempty"`
+	AppVersion    uint64 `json:"app_version,string,omitempty"`
+}
+
+// LoadUpgradeInfo reads the upgrade-info file at path. It returns nil if the
+// file does not exist.
+func LoadUpgradeInfo(path string) (*UpgradeInfo, error) {
+	bz, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	info := &UpgradeInfo{}
+	if err := json.Unmarshal(bz, info); err != nil {
+		return nil, fmt.Errorf("invalid upgrade-info file %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// Save atomically writes the upgrade info to path.
+func (info UpgradeInfo) Save(path string) error {
+	bz, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(path, bz, 0644)
+}
+
+// Upgrader keeps track of the scheduled upgrade plan, persisting it to the
+// upgrade-info file, and halts block execution at the upgrade height.
+type Upgrader struct {
+	path string
+
+	mtx  sync.Mutex
+	plan UpgradePlan
+}
+
+// NewUpgrader returns an Upgrader using the upgrade-info file at path. The
+// configured plan, if any, is used unless the file holds a plan scheduled by
+// the application for an earlier height.
+func NewUpgrader(path string, plan UpgradePlan) (*Upgrader, error) {
+	info, err := LoadUpgradeInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && !info.Halted && (plan.IsZero() || info.Height < plan.Height) {
+		plan = info.UpgradePlan
+	}
+	return &Upgrader{path: path, plan: plan}, nil
+}
+
+// Plan returns the scheduled upgrade plan, or the zero plan if none is.
+func (u *Upgrader) Plan() UpgradePlan {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	return u.plan
+}
+
+// Schedule replaces the scheduled plan with one received while executing the
+// block at height, and persists it to the upgrade-info file.
+func (u *Upgrader) Schedule(plan UpgradePlan, height int64) error {
+	if err := plan.ValidateBasic(); err != nil {
+		return err
+	}
+	if plan.Height < height {
+		return fmt.Errorf("upgrade %q: height %d is below the current height %d", plan.Name, plan.Height, height)
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if plan == u.plan {
+		return nil
+	}
+	if err := (UpgradeInfo{UpgradePlan: plan}).Save(u.path); err != nil {
+		return err
+	}
+	u.plan = plan
+	return nil
+}
+
+// CheckStartup is called once the application has been synced with the
+// state at startup. If the node halted for an upgrade, it refuses to continue
+// unless the node binary or the application has been upgraded since, and
+// checks that both match the versions recorded in the state. If the block at
+// the upgrade height was committed without halting, e.g. while replaying
+// blocks, it halts now.
+func (u *Upgrader) CheckStartup(state State, appVersion uint64) error {
+	info, err := LoadUpgradeInfo(u.path)
+	if err != nil {
+		return err
+	}
+	if info != nil && info.Halted && state.LastBlockHeight >= info.Height {
+		// Either the node binary or the application must have been upgraded,
+		// so that app-only upgrades do not require a new node binary.
+		if info.Software == version.FullVersion() && appVersion <= info.AppVersion {
+			return fmt.Errorf("the node halted for upgrade %q at height %d; this binary (%s) or the application "+
+				"(version %d) must be upgraded (remove %s to override)",
+				info.Name, info.Height, info.Software, appVersion, u.path)
+		}
+		if state.Version.Consensus.Block != version.BlockProtocol {
+			return fmt.Errorf("upgrade %q: state has block protocol %d, but this binary implements %d",
+				info.Name, state.Version.Consensus.Block, version.BlockProtocol)
+		}
+		if required := state.ConsensusParams.Version.AppVersion; appVersion < required {
+			return fmt.Errorf("upgrade %q: application version %d is older than version %d required by the consensus params",
+				info.Name, appVersion, required)
+		}
+		return nil
+	}
+	return u.checkHalt(state)
+}
+
+// checkHalt returns ErrUpgradeHalt if the state has reached the height of the
+// scheduled plan, after recording the halt in the upgrade-info file.
+func (u *Upgrader) checkHalt(state State) error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if u.plan.IsZero() || state.LastBlockHeight != u.plan.Height {
+		return nil
+	}
+
+	info := UpgradeInfo{
+		UpgradePlan:   u.plan,
+		Halted:        true,
+		Software:      version.FullVersion(),
+		BlockProtocol: state.Version.Consensus.Block,
+		AppVersion:    state.Version.Consensus.App,
+	}
+	if err := info.Save(u.path); err != nil {
+		return fmt.Errorf("writing upgrade-info file: %w", err)
+	}
+	return ErrUpgradeHalt{Name: u.plan.Name, Height: u.plan.Height}
+}
@@ -0,0 +1,212 @@
+package state_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/internal/eventbus"
+	mpmocks "github.com/bhojpur/state/internal/mempool/mocks"
+	"github.com/bhojpur/state/internal/proxy"
+	sm "github.com/bhojpur/state/internal/state"
+	sf "github.com/bhojpur/state/internal/state/test/factory"
+	"github.com/bhojpur/state/internal/store"
+	abciclient "github.com/bhojpur/state/pkg/abci/client"
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	dbm "github.com/bhojpur/state/pkg/database"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/types"
+	"github.com/bhojpur/state/pkg/version"
+)
+
+func TestApplyBlockUpgradeHalt(t *testing.T) {
+	app := &testApp{UpgradePlan: &abci.UpgradePlan{Name: "v2", Height: 1}}
+	logger := log.NewNopLogger()
+	cc := abciclient.NewLocalClient(logger, app)
+	proxyApp := proxy.New(cc, logger, proxy.NopMetrics())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, proxyApp.Start(ctx))
+
+	eventBus := eventbus.NewDefault(logger)
+	require.NoError(t, eventBus.Start(ctx))
+
+	state, stateDB, _ := makeState(t, 1, 1)
+	stateStore := sm.NewStore(stateDB)
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	mp := &mpmocks.Mempool{}
+	mp.On("Lock").Return()
+	mp.On("Unlock").Return()
+	mp.On("FlushAppConn", mock.Anything).Return(nil)
+	mp.On("Update",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		mock.Anything).Return(nil)
+
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	upgrader, err := sm.NewUpgrader(path, sm.UpgradePlan{})
+	require.NoError(t, err)
+	blockExec := sm.NewBlockExecutor(stateStore, logger, proxyApp, mp, sm.EmptyEvidencePool{}, blockStore, eventBus,
+		sm.NopMetrics(), sm.BlockExecutorWithUpgrader(upgrader))
+
+	block := sf.MakeBlock(state, 1, new(types.Commit))
+	bps, err := block.MakePartSet(testPartSize)
+	require.NoError(t, err)
+	blockID := types.BlockID{Hash: block.Hash(), PartSetHeader: bps.Header()}
+
+	state, err = blockExec.ApplyBlock(ctx, state, blockID, block)
+	require.Equal(t, sm.ErrUpgradeHalt{Name: "v2", Height: 1}, err)
+
+	// the block is committed before halting
+	assert.EqualValues(t, 1, state.LastBlockHeight)
+	saved, err := stateStore.Load()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, saved.LastBlockHeight)
+
+	info, err := sm.LoadUpgradeInfo(path)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.True(t, info.Halted)
+	assert.Equal(t, "v2", info.Name)
+	assert.Equal(t, version.FullVersion(), info.Software)
+	assert.EqualValues(t, 1, info.AppVersion)
+}
+
+func TestUpgraderSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	upgrader, err := sm.NewUpgrader(path, sm.UpgradePlan{Name: "configured", Height: 20})
+	require.NoError(t, err)
+
+	require.Error(t, upgrader.Schedule(sm.UpgradePlan{Height: 10}, 5), "plan without a name")
+	require.Error(t, upgrader.Schedule(sm.UpgradePlan{Name: "v2", Height: 4}, 5), "plan below the current height")
+	assert.Equal(t, "configured", upgrader.Plan().Name)
+
+	plan := sm.UpgradePlan{Name: "v2", Height: 10, Info: "https://example.com/v2"}
+	require.NoError(t, upgrader.Schedule(plan, 5))
+	assert.Equal(t, plan, upgrader.Plan())
+
+	// the scheduled plan survives a restart and takes precedence over a
+	// configured plan for a later height
+	upgrader, err = sm.NewUpgrader(path, sm.UpgradePlan{Name: "configured", Height: 20})
+	require.NoError(t, err)
+	assert.Equal(t, plan, upgrader.Plan())
+
+	upgrader, err = sm.NewUpgrader(path, sm.UpgradePlan{Name: "configured", Height: 8})
+	require.NoError(t, err)
+	assert.Equal(t, "configured", upgrader.Plan().Name)
+}
+
+func TestUpgraderCheckStartup(t *testing.T) {
+	state, _, _ := makeState(t, 1, 1)
+	state.LastBlockHeight = 10
+	state.ConsensusParams.Version.AppVersion = 2
+
+	testCases := []struct {
+		name       string
+		info       *sm.UpgradeInfo
+		plan       sm.UpgradePlan
+		appVersion uint64
+		expectErr  bool
+		expectHalt bool
+	}{
+		{"no upgrade", nil, sm.UpgradePlan{}, 2, false, false},
+		{"pending upgrade", &sm.UpgradeInfo{UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 11}}, sm.UpgradePlan{}, 2, false, false},
+		{"plan at committed height", nil, sm.UpgradePlan{Name: "v2", Height: 10}, 2, true, true},
+		{"halted, old binary and application", &sm.UpgradeInfo{
+			UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 10}, Halted: true, Software: version.FullVersion(),
+			AppVersion: 2,
+		}, sm.UpgradePlan{}, 2, true, false},
+		{"halted, upgraded application only", &sm.UpgradeInfo{
+			UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 10}, Halted: true, Software: version.FullVersion(),
+			AppVersion: 1,
+		}, sm.UpgradePlan{}, 2, false, false},
+		{"halted, upgraded binary only", &sm.UpgradeInfo{
+			UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 10}, Halted: true, Software: "v1.0.0",
+			AppVersion: 2,
+		}, sm.UpgradePlan{}, 2, false, false},
+		{"halted, old application", &sm.UpgradeInfo{
+			UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 10}, Halted: true, Software: "v1.0.0",
+		}, sm.UpgradePlan{}, 1, true, false},
+		{"halted, upgraded", &sm.UpgradeInfo{
+			UpgradePlan: sm.UpgradePlan{Name: "v2", Height: 10}, Halted: true, Software: "v1.0.0",
+		}, sm.UpgradePlan{Name: "v2", Height: 10}, 2, false, false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upgrade-info.json")
+			if tc.info != nil {
+				require.NoError(t, tc.info.Save(path))
+			}
+			upgrader, err := sm.NewUpgrader(path, tc.plan)
+			require.NoError(t, err)
+
+			err = upgrader.CheckStartup(state, tc.appVersion)
+			if !tc.expectErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.expectHalt, errors.As(err, &sm.ErrUpgradeHalt{}))
+		})
+	}
+}
+
+func TestUpgraderRestartAfterHalt(t *testing.T) {
+	// The consensus params keep their default app version, the state has the
+	// version reported by the application during the handshake.
+	state, _, _ := makeState(t, 1, 1)
+	state.LastBlockHeight = 10
+	state.Version.Consensus.App = 1
+	require.Zero(t, state.ConsensusParams.Version.AppVersion)
+
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	upgrader, err := sm.NewUpgrader(path, sm.UpgradePlan{Name: "v2", Height: 10})
+	require.NoError(t, err)
+	require.Equal(t, sm.ErrUpgradeHalt{Name: "v2", Height: 10}, upgrader.CheckStartup(state, 1))
+
+	info, err := sm.LoadUpgradeInfo(path)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.EqualValues(t, 1, info.AppVersion)
+
+	// restarting the same binary and application does not pass the halt
+	upgrader, err = sm.NewUpgrader(path, sm.UpgradePlan{})
+	require.NoError(t, err)
+	err = upgrader.CheckStartup(state, 1)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &sm.ErrUpgradeHalt{}))
+
+	// an upgraded application does
+	require.NoError(t, upgrader.CheckStartup(state, 2))
+}
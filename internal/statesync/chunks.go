@@ -21,6 +21,9 @@ package statesync
 // THE SOFTWARE.
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -29,9 +32,18 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bhojpur/state/internal/libs/tempfile"
 	"github.com/bhojpur/state/pkg/types"
 )
 
+const (
+	// chunkQueueDir is the directory, below the state sync temp dir, where chunks are stored.
+	chunkQueueDir = "statesync-chunks"
+	// chunkQueueStateFile records the snapshot and chunks of a queue, so that an interrupted
+	// restore can be resumed.
+	chunkQueueStateFile = "queue.json"
+)
+
 // errDone is returned by chunkQueue.Next() when all chunks have been returned.
 var errDone = errors.New("chunk queue has completed")
 
@@ -55,29 +67,178 @@ type chunkQueue struct {
 	chunkSenders   map[uint32]types.NodeID    // the peer who sent the given chunk
 	chunkAllocated map[uint32]bool            // chunks that have been allocated via Allocate()
 	chunkReturned  map[uint32]bool            // chunks returned via Next()
+	chunkHashes    map[uint32][]byte          // SHA-256 hash of stored chunk files
+	chunkApplied   map[uint32]bool            // chunks the application has accepted
 	waiters        map[uint32][]chan<- uint32 // signals WaitFor() waiters about chunk arrival
 }
 
-// newChunkQueue creates a new chunk queue for a snapshot, using a temp dir for storage.
-// Callers must call Close() when done.
+// chunkQueueState is the on-disk record of a chunk queue, used to resume a restore after a
+// restart.
+type chunkQueueState struct {
+	Height   uint64            `json:"height"`
+	Format   uint32            `json:"format"`
+	Chunks   uint32            `json:"chunks"`
+	Hash     []byte            `json:"hash"`
+	Metadata []byte            `json:"metadata"`
+	Hashes   map[uint32][]byte `json:"chunk_hashes"`
+	Senders  map[uint32]string `json:"chunk_senders"`
+	Applied  []uint32          `json:"applied"`
+}
+
+// newChunkQueue creates a new chunk queue for a snapshot, using a temp dir for storage. Any
+// chunks left behind by a previous restore are removed. Callers must call Close() when done.
 func newChunkQueue(snapshot *snapshot, tempDir string) (*chunkQueue, error) {
-	dir, err := os.MkdirTemp(tempDir, "tm-statesync")
-	if err != nil {
-		return nil, fmt.Errorf("unable to create temp dir for state sync chunks: %w", err)
-	}
 	if snapshot.Chunks == 0 {
 		return nil, errors.New("snapshot has no chunks")
 	}
 
-	return &chunkQueue{
+	dir := chunkQueuePath(tempDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("unable to clean up temp dir for state sync chunks: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create temp dir for state sync chunks: %w", err)
+	}
+
+	q := &chunkQueue{
 		snapshot:       snapshot,
 		dir:            dir,
 		chunkFiles:     make(map[uint32]string, snapshot.Chunks),
 		chunkSenders:   make(map[uint32]types.NodeID, snapshot.Chunks),
 		chunkAllocated: make(map[uint32]bool, snapshot.Chunks),
 		chunkReturned:  make(map[uint32]bool, snapshot.Chunks),
+		chunkHashes:    make(map[uint32][]byte, snapshot.Chunks),
+		chunkApplied:   make(map[uint32]bool, snapshot.Chunks),
 		waiters:        make(map[uint32][]chan<- uint32),
-	}, nil
+	}
+	if err := q.persist(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// loadChunkQueue loads the chunk queue of an interrupted restore from the temp dir, or returns
+// nil if there is none. Chunk files that are missing or do not match their recorded hash are
+// dropped and will be refetched, and chunks already applied are not returned by Next() again.
+func loadChunkQueue(tempDir string) (*chunkQueue, error) {
+	dir := chunkQueuePath(tempDir)
+	bz, err := os.ReadFile(filepath.Join(dir, chunkQueueStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state sync chunk queue: %w", err)
+	}
+
+	var state chunkQueueState
+	if err := json.Unmarshal(bz, &state); err != nil {
+		return nil, fmt.Errorf("invalid state sync chunk queue: %w", err)
+	}
+	if state.Chunks == 0 {
+		return nil, errors.New("invalid state sync chunk queue: snapshot has no chunks")
+	}
+
+	q := &chunkQueue{
+		snapshot: &snapshot{
+			Height:   state.Height,
+			Format:   state.Format,
+			Chunks:   state.Chunks,
+			Hash:     state.Hash,
+			Metadata: state.Metadata,
+		},
+		dir:            dir,
+		chunkFiles:     make(map[uint32]string, state.Chunks),
+		chunkSenders:   make(map[uint32]types.NodeID, state.Chunks),
+		chunkAllocated: make(map[uint32]bool, state.Chunks),
+		chunkReturned:  make(map[uint32]bool, state.Chunks),
+		chunkHashes:    make(map[uint32][]byte, state.Chunks),
+		chunkApplied:   make(map[uint32]bool, state.Chunks),
+		waiters:        make(map[uint32][]chan<- uint32),
+	}
+
+	for index, hash := range state.Hashes {
+		if index >= state.Chunks {
+			continue
+		}
+		path := q.chunkPath(index)
+		body, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if sum := sha256.Sum256(body); !bytes.Equal(sum[:], hash) {
+			_ = os.Remove(path)
+			continue
+		}
+		q.chunkFiles[index] = path
+		q.chunkHashes[index] = hash
+		q.chunkAllocated[index] = true
+		q.chunkSenders[index] = types.NodeID(state.Senders[index])
+	}
+
+	// Chunks are applied in order, so only the unbroken sequence of applied chunks that are
+	// still on disk can be skipped.
+	for _, index := range state.Applied {
+		q.chunkApplied[index] = true
+	}
+	for i := uint32(0); i < state.Chunks && q.chunkApplied[i] && q.chunkFiles[i] != ""; i++ {
+		q.chunkReturned[i] = true
+	}
+	for index := range q.chunkApplied {
+		if !q.chunkReturned[index] {
+			delete(q.chunkApplied, index)
+		}
+	}
+
+	if err := q.persist(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// chunkQueuePath returns the chunk queue directory below the given temp dir, defaulting to
+// os.TempDir().
+func chunkQueuePath(tempDir string) string {
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	return filepath.Join(tempDir, chunkQueueDir)
+}
+
+// chunkPath returns the path of the file storing the given chunk.
+func (q *chunkQueue) chunkPath(index uint32) string {
+	return filepath.Join(q.dir, strconv.FormatUint(uint64(index), 10))
+}
+
+// persist atomically writes the queue state to disk. The caller must hold the mutex lock.
+func (q *chunkQueue) persist() error {
+	state := chunkQueueState{
+		Height:   q.snapshot.Height,
+		Format:   q.snapshot.Format,
+		Chunks:   q.snapshot.Chunks,
+		Hash:     q.snapshot.Hash,
+		Metadata: q.snapshot.Metadata,
+		Hashes:   q.chunkHashes,
+		Senders:  make(map[uint32]string, len(q.chunkSenders)),
+		Applied:  make([]uint32, 0, len(q.chunkApplied)),
+	}
+	for index, sender := range q.chunkSenders {
+		state.Senders[index] = string(sender)
+	}
+	for i := uint32(0); i < q.snapshot.Chunks; i++ {
+		if q.chunkApplied[i] {
+			state.Applied = append(state.Applied, i)
+		}
+	}
+
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := tempfile.WriteFileAtomic(filepath.Join(q.dir, chunkQueueStateFile), bz, 0600); err != nil {
+		return fmt.Errorf("failed to save state sync chunk queue: %w", err)
+	}
+	return nil
 }
 
 // Add adds a chunk to the queue. It ignores chunks that already exist, returning false.
@@ -105,14 +266,19 @@ func (q *chunkQueue) Add(chunk *chunk) (bool, error) {
 		return false, nil
 	}
 
-	path := filepath.Join(q.dir, strconv.FormatUint(uint64(chunk.Index), 10))
+	path := q.chunkPath(chunk.Index)
 	err := os.WriteFile(path, chunk.Chunk, 0600)
 	if err != nil {
 		return false, fmt.Errorf("failed to save chunk %v to file %v: %w", chunk.Index, path, err)
 	}
 
+	hash := sha256.Sum256(chunk.Chunk)
 	q.chunkFiles[chunk.Index] = path
 	q.chunkSenders[chunk.Index] = chunk.Sender
+	q.chunkHashes[chunk.Index] = hash[:]
+	if err := q.persist(); err != nil {
+		return false, err
+	}
 
 	// Signal any waiters that the chunk has arrived.
 	for _, waiter := range q.waiters[chunk.Index] {
@@ -154,10 +320,32 @@ func (q *chunkQueue) Close() error {
 	q.Lock()
 	defer q.Unlock()
 
-	if q.snapshot == nil {
+	if !q.close() {
 		return nil
 	}
 
+	if err := os.RemoveAll(q.dir); err != nil {
+		return fmt.Errorf("failed to clean up state sync tempdir %v: %w", q.dir, err)
+	}
+
+	return nil
+}
+
+// Suspend closes the chunk queue but keeps its files on disk, so that the restore can be resumed
+// via loadChunkQueue() after a restart.
+func (q *chunkQueue) Suspend() {
+	q.Lock()
+	defer q.Unlock()
+	q.close()
+}
+
+// close closes the queue and its waiters, returning false if it was already closed. The caller
+// must hold the mutex lock.
+func (q *chunkQueue) close() bool {
+	if q.snapshot == nil {
+		return false
+	}
+
 	for _, waiters := range q.waiters {
 		for _, waiter := range waiters {
 			close(waiter)
@@ -166,12 +354,14 @@ func (q *chunkQueue) Close() error {
 
 	q.waiters = nil
 	q.snapshot = nil
+	return true
+}
 
-	if err := os.RemoveAll(q.dir); err != nil {
-		return fmt.Errorf("failed to clean up state sync tempdir %v: %w", q.dir, err)
-	}
-
-	return nil
+// Complete checks whether all chunks of the snapshot are stored in the queue.
+func (q *chunkQueue) Complete() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.snapshot != nil && uint32(len(q.chunkFiles)) == q.snapshot.Chunks
 }
 
 // Discard discards a chunk. It will be removed from the queue, available for allocation, and can
@@ -201,8 +391,10 @@ func (q *chunkQueue) discard(index uint32) error {
 	delete(q.chunkFiles, index)
 	delete(q.chunkReturned, index)
 	delete(q.chunkAllocated, index)
+	delete(q.chunkHashes, index)
+	delete(q.chunkApplied, index)
 
-	return nil
+	return q.persist()
 }
 
 // DiscardSender discards all *unreturned* chunks from a given sender. If the caller wants to
@@ -319,11 +511,36 @@ func (q *chunkQueue) nextUp() (uint32, error) {
 	return 0, errDone
 }
 
+// MarkApplied records that the application has accepted a chunk, so that it is skipped when the
+// restore is resumed after a restart.
+func (q *chunkQueue) MarkApplied(index uint32) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.snapshot == nil || q.chunkApplied[index] {
+		return nil
+	}
+
+	q.chunkApplied[index] = true
+	return q.persist()
+}
+
+// NumApplied returns the number of chunks the application has accepted.
+func (q *chunkQueue) NumApplied() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.chunkApplied)
+}
+
 // Retry schedules a chunk to be retried, without refetching it.
 func (q *chunkQueue) Retry(index uint32) {
 	q.Lock()
 	defer q.Unlock()
 	delete(q.chunkReturned, index)
+	if q.chunkApplied[index] {
+		delete(q.chunkApplied, index)
+		_ = q.persist()
+	}
 }
 
 // RetryAll schedules all chunks to be retried, without refetching them.
@@ -331,6 +548,10 @@ func (q *chunkQueue) RetryAll() {
 	q.Lock()
 	defer q.Unlock()
 	q.chunkReturned = make(map[uint32]bool)
+	q.chunkApplied = make(map[uint32]bool)
+	if q.snapshot != nil {
+		_ = q.persist()
+	}
 }
 
 // Size returns the total number of chunks for the snapshot and queue, or 0 when closed.
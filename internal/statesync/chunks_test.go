@@ -579,3 +579,82 @@ func allocateAddChunksToQueue(t *testing.T, q *chunkQueue) {
 		require.NoError(t, err)
 	}
 }
+
+func TestChunkQueue_Resume(t *testing.T) {
+	snapshot := &snapshot{
+		Height:   3,
+		Format:   1,
+		Chunks:   5,
+		Hash:     []byte{7},
+		Metadata: []byte{1, 2},
+	}
+	dir := t.TempDir()
+
+	// Without a previous restore there is nothing to resume.
+	queue, err := loadChunkQueue(dir)
+	require.NoError(t, err)
+	require.Nil(t, queue)
+
+	queue, err = newChunkQueue(snapshot, dir)
+	require.NoError(t, err)
+
+	for i := uint32(0); i < 4; i++ {
+		_, err = queue.Allocate()
+		require.NoError(t, err)
+		_, err = queue.Add(&chunk{Height: 3, Format: 1, Index: i, Chunk: []byte{3, 1, byte(i)}, Sender: types.NodeID("a")})
+		require.NoError(t, err)
+	}
+	for i := uint32(0); i < 2; i++ {
+		c, err := queue.Next()
+		require.NoError(t, err)
+		require.NoError(t, queue.MarkApplied(c.Index))
+	}
+	queue.Suspend()
+
+	// Corrupt chunk 3, which should be dropped and refetched.
+	err = os.WriteFile(chunkQueuePath(dir)+"/3", []byte{9}, 0600)
+	require.NoError(t, err)
+
+	queue, err = loadChunkQueue(dir)
+	require.NoError(t, err)
+	require.NotNil(t, queue)
+	defer queue.Close()
+
+	assert.Equal(t, snapshot.Hash, queue.snapshot.Hash)
+	assert.Equal(t, snapshot.Metadata, queue.snapshot.Metadata)
+	assert.EqualValues(t, 5, queue.Size())
+	assert.Equal(t, 2, queue.NumApplied())
+	assert.False(t, queue.Complete())
+	assert.True(t, queue.Has(2))
+	assert.False(t, queue.Has(3))
+	assert.Equal(t, types.NodeID("a"), queue.GetSender(2))
+
+	// Only the dropped and missing chunks are allocated for fetching.
+	index, err := queue.Allocate()
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, index)
+	index, err = queue.Allocate()
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, index)
+	_, err = queue.Allocate()
+	assert.Equal(t, errDone, err)
+
+	// Applied chunks are skipped.
+	c, err := queue.Next()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, c.Index)
+	assert.Equal(t, []byte{3, 1, 2}, c.Chunk)
+
+	// Retrying the snapshot forgets about applied chunks.
+	queue.RetryAll()
+	assert.Equal(t, 0, queue.NumApplied())
+	c, err = queue.Next()
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, c.Index)
+
+	// Closing the queue removes it for good.
+	require.NoError(t, queue.Close())
+	queue, err = loadChunkQueue(dir)
+	require.NoError(t, err)
+	assert.Nil(t, queue)
+}
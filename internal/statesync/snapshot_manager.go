@@ -0,0 +1,293 @@
+package statesync
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bhojpur/state/internal/eventbus"
+	"github.com/bhojpur/state/internal/pubsub"
+	abciclient "github.com/bhojpur/state/pkg/abci/client"
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/libs/service"
+	"github.com/bhojpur/state/pkg/types"
+)
+
+var _ service.Service = (*SnapshotManager)(nil)
+
+// SnapshotManager takes, stores, prunes and serves state sync snapshots on
+// behalf of an in-process application implementing abci.StateSnapshotter.
+// It takes a snapshot every SnapshotInterval heights once the height has been
+// committed, and restores snapshots offered during state sync by streaming
+// their chunks into a single RestoreState call.
+//
+// The state sync reactor reaches the manager through the client returned by
+// Client, which answers the snapshot calls of the ABCI application.
+type SnapshotManager struct {
+	service.BaseService
+	logger log.Logger
+
+	app      abci.StateSnapshotter
+	store    *SnapshotStore
+	eventBus *eventbus.EventBus
+
+	interval   uint64
+	keepRecent int
+	chunkSize  int
+
+	// holds a token while a snapshot is being taken
+	snapshotting chan struct{}
+
+	mtx     sync.Mutex
+	restore *snapshotRestore
+}
+
+// NewSnapshotManager creates a snapshot manager for the given application.
+func NewSnapshotManager(
+	logger log.Logger,
+	cfg config.StateSyncConfig,
+	app abci.StateSnapshotter,
+	store *SnapshotStore,
+	eventBus *eventbus.EventBus,
+) *SnapshotManager {
+	m := &SnapshotManager{
+		logger:       logger,
+		app:          app,
+		store:        store,
+		eventBus:     eventBus,
+		interval:     cfg.SnapshotInterval,
+		keepRecent:   int(cfg.SnapshotKeepRecent),
+		chunkSize:    int(cfg.SnapshotChunkSize),
+		snapshotting: make(chan struct{}, 1),
+	}
+	m.BaseService = *service.NewBaseService(logger, "SnapshotManager", m)
+	return m
+}
+
+// OnStart starts taking snapshots of committed heights, if enabled.
+func (m *SnapshotManager) OnStart(ctx context.Context) error {
+	if m.interval == 0 {
+		return nil
+	}
+	return m.eventBus.Observe(ctx, func(msg pubsub.Message) error {
+		height := msg.Data().(types.EventDataNewBlockHeader).Header.Height
+		if height <= 0 || uint64(height)%m.interval != 0 {
+			return nil
+		}
+
+		select {
+		case m.snapshotting <- struct{}{}:
+			go func() {
+				defer func() { <-m.snapshotting }()
+				m.takeSnapshot(ctx, uint64(height))
+			}()
+		default:
+			m.logger.Info("skipping snapshot, previous snapshot still in progress", "height", height)
+		}
+		return nil
+	}, types.EventQueryNewBlockHeader)
+}
+
+// OnStop aborts any restore in progress.
+func (m *SnapshotManager) OnStop() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.restore != nil {
+		m.restore.abort()
+		m.restore = nil
+	}
+}
+
+func (m *SnapshotManager) takeSnapshot(ctx context.Context, height uint64) {
+	m.logger.Info("taking snapshot", "height", height)
+
+	snapshot, err := m.store.Create(height, m.chunkSize, func(w io.Writer) error {
+		return m.app.ExportState(ctx, height, w)
+	})
+	if err != nil {
+		m.logger.Error("failed to take snapshot", "height", height, "err", err)
+		return
+	}
+	m.logger.Info("took snapshot", "height", height, "chunks", snapshot.Chunks, "hash", fmt.Sprintf("%X", snapshot.Hash))
+
+	if err := m.store.Prune(m.keepRecent); err != nil {
+		m.logger.Error("failed to prune snapshots", "err", err)
+	}
+}
+
+// Client wraps an application connection so that the snapshot calls made by
+// state sync are served by the manager. All other calls go to conn.
+func (m *SnapshotManager) Client(conn abciclient.Client) abciclient.Client {
+	return &snapshotClient{Client: conn, manager: m}
+}
+
+// snapshotRestore tracks a restore in progress. Verified chunks are written to
+// the pipe in order, and RestoreState reads them from the other end.
+type snapshotRestore struct {
+	snapshot *abci.Snapshot
+	next     uint32
+	writer   *io.PipeWriter
+	cancel   context.CancelFunc
+	done     chan error
+}
+
+func (r *snapshotRestore) abort() {
+	r.cancel()
+	_ = r.writer.CloseWithError(errors.New("snapshot restore aborted"))
+}
+
+func (m *SnapshotManager) offer(req *abci.RequestOfferSnapshot) (*abci.ResponseOfferSnapshot, error) {
+	snapshot := req.Snapshot
+	if snapshot == nil {
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT}, nil
+	}
+	if snapshot.Format != ManagedSnapshotFormat {
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT_FORMAT}, nil
+	}
+	if err := validateManagedSnapshot(snapshot); err != nil {
+		m.logger.Info("rejecting invalid snapshot", "height", snapshot.Height, "err", err)
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT}, nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.restore != nil {
+		m.restore.abort()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+	restore := &snapshotRestore{
+		snapshot: snapshot,
+		writer:   writer,
+		cancel:   cancel,
+		done:     make(chan error, 1),
+	}
+	go func() {
+		err := m.app.RestoreState(ctx, snapshot.Height, req.AppHash, reader)
+		if err == nil {
+			err = io.EOF
+		}
+		// unblock pending writes if the application stopped reading early
+		_ = reader.CloseWithError(err)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		restore.done <- err
+	}()
+	m.restore = restore
+
+	return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_ACCEPT}, nil
+}
+
+func (m *SnapshotManager) apply(req *abci.RequestApplySnapshotChunk) (*abci.ResponseApplySnapshotChunk, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	restore := m.restore
+	if restore == nil {
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_RETRY_SNAPSHOT}, nil
+	}
+
+	switch {
+	case req.Index < restore.next:
+		// already applied
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil
+	case req.Index > restore.next:
+		// The restore was resumed after a restart and skipped chunks that were applied to the
+		// previous, lost restore, so all chunks must be applied again.
+		m.logger.Info("restarting snapshot restore", "chunk", req.Index, "expected", restore.next)
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_RETRY_SNAPSHOT}, nil
+	}
+
+	hash := sha256.Sum256(req.Chunk)
+	if !bytes.Equal(hash[:], snapshotChunkHash(restore.snapshot, req.Index)) {
+		m.logger.Info("rejecting chunk with invalid hash", "chunk", req.Index, "sender", req.Sender)
+		return &abci.ResponseApplySnapshotChunk{
+			Result:        abci.ResponseApplySnapshotChunk_RETRY,
+			RefetchChunks: []uint32{req.Index},
+			RejectSenders: []string{req.Sender},
+		}, nil
+	}
+
+	if _, err := restore.writer.Write(req.Chunk); err != nil {
+		m.logger.Error("failed to restore snapshot", "height", restore.snapshot.Height, "err", err)
+		restore.abort()
+		m.restore = nil
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_REJECT_SNAPSHOT}, nil
+	}
+	restore.next++
+
+	if restore.next < restore.snapshot.Chunks {
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil
+	}
+
+	// All chunks have been written; wait for the application to finish. The
+	// lock is released meanwhile so a new offer or OnStop can abort the restore.
+	_ = restore.writer.Close()
+	m.mtx.Unlock()
+	err := <-restore.done
+	m.mtx.Lock()
+	restore.cancel()
+	if m.restore == restore {
+		m.restore = nil
+	}
+	if err != nil {
+		m.logger.Error("failed to restore snapshot", "height", restore.snapshot.Height, "err", err)
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_REJECT_SNAPSHOT}, nil
+	}
+	return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil
+}
+
+// snapshotClient serves the snapshot calls of the ABCI application from a
+// SnapshotManager.
+type snapshotClient struct {
+	abciclient.Client
+	manager *SnapshotManager
+}
+
+func (c *snapshotClient) ListSnapshots(context.Context, *abci.RequestListSnapshots) (*abci.ResponseListSnapshots, error) {
+	return &abci.ResponseListSnapshots{Snapshots: c.manager.store.List()}, nil
+}
+
+func (c *snapshotClient) LoadSnapshotChunk(_ context.Context, req *abci.RequestLoadSnapshotChunk) (*abci.ResponseLoadSnapshotChunk, error) {
+	chunk, err := c.manager.store.LoadChunk(req.Height, req.Format, req.Chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &abci.ResponseLoadSnapshotChunk{Chunk: chunk}, nil
+}
+
+func (c *snapshotClient) OfferSnapshot(_ context.Context, req *abci.RequestOfferSnapshot) (*abci.ResponseOfferSnapshot, error) {
+	return c.manager.offer(req)
+}
+
+func (c *snapshotClient) ApplySnapshotChunk(_ context.Context, req *abci.RequestApplySnapshotChunk) (*abci.ResponseApplySnapshotChunk, error) {
+	return c.manager.apply(req)
+}
@@ -0,0 +1,263 @@
+package statesync
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+// snapshotterApp is an application state that can be exported and restored
+// through abci.StateSnapshotter.
+type snapshotterApp struct {
+	mtx      sync.Mutex
+	state    []byte
+	restored uint64
+}
+
+func (app *snapshotterApp) ExportState(_ context.Context, _ uint64, w io.Writer) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	_, err := w.Write(app.state)
+	return err
+}
+
+func (app *snapshotterApp) RestoreState(_ context.Context, height uint64, _ []byte, r io.Reader) error {
+	bz, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	app.state = bz
+	app.restored = height
+	return nil
+}
+
+func newTestSnapshotManager(t *testing.T, app abci.StateSnapshotter, chunkSize uint32) *SnapshotManager {
+	t.Helper()
+
+	store, err := NewSnapshotStore(t.TempDir())
+	require.NoError(t, err)
+
+	cfg := config.TestStateSyncConfig()
+	cfg.SnapshotChunkSize = chunkSize
+	return NewSnapshotManager(log.NewNopLogger(), *cfg, app, store, nil)
+}
+
+func TestSnapshotManager_SnapshotAndRestore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &snapshotterApp{state: []byte("the quick brown fox jumps over the lazy dog")}
+	sourceManager := newTestSnapshotManager(t, source, 10)
+	sourceManager.takeSnapshot(ctx, 5)
+	sourceConn := sourceManager.Client(nil)
+
+	list, err := sourceConn.ListSnapshots(ctx, &abci.RequestListSnapshots{})
+	require.NoError(t, err)
+	require.Len(t, list.Snapshots, 1)
+	snapshot := list.Snapshots[0]
+	assert.EqualValues(t, 5, snapshot.Height)
+	assert.Equal(t, ManagedSnapshotFormat, snapshot.Format)
+	assert.EqualValues(t, 5, snapshot.Chunks)
+
+	target := &snapshotterApp{}
+	targetConn := newTestSnapshotManager(t, target, 10).Client(nil)
+
+	offer, err := targetConn.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: snapshot})
+	require.NoError(t, err)
+	require.Equal(t, abci.ResponseOfferSnapshot_ACCEPT, offer.Result)
+
+	for index := uint32(0); index < snapshot.Chunks; index++ {
+		chunk, err := sourceConn.LoadSnapshotChunk(ctx, &abci.RequestLoadSnapshotChunk{
+			Height: snapshot.Height,
+			Format: snapshot.Format,
+			Chunk:  index,
+		})
+		require.NoError(t, err)
+
+		// a corrupted chunk is refetched and its sender rejected
+		bad, err := targetConn.ApplySnapshotChunk(ctx, &abci.RequestApplySnapshotChunk{
+			Index:  index,
+			Chunk:  append([]byte{0xff}, chunk.Chunk...),
+			Sender: "bad",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, abci.ResponseApplySnapshotChunk_RETRY, bad.Result)
+		assert.Equal(t, []uint32{index}, bad.RefetchChunks)
+		assert.Equal(t, []string{"bad"}, bad.RejectSenders)
+
+		resp, err := targetConn.ApplySnapshotChunk(ctx, &abci.RequestApplySnapshotChunk{
+			Index:  index,
+			Chunk:  chunk.Chunk,
+			Sender: "good",
+		})
+		require.NoError(t, err)
+		require.Equal(t, abci.ResponseApplySnapshotChunk_ACCEPT, resp.Result)
+	}
+
+	assert.Equal(t, source.state, target.state)
+	assert.EqualValues(t, 5, target.restored)
+}
+
+// blockingRestoreApp reads the whole snapshot and then blocks until its
+// restore is aborted.
+type blockingRestoreApp struct {
+	snapshotterApp
+	read chan struct{}
+}
+
+func (app *blockingRestoreApp) RestoreState(ctx context.Context, _ uint64, _ []byte, r io.Reader) error {
+	if _, err := io.ReadAll(r); err != nil {
+		return err
+	}
+	close(app.read)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSnapshotManager_OfferDuringFinalApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &snapshotterApp{state: []byte("the quick brown fox jumps over the lazy dog")}
+	sourceManager := newTestSnapshotManager(t, source, 10)
+	sourceManager.takeSnapshot(ctx, 5)
+	sourceManager.takeSnapshot(ctx, 6)
+	sourceConn := sourceManager.Client(nil)
+
+	list, err := sourceConn.ListSnapshots(ctx, &abci.RequestListSnapshots{})
+	require.NoError(t, err)
+	require.Len(t, list.Snapshots, 2)
+	snapshot, next := list.Snapshots[0], list.Snapshots[1]
+
+	target := &blockingRestoreApp{read: make(chan struct{})}
+	targetConn := newTestSnapshotManager(t, target, 10).Client(nil)
+
+	offer, err := targetConn.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: snapshot})
+	require.NoError(t, err)
+	require.Equal(t, abci.ResponseOfferSnapshot_ACCEPT, offer.Result)
+
+	applyChunk := func(snapshot *abci.Snapshot, index uint32) (*abci.ResponseApplySnapshotChunk, error) {
+		chunk, err := sourceConn.LoadSnapshotChunk(ctx, &abci.RequestLoadSnapshotChunk{
+			Height: snapshot.Height,
+			Format: snapshot.Format,
+			Chunk:  index,
+		})
+		require.NoError(t, err)
+		return targetConn.ApplySnapshotChunk(ctx, &abci.RequestApplySnapshotChunk{Index: index, Chunk: chunk.Chunk})
+	}
+
+	for index := uint32(0); index < snapshot.Chunks-1; index++ {
+		resp, err := applyChunk(snapshot, index)
+		require.NoError(t, err)
+		require.Equal(t, abci.ResponseApplySnapshotChunk_ACCEPT, resp.Result)
+	}
+
+	// the final chunk waits for the application to finish restoring
+	final := make(chan *abci.ResponseApplySnapshotChunk, 1)
+	go func() {
+		resp, err := applyChunk(snapshot, snapshot.Chunks-1)
+		assert.NoError(t, err)
+		final <- resp
+	}()
+	<-target.read
+
+	// a new offer is not blocked by the pending restore, and aborts it
+	offer, err = targetConn.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: next})
+	require.NoError(t, err)
+	require.Equal(t, abci.ResponseOfferSnapshot_ACCEPT, offer.Result)
+
+	select {
+	case resp := <-final:
+		require.NotNil(t, resp)
+		assert.Equal(t, abci.ResponseApplySnapshotChunk_REJECT_SNAPSHOT, resp.Result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the aborted restore")
+	}
+
+	// the aborted restore did not clear the newly offered one
+	resp, err := applyChunk(next, 0)
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseApplySnapshotChunk_ACCEPT, resp.Result)
+}
+
+func TestSnapshotManager_OfferRejectsInvalidSnapshots(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestSnapshotManager(t, &snapshotterApp{}, 10).Client(nil)
+
+	resp, err := conn.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{
+		Snapshot: &abci.Snapshot{Height: 1, Format: ManagedSnapshotFormat + 1, Chunks: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_REJECT_FORMAT, resp.Result)
+
+	resp, err = conn.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{
+		Snapshot: &abci.Snapshot{Height: 1, Format: ManagedSnapshotFormat, Chunks: 1, Metadata: []byte{1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_REJECT, resp.Result)
+}
+
+func TestSnapshotStore_Prune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSnapshotStore(dir)
+	require.NoError(t, err)
+
+	for height := uint64(1); height <= 4; height++ {
+		_, err := store.Create(height, 3, func(w io.Writer) error {
+			_, err := w.Write(bytes.Repeat([]byte{byte(height)}, 7))
+			return err
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Prune(2))
+
+	snapshots := store.List()
+	require.Len(t, snapshots, 2)
+	assert.EqualValues(t, 3, snapshots[0].Height)
+	assert.EqualValues(t, 4, snapshots[1].Height)
+
+	chunk, err := store.LoadChunk(1, ManagedSnapshotFormat, 0)
+	require.NoError(t, err)
+	assert.Nil(t, chunk)
+
+	chunk, err = store.LoadChunk(4, ManagedSnapshotFormat, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{4}, chunk)
+
+	// snapshots survive reopening the store
+	store, err = NewSnapshotStore(dir)
+	require.NoError(t, err)
+	assert.Len(t, store.List(), 2)
+}
@@ -0,0 +1,294 @@
+package statesync
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	abci "github.com/bhojpur/state/pkg/abci/types"
+)
+
+const (
+	// ManagedSnapshotFormat is the snapshot format of snapshots taken by the
+	// node on behalf of applications implementing abci.StateSnapshotter.
+	ManagedSnapshotFormat uint32 = 1
+
+	snapshotMetadataFile = "snapshot.json"
+)
+
+// SnapshotStore stores snapshots of application state exported through
+// abci.StateSnapshotter. Every snapshot is kept in its own directory named by
+// its height, holding one file per chunk and a metadata file. The metadata of
+// a snapshot is the concatenation of the SHA-256 hashes of its chunks, and the
+// snapshot hash is the SHA-256 hash of the metadata, so that restoring nodes
+// can verify every chunk as it arrives.
+type SnapshotStore struct {
+	mtx       sync.RWMutex
+	dir       string
+	snapshots []*abci.Snapshot // ordered by height
+}
+
+// NewSnapshotStore opens the snapshot store in dir, creating it if needed.
+// Incomplete snapshots left behind by an interrupted export are removed.
+func NewSnapshotStore(dir string) (*SnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SnapshotStore{dir: dir}
+	for _, entry := range entries {
+		if _, err := strconv.ParseUint(entry.Name(), 10, 64); err != nil || !entry.IsDir() {
+			// leftovers of interrupted exports are named <height>.tmp
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		bz, err := os.ReadFile(filepath.Join(dir, entry.Name(), snapshotMetadataFile))
+		if errors.Is(err, os.ErrNotExist) {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		snapshot := &abci.Snapshot{}
+		if err := json.Unmarshal(bz, snapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot metadata in %q: %w", entry.Name(), err)
+		}
+		s.snapshots = append(s.snapshots, snapshot)
+	}
+	sort.Slice(s.snapshots, func(i, j int) bool {
+		return s.snapshots[i].Height < s.snapshots[j].Height
+	})
+
+	return s, nil
+}
+
+// Create stores a new snapshot at the given height, with the state written
+// by export split into chunks of chunkSize bytes.
+func (s *SnapshotStore) Create(height uint64, chunkSize int, export func(io.Writer) error) (*abci.Snapshot, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	if s.Get(height, ManagedSnapshotFormat) != nil {
+		return nil, fmt.Errorf("snapshot at height %d already exists", height)
+	}
+
+	tmpDir := filepath.Join(s.dir, fmt.Sprintf("%d.tmp", height))
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, err
+	}
+
+	w := &snapshotChunkWriter{dir: tmpDir, size: chunkSize}
+	if err := export(w); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("failed to export state at height %d: %w", height, err)
+	}
+	if err := w.Close(); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	metadata := bytes.Join(w.hashes, nil)
+	hash := sha256.Sum256(metadata)
+	snapshot := &abci.Snapshot{
+		Height:   height,
+		Format:   ManagedSnapshotFormat,
+		Chunks:   uint32(len(w.hashes)),
+		Hash:     hash[:],
+		Metadata: metadata,
+	}
+
+	bz, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, snapshotMetadataFile), bz, 0644); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	if err := os.Rename(tmpDir, s.snapshotDir(height)); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.snapshots = append(s.snapshots, snapshot)
+	sort.Slice(s.snapshots, func(i, j int) bool {
+		return s.snapshots[i].Height < s.snapshots[j].Height
+	})
+	return snapshot, nil
+}
+
+// Get returns the snapshot with the given height and format, or nil if there
+// is none.
+func (s *SnapshotStore) Get(height uint64, format uint32) *abci.Snapshot {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	for _, snapshot := range s.snapshots {
+		if snapshot.Height == height && snapshot.Format == format {
+			return snapshot
+		}
+	}
+	return nil
+}
+
+// List returns all stored snapshots, ordered by height.
+func (s *SnapshotStore) List() []*abci.Snapshot {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	snapshots := make([]*abci.Snapshot, len(s.snapshots))
+	copy(snapshots, s.snapshots)
+	return snapshots
+}
+
+// LoadChunk returns the contents of a snapshot chunk, or nil if the snapshot
+// or chunk does not exist.
+func (s *SnapshotStore) LoadChunk(height uint64, format uint32, index uint32) ([]byte, error) {
+	snapshot := s.Get(height, format)
+	if snapshot == nil || index >= snapshot.Chunks {
+		return nil, nil
+	}
+	bz, err := os.ReadFile(filepath.Join(s.snapshotDir(height), chunkFileName(index)))
+	if errors.Is(err, os.ErrNotExist) {
+		// pruned concurrently
+		return nil, nil
+	}
+	return bz, err
+}
+
+// Prune removes all but the keepRecent most recent snapshots.
+func (s *SnapshotStore) Prune(keepRecent int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for len(s.snapshots) > keepRecent {
+		if err := os.RemoveAll(s.snapshotDir(s.snapshots[0].Height)); err != nil {
+			return err
+		}
+		s.snapshots = s.snapshots[1:]
+	}
+	return nil
+}
+
+func (s *SnapshotStore) snapshotDir(height uint64) string {
+	return filepath.Join(s.dir, strconv.FormatUint(height, 10))
+}
+
+func chunkFileName(index uint32) string {
+	return fmt.Sprintf("chunk-%08d", index)
+}
+
+// snapshotChunkHash returns the hash of chunk index recorded in the metadata
+// of a managed snapshot.
+func snapshotChunkHash(snapshot *abci.Snapshot, index uint32) []byte {
+	start := int(index) * sha256.Size
+	if start+sha256.Size > len(snapshot.Metadata) {
+		return nil
+	}
+	return snapshot.Metadata[start : start+sha256.Size]
+}
+
+// validateManagedSnapshot checks that the metadata of a managed snapshot is
+// consistent with its chunk count and hash.
+func validateManagedSnapshot(snapshot *abci.Snapshot) error {
+	if snapshot.Chunks == 0 {
+		return errors.New("snapshot has no chunks")
+	}
+	if len(snapshot.Metadata) != int(snapshot.Chunks)*sha256.Size {
+		return fmt.Errorf("snapshot metadata has %d bytes, expected %d",
+			len(snapshot.Metadata), int(snapshot.Chunks)*sha256.Size)
+	}
+	if hash := sha256.Sum256(snapshot.Metadata); !bytes.Equal(hash[:], snapshot.Hash) {
+		return errors.New("snapshot hash does not match its metadata")
+	}
+	return nil
+}
+
+// snapshotChunkWriter splits the data written to it into chunk files of a
+// fixed size, recording the hash of every chunk.
+type snapshotChunkWriter struct {
+	dir    string
+	size   int
+	buf    []byte
+	hashes [][]byte
+}
+
+func (w *snapshotChunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := w.size - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		if len(w.buf) == w.size {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the final partial chunk. A snapshot always has at least one
+// chunk, which may be empty.
+func (w *snapshotChunkWriter) Close() error {
+	if len(w.buf) > 0 || len(w.hashes) == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *snapshotChunkWriter) flush() error {
+	index := uint32(len(w.hashes))
+	if err := os.WriteFile(filepath.Join(w.dir, chunkFileName(index)), w.buf, 0644); err != nil {
+		return err
+	}
+	hash := sha256.Sum256(w.buf)
+	w.hashes = append(w.hashes, hash[:])
+	w.buf = w.buf[:0]
+	return nil
+}
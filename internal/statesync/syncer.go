@@ -25,6 +25,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -172,6 +173,18 @@ func (s *syncer) SyncAny(
 		chunks   *chunkQueue
 		err      error
 	)
+	defer func() {
+		// Clean up the chunk queue, unless it was suspended to resume the restore later.
+		if chunks != nil {
+			if err := chunks.Close(); err != nil {
+				s.logger.Error("Failed to clean up chunk queue", "err", err)
+			}
+		}
+	}()
+
+	// A previous restore may have been interrupted, in which case we pick up where it left off.
+	snapshot, chunks = s.resumeSnapshot()
+
 	for {
 		// If not nil, we're going to retry restoration of the same snapshot.
 		if snapshot == nil {
@@ -191,7 +204,6 @@ func (s *syncer) SyncAny(
 			if err != nil {
 				return sm.State{}, nil, fmt.Errorf("failed to create chunk queue: %w", err)
 			}
-			defer chunks.Close() // in case we forget to close it elsewhere
 		}
 
 		s.processingSnapshot = snapshot
@@ -236,6 +248,9 @@ func (s *syncer) SyncAny(
 			}
 
 		default:
+			// Keep the fetched chunks on disk, so that the restore can be resumed on restart.
+			chunks.Suspend()
+			chunks = nil
 			return sm.State{}, nil, fmt.Errorf("snapshot restoration failed: %w", err)
 		}
 
@@ -250,6 +265,35 @@ func (s *syncer) SyncAny(
 	}
 }
 
+// resumeSnapshot loads the snapshot and chunk queue of an interrupted restore from the temp dir.
+// It returns nil if there is nothing to resume, or if the remaining chunks can no longer be
+// fetched because no peers serve the snapshot anymore, in which case the chunks are discarded.
+func (s *syncer) resumeSnapshot() (*snapshot, *chunkQueue) {
+	chunks, err := loadChunkQueue(s.tempDir)
+	if err != nil {
+		s.logger.Error("Failed to load chunk queue of interrupted snapshot restore", "err", err)
+		_ = os.RemoveAll(chunkQueuePath(s.tempDir))
+		return nil, nil
+	}
+	if chunks == nil {
+		return nil, nil
+	}
+
+	snapshot := chunks.snapshot
+	if !chunks.Complete() && len(s.snapshots.GetPeers(snapshot)) == 0 {
+		s.logger.Info("Peers no longer serve snapshot of interrupted restore, discarding it",
+			"height", snapshot.Height, "format", snapshot.Format, "hash", snapshot.Hash)
+		if err := chunks.Close(); err != nil {
+			s.logger.Error("Failed to clean up chunk queue", "err", err)
+		}
+		return nil, nil
+	}
+
+	s.logger.Info("Resuming interrupted snapshot restore", "height", snapshot.Height,
+		"format", snapshot.Format, "hash", snapshot.Hash, "applied", chunks.NumApplied())
+	return snapshot, chunks
+}
+
 // Sync executes a sync for a specific snapshot, returning the latest state and block commit which
 // the caller must use to bootstrap the node.
 func (s *syncer) Sync(ctx context.Context, snapshot *snapshot, chunks *chunkQueue) (sm.State, *types.Commit, error) {
@@ -432,6 +476,9 @@ func (s *syncer) applyChunks(ctx context.Context, chunks *chunkQueue, start time
 
 		switch resp.Result {
 		case abci.ResponseApplySnapshotChunk_ACCEPT:
+			if err := chunks.MarkApplied(chunk.Index); err != nil {
+				return fmt.Errorf("failed to record applied chunk %v: %w", chunk.Index, err)
+			}
 			s.metrics.SnapshotChunk.Add(1)
 			s.avgChunkTime = time.Since(start).Nanoseconds() / int64(chunks.numChunksReturned())
 			s.metrics.ChunkProcessAvgTime.Set(float64(s.avgChunkTime))
@@ -431,6 +431,159 @@ func TestSyncer_SyncAny_abciError(t *testing.T) {
 	rts.conn.AssertExpectations(t)
 }
 
+// interruptRestore leaves a suspended chunk queue for s in dir, as left behind by a restore that
+// fetched the given chunks from peer "aa" and applied the first applied of them before the node
+// was restarted.
+func interruptRestore(t *testing.T, dir string, s *snapshot, chunks []*chunk, applied int) {
+	t.Helper()
+
+	queue, err := newChunkQueue(s, dir)
+	require.NoError(t, err)
+	for _, c := range chunks {
+		_, err = queue.Allocate()
+		require.NoError(t, err)
+		_, err = queue.Add(&chunk{Height: c.Height, Format: c.Format, Index: c.Index, Chunk: c.Chunk, Sender: "aa"})
+		require.NoError(t, err)
+	}
+	for i := 0; i < applied; i++ {
+		c, err := queue.Next()
+		require.NoError(t, err)
+		require.NoError(t, queue.MarkApplied(c.Index))
+	}
+	queue.Suspend()
+}
+
+func TestSyncer_SyncAny_resume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	state := sm.State{
+		ChainID: "chain",
+		Version: sm.Version{
+			Consensus: version.Consensus{
+				Block: version.BlockProtocol,
+				App:   testAppVersion,
+			},
+			Software: version.Version,
+		},
+		LastBlockHeight: 1,
+		AppHash:         []byte("app_hash"),
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}
+	commit := &types.Commit{BlockID: types.BlockID{Hash: []byte("blockhash")}}
+
+	chunks := []*chunk{
+		{Height: 1, Format: 1, Index: 0, Chunk: []byte{1, 1, 0}},
+		{Height: 1, Format: 1, Index: 1, Chunk: []byte{1, 1, 1}},
+		{Height: 1, Format: 1, Index: 2, Chunk: []byte{1, 1, 2}},
+		{Height: 1, Format: 1, Index: 3, Chunk: []byte{1, 1, 3}},
+	}
+	s := &snapshot{Height: 1, Format: 1, Chunks: 4, Hash: []byte{1, 2, 3}}
+
+	stateProvider := &mocks.StateProvider{}
+	stateProvider.On("AppHash", mock.Anything, uint64(1)).Return(state.AppHash, nil)
+	stateProvider.On("Commit", mock.Anything, uint64(1)).Return(commit, nil)
+	stateProvider.On("State", mock.Anything, uint64(1)).Return(state, nil)
+
+	rts := setup(ctx, t, nil, stateProvider, 2)
+
+	// Before the restart, chunks 0-2 were fetched from peer aa and chunks 0-1 were applied.
+	interruptRestore(t, rts.syncer.tempDir, s, chunks[:3], 2)
+
+	// After the restart, peer aa is gone but peer bb serves the same snapshot.
+	peerBID := types.NodeID("bb")
+	_, err := rts.syncer.AddSnapshot(peerBID, s)
+	require.NoError(t, err)
+
+	chunkRequests := make(chan uint32, len(chunks))
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-rts.chunkOutCh:
+				msg, ok := e.Message.(*ssproto.ChunkRequest)
+				assert.True(t, ok)
+				assert.Equal(t, peerBID, e.To)
+
+				added, err := rts.syncer.AddChunk(&chunk{
+					Height: msg.Height,
+					Format: msg.Format,
+					Index:  msg.Index,
+					Chunk:  chunks[msg.Index].Chunk,
+					Sender: e.To,
+				})
+				assert.NoError(t, err)
+				assert.True(t, added)
+				chunkRequests <- msg.Index
+			}
+		}
+	}()
+
+	// Only the chunks that were not applied before the restart are applied.
+	rts.conn.On("OfferSnapshot", mock.Anything, &abci.RequestOfferSnapshot{
+		Snapshot: toABCI(s), AppHash: []byte("app_hash"),
+	}).Once().Return(&abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_ACCEPT}, nil)
+	for _, index := range []uint32{2, 3} {
+		rts.conn.On("ApplySnapshotChunk", mock.Anything, &abci.RequestApplySnapshotChunk{
+			Index: index, Chunk: chunks[index].Chunk,
+		}).Once().Return(&abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil)
+	}
+	rts.conn.On("Info", mock.Anything, &proxy.RequestInfo).Return(&abci.ResponseInfo{
+		AppVersion:       testAppVersion,
+		LastBlockHeight:  1,
+		LastBlockAppHash: []byte("app_hash"),
+	}, nil)
+
+	newState, lastCommit, err := rts.syncer.SyncAny(ctx, 0, func() error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, state, newState)
+	require.Equal(t, commit, lastCommit)
+
+	// Only the chunk missing on disk was fetched, from the remaining peer.
+	require.Len(t, chunkRequests, 1)
+	require.EqualValues(t, 3, <-chunkRequests)
+	rts.conn.AssertExpectations(t)
+
+	// The completed restore is not resumed again.
+	queue, err := loadChunkQueue(rts.syncer.tempDir)
+	require.NoError(t, err)
+	require.Nil(t, queue)
+}
+
+func TestSyncer_SyncAny_resumeWithoutPeers(t *testing.T) {
+	stateProvider := &mocks.StateProvider{}
+	stateProvider.On("AppHash", mock.Anything, mock.Anything).Return([]byte("app_hash"), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rts := setup(ctx, t, nil, stateProvider, 2)
+
+	s1 := &snapshot{Height: 1, Format: 1, Chunks: 3, Hash: []byte{1, 2, 3}}
+	s2 := &snapshot{Height: 2, Format: 1, Chunks: 3, Hash: []byte{4, 5, 6}}
+
+	// The interrupted restore of s1 cannot be resumed since no peer serves it anymore, so the
+	// syncer falls back to the snapshots that are available.
+	interruptRestore(t, rts.syncer.tempDir, s1, []*chunk{{Height: 1, Format: 1, Index: 0, Chunk: []byte{1}}}, 1)
+
+	_, err := rts.syncer.AddSnapshot(types.NodeID("bb"), s2)
+	require.NoError(t, err)
+
+	rts.conn.On("OfferSnapshot", mock.Anything, &abci.RequestOfferSnapshot{
+		Snapshot: toABCI(s2), AppHash: []byte("app_hash"),
+	}).Once().Return(&abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_ABORT}, nil)
+
+	_, _, err = rts.syncer.SyncAny(ctx, 0, func() error { return nil })
+	require.Equal(t, errAbort, err)
+	rts.conn.AssertExpectations(t)
+
+	// The chunks of the interrupted restore were discarded.
+	queue, err := loadChunkQueue(rts.syncer.tempDir)
+	require.NoError(t, err)
+	require.Nil(t, queue)
+}
+
 func TestSyncer_offerSnapshot(t *testing.T) {
 	unknownErr := errors.New("unknown error")
 	boom := errors.New("boom")
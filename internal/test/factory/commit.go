@@ -51,7 +51,9 @@ func MakeCommit(ctx context.Context, blockID types.BlockID, height int64, round
 			return nil, err
 		}
 		vote.Signature = v.Signature
-		vote.ExtensionSignature = v.ExtensionSignature
+		if voteSet.ExtensionsEnabled() {
+			vote.ExtensionSignature = v.ExtensionSignature
+		}
 		if _, err := voteSet.AddVote(vote); err != nil {
 			return nil, err
 		}
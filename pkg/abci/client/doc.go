@@ -32,6 +32,12 @@ package abciclient
 // The client blocks for enqueuing the request, for enqueuing the
 // Flush to send the request, and for the Flush response to return.
 //
+// Requests carry an id and a lane (consensus, mempool, query or snapshot),
+// so that a server with lanes enabled may answer the lanes independently: a
+// CheckTx doesn't wait for a FinalizeBlock sent before it. Requests of a lane
+// are answered in order, and a Flush once every request sent before it is.
+// Servers answer all requests in order unless lanes are enabled.
+//
 // ## Local client
 //
 // The global mutex is locked during each call
@@ -35,6 +35,7 @@ import (
 	"github.com/bhojpur/state/pkg/libs/log"
 	libnet "github.com/bhojpur/state/pkg/libs/net"
 	"github.com/bhojpur/state/pkg/libs/service"
+	"github.com/bhojpur/state/pkg/libs/trace"
 )
 
 // A gRPC client.
@@ -80,6 +81,7 @@ RETRY_LOOP:
 		conn, err := grpc.Dial(cli.addr,
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
 			grpc.WithContextDialer(dialerFunc),
+			grpc.WithUnaryInterceptor(trace.UnaryClientInterceptor()),
 		)
 		if err != nil {
 			if cli.mustConnect {
@@ -40,7 +40,8 @@ type localClient struct {
 var _ Client = (*localClient)(nil)
 
 // NewLocalClient creates a local client, which will be directly calling the
-// methods of the given app.
+// methods of the given app. To upgrade the app without restarting the node,
+// pass a types.Multiplexer holding every version of it.
 //
 // The client methods ignore their context argument.
 func NewLocalClient(logger log.Logger, app types.Application) Client {
@@ -51,6 +52,15 @@ func NewLocalClient(logger log.Logger, app types.Application) Client {
 	return cli
 }
 
+// LocalApplication returns the application called by a client created with
+// NewLocalClient, or nil if the client is not a local client.
+func LocalApplication(client Client) types.Application {
+	if cli, ok := client.(*localClient); ok {
+		return cli.Application
+	}
+	return nil
+}
+
 func (*localClient) OnStart(context.Context) error { return nil }
 func (*localClient) OnStop()                       {}
 func (*localClient) Error() error                  { return nil }
@@ -0,0 +1,354 @@
+package abciclient
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	auto "github.com/bhojpur/state/internal/libs/autofile"
+	"github.com/bhojpur/state/internal/libs/crcframe"
+	"github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+// maxRecordSize is the max size of a record: a request and its response.
+const maxRecordSize = 2*maxMsgSize + 16
+
+// maxMsgSize is the max size of an ABCI message, see types.ReadMessage.
+const maxMsgSize = 104857600 // 100MB
+
+// Record is an ABCI call recorded by a recording client.
+type Record struct {
+	// Time the call started at, and how long it took.
+	Time     time.Time
+	Duration time.Duration
+
+	Request *types.Request
+	// Response is a ResponseException for calls that returned an error or
+	// panicked.
+	Response *types.Response
+}
+
+// recordingClient records every call to the wrapped client.
+type recordingClient struct {
+	Client
+	logger log.Logger
+
+	mtx   sync.Mutex
+	group *auto.Group
+	enc   *RecordEncoder
+}
+
+var _ Client = (*recordingClient)(nil)
+
+// NewRecordingClient returns a client that records every call to client,
+// with its request, response and timing, in the autofile group with head at
+// path. The group is never pruned, so that the recorded calls can be replayed
+// against a fresh application. Records are synced to disk after every
+// InitChain, FinalizeBlock and Commit, after every failed call and before a
+// panic of the application is propagated. Read them back with a
+// RecordDecoder.
+func NewRecordingClient(ctx context.Context, logger log.Logger, client Client, path string) (Client, error) {
+	group, err := auto.OpenGroup(ctx, logger, path, auto.GroupTotalSizeLimit(0))
+	if err != nil {
+		return nil, err
+	}
+	return &recordingClient{
+		Client: client,
+		logger: logger,
+		group:  group,
+		enc:    NewRecordEncoder(group),
+	}, nil
+}
+
+// Start starts the autofile group and the wrapped client.
+func (c *recordingClient) Start(ctx context.Context) error {
+	if err := c.group.Start(ctx); err != nil {
+		return err
+	}
+	return c.Client.Start(ctx)
+}
+
+// Stop stops the wrapped client, and closes the autofile group.
+func (c *recordingClient) Stop() {
+	if cli, ok := c.Client.(interface{ Stop() }); ok {
+		cli.Stop()
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.group.IsRunning() {
+		c.group.Stop()
+	}
+	c.group.Close()
+}
+
+// call is a call being recorded.
+type call struct {
+	c     *recordingClient
+	req   *types.Request
+	start time.Time
+}
+
+func (c *recordingClient) begin(req *types.Request) *call {
+	return &call{c: c, req: req, start: time.Now()}
+}
+
+// end records the call with its response, or with err if it failed.
+func (cl *call) end(res *types.Response, err error) {
+	flush := err != nil
+	if err != nil {
+		res = types.ToResponseException(err.Error())
+	}
+	switch cl.req.Value.(type) {
+	case *types.Request_InitChain, *types.Request_FinalizeBlock, *types.Request_Commit:
+		flush = true
+	}
+	cl.c.write(&Record{
+		Time:     cl.start,
+		Duration: time.Since(cl.start),
+		Request:  cl.req,
+		Response: res,
+	}, flush)
+}
+
+// recoverPanic records a panicking call, and propagates the panic. It must
+// be deferred.
+func (cl *call) recoverPanic() {
+	if r := recover(); r != nil {
+		cl.end(nil, fmt.Errorf("panic: %v", r))
+		panic(r)
+	}
+}
+
+func (c *recordingClient) write(rec *Record, flush bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := c.enc.Encode(rec); err != nil {
+		c.logger.Error("failed to record ABCI call", "request", fmt.Sprintf("%T", rec.Request.Value), "err", err)
+		return
+	}
+	if flush {
+		if err := c.group.FlushAndSync(); err != nil {
+			c.logger.Error("failed to sync recorded ABCI calls", "err", err)
+		}
+	}
+}
+
+func (c *recordingClient) Flush(ctx context.Context) error {
+	cl := c.begin(types.ToRequestFlush())
+	defer cl.recoverPanic()
+	err := c.Client.Flush(ctx)
+	cl.end(types.ToResponseFlush(), err)
+	return err
+}
+
+func (c *recordingClient) Echo(ctx context.Context, msg string) (*types.ResponseEcho, error) {
+	cl := c.begin(types.ToRequestEcho(msg))
+	defer cl.recoverPanic()
+	res, err := c.Client.Echo(ctx, msg)
+	cl.end(types.ToResponseEcho(res.GetMessage()), err)
+	return res, err
+}
+
+func (c *recordingClient) Info(ctx context.Context, req *types.RequestInfo) (*types.ResponseInfo, error) {
+	cl := c.begin(types.ToRequestInfo(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.Info(ctx, req)
+	cl.end(types.ToResponseInfo(res), err)
+	return res, err
+}
+
+func (c *recordingClient) Query(ctx context.Context, req *types.RequestQuery) (*types.ResponseQuery, error) {
+	cl := c.begin(types.ToRequestQuery(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.Query(ctx, req)
+	cl.end(types.ToResponseQuery(res), err)
+	return res, err
+}
+
+func (c *recordingClient) CheckTx(ctx context.Context, req *types.RequestCheckTx) (*types.ResponseCheckTx, error) {
+	cl := c.begin(types.ToRequestCheckTx(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.CheckTx(ctx, req)
+	cl.end(types.ToResponseCheckTx(res), err)
+	return res, err
+}
+
+func (c *recordingClient) InitChain(ctx context.Context, req *types.RequestInitChain) (*types.ResponseInitChain, error) {
+	cl := c.begin(types.ToRequestInitChain(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.InitChain(ctx, req)
+	cl.end(types.ToResponseInitChain(res), err)
+	return res, err
+}
+
+func (c *recordingClient) PrepareProposal(ctx context.Context, req *types.RequestPrepareProposal) (*types.ResponsePrepareProposal, error) {
+	cl := c.begin(types.ToRequestPrepareProposal(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.PrepareProposal(ctx, req)
+	cl.end(types.ToResponsePrepareProposal(res), err)
+	return res, err
+}
+
+func (c *recordingClient) ProcessProposal(ctx context.Context, req *types.RequestProcessProposal) (*types.ResponseProcessProposal, error) {
+	cl := c.begin(types.ToRequestProcessProposal(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.ProcessProposal(ctx, req)
+	cl.end(types.ToResponseProcessProposal(res), err)
+	return res, err
+}
+
+func (c *recordingClient) ExtendVote(ctx context.Context, req *types.RequestExtendVote) (*types.ResponseExtendVote, error) {
+	cl := c.begin(types.ToRequestExtendVote(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.ExtendVote(ctx, req)
+	cl.end(types.ToResponseExtendVote(res), err)
+	return res, err
+}
+
+func (c *recordingClient) VerifyVoteExtension(ctx context.Context, req *types.RequestVerifyVoteExtension) (*types.ResponseVerifyVoteExtension, error) {
+	cl := c.begin(types.ToRequestVerifyVoteExtension(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.VerifyVoteExtension(ctx, req)
+	cl.end(types.ToResponseVerifyVoteExtension(res), err)
+	return res, err
+}
+
+func (c *recordingClient) FinalizeBlock(ctx context.Context, req *types.RequestFinalizeBlock) (*types.ResponseFinalizeBlock, error) {
+	cl := c.begin(types.ToRequestFinalizeBlock(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.FinalizeBlock(ctx, req)
+	cl.end(types.ToResponseFinalizeBlock(res), err)
+	return res, err
+}
+
+func (c *recordingClient) Commit(ctx context.Context) (*types.ResponseCommit, error) {
+	cl := c.begin(types.ToRequestCommit())
+	defer cl.recoverPanic()
+	res, err := c.Client.Commit(ctx)
+	cl.end(types.ToResponseCommit(res), err)
+	return res, err
+}
+
+func (c *recordingClient) ListSnapshots(ctx context.Context, req *types.RequestListSnapshots) (*types.ResponseListSnapshots, error) {
+	cl := c.begin(types.ToRequestListSnapshots(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.ListSnapshots(ctx, req)
+	cl.end(types.ToResponseListSnapshots(res), err)
+	return res, err
+}
+
+func (c *recordingClient) OfferSnapshot(ctx context.Context, req *types.RequestOfferSnapshot) (*types.ResponseOfferSnapshot, error) {
+	cl := c.begin(types.ToRequestOfferSnapshot(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.OfferSnapshot(ctx, req)
+	cl.end(types.ToResponseOfferSnapshot(res), err)
+	return res, err
+}
+
+func (c *recordingClient) LoadSnapshotChunk(ctx context.Context, req *types.RequestLoadSnapshotChunk) (*types.ResponseLoadSnapshotChunk, error) {
+	cl := c.begin(types.ToRequestLoadSnapshotChunk(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.LoadSnapshotChunk(ctx, req)
+	cl.end(types.ToResponseLoadSnapshotChunk(res), err)
+	return res, err
+}
+
+func (c *recordingClient) ApplySnapshotChunk(ctx context.Context, req *types.RequestApplySnapshotChunk) (*types.ResponseApplySnapshotChunk, error) {
+	cl := c.begin(types.ToRequestApplySnapshotChunk(req))
+	defer cl.recoverPanic()
+	res, err := c.Client.ApplySnapshotChunk(ctx, req)
+	cl.end(types.ToResponseApplySnapshotChunk(res), err)
+	return res, err
+}
+
+// A RecordEncoder writes records to an output stream.
+//
+// Each record is written as a checksummed frame (see crcframe.Write), whose
+// value is the start time in Unix nanoseconds (8 bytes), the duration in
+// nanoseconds (8 bytes), and the length-delimited request and response.
+type RecordEncoder struct {
+	wr io.Writer
+}
+
+// NewRecordEncoder returns a new encoder that writes to wr.
+func NewRecordEncoder(wr io.Writer) *RecordEncoder {
+	return &RecordEncoder{wr}
+}
+
+// Encode writes rec to the stream in a single write.
+func (enc *RecordEncoder) Encode(rec *Record) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 16))
+	if err := types.WriteMessage(rec.Request, &buf); err != nil {
+		return err
+	}
+	if err := types.WriteMessage(rec.Response, &buf); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint64(data[0:8], uint64(rec.Time.UnixNano()))
+	binary.BigEndian.PutUint64(data[8:16], uint64(rec.Duration))
+	return crcframe.Write(enc.wr, data, maxRecordSize)
+}
+
+// A RecordDecoder reads records written by a RecordEncoder from an input
+// stream, checking their checksums.
+type RecordDecoder struct {
+	rd io.Reader
+}
+
+// NewRecordDecoder returns a new decoder that reads from rd.
+func NewRecordDecoder(rd io.Reader) *RecordDecoder {
+	return &RecordDecoder{rd}
+}
+
+// Decode reads the next record. It returns io.EOF at the end of the stream,
+// and io.ErrUnexpectedEOF if the stream ends within a record, as it may if
+// the recording process crashed.
+func (dec *RecordDecoder) Decode() (*Record, error) {
+	data, err := crcframe.Read(dec.rd, 16, maxRecordSize)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		Time:     time.Unix(0, int64(binary.BigEndian.Uint64(data[0:8]))),
+		Duration: time.Duration(binary.BigEndian.Uint64(data[8:16])),
+		Request:  &types.Request{},
+		Response: &types.Response{},
+	}
+	r := bytes.NewReader(data[16:])
+	if err := types.ReadMessage(r, rec.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+	if err := types.ReadMessage(r, rec.Response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return rec, nil
+}
@@ -0,0 +1,134 @@
+package abciclient_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/example/kvstore"
+	auto "github.com/bhojpur/state/internal/libs/autofile"
+	abciclient "github.com/bhojpur/state/pkg/abci/client"
+	"github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+func TestRecordEncoderDecoder(t *testing.T) {
+	records := []*abciclient.Record{
+		{
+			Time:     time.Unix(0, 1600000000000000000),
+			Duration: time.Millisecond,
+			Request:  types.ToRequestCheckTx(&types.RequestCheckTx{Tx: []byte("a=b")}),
+			Response: types.ToResponseCheckTx(&types.ResponseCheckTx{Code: 1, Log: "bad"}),
+		},
+		{
+			Time:     time.Unix(0, 1600000000100000000),
+			Duration: 2 * time.Second,
+			Request:  types.ToRequestCommit(),
+			Response: types.ToResponseException("app crashed"),
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := abciclient.NewRecordEncoder(&buf)
+	for _, rec := range records {
+		require.NoError(t, enc.Encode(rec))
+	}
+	full := buf.Len()
+
+	dec := abciclient.NewRecordDecoder(bytes.NewReader(buf.Bytes()))
+	for _, want := range records {
+		rec, err := dec.Decode()
+		require.NoError(t, err)
+		assert.True(t, want.Time.Equal(rec.Time))
+		assert.Equal(t, want.Duration, rec.Duration)
+		assert.Equal(t, want.Request.String(), rec.Request.String())
+		assert.Equal(t, want.Response.String(), rec.Response.String())
+	}
+	_, err := dec.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// a record cut short by a crash
+	dec = abciclient.NewRecordDecoder(bytes.NewReader(buf.Bytes()[:full-3]))
+	_, err = dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// a corrupted record
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+	dec = abciclient.NewRecordDecoder(bytes.NewReader(data))
+	_, err = dec.Decode()
+	require.NoError(t, err)
+	_, err = dec.Decode()
+	assert.Error(t, err)
+}
+
+func TestRecordingClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewNopLogger()
+	path := filepath.Join(t.TempDir(), "abci.rec")
+	client, err := abciclient.NewRecordingClient(ctx, logger,
+		abciclient.NewLocalClient(logger, kvstore.NewApplication()), path)
+	require.NoError(t, err)
+	require.NoError(t, client.Start(ctx))
+
+	_, err = client.CheckTx(ctx, &types.RequestCheckTx{Tx: []byte("a=b")})
+	require.NoError(t, err)
+	res, err := client.FinalizeBlock(ctx, &types.RequestFinalizeBlock{Height: 1, Txs: [][]byte{[]byte("a=b")}})
+	require.NoError(t, err)
+	_, err = client.Commit(ctx)
+	require.NoError(t, err)
+	client.(interface{ Stop() }).Stop()
+
+	group, err := auto.OpenGroup(ctx, logger, path)
+	require.NoError(t, err)
+	defer group.Close()
+	rd, err := group.NewReader(group.MinIndex())
+	require.NoError(t, err)
+	defer rd.Close()
+
+	dec := abciclient.NewRecordDecoder(rd)
+	var recs []*abciclient.Record
+	for {
+		rec, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		recs = append(recs, rec)
+	}
+	require.Len(t, recs, 3)
+	assert.NotNil(t, recs[0].Request.GetCheckTx())
+	assert.Equal(t, int64(1), recs[1].Request.GetFinalizeBlock().Height)
+	assert.Equal(t, res.AppHash, recs[1].Response.GetFinalizeBlock().AppHash)
+	assert.NotNil(t, recs[2].Response.GetCommit())
+}
@@ -45,6 +45,11 @@ const (
 
 // This is goroutine-safe, but users should beware that the application in
 // general is not meant to be interfaced with concurrent callers.
+//
+// Every request is sent with an id and the lane it belongs to, so that a
+// server with lanes enabled may answer requests of different lanes out of
+// order. Other servers, including servers that don't know about lanes, ignore
+// them and answer in order, without ids.
 type socketClient struct {
 	service.BaseService
 	logger log.Logger
@@ -57,7 +62,9 @@ type socketClient struct {
 
 	mtx     sync.Mutex
 	err     error
-	reqSent *list.List // list of requests sent, waiting for response
+	reqSent *list.List               // list of requests sent, waiting for response
+	reqIDs  map[uint64]*list.Element // requests sent, by id
+	lastID  uint64
 }
 
 var _ Client = (*socketClient)(nil)
@@ -72,6 +79,7 @@ func NewSocketClient(logger log.Logger, addr string, mustConnect bool) Client {
 		mustConnect: mustConnect,
 		addr:        addr,
 		reqSent:     list.New(),
+		reqIDs:      make(map[uint64]*list.Element),
 	}
 	cli.BaseService = *service.NewBaseService(logger, "socketClient", cli)
 	return cli
@@ -184,17 +192,30 @@ func (cli *socketClient) willSendReq(reqres *requestAndResponse) {
 		return
 	}
 
-	cli.reqSent.PushBack(reqres)
+	cli.lastID++
+	reqres.Request.Id = cli.lastID
+	reqres.Request.Lane = types.RequestLane(reqres.Request)
+	cli.reqIDs[reqres.Request.Id] = cli.reqSent.PushBack(reqres)
 }
 
 func (cli *socketClient) didRecvResponse(res *types.Response) error {
 	cli.mtx.Lock()
 	defer cli.mtx.Unlock()
 
-	// Get the first ReqRes.
-	next := cli.reqSent.Front()
-	if next == nil {
-		return fmt.Errorf("unexpected %T when nothing expected", res.Value)
+	// Responses without an id answer the first ReqRes, others the ReqRes
+	// with their id.
+	var next *list.Element
+	if res.Id == 0 {
+		next = cli.reqSent.Front()
+		if next == nil {
+			return fmt.Errorf("unexpected %T when nothing expected", res.Value)
+		}
+	} else {
+		var ok bool
+		next, ok = cli.reqIDs[res.Id]
+		if !ok {
+			return fmt.Errorf("unexpected %T with unknown id %d", res.Value, res.Id)
+		}
 	}
 
 	reqres := next.Value.(*requestAndResponse)
@@ -203,8 +224,9 @@ func (cli *socketClient) didRecvResponse(res *types.Response) error {
 	}
 
 	reqres.Response = res
-	reqres.markDone()        // release waiters
-	cli.reqSent.Remove(next) // pop first item from linked list
+	reqres.markDone() // release waiters
+	cli.reqSent.Remove(next)
+	delete(cli.reqIDs, reqres.Request.Id)
 
 	return nil
 }
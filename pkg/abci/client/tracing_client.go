@@ -0,0 +1,160 @@
+package abciclient
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+
+	"github.com/bhojpur/state/pkg/abci/types"
+	"github.com/bhojpur/state/pkg/libs/trace"
+)
+
+// tracingClient records a span for each call to the wrapped client.
+type tracingClient struct {
+	Client
+}
+
+var _ Client = (*tracingClient)(nil)
+
+// NewTracingClient returns a client that records a span for each call to
+// client, as a child of the span in the context of the call. Calls whose
+// context holds no span or tracer are not recorded. The span context is
+// propagated to gRPC applications as metadata.
+func NewTracingClient(client Client) Client {
+	return &tracingClient{Client: client}
+}
+
+func endSpan(span *trace.Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
+
+func (c *tracingClient) Flush(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "abci.Flush")
+	err := c.Client.Flush(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingClient) Echo(ctx context.Context, msg string) (*types.ResponseEcho, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.Echo")
+	res, err := c.Client.Echo(ctx, msg)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) Info(ctx context.Context, req *types.RequestInfo) (*types.ResponseInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.Info")
+	res, err := c.Client.Info(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) Query(ctx context.Context, req *types.RequestQuery) (*types.ResponseQuery, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.Query", "path", req.GetPath(), "height", req.GetHeight())
+	res, err := c.Client.Query(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) CheckTx(ctx context.Context, req *types.RequestCheckTx) (*types.ResponseCheckTx, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.CheckTx")
+	res, err := c.Client.CheckTx(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) InitChain(ctx context.Context, req *types.RequestInitChain) (*types.ResponseInitChain, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.InitChain")
+	res, err := c.Client.InitChain(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) PrepareProposal(ctx context.Context, req *types.RequestPrepareProposal) (*types.ResponsePrepareProposal, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.PrepareProposal", "height", req.GetHeight(), "txs", len(req.GetTxs()))
+	res, err := c.Client.PrepareProposal(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) ProcessProposal(ctx context.Context, req *types.RequestProcessProposal) (*types.ResponseProcessProposal, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.ProcessProposal", "height", req.GetHeight(), "txs", len(req.GetTxs()))
+	res, err := c.Client.ProcessProposal(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) ExtendVote(ctx context.Context, req *types.RequestExtendVote) (*types.ResponseExtendVote, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.ExtendVote", "height", req.GetHeight())
+	res, err := c.Client.ExtendVote(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) VerifyVoteExtension(ctx context.Context, req *types.RequestVerifyVoteExtension) (*types.ResponseVerifyVoteExtension, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.VerifyVoteExtension", "height", req.GetHeight())
+	res, err := c.Client.VerifyVoteExtension(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) FinalizeBlock(ctx context.Context, req *types.RequestFinalizeBlock) (*types.ResponseFinalizeBlock, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.FinalizeBlock", "height", req.GetHeight(), "txs", len(req.GetTxs()))
+	res, err := c.Client.FinalizeBlock(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) Commit(ctx context.Context) (*types.ResponseCommit, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.Commit")
+	res, err := c.Client.Commit(ctx)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) ListSnapshots(ctx context.Context, req *types.RequestListSnapshots) (*types.ResponseListSnapshots, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.ListSnapshots")
+	res, err := c.Client.ListSnapshots(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) OfferSnapshot(ctx context.Context, req *types.RequestOfferSnapshot) (*types.ResponseOfferSnapshot, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.OfferSnapshot")
+	res, err := c.Client.OfferSnapshot(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) LoadSnapshotChunk(ctx context.Context, req *types.RequestLoadSnapshotChunk) (*types.ResponseLoadSnapshotChunk, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.LoadSnapshotChunk", "height", req.GetHeight(), "chunk", req.GetChunk())
+	res, err := c.Client.LoadSnapshotChunk(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *tracingClient) ApplySnapshotChunk(ctx context.Context, req *types.RequestApplySnapshotChunk) (*types.ResponseApplySnapshotChunk, error) {
+	ctx, span := trace.StartSpan(ctx, "abci.ApplySnapshotChunk", "chunk", req.GetIndex())
+	res, err := c.Client.ApplySnapshotChunk(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/bhojpur/state/pkg/libs/log"
 	libnet "github.com/bhojpur/state/pkg/libs/net"
 	"github.com/bhojpur/state/pkg/libs/service"
+	"github.com/bhojpur/state/pkg/libs/trace"
 )
 
 type GRPCServer struct {
@@ -63,7 +64,9 @@ func (s *GRPCServer) OnStart(ctx context.Context) error {
 		return err
 	}
 
-	s.server = grpc.NewServer()
+	// The span context of the node is made available to the application
+	// through the context of each call; see trace.SpanContextFromContext.
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(trace.UnaryServerInterceptor()))
 	RegisterABCIApplicationServer(s.server, &gRPCApplication{Application: s.app})
 
 	s.logger.Info("Listening", "proto", s.proto, "addr", s.addr)
@@ -36,12 +36,19 @@ import (
 	"github.com/bhojpur/state/pkg/libs/service"
 )
 
-func NewServer(logger log.Logger, protoAddr, transport string, app types.Application) (service.Service, error) {
+// NewServer returns a server of the given transport, socket or grpc, serving
+// app on protoAddr. The options only apply to socket servers.
+func NewServer(
+	logger log.Logger,
+	protoAddr, transport string,
+	app types.Application,
+	options ...SocketServerOption,
+) (service.Service, error) {
 	var s service.Service
 	var err error
 	switch transport {
 	case "socket":
-		s = NewSocketServer(logger, protoAddr, app)
+		s = NewSocketServer(logger, protoAddr, app, options...)
 	case "grpc":
 		s = NewGRPCServer(logger, protoAddr, app)
 	default:
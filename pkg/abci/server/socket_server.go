@@ -38,6 +38,15 @@ import (
 
 // var maxNumberConnections = 2
 
+// laneQueueSize is the max number of requests queued on a lane of a
+// connection.
+const laneQueueSize = 1000
+
+// SocketServer serves an application over a socket. By default, requests are
+// served one by one, in the order they were received. If lanes are enabled
+// with SocketServerWithLanes, requests sent with an id are served on the lane
+// they name instead: each lane serves its requests in order, and the lanes of
+// a connection run concurrently.
 type SocketServer struct {
 	service.BaseService
 	logger log.Logger
@@ -50,10 +59,24 @@ type SocketServer struct {
 	connsClose map[int]func()
 	nextConnID int
 
-	app types.Application
+	app   types.Application
+	lanes bool
 }
 
-func NewSocketServer(logger log.Logger, protoAddr string, app types.Application) service.Service {
+// SocketServerOption sets an optional parameter on the SocketServer.
+type SocketServerOption func(*SocketServer)
+
+// SocketServerWithLanes makes the server serve requests sent with an id on
+// the lane they name, so that requests of different lanes may be answered out
+// of order. The application is then called concurrently and must be safe for
+// concurrent use, as it must be with the gRPC server.
+func SocketServerWithLanes() SocketServerOption {
+	return func(s *SocketServer) {
+		s.lanes = true
+	}
+}
+
+func NewSocketServer(logger log.Logger, protoAddr string, app types.Application, options ...SocketServerOption) service.Service {
 	proto, addr := libnet.ProtocolAndAddress(protoAddr)
 	s := &SocketServer{
 		logger:     logger,
@@ -63,6 +86,9 @@ func NewSocketServer(logger log.Logger, protoAddr string, app types.Application)
 		app:        app,
 		connsClose: make(map[int]func()),
 	}
+	for _, option := range options {
+		option(s)
+	}
 	s.BaseService = *service.NewBaseService(logger, "ABCIServer", s)
 	return s
 }
@@ -182,16 +208,9 @@ func (s *SocketServer) acceptConnectionsRoutine(ctx context.Context) {
 func (s *SocketServer) handleRequests(ctx context.Context, closer func(error), conn io.Reader, responses chan<- *types.Response) {
 	var bufReader = bufio.NewReader(conn)
 
-	defer func() {
-		// make sure to recover from any app-related panics to allow proper socket cleanup
-		if r := recover(); r != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			closer(fmt.Errorf("recovered from panic: %v\n%s", r, buf))
-		}
-	}()
+	defer recoverApp(closer)
 
+	lanes := make(map[types.Lane]chan laneItem)
 	for {
 		req := &types.Request{}
 		if err := types.ReadMessage(bufReader, req); err != nil {
@@ -199,11 +218,39 @@ func (s *SocketServer) handleRequests(ctx context.Context, closer func(error), c
 			return
 		}
 
+		if s.lanes && req.Id != 0 {
+			if _, ok := req.Value.(*types.Request_Flush); ok {
+				// A flush is answered once the requests received before it
+				// on every lane are.
+				if err := drainLanes(ctx, lanes); err != nil {
+					closer(err)
+					return
+				}
+			} else {
+				lane, ok := lanes[req.Lane]
+				if !ok {
+					lane = make(chan laneItem, laneQueueSize)
+					lanes[req.Lane] = lane
+					go s.handleLane(ctx, closer, lane, responses)
+				}
+				select {
+				case <-ctx.Done():
+					closer(ctx.Err())
+					return
+				case lane <- laneItem{req: req}:
+				}
+				continue
+			}
+		}
+
 		resp, err := s.processRequest(ctx, req)
 		if err != nil {
 			closer(err)
 			return
 		}
+		if s.lanes {
+			resp.Id = req.Id
+		}
 
 		select {
 		case <-ctx.Done():
@@ -214,6 +261,76 @@ func (s *SocketServer) handleRequests(ctx context.Context, closer func(error), c
 	}
 }
 
+// laneItem is either a request to serve on a lane, or a marker to signal on
+// drained once the requests queued before it are answered.
+type laneItem struct {
+	req     *types.Request
+	drained chan<- struct{}
+}
+
+// drainLanes waits until the requests queued on lanes are answered.
+func drainLanes(ctx context.Context, lanes map[types.Lane]chan laneItem) error {
+	drained := make(chan struct{}, len(lanes))
+	for _, lane := range lanes {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case lane <- laneItem{drained: drained}:
+		}
+	}
+	for range lanes {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-drained:
+		}
+	}
+	return nil
+}
+
+// handleLane serves the requests of a lane in order, answering each with the
+// id of its request.
+func (s *SocketServer) handleLane(ctx context.Context, closer func(error), items <-chan laneItem, responses chan<- *types.Response) {
+	defer recoverApp(closer)
+
+	for {
+		var item laneItem
+		select {
+		case <-ctx.Done():
+			return
+		case item = <-items:
+		}
+		if item.drained != nil {
+			item.drained <- struct{}{}
+			continue
+		}
+
+		resp, err := s.processRequest(ctx, item.req)
+		if err != nil {
+			closer(err)
+			return
+		}
+		resp.Id = item.req.Id
+
+		select {
+		case <-ctx.Done():
+			return
+		case responses <- resp:
+		}
+	}
+}
+
+// recoverApp recovers from any app-related panics to allow proper socket
+// cleanup. It must be deferred.
+func recoverApp(closer func(error)) {
+	if r := recover(); r != nil {
+		const size = 64 << 10
+		buf := make([]byte, size)
+		buf = buf[:runtime.Stack(buf, false)]
+		closer(fmt.Errorf("recovered from panic: %v\n%s", r, buf))
+	}
+}
+
 func (s *SocketServer) processRequest(ctx context.Context, req *types.Request) (*types.Response, error) {
 	switch r := req.Value.(type) {
 	case *types.Request_Echo:
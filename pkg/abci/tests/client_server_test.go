@@ -21,15 +21,21 @@ package tests
 // THE SOFTWARE.
 
 import (
+	"bufio"
 	"context"
+	"net"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fortytw2/leaktest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/bhojpur/state/example/kvstore"
 	abciclientent "github.com/bhojpur/state/pkg/abci/client"
 	abciserver "github.com/bhojpur/state/pkg/abci/server"
+	"github.com/bhojpur/state/pkg/abci/types"
 	"github.com/bhojpur/state/pkg/libs/log"
 )
 
@@ -58,3 +64,145 @@ func TestClientServerNoAddrPrefix(t *testing.T) {
 	assert.NoError(t, err, "expected no error on client.Start")
 	t.Cleanup(client.Wait)
 }
+
+// blockingApp blocks FinalizeBlock until release is closed.
+type blockingApp struct {
+	types.BaseApplication
+	release chan struct{}
+}
+
+func (app *blockingApp) FinalizeBlock(ctx context.Context, req *types.RequestFinalizeBlock) (*types.ResponseFinalizeBlock, error) {
+	select {
+	case <-app.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return app.BaseApplication.FinalizeBlock(ctx, req)
+}
+
+func startSocketServer(ctx context.Context, t *testing.T, app types.Application, options ...abciserver.SocketServerOption) string {
+	t.Helper()
+
+	addr := "unix://" + filepath.Join(t.TempDir(), "abci.sock")
+	server, err := abciserver.NewServer(log.NewTestingLogger(t), addr, "socket", app, options...)
+	require.NoError(t, err)
+	require.NoError(t, server.Start(ctx))
+	t.Cleanup(server.Wait)
+	return addr
+}
+
+func TestSocketClientServerLanes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app := &blockingApp{release: make(chan struct{})}
+	addr := startSocketServer(ctx, t, app, abciserver.SocketServerWithLanes())
+
+	client := abciclientent.NewSocketClient(log.NewTestingLogger(t), addr, true)
+	require.NoError(t, client.Start(ctx))
+	t.Cleanup(client.Wait)
+
+	finalized := make(chan error, 1)
+	go func() {
+		_, err := client.FinalizeBlock(ctx, &types.RequestFinalizeBlock{Height: 1})
+		finalized <- err
+	}()
+
+	// CheckTx and Query are answered while FinalizeBlock is pending
+	cctx, ccancel := context.WithTimeout(ctx, 5*time.Second)
+	defer ccancel()
+	_, err := client.CheckTx(cctx, &types.RequestCheckTx{Tx: []byte("tx")})
+	require.NoError(t, err)
+	_, err = client.Query(cctx, &types.RequestQuery{Path: "/store"})
+	require.NoError(t, err)
+
+	// Flush waits for FinalizeBlock
+	flushed := make(chan error, 1)
+	go func() { flushed <- client.Flush(ctx) }()
+
+	select {
+	case err := <-finalized:
+		t.Fatalf("FinalizeBlock returned before it was released: %v", err)
+	case err := <-flushed:
+		t.Fatalf("Flush returned before FinalizeBlock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(app.release)
+	require.NoError(t, <-finalized)
+	require.NoError(t, <-flushed)
+}
+
+func TestSocketClientServerWithoutLanes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app := &blockingApp{release: make(chan struct{})}
+	addr := startSocketServer(ctx, t, app)
+
+	client := abciclientent.NewSocketClient(log.NewTestingLogger(t), addr, true)
+	require.NoError(t, client.Start(ctx))
+	t.Cleanup(client.Wait)
+
+	finalized := make(chan error, 1)
+	go func() {
+		_, err := client.FinalizeBlock(ctx, &types.RequestFinalizeBlock{Height: 1})
+		finalized <- err
+	}()
+
+	// unless lanes are enabled, CheckTx waits for FinalizeBlock
+	checked := make(chan error, 1)
+	go func() {
+		_, err := client.CheckTx(ctx, &types.RequestCheckTx{Tx: []byte("tx")})
+		checked <- err
+	}()
+
+	select {
+	case err := <-checked:
+		t.Fatalf("CheckTx returned before FinalizeBlock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(app.release)
+	require.NoError(t, <-finalized)
+	require.NoError(t, <-checked)
+}
+
+func TestSocketServerWithoutIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr := startSocketServer(ctx, t, types.NewBaseApplication())
+
+	// a client that doesn't send ids gets responses in order, without ids
+	conn, err := net.Dial("unix", addr[len("unix://"):])
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reqs := []*types.Request{
+		types.ToRequestEcho("hello"),
+		types.ToRequestCheckTx(&types.RequestCheckTx{Tx: []byte("tx")}),
+		types.ToRequestInfo(&types.RequestInfo{}),
+		types.ToRequestFlush(),
+	}
+	w := bufio.NewWriter(conn)
+	for _, req := range reqs {
+		require.NoError(t, types.WriteMessage(req, w))
+	}
+	require.NoError(t, w.Flush())
+
+	r := bufio.NewReader(conn)
+	for _, req := range reqs {
+		res := &types.Response{}
+		require.NoError(t, types.ReadMessage(r, res))
+		assert.Zero(t, res.Id)
+		switch req.Value.(type) {
+		case *types.Request_Echo:
+			assert.Equal(t, "hello", res.GetEcho().GetMessage())
+		case *types.Request_CheckTx:
+			assert.NotNil(t, res.GetCheckTx())
+		case *types.Request_Info:
+			assert.NotNil(t, res.GetInfo())
+		case *types.Request_Flush:
+			assert.NotNil(t, res.GetFlush())
+		}
+	}
+}
@@ -22,6 +22,7 @@ package types
 
 import (
 	"context"
+	"io"
 )
 
 //go:generate ../../../scripts/mockery_generate.sh Application
@@ -56,6 +57,33 @@ type Application interface {
 	ApplySnapshotChunk(context.Context, *RequestApplySnapshotChunk) (*ResponseApplySnapshotChunk, error) // Apply a shapshot chunk
 }
 
+// StateSnapshotter is an optional interface for applications running in the
+// same process as the node. If the application implements it, the node takes,
+// stores, prunes and serves state sync snapshots on the application's behalf,
+// and the application only has to export and restore its state as a stream.
+// The snapshot methods of Application are then not called by state sync.
+type StateSnapshotter interface {
+	// ExportState writes the application state as of the given committed
+	// height to w. It is called after the height is committed, and may run
+	// concurrently with the execution of later blocks.
+	ExportState(ctx context.Context, height uint64, w io.Writer) error
+	// RestoreState replaces the application state with the state read from
+	// r, as exported at the given height. appHash is the light client
+	// verified app hash at that height.
+	RestoreState(ctx context.Context, height uint64, appHash []byte, r io.Reader) error
+}
+
+// AsStateSnapshotter returns app as a StateSnapshotter if it implements the
+// interface, so that the node manages its snapshots. A Multiplexer only does
+// if every version of it implements the interface.
+func AsStateSnapshotter(app Application) (StateSnapshotter, bool) {
+	if m, ok := app.(*Multiplexer); ok && !m.snapshotters {
+		return nil, false
+	}
+	s, ok := app.(StateSnapshotter)
+	return s, ok
+}
+
 // BaseApplication is a base form of Application
 
 var _ Application = (*BaseApplication)(nil)
@@ -45,6 +45,24 @@ func ReadMessage(r io.Reader, msg proto.Message) error {
 	return err
 }
 
+// RequestLane returns the lane req is served on when multiplexed over a socket
+// connection. CheckTx has a lane of its own, and so do the state sync
+// methods, so that neither queues behind the methods driving consensus. Flush
+// is not served on a lane: it is answered once the requests sent before it on
+// every lane are.
+func RequestLane(req *Request) Lane {
+	switch req.Value.(type) {
+	case *Request_CheckTx:
+		return Lane_MEMPOOL
+	case *Request_Echo, *Request_Info, *Request_Query:
+		return Lane_QUERY
+	case *Request_ListSnapshots, *Request_OfferSnapshot, *Request_LoadSnapshotChunk, *Request_ApplySnapshotChunk:
+		return Lane_SNAPSHOT
+	default:
+		return Lane_CONSENSUS
+	}
+}
+
 func ToRequestEcho(message string) *Request {
 	return &Request{
 		Value: &Request_Echo{&RequestEcho{Message: message}},
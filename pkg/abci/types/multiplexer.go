@@ -0,0 +1,368 @@
+package types
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// VersionedApp is a version of an application registered with a
+// Multiplexer.
+type VersionedApp struct {
+	Application
+
+	// AppVersion is the application version, as in VersionParams.AppVersion.
+	// It must be unique among the versions of a multiplexer.
+	AppVersion uint64
+	// Height is the first height executed by this version, typically the
+	// height after the one of the upgrade plan that introduces it. If zero,
+	// the version takes over once the consensus params switch to its
+	// AppVersion, or from genesis if it is the first version.
+	Height int64
+}
+
+// activation is the height from which a version executes blocks.
+type activation struct {
+	height  int64
+	version *VersionedApp
+}
+
+// Multiplexer is an Application that routes every call to one of several
+// versions of an application, so that an upgrade can ship as a single binary
+// containing the old and the new logic, and that replaying the chain from
+// genesis executes every height with the version that originally executed
+// it. It is meant for applications running in the same process as the node,
+// and is used with a local client.
+//
+// A version executes blocks from its Height onwards, or from the height
+// after the block whose ConsensusParamUpdates switched to its AppVersion,
+// until the next version takes over. Upgrade plans returned by a version are
+// not passed on to the node when a registered version takes over from the
+// height after the plan's height, so that the node does not halt.
+//
+// Mempool calls and queries for the latest state go to the version executing
+// the next height. At startup, that height is taken from the Info response of
+// the version serving it by height; if that response reports the AppVersion
+// of another registered version, that version answers Info instead and takes
+// over from the next height.
+//
+// If every version implements StateSnapshotter, so does the multiplexer: the
+// state at a height is exported and restored by the version that executed it.
+// Use AsStateSnapshotter to find out whether it does.
+type Multiplexer struct {
+	mtx         sync.Mutex
+	versions    map[uint64]*VersionedApp
+	activations []activation // sorted by height
+	lastHeight  int64        // last committed height
+	finalizing  activation   // block being finalized, until committed
+	restoring   *VersionedApp
+
+	snapshotters bool // whether every version implements StateSnapshotter
+}
+
+var (
+	_ Application      = (*Multiplexer)(nil)
+	_ StateSnapshotter = (*Multiplexer)(nil)
+)
+
+// NewMultiplexer returns a multiplexer routing calls to the given versions.
+// The first version executes blocks from genesis, unless another version
+// takes over from its height or AppVersion.
+func NewMultiplexer(versions ...VersionedApp) (*Multiplexer, error) {
+	if len(versions) == 0 {
+		return nil, errors.New("no application versions")
+	}
+	if versions[0].Height != 0 {
+		return nil, fmt.Errorf("application version %d: the first version can't have a height", versions[0].AppVersion)
+	}
+	m := &Multiplexer{versions: make(map[uint64]*VersionedApp, len(versions)), snapshotters: true}
+	for i := range versions {
+		v := &versions[i]
+		if v.Application == nil {
+			return nil, fmt.Errorf("application version %d is nil", v.AppVersion)
+		}
+		if _, ok := v.Application.(StateSnapshotter); !ok {
+			m.snapshotters = false
+		}
+		if _, ok := m.versions[v.AppVersion]; ok {
+			return nil, fmt.Errorf("application version %d is registered twice", v.AppVersion)
+		}
+		if v.Height < 0 {
+			return nil, fmt.Errorf("application version %d: height can't be negative", v.AppVersion)
+		}
+		m.versions[v.AppVersion] = v
+		if i > 0 && v.Height == 0 {
+			continue
+		}
+		for _, a := range m.activations {
+			if a.height == v.Height {
+				return nil, fmt.Errorf("application versions %d and %d both start at height %d",
+					a.version.AppVersion, v.AppVersion, v.Height)
+			}
+		}
+		m.activations = append(m.activations, activation{height: v.Height, version: v})
+	}
+	sort.Slice(m.activations, func(i, j int) bool {
+		return m.activations[i].height < m.activations[j].height
+	})
+	return m, nil
+}
+
+// versionAt returns the version executing height. The caller must hold mtx.
+func (m *Multiplexer) versionAt(height int64) *VersionedApp {
+	i := sort.Search(len(m.activations), func(i int) bool {
+		return m.activations[i].height > height
+	})
+	if i == 0 {
+		return m.activations[0].version
+	}
+	return m.activations[i-1].version
+}
+
+// activate makes v execute blocks from height onwards, until the next
+// version registered by height. The caller must hold mtx.
+func (m *Multiplexer) activate(v *VersionedApp, height int64) {
+	i := sort.Search(len(m.activations), func(i int) bool {
+		return m.activations[i].height >= height
+	})
+	if i < len(m.activations) && m.activations[i].height == height {
+		m.activations[i].version = v
+		return
+	}
+	if m.versionAt(height) == v {
+		return
+	}
+	m.activations = append(m.activations, activation{})
+	copy(m.activations[i+1:], m.activations[i:])
+	m.activations[i] = activation{height: height, version: v}
+}
+
+// at returns the version executing height.
+func (m *Multiplexer) at(height int64) Application {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.versionAt(height)
+}
+
+// next returns the version executing the height after the last committed
+// one.
+func (m *Multiplexer) next() Application {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.versionAt(m.lastHeight + 1)
+}
+
+// Version returns the AppVersion of the version executing height, as far as
+// the multiplexer knows from the calls it has routed.
+func (m *Multiplexer) Version(height int64) uint64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.versionAt(height).AppVersion
+}
+
+func (m *Multiplexer) Info(ctx context.Context, req *RequestInfo) (*ResponseInfo, error) {
+	res, err := m.next().Info(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mtx.Lock()
+	m.lastHeight = res.LastBlockHeight
+	cur := m.versionAt(res.LastBlockHeight + 1)
+	v, ok := m.versions[res.AppVersion]
+	if !ok || v == cur {
+		m.mtx.Unlock()
+		return res, nil
+	}
+	m.activate(v, res.LastBlockHeight+1)
+	m.mtx.Unlock()
+
+	return v.Info(ctx, req)
+}
+
+func (m *Multiplexer) Query(ctx context.Context, req *RequestQuery) (*ResponseQuery, error) {
+	if req.Height == 0 {
+		return m.next().Query(ctx, req)
+	}
+	return m.at(req.Height).Query(ctx, req)
+}
+
+func (m *Multiplexer) CheckTx(ctx context.Context, req *RequestCheckTx) (*ResponseCheckTx, error) {
+	return m.next().CheckTx(ctx, req)
+}
+
+func (m *Multiplexer) InitChain(ctx context.Context, req *RequestInitChain) (*ResponseInitChain, error) {
+	height := req.InitialHeight
+	if height == 0 {
+		height = 1
+	}
+
+	m.mtx.Lock()
+	m.lastHeight = height - 1
+	if v, ok := m.versions[req.ConsensusParams.GetVersion().GetAppVersion()]; ok {
+		m.activate(v, height)
+	}
+	app := m.versionAt(height)
+	m.mtx.Unlock()
+
+	return app.InitChain(ctx, req)
+}
+
+func (m *Multiplexer) PrepareProposal(ctx context.Context, req *RequestPrepareProposal) (*ResponsePrepareProposal, error) {
+	return m.at(req.Height).PrepareProposal(ctx, req)
+}
+
+func (m *Multiplexer) ProcessProposal(ctx context.Context, req *RequestProcessProposal) (*ResponseProcessProposal, error) {
+	return m.at(req.Height).ProcessProposal(ctx, req)
+}
+
+func (m *Multiplexer) ExtendVote(ctx context.Context, req *RequestExtendVote) (*ResponseExtendVote, error) {
+	return m.at(req.Height).ExtendVote(ctx, req)
+}
+
+func (m *Multiplexer) VerifyVoteExtension(ctx context.Context, req *RequestVerifyVoteExtension) (*ResponseVerifyVoteExtension, error) {
+	return m.at(req.Height).VerifyVoteExtension(ctx, req)
+}
+
+func (m *Multiplexer) FinalizeBlock(ctx context.Context, req *RequestFinalizeBlock) (*ResponseFinalizeBlock, error) {
+	m.mtx.Lock()
+	app := m.versionAt(req.Height)
+	m.finalizing = activation{height: req.Height, version: app}
+	m.mtx.Unlock()
+
+	res, err := app.FinalizeBlock(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if v, ok := m.versions[res.ConsensusParamUpdates.GetVersion().GetAppVersion()]; ok {
+		m.activate(v, req.Height+1)
+	}
+	if plan := res.UpgradePlan; plan != nil && m.versionAt(plan.Height+1) != m.versionAt(plan.Height) {
+		res.UpgradePlan = nil
+	}
+	return res, nil
+}
+
+func (m *Multiplexer) Commit(ctx context.Context) (*ResponseCommit, error) {
+	m.mtx.Lock()
+	fin := m.finalizing
+	if fin.version == nil {
+		fin = activation{height: m.lastHeight, version: m.versionAt(m.lastHeight + 1)}
+	}
+	m.mtx.Unlock()
+
+	res, err := fin.version.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mtx.Lock()
+	m.lastHeight = fin.height
+	m.finalizing = activation{}
+	m.mtx.Unlock()
+	return res, nil
+}
+
+func (m *Multiplexer) ListSnapshots(ctx context.Context, req *RequestListSnapshots) (*ResponseListSnapshots, error) {
+	return m.next().ListSnapshots(ctx, req)
+}
+
+func (m *Multiplexer) OfferSnapshot(ctx context.Context, req *RequestOfferSnapshot) (*ResponseOfferSnapshot, error) {
+	height := int64(req.GetSnapshot().GetHeight())
+	m.mtx.Lock()
+	app := m.versionAt(height)
+	m.mtx.Unlock()
+
+	res, err := app.OfferSnapshot(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Result == ResponseOfferSnapshot_ACCEPT {
+		m.mtx.Lock()
+		m.restoring = app
+		m.lastHeight = height
+		m.mtx.Unlock()
+	}
+	return res, nil
+}
+
+func (m *Multiplexer) LoadSnapshotChunk(ctx context.Context, req *RequestLoadSnapshotChunk) (*ResponseLoadSnapshotChunk, error) {
+	return m.at(int64(req.Height)).LoadSnapshotChunk(ctx, req)
+}
+
+func (m *Multiplexer) ApplySnapshotChunk(ctx context.Context, req *RequestApplySnapshotChunk) (*ResponseApplySnapshotChunk, error) {
+	m.mtx.Lock()
+	app := m.restoring
+	m.mtx.Unlock()
+	if app == nil {
+		return nil, errors.New("no snapshot is being restored")
+	}
+	return app.ApplySnapshotChunk(ctx, req)
+}
+
+// ExportState implements StateSnapshotter by exporting the state with the
+// version that executed height.
+func (m *Multiplexer) ExportState(ctx context.Context, height uint64, w io.Writer) error {
+	app, err := m.snapshotterAt(int64(height))
+	if err != nil {
+		return err
+	}
+	return app.ExportState(ctx, height, w)
+}
+
+// RestoreState implements StateSnapshotter by restoring the state with the
+// version that executed height. The version executing the following heights
+// then serves the calls for the latest state.
+func (m *Multiplexer) RestoreState(ctx context.Context, height uint64, appHash []byte, r io.Reader) error {
+	app, err := m.snapshotterAt(int64(height))
+	if err != nil {
+		return err
+	}
+	if err := app.RestoreState(ctx, height, appHash, r); err != nil {
+		return err
+	}
+
+	m.mtx.Lock()
+	m.lastHeight = int64(height)
+	m.mtx.Unlock()
+	return nil
+}
+
+// snapshotterAt returns the version executing height as a StateSnapshotter.
+func (m *Multiplexer) snapshotterAt(height int64) (StateSnapshotter, error) {
+	m.mtx.Lock()
+	v := m.versionAt(height)
+	m.mtx.Unlock()
+
+	app, ok := v.Application.(StateSnapshotter)
+	if !ok {
+		return nil, fmt.Errorf("application version %d does not implement StateSnapshotter", v.AppVersion)
+	}
+	return app, nil
+}
@@ -0,0 +1,228 @@
+package types_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/bhojpur/state/pkg/abci/types"
+	typespb "github.com/bhojpur/state/pkg/api/v1/types"
+)
+
+// versionApp executes blocks by returning its name as the app hash.
+type versionApp struct {
+	abci.BaseApplication
+	name       string
+	appVersion uint64
+	lastHeight int64
+
+	// returned by FinalizeBlock at the given heights
+	plans    map[int64]*abci.UpgradePlan
+	switches map[int64]uint64
+}
+
+func (app *versionApp) Info(context.Context, *abci.RequestInfo) (*abci.ResponseInfo, error) {
+	return &abci.ResponseInfo{AppVersion: app.appVersion, LastBlockHeight: app.lastHeight}, nil
+}
+
+func (app *versionApp) FinalizeBlock(_ context.Context, req *abci.RequestFinalizeBlock) (*abci.ResponseFinalizeBlock, error) {
+	res := &abci.ResponseFinalizeBlock{
+		AppHash:     []byte(app.name),
+		UpgradePlan: app.plans[req.Height],
+	}
+	if v, ok := app.switches[req.Height]; ok {
+		res.ConsensusParamUpdates = &typespb.ConsensusParams{Version: &typespb.VersionParams{AppVersion: v}}
+	}
+	return res, nil
+}
+
+// snapshotterApp is a versionApp that exports its name as its state.
+type snapshotterApp struct {
+	versionApp
+	restored []byte
+}
+
+func (app *snapshotterApp) ExportState(_ context.Context, _ uint64, w io.Writer) error {
+	_, err := io.WriteString(w, app.name)
+	return err
+}
+
+func (app *snapshotterApp) RestoreState(_ context.Context, _ uint64, _ []byte, r io.Reader) error {
+	var err error
+	app.restored, err = io.ReadAll(r)
+	return err
+}
+
+func (app *snapshotterApp) CheckTx(context.Context, *abci.RequestCheckTx) (*abci.ResponseCheckTx, error) {
+	return &abci.ResponseCheckTx{Codespace: app.name}, nil
+}
+
+func finalize(ctx context.Context, t *testing.T, m *abci.Multiplexer, height int64) *abci.ResponseFinalizeBlock {
+	t.Helper()
+	res, err := m.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: height})
+	require.NoError(t, err)
+	_, err = m.Commit(ctx)
+	require.NoError(t, err)
+	return res
+}
+
+func TestMultiplexerHeights(t *testing.T) {
+	ctx := context.Background()
+	plan := &abci.UpgradePlan{Name: "v2", Height: 2}
+	v1app := &versionApp{name: "v1", plans: map[int64]*abci.UpgradePlan{2: plan}}
+	m, err := abci.NewMultiplexer(
+		abci.VersionedApp{Application: v1app, AppVersion: 1},
+		abci.VersionedApp{Application: &versionApp{name: "v2"}, AppVersion: 2, Height: 3},
+	)
+	require.NoError(t, err)
+
+	_, err = m.InitChain(ctx, &abci.RequestInitChain{InitialHeight: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(finalize(ctx, t, m, 1).AppHash))
+
+	// the upgrade is handled by the multiplexer, so the node must not halt
+	res := finalize(ctx, t, m, 2)
+	assert.Equal(t, "v1", string(res.AppHash))
+	assert.Nil(t, res.UpgradePlan)
+
+	assert.Equal(t, "v2", string(finalize(ctx, t, m, 3).AppHash))
+	assert.Equal(t, "v2", string(finalize(ctx, t, m, 4).AppHash))
+	assert.EqualValues(t, 1, m.Version(2))
+	assert.EqualValues(t, 2, m.Version(3))
+
+	// plans for upgrades the binary doesn't contain are passed on
+	v1app.plans[1] = &abci.UpgradePlan{Name: "v3", Height: 10}
+	m, err = abci.NewMultiplexer(abci.VersionedApp{Application: v1app, AppVersion: 1})
+	require.NoError(t, err)
+	assert.NotNil(t, finalize(ctx, t, m, 1).UpgradePlan)
+}
+
+func TestMultiplexerAppVersion(t *testing.T) {
+	ctx := context.Background()
+	newMux := func(lastHeight int64, appVersion uint64) *abci.Multiplexer {
+		m, err := abci.NewMultiplexer(
+			abci.VersionedApp{
+				Application: &versionApp{
+					name:       "v1",
+					appVersion: appVersion,
+					lastHeight: lastHeight,
+					switches:   map[int64]uint64{2: 2},
+				},
+				AppVersion: 1,
+			},
+			abci.VersionedApp{Application: &versionApp{name: "v2", appVersion: 2}, AppVersion: 2},
+		)
+		require.NoError(t, err)
+		return m
+	}
+
+	m := newMux(0, 1)
+	_, err := m.InitChain(ctx, &abci.RequestInitChain{InitialHeight: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(finalize(ctx, t, m, 1).AppHash))
+	assert.Equal(t, "v1", string(finalize(ctx, t, m, 2).AppHash))
+	assert.Equal(t, "v2", string(finalize(ctx, t, m, 3).AppHash))
+
+	// after a restart, Info reports the version in use
+	m = newMux(5, 2)
+	res, err := m.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, res.AppVersion)
+	assert.Equal(t, "v2", string(finalize(ctx, t, m, 6).AppHash))
+
+	// genesis may start at a later version
+	m = newMux(0, 1)
+	_, err = m.InitChain(ctx, &abci.RequestInitChain{
+		InitialHeight:   1,
+		ConsensusParams: &typespb.ConsensusParams{Version: &typespb.VersionParams{AppVersion: 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(finalize(ctx, t, m, 1).AppHash))
+}
+
+func TestNewMultiplexerErrors(t *testing.T) {
+	app := abci.NewBaseApplication()
+	testCases := map[string][]abci.VersionedApp{
+		"no versions":     nil,
+		"nil application": {{AppVersion: 1}},
+		"duplicate": {
+			{Application: app, AppVersion: 1},
+			{Application: app, AppVersion: 1, Height: 5},
+		},
+		"first with height": {{Application: app, AppVersion: 1, Height: 5}},
+		"same height": {
+			{Application: app, AppVersion: 1},
+			{Application: app, AppVersion: 2, Height: 5},
+			{Application: app, AppVersion: 3, Height: 5},
+		},
+	}
+	for name, versions := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := abci.NewMultiplexer(versions...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMultiplexerStateSnapshotter(t *testing.T) {
+	ctx := context.Background()
+	v1app := &snapshotterApp{versionApp: versionApp{name: "v1"}}
+	v2app := &snapshotterApp{versionApp: versionApp{name: "v2"}}
+	m, err := abci.NewMultiplexer(
+		abci.VersionedApp{Application: v1app, AppVersion: 1},
+		abci.VersionedApp{Application: v2app, AppVersion: 2, Height: 3},
+	)
+	require.NoError(t, err)
+	_, ok := abci.AsStateSnapshotter(m)
+	require.True(t, ok)
+
+	// the state is exported by the version that executed the height
+	for height, name := range map[uint64]string{2: "v1", 3: "v2"} {
+		var buf bytes.Buffer
+		require.NoError(t, m.ExportState(ctx, height, &buf))
+		assert.Equal(t, name, buf.String())
+	}
+
+	// restoring a height routes the following calls to the next version
+	require.NoError(t, m.RestoreState(ctx, 2, nil, bytes.NewBufferString("state")))
+	assert.Equal(t, []byte("state"), v1app.restored)
+	assert.Nil(t, v2app.restored)
+	res, err := m.CheckTx(ctx, &abci.RequestCheckTx{})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", res.Codespace)
+
+	// versions without the interface leave the snapshots to the application
+	m, err = abci.NewMultiplexer(
+		abci.VersionedApp{Application: v1app, AppVersion: 1},
+		abci.VersionedApp{Application: &versionApp{name: "v2"}, AppVersion: 2, Height: 3},
+	)
+	require.NoError(t, err)
+	_, ok = abci.AsStateSnapshotter(m)
+	assert.False(t, ok)
+	assert.Error(t, m.ExportState(ctx, 3, io.Discard))
+	assert.NoError(t, m.ExportState(ctx, 2, io.Discard))
+}
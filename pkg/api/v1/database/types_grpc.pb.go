@@ -32,6 +32,9 @@ type DBClient interface {
 	Stats(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Stats, error)
 	BatchWrite(ctx context.Context, in *Batch, opts ...grpc.CallOption) (*Nothing, error)
 	BatchWriteSync(ctx context.Context, in *Batch, opts ...grpc.CallOption) (*Nothing, error)
+	// batchWriteStream writes the operations of all streamed messages as a
+	// single batch once the client closes the stream.
+	BatchWriteStream(ctx context.Context, opts ...grpc.CallOption) (DB_BatchWriteStreamClient, error)
 }
 
 type dBClient struct {
@@ -227,6 +230,40 @@ func (c *dBClient) BatchWriteSync(ctx context.Context, in *Batch, opts ...grpc.C
 	return out, nil
 }
 
+func (c *dBClient) BatchWriteStream(ctx context.Context, opts ...grpc.CallOption) (DB_BatchWriteStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DB_ServiceDesc.Streams[3], "/v1.database.DB/batchWriteStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dBBatchWriteStreamClient{stream}
+	return x, nil
+}
+
+type DB_BatchWriteStreamClient interface {
+	Send(*Batch) error
+	CloseAndRecv() (*Nothing, error)
+	grpc.ClientStream
+}
+
+type dBBatchWriteStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *dBBatchWriteStreamClient) Send(m *Batch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *dBBatchWriteStreamClient) CloseAndRecv() (*Nothing, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Nothing)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DBServer is the server API for DB service.
 // All implementations must embed UnimplementedDBServer
 // for forward compatibility
@@ -245,6 +282,9 @@ type DBServer interface {
 	Stats(context.Context, *Nothing) (*Stats, error)
 	BatchWrite(context.Context, *Batch) (*Nothing, error)
 	BatchWriteSync(context.Context, *Batch) (*Nothing, error)
+	// batchWriteStream writes the operations of all streamed messages as a
+	// single batch once the client closes the stream.
+	BatchWriteStream(DB_BatchWriteStreamServer) error
 	mustEmbedUnimplementedDBServer()
 }
 
@@ -291,6 +331,9 @@ func (UnimplementedDBServer) BatchWrite(context.Context, *Batch) (*Nothing, erro
 func (UnimplementedDBServer) BatchWriteSync(context.Context, *Batch) (*Nothing, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchWriteSync not implemented")
 }
+func (UnimplementedDBServer) BatchWriteStream(DB_BatchWriteStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchWriteStream not implemented")
+}
 func (UnimplementedDBServer) mustEmbedUnimplementedDBServer() {}
 
 // UnsafeDBServer may be embedded to opt out of forward compatibility for this service.
@@ -552,6 +595,32 @@ func _DB_BatchWriteSync_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DB_BatchWriteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DBServer).BatchWriteStream(&dBBatchWriteStreamServer{stream})
+}
+
+type DB_BatchWriteStreamServer interface {
+	SendAndClose(*Nothing) error
+	Recv() (*Batch, error)
+	grpc.ServerStream
+}
+
+type dBBatchWriteStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *dBBatchWriteStreamServer) SendAndClose(m *Nothing) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *dBBatchWriteStreamServer) Recv() (*Batch, error) {
+	m := new(Batch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DB_ServiceDesc is the grpc.ServiceDesc for DB service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -617,6 +686,11 @@ var DB_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _DB_ReverseIterator_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "batchWriteStream",
+			Handler:       _DB_BatchWriteStream_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "pkg/api/v1/database/types.proto",
 }
@@ -0,0 +1,53 @@
+package p2p
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Wrap implements the p2p Wrapper interface and wraps a DHT message.
+func (m *DhtMessage) Wrap(pb proto.Message) error {
+	switch msg := pb.(type) {
+	case *DhtFindNodeRequest:
+		m.Sum = &DhtMessage_FindNodeRequest{FindNodeRequest: msg}
+	case *DhtFindNodeResponse:
+		m.Sum = &DhtMessage_FindNodeResponse{FindNodeResponse: msg}
+	default:
+		return fmt.Errorf("unknown DHT message: %T", msg)
+	}
+	return nil
+}
+
+// Unwrap implements the p2p Wrapper interface and unwraps a wrapped DHT
+// message.
+func (m *DhtMessage) Unwrap() (proto.Message, error) {
+	switch msg := m.Sum.(type) {
+	case *DhtMessage_FindNodeRequest:
+		return msg.FindNodeRequest, nil
+	case *DhtMessage_FindNodeResponse:
+		return msg.FindNodeResponse, nil
+	default:
+		return nil, fmt.Errorf("unknown DHT message: %T", msg)
+	}
+}
@@ -0,0 +1,456 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.19.4
+// source: pkg/api/v1/p2p/dht.proto
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package p2p
+
+import (
+	_ "github.com/gogo/protobuf/gogoproto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DhtPeer is a routing table entry shared in FIND_NODE responses.
+type DhtPeer struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeId    string   `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Addresses []string `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	// address record signed by the node, if known
+	Record *AddressRecord `protobuf:"bytes,3,opt,name=record,proto3" json:"record,omitempty"`
+}
+
+func (x *DhtPeer) Reset() {
+	*x = DhtPeer{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DhtPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DhtPeer) ProtoMessage() {}
+
+func (x *DhtPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DhtPeer.ProtoReflect.Descriptor instead.
+func (*DhtPeer) Descriptor() ([]byte, []int) {
+	return file_pkg_api_v1_p2p_dht_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DhtPeer) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *DhtPeer) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *DhtPeer) GetRecord() *AddressRecord {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+// DhtFindNodeRequest asks a peer for the nodes closest to the target in its
+// routing table.
+type DhtFindNodeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RequestId uint64 `protobuf:"varint,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Target    string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *DhtFindNodeRequest) Reset() {
+	*x = DhtFindNodeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DhtFindNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DhtFindNodeRequest) ProtoMessage() {}
+
+func (x *DhtFindNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DhtFindNodeRequest.ProtoReflect.Descriptor instead.
+func (*DhtFindNodeRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_api_v1_p2p_dht_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DhtFindNodeRequest) GetRequestId() uint64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
+func (x *DhtFindNodeRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+type DhtFindNodeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RequestId uint64     `protobuf:"varint,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Peers     []*DhtPeer `protobuf:"bytes,2,rep,name=peers,proto3" json:"peers,omitempty"`
+}
+
+func (x *DhtFindNodeResponse) Reset() {
+	*x = DhtFindNodeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DhtFindNodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DhtFindNodeResponse) ProtoMessage() {}
+
+func (x *DhtFindNodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DhtFindNodeResponse.ProtoReflect.Descriptor instead.
+func (*DhtFindNodeResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_api_v1_p2p_dht_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DhtFindNodeResponse) GetRequestId() uint64 {
+	if x != nil {
+		return x.RequestId
+	}
+	return 0
+}
+
+func (x *DhtFindNodeResponse) GetPeers() []*DhtPeer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type DhtMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Sum:
+	//	*DhtMessage_FindNodeRequest
+	//	*DhtMessage_FindNodeResponse
+	Sum isDhtMessage_Sum `protobuf_oneof:"sum"`
+}
+
+func (x *DhtMessage) Reset() {
+	*x = DhtMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DhtMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DhtMessage) ProtoMessage() {}
+
+func (x *DhtMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_api_v1_p2p_dht_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DhtMessage.ProtoReflect.Descriptor instead.
+func (*DhtMessage) Descriptor() ([]byte, []int) {
+	return file_pkg_api_v1_p2p_dht_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *DhtMessage) GetSum() isDhtMessage_Sum {
+	if m != nil {
+		return m.Sum
+	}
+	return nil
+}
+
+func (x *DhtMessage) GetFindNodeRequest() *DhtFindNodeRequest {
+	if x, ok := x.GetSum().(*DhtMessage_FindNodeRequest); ok {
+		return x.FindNodeRequest
+	}
+	return nil
+}
+
+func (x *DhtMessage) GetFindNodeResponse() *DhtFindNodeResponse {
+	if x, ok := x.GetSum().(*DhtMessage_FindNodeResponse); ok {
+		return x.FindNodeResponse
+	}
+	return nil
+}
+
+type isDhtMessage_Sum interface {
+	isDhtMessage_Sum()
+}
+
+type DhtMessage_FindNodeRequest struct {
+	FindNodeRequest *DhtFindNodeRequest `protobuf:"bytes,1,opt,name=find_node_request,json=findNodeRequest,proto3,oneof"`
+}
+
+type DhtMessage_FindNodeResponse struct {
+	FindNodeResponse *DhtFindNodeResponse `protobuf:"bytes,2,opt,name=find_node_response,json=findNodeResponse,proto3,oneof"`
+}
+
+func (*DhtMessage_FindNodeRequest) isDhtMessage_Sum() {}
+
+func (*DhtMessage_FindNodeResponse) isDhtMessage_Sum() {}
+
+var File_pkg_api_v1_p2p_dht_proto protoreflect.FileDescriptor
+
+var file_pkg_api_v1_p2p_dht_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x32, 0x70,
+	0x2f, 0x64, 0x68, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x76, 0x31, 0x2e, 0x70,
+	0x32, 0x70, 0x1a, 0x14, 0x67, 0x6f, 0x67, 0x6f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x6f,
+	0x67, 0x6f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1a, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70,
+	0x69, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x32, 0x70, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x7b, 0x0a, 0x07, 0x44, 0x68, 0x74, 0x50, 0x65, 0x65, 0x72, 0x12,
+	0x23, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x42, 0x0a, 0xe2, 0xde, 0x1f, 0x06, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x44, 0x52, 0x06, 0x6e, 0x6f,
+	0x64, 0x65, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x12, 0x2d, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x76, 0x31, 0x2e, 0x70, 0x32, 0x70, 0x2e, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72,
+	0x64, 0x22, 0x5a, 0x0a, 0x12, 0x44, 0x68, 0x74, 0x46, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x0a, 0x72, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x42, 0x0d, 0xe2, 0xde, 0x1f,
+	0x09, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22, 0x6a, 0x0a,
+	0x13, 0x44, 0x68, 0x74, 0x46, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2c, 0x0a, 0x0a, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x42, 0x0d, 0xe2, 0xde, 0x1f, 0x09, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x49, 0x64, 0x12, 0x25, 0x0a, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x76, 0x31, 0x2e, 0x70, 0x32, 0x70, 0x2e, 0x44, 0x68, 0x74, 0x50, 0x65,
+	0x65, 0x72, 0x52, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x22, 0xaa, 0x01, 0x0a, 0x0a, 0x44, 0x68,
+	0x74, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x48, 0x0a, 0x11, 0x66, 0x69, 0x6e, 0x64,
+	0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x76, 0x31, 0x2e, 0x70, 0x32, 0x70, 0x2e, 0x44, 0x68, 0x74,
+	0x46, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48,
+	0x00, 0x52, 0x0f, 0x66, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x4b, 0x0a, 0x12, 0x66, 0x69, 0x6e, 0x64, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x5f,
+	0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b,
+	0x2e, 0x76, 0x31, 0x2e, 0x70, 0x32, 0x70, 0x2e, 0x44, 0x68, 0x74, 0x46, 0x69, 0x6e, 0x64, 0x4e,
+	0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x10, 0x66,
+	0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
+	0x05, 0x0a, 0x03, 0x73, 0x75, 0x6d, 0x42, 0x2d, 0x5a, 0x2b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x68, 0x6f, 0x6a, 0x70, 0x75, 0x72, 0x2f, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x70, 0x32,
+	0x70, 0x3b, 0x70, 0x32, 0x70, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_api_v1_p2p_dht_proto_rawDescOnce sync.Once
+	file_pkg_api_v1_p2p_dht_proto_rawDescData = file_pkg_api_v1_p2p_dht_proto_rawDesc
+)
+
+func file_pkg_api_v1_p2p_dht_proto_rawDescGZIP() []byte {
+	file_pkg_api_v1_p2p_dht_proto_rawDescOnce.Do(func() {
+		file_pkg_api_v1_p2p_dht_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_api_v1_p2p_dht_proto_rawDescData)
+	})
+	return file_pkg_api_v1_p2p_dht_proto_rawDescData
+}
+
+var file_pkg_api_v1_p2p_dht_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_pkg_api_v1_p2p_dht_proto_goTypes = []interface{}{
+	(*DhtPeer)(nil),             // 0: v1.p2p.DhtPeer
+	(*DhtFindNodeRequest)(nil),  // 1: v1.p2p.DhtFindNodeRequest
+	(*DhtFindNodeResponse)(nil), // 2: v1.p2p.DhtFindNodeResponse
+	(*DhtMessage)(nil),          // 3: v1.p2p.DhtMessage
+	(*AddressRecord)(nil),       // 4: v1.p2p.AddressRecord
+}
+var file_pkg_api_v1_p2p_dht_proto_depIdxs = []int32{
+	4, // 0: v1.p2p.DhtPeer.record:type_name -> v1.p2p.AddressRecord
+	0, // 1: v1.p2p.DhtFindNodeResponse.peers:type_name -> v1.p2p.DhtPeer
+	1, // 2: v1.p2p.DhtMessage.find_node_request:type_name -> v1.p2p.DhtFindNodeRequest
+	2, // 3: v1.p2p.DhtMessage.find_node_response:type_name -> v1.p2p.DhtFindNodeResponse
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_pkg_api_v1_p2p_dht_proto_init() }
+func file_pkg_api_v1_p2p_dht_proto_init() {
+	if File_pkg_api_v1_p2p_dht_proto != nil {
+		return
+	}
+	file_pkg_api_v1_p2p_types_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_api_v1_p2p_dht_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DhtPeer); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_api_v1_p2p_dht_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DhtFindNodeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_api_v1_p2p_dht_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DhtFindNodeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_api_v1_p2p_dht_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DhtMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_pkg_api_v1_p2p_dht_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*DhtMessage_FindNodeRequest)(nil),
+		(*DhtMessage_FindNodeResponse)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pkg_api_v1_p2p_dht_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_api_v1_p2p_dht_proto_goTypes,
+		DependencyIndexes: file_pkg_api_v1_p2p_dht_proto_depIdxs,
+		MessageInfos:      file_pkg_api_v1_p2p_dht_proto_msgTypes,
+	}.Build()
+	File_pkg_api_v1_p2p_dht_proto = out.File
+	file_pkg_api_v1_p2p_dht_proto_rawDesc = nil
+	file_pkg_api_v1_p2p_dht_proto_goTypes = nil
+	file_pkg_api_v1_p2p_dht_proto_depIdxs = nil
+}
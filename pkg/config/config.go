@@ -28,11 +28,14 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	dbm "github.com/bhojpur/state/pkg/database"
 	"github.com/bhojpur/state/pkg/libs/log"
 	bos "github.com/bhojpur/state/pkg/libs/os"
+	libstrings "github.com/bhojpur/state/pkg/libs/strings"
 	"github.com/bhojpur/state/pkg/types"
 )
 
@@ -88,10 +91,13 @@ type Config struct {
 	P2P             *P2PConfig             `mapstructure:"p2p"`
 	Mempool         *MempoolConfig         `mapstructure:"mempool"`
 	StateSync       *StateSyncConfig       `mapstructure:"statesync"`
+	BlockSync       *BlockSyncConfig       `mapstructure:"blocksync"`
 	Consensus       *ConsensusConfig       `mapstructure:"consensus"`
 	TxIndex         *TxIndexConfig         `mapstructure:"tx-index"`
 	Instrumentation *InstrumentationConfig `mapstructure:"instrumentation"`
 	PrivValidator   *PrivValidatorConfig   `mapstructure:"priv-validator"`
+	RemoteDB        *RemoteDBConfig        `mapstructure:"remote-db"`
+	RocksDB         *RocksDBConfig         `mapstructure:"rocksdb"`
 }
 
 // DefaultConfig returns a default configuration for a Bhojpur State node
@@ -102,10 +108,13 @@ func DefaultConfig() *Config {
 		P2P:             DefaultP2PConfig(),
 		Mempool:         DefaultMempoolConfig(),
 		StateSync:       DefaultStateSyncConfig(),
+		BlockSync:       DefaultBlockSyncConfig(),
 		Consensus:       DefaultConsensusConfig(),
 		TxIndex:         DefaultTxIndexConfig(),
 		Instrumentation: DefaultInstrumentationConfig(),
 		PrivValidator:   DefaultPrivValidatorConfig(),
+		RemoteDB:        DefaultRemoteDBConfig(),
+		RocksDB:         DefaultRocksDBConfig(),
 	}
 }
 
@@ -124,10 +133,13 @@ func TestConfig() *Config {
 		P2P:             TestP2PConfig(),
 		Mempool:         TestMempoolConfig(),
 		StateSync:       TestStateSyncConfig(),
+		BlockSync:       TestBlockSyncConfig(),
 		Consensus:       TestConsensusConfig(),
 		TxIndex:         TestTxIndexConfig(),
 		Instrumentation: TestInstrumentationConfig(),
 		PrivValidator:   DefaultPrivValidatorConfig(),
+		RemoteDB:        DefaultRemoteDBConfig(),
+		RocksDB:         DefaultRocksDBConfig(),
 	}
 }
 
@@ -137,8 +149,12 @@ func (cfg *Config) SetRoot(root string) *Config {
 	cfg.RPC.RootDir = root
 	cfg.P2P.RootDir = root
 	cfg.Mempool.RootDir = root
+	cfg.StateSync.RootDir = root
+	cfg.BlockSync.RootDir = root
 	cfg.Consensus.RootDir = root
 	cfg.PrivValidator.RootDir = root
+	cfg.Instrumentation.RootDir = root
+	cfg.RemoteDB.RootDir = root
 	return cfg
 }
 
@@ -163,6 +179,14 @@ func (cfg *Config) ValidateBasic() error {
 	if err := cfg.Instrumentation.ValidateBasic(); err != nil {
 		return fmt.Errorf("error in [instrumentation] section: %w", err)
 	}
+	if dbm.BackendType(cfg.DBBackend) == dbm.RemoteDBBackend {
+		if err := cfg.RemoteDB.ValidateBasic(); err != nil {
+			return fmt.Errorf("error in [remote-db] section: %w", err)
+		}
+	}
+	if err := cfg.RocksDB.ValidateBasic(); err != nil {
+		return fmt.Errorf("error in [rocksdb] section: %w", err)
+	}
 	return nil
 }
 
@@ -216,20 +240,39 @@ type BaseConfig struct { //nolint: maligned
 	//   - EXPERIMENTAL
 	//   - requires gcc
 	//   - use rocksdb build tag (go build -tags rocksdb)
+	//   - tuned by RocksDBConfig
 	// * badgerdb (uses github.com/dgraph-io/badger)
 	//   - EXPERIMENTAL
 	//   - use badgerdb build tag (go build -tags badgerdb)
+	// * remote (a remote database server, see RemoteDBConfig)
+	//   - EXPERIMENTAL
 	DBBackend string `mapstructure:"db-backend"`
 
 	// Database directory
 	DBPath string `mapstructure:"db-dir"`
 
-	// Output level for logging
+	// Output level for logging, either a single level or a level followed by
+	// per-module overrides, e.g. "info,consensus=debug,p2p=error"
 	LogLevel string `mapstructure:"log-level"`
 
 	// Output format: 'plain' (colored text) or 'json'
 	LogFormat string `mapstructure:"log-format"`
 
+	// Limits the Debug and Info messages logged by busy modules, as a list of
+	// module=burst/period entries, e.g. "mempool=10/1s"
+	LogSampling string `mapstructure:"log-sampling"`
+
+	// If set, log entries are also written in JSON format to this file,
+	// which is rotated once it reaches LogFileMaxSize
+	LogFile string `mapstructure:"log-file"`
+
+	// Maximum size of the log file before it is rotated
+	LogFileMaxSize int64 `mapstructure:"log-file-max-size"`
+
+	// Maximum total size of the log file and its rotated files, beyond which
+	// the oldest files are removed
+	LogFileMaxTotalSize int64 `mapstructure:"log-file-max-total-size"`
+
 	// Path to the JSON file containing the initial validator set and other meta data
 	Genesis string `mapstructure:"genesis-file"`
 
@@ -239,6 +282,10 @@ type BaseConfig struct { //nolint: maligned
 	// Mechanism to connect to the ABCI application: socket | grpc
 	ABCI string `mapstructure:"abci"`
 
+	// If set, every ABCI call is recorded with its request, response and
+	// timing to this file, for replay with "statectl abci replay"
+	ABCIRecordFile string `mapstructure:"abci-record-file"`
+
 	// If true, query the ABCI app on connecting to a new peer
 	// so the app can decide if we should keep the connection or not
 	FilterPeers bool `mapstructure:"filter-peers"` // false
@@ -260,6 +307,9 @@ func DefaultBaseConfig() BaseConfig {
 		FilterPeers: false,
 		DBBackend:   "goleveldb",
 		DBPath:      "data",
+
+		LogFileMaxSize:      10 * 1024 * 1024,   // 10MB
+		LogFileMaxTotalSize: 1024 * 1024 * 1024, // 1GB
 	}
 }
 
@@ -327,6 +377,24 @@ func (cfg BaseConfig) DBDir() string {
 	return rootify(cfg.DBPath, cfg.RootDir)
 }
 
+// LogFilePath returns the full path to the log file, or "" if logging to a
+// file is disabled.
+func (cfg BaseConfig) LogFilePath() string {
+	if cfg.LogFile == "" {
+		return ""
+	}
+	return rootify(cfg.LogFile, cfg.RootDir)
+}
+
+// ABCIRecordFilePath returns the full path to the ABCI record file, or "" if
+// recording ABCI calls is disabled.
+func (cfg BaseConfig) ABCIRecordFilePath() string {
+	if cfg.ABCIRecordFile == "" {
+		return ""
+	}
+	return rootify(cfg.ABCIRecordFile, cfg.RootDir)
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg BaseConfig) ValidateBasic() error {
@@ -335,6 +403,18 @@ func (cfg BaseConfig) ValidateBasic() error {
 	default:
 		return errors.New("unknown log format (must be 'plain', 'text' or 'json')")
 	}
+	if err := log.ValidateLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("invalid log-level: %w", err)
+	}
+	if err := log.ValidateSampling(cfg.LogSampling); err != nil {
+		return fmt.Errorf("invalid log-sampling: %w", err)
+	}
+	if cfg.LogFileMaxSize < 0 {
+		return errors.New("log-file-max-size can't be negative")
+	}
+	if cfg.LogFileMaxTotalSize < 0 {
+		return errors.New("log-file-max-total-size can't be negative")
+	}
 
 	switch cfg.Mode {
 	case ModeFull, ModeValidator, ModeSeed:
@@ -422,6 +502,134 @@ func (cfg *PrivValidatorConfig) AreSecurityOptionsPresent() bool {
 	}
 }
 
+// RemoteDBConfig
+
+// RemoteDBConfig defines how to connect to the remote database server used by
+// the "remote" db-backend. Each database of the node is hosted under its own
+// name on the server, and the connection is authenticated on both sides.
+type RemoteDBConfig struct {
+	RootDir string `mapstructure:"home"`
+
+	// Address of the remote database server (host:port)
+	Address string `mapstructure:"address"`
+
+	// Path to the client certificate presented to the server
+	ClientCertificate string `mapstructure:"client-certificate-file"`
+
+	// Path to the key of the client certificate
+	ClientKey string `mapstructure:"client-key-file"`
+
+	// Path to the Root Certificate Authority used to verify the server certificate
+	RootCA string `mapstructure:"root-ca-file"`
+}
+
+// DefaultRemoteDBConfig returns a default configuration for connecting to a
+// remote database server.
+func DefaultRemoteDBConfig() *RemoteDBConfig {
+	return &RemoteDBConfig{}
+}
+
+// ClientKeyFile returns the full path to the client key file
+func (cfg *RemoteDBConfig) ClientKeyFile() string {
+	return rootify(cfg.ClientKey, cfg.RootDir)
+}
+
+// ClientCertificateFile returns the full path to the client certificate file
+func (cfg *RemoteDBConfig) ClientCertificateFile() string {
+	return rootify(cfg.ClientCertificate, cfg.RootDir)
+}
+
+// RootCAFile returns the full path to the root CA file
+func (cfg *RemoteDBConfig) RootCAFile() string {
+	return rootify(cfg.RootCA, cfg.RootDir)
+}
+
+// ValidateBasic performs basic validation (checking param bounds, etc.) and
+// returns an error if any check fails.
+func (cfg *RemoteDBConfig) ValidateBasic() error {
+	switch {
+	case cfg.Address == "":
+		return errors.New("address must be set")
+	case cfg.ClientCertificate == "":
+		return errors.New("client-certificate-file must be set")
+	case cfg.ClientKey == "":
+		return errors.New("client-key-file must be set")
+	case cfg.RootCA == "":
+		return errors.New("root-ca-file must be set")
+	}
+	return nil
+}
+
+// RocksDBConfig
+
+// RocksDBConfig defines the layout and tuning of the databases of the rocksdb
+// db-backend.
+type RocksDBConfig struct {
+	// Store all databases as column families of a single RocksDB instance
+	ColumnFamilies bool `mapstructure:"column-families"`
+
+	// Size in bytes of the block cache shared by the databases
+	BlockCacheSize uint64 `mapstructure:"block-cache-size"`
+
+	// Compression and bloom filter bits of databases without family settings
+	Compression     string `mapstructure:"compression"`
+	BloomFilterBits int    `mapstructure:"bloom-filter-bits"`
+
+	// Settings of individual databases, by name
+	Families map[string]*RocksDBFamilyConfig `mapstructure:"families"`
+}
+
+// RocksDBFamilyConfig tunes the column family of a single database.
+type RocksDBFamilyConfig struct {
+	Compression     string  `mapstructure:"compression"`
+	BloomFilterBits int     `mapstructure:"bloom-filter-bits"`
+	BlockCacheShare float64 `mapstructure:"block-cache-share"`
+}
+
+// DefaultRocksDBConfig returns a default configuration for the rocksdb
+// db-backend.
+func DefaultRocksDBConfig() *RocksDBConfig {
+	opts := dbm.DefaultRocksDBOptions()
+	return &RocksDBConfig{
+		BlockCacheSize:  opts.BlockCacheSize,
+		Compression:     opts.Default.Compression,
+		BloomFilterBits: opts.Default.BloomFilterBits,
+		Families:        map[string]*RocksDBFamilyConfig{},
+	}
+}
+
+// Options returns the RocksDB options of the configuration.
+func (cfg *RocksDBConfig) Options() *dbm.RocksDBOptions {
+	opts := &dbm.RocksDBOptions{
+		ColumnFamilies: cfg.ColumnFamilies,
+		BlockCacheSize: cfg.BlockCacheSize,
+		Default: dbm.RocksDBFamilyOptions{
+			Compression:     cfg.Compression,
+			BloomFilterBits: cfg.BloomFilterBits,
+		},
+		Families: make(map[string]dbm.RocksDBFamilyOptions, len(cfg.Families)),
+	}
+	for name, f := range cfg.Families {
+		opts.Families[name] = dbm.RocksDBFamilyOptions{
+			Compression:     f.Compression,
+			BloomFilterBits: f.BloomFilterBits,
+			BlockCacheShare: f.BlockCacheShare,
+		}
+	}
+	return opts
+}
+
+// ValidateBasic performs basic validation (checking param bounds, etc.) and
+// returns an error if any check fails.
+func (cfg *RocksDBConfig) ValidateBasic() error {
+	for name, f := range cfg.Families {
+		if f == nil {
+			return fmt.Errorf("families.%s must be set", name)
+		}
+	}
+	return cfg.Options().Validate()
+}
+
 // RPCConfig
 
 // RPCConfig defines the configuration options for the Bhojpur State RPC server
@@ -464,6 +672,35 @@ type RPCConfig struct {
 	// to the estimated maximum number of broadcast_tx_commit calls per block.
 	MaxSubscriptionsPerClient int `mapstructure:"max-subscriptions-per-client"`
 
+	// Maximum number of subscriptions a client can hold across all of its
+	// websocket connections, where a client is identified by its API key or
+	// its IP address.
+	// 0 - unlimited.
+	MaxClientSubscriptions int `mapstructure:"max-client-subscriptions"`
+
+	// Number of RPC calls per second allowed for each client IP address, with
+	// bursts of up to RateLimitBurst calls. Calls cost a number of tokens set
+	// by MethodCosts. Rejected calls return an error with a retry-after hint.
+	// 0 - unlimited.
+	RateLimit      float64 `mapstructure:"rate-limit"`
+	RateLimitBurst int     `mapstructure:"rate-limit-burst"`
+
+	// HTTP header carrying the API key of a client. Clients presenting one of
+	// the APIKeys are rate limited by key, using APIKeyRateLimit and
+	// APIKeyRateLimitBurst, instead of by IP address.
+	APIKeyHeader string   `mapstructure:"api-key-header"`
+	APIKeys      []string `mapstructure:"api-keys"`
+
+	// Number of RPC calls per second allowed for each API key, with bursts of
+	// up to APIKeyRateLimitBurst calls.
+	// 0 - unlimited.
+	APIKeyRateLimit      float64 `mapstructure:"api-key-rate-limit"`
+	APIKeyRateLimitBurst int     `mapstructure:"api-key-rate-limit-burst"`
+
+	// Cost of RPC calls in rate limit tokens, as a list of "method=cost"
+	// entries. Methods that are not listed cost a single token.
+	MethodCosts []string `mapstructure:"method-costs"`
+
 	// If true, disable the websocket interface to the RPC service.  This has
 	// the effect of disabling the /subscribe, /unsubscribe, and /unsubscribe_all
 	// methods for event subscription.
@@ -496,6 +733,11 @@ type RPCConfig struct {
 	// global HTTP write timeout, which applies to all connections and endpoints.
 	TimeoutBroadcastTxCommit time.Duration `mapstructure:"timeout-broadcast-tx-commit"`
 
+	// Maximum size, in bytes, of the cache of responses from block,
+	// block_results, commit, header and validators for past heights, which
+	// never change. 0 - disables the cache.
+	ResponseCacheSize int64 `mapstructure:"response-cache-size"`
+
 	// Maximum size of request body, in bytes
 	MaxBodyBytes int64 `mapstructure:"max-body-bytes"`
 
@@ -538,12 +780,24 @@ func DefaultRPCConfig() *RPCConfig {
 		// Settings for event subscription.
 		MaxSubscriptionClients:       100,
 		MaxSubscriptionsPerClient:    5,
+		MaxClientSubscriptions:       0,
 		ExperimentalDisableWebsocket: false, // compatible with TM v0.35 and earlier
 		EventLogWindowSize:           0,     // disables /events RPC by default
 		EventLogMaxItems:             0,
 
+		// Settings for rate limiting.
+		RateLimit:            0, // disables rate limiting by default
+		RateLimitBurst:       100,
+		APIKeyHeader:         "X-API-Key",
+		APIKeys:              []string{},
+		APIKeyRateLimit:      0,
+		APIKeyRateLimitBurst: 1000,
+		MethodCosts:          []string{"tx_search=10", "block_search=10", "block_results=5"},
+
 		TimeoutBroadcastTxCommit: 10 * time.Second,
 
+		ResponseCacheSize: 32 << 20, // 32MB
+
 		MaxBodyBytes:   int64(1000000), // 1MB
 		MaxHeaderBytes: 1 << 20,        // same as the net/http default
 
@@ -572,6 +826,27 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	if cfg.MaxSubscriptionsPerClient < 0 {
 		return errors.New("max-subscriptions-per-client can't be negative")
 	}
+	if cfg.MaxClientSubscriptions < 0 {
+		return errors.New("max-client-subscriptions can't be negative")
+	}
+	if cfg.RateLimit < 0 {
+		return errors.New("rate-limit can't be negative")
+	}
+	if cfg.RateLimit > 0 && cfg.RateLimitBurst < 1 {
+		return errors.New("rate-limit-burst must be positive when rate-limit is set")
+	}
+	if cfg.APIKeyRateLimit < 0 {
+		return errors.New("api-key-rate-limit can't be negative")
+	}
+	if cfg.APIKeyRateLimit > 0 && cfg.APIKeyRateLimitBurst < 1 {
+		return errors.New("api-key-rate-limit-burst must be positive when api-key-rate-limit is set")
+	}
+	if len(cfg.APIKeys) > 0 && cfg.APIKeyHeader == "" {
+		return errors.New("api-key-header must be set when api-keys are configured")
+	}
+	if _, err := cfg.MethodCostWeights(); err != nil {
+		return fmt.Errorf("method-costs: %w", err)
+	}
 	if cfg.EventLogWindowSize < 0 {
 		return errors.New("event-log-window-size must not be negative")
 	}
@@ -581,6 +856,9 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	if cfg.TimeoutBroadcastTxCommit < 0 {
 		return errors.New("timeout-broadcast-tx-commit can't be negative")
 	}
+	if cfg.ResponseCacheSize < 0 {
+		return errors.New("response-cache-size can't be negative")
+	}
 	if cfg.MaxBodyBytes < 0 {
 		return errors.New("max-body-bytes can't be negative")
 	}
@@ -590,6 +868,24 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	return nil
 }
 
+// MethodCostWeights returns the rate limit cost of RPC methods, parsed from
+// MethodCosts.
+func (cfg *RPCConfig) MethodCostWeights() (map[string]int, error) {
+	costs := make(map[string]int, len(cfg.MethodCosts))
+	for _, entry := range cfg.MethodCosts {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected \"method=cost\"", entry)
+		}
+		cost, err := strconv.Atoi(parts[1])
+		if err != nil || cost < 1 {
+			return nil, fmt.Errorf("invalid cost for method %q: %q", parts[0], parts[1])
+		}
+		costs[parts[0]] = cost
+	}
+	return costs, nil
+}
+
 // IsCorsEnabled returns true if cross-origin resource sharing is enabled.
 func (cfg *RPCConfig) IsCorsEnabled() bool {
 	return len(cfg.CORSAllowedOrigins) != 0
@@ -658,6 +954,10 @@ type P2PConfig struct { //nolint: maligned
 	// Set true to enable the peer-exchange reactor
 	PexReactor bool `mapstructure:"pex"`
 
+	// Set true to enable the DHT peer discovery reactor, which finds peers
+	// by node ID through a Kademlia-style distributed hash table
+	DHTReactor bool `mapstructure:"dht"`
+
 	// Comma separated list of peer IDs to keep private (will not be gossiped to
 	// other peers)
 	PrivatePeerIDs string `mapstructure:"private-peer-ids"`
@@ -677,6 +977,15 @@ type P2PConfig struct { //nolint: maligned
 	// Rate at which packets can be received, in bytes/second
 	RecvRate int64 `mapstructure:"recv-rate"`
 
+	// Comma separated list of channel:rate pairs, limiting the bytes/second
+	// sent on a channel across all peers, e.g. "0x40:1048576". The channel
+	// ID is decimal, or hexadecimal with a 0x prefix.
+	ChannelSendRates string `mapstructure:"channel-send-rates"`
+
+	// Rate at which messages can be sent to each peer across all channels,
+	// in bytes/second. 0 means no limit.
+	PeerSendRate int64 `mapstructure:"peer-send-rate"`
+
 	// Peer connection configuration.
 	HandshakeTimeout time.Duration `mapstructure:"handshake-timeout"`
 	DialTimeout      time.Duration `mapstructure:"dial-timeout"`
@@ -732,9 +1041,43 @@ func (cfg *P2PConfig) ValidateBasic() error {
 	if cfg.RecvRate < 0 {
 		return errors.New("recv-rate can't be negative")
 	}
+	if _, err := cfg.ChannelSendRateLimits(); err != nil {
+		return fmt.Errorf("invalid channel-send-rates: %w", err)
+	}
+	if cfg.PeerSendRate < 0 {
+		return errors.New("peer-send-rate can't be negative")
+	}
 	return nil
 }
 
+// ChannelSendRateLimits parses ChannelSendRates into send rates by channel
+// ID.
+func (cfg *P2PConfig) ChannelSendRateLimits() (map[uint16]int64, error) {
+	rates := make(map[uint16]int64)
+	for _, pair := range libstrings.SplitAndTrimEmpty(cfg.ChannelSendRates, ",", " ") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q is not a channel:rate pair", pair)
+		}
+		chID, err := strconv.ParseUint(parts[0], 0, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel ID %q: %w", parts[0], err)
+		}
+		rate, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate %q: %w", parts[1], err)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("rate of channel %q must be positive", parts[0])
+		}
+		if _, ok := rates[uint16(chID)]; ok {
+			return nil, fmt.Errorf("duplicate channel %q", parts[0])
+		}
+		rates[uint16(chID)] = rate
+	}
+	return rates, nil
+}
+
 // TestP2PConfig returns a configuration for testing the peer-to-peer layer
 func TestP2PConfig() *P2PConfig {
 	cfg := DefaultP2PConfig()
@@ -845,6 +1188,8 @@ func (cfg *MempoolConfig) ValidateBasic() error {
 
 // StateSyncConfig defines the configuration for the Bhojpur State sync service
 type StateSyncConfig struct {
+	RootDir string `mapstructure:"home"`
+
 	// State sync rapidly bootstraps a new node by discovering, fetching, and restoring a
 	// state machine snapshot from peers instead of fetching and replaying historical
 	// blocks. Requires some peers in the network to take and serve state machine
@@ -874,9 +1219,11 @@ type StateSyncConfig struct {
 	// Time to spend discovering snapshots before initiating a restore.
 	DiscoveryTime time.Duration `mapstructure:"discovery-time"`
 
-	// Temporary directory for state sync snapshot chunks, defaults to os.TempDir().
-	// The synchronizer will create a new, randomly named directory within this directory
-	// and remove it when the sync is complete.
+	// Directory for state sync snapshot chunks, defaults to the data directory.
+	// The synchronizer keeps fetched chunks in a statesync-chunks directory within this
+	// directory and removes it when the sync is complete. If the node is stopped during
+	// a sync, the restore is resumed from these chunks on restart, so this should not be
+	// a directory that is cleared on reboot.
 	TempDir string `mapstructure:"temp-dir"`
 
 	// The timeout duration before re-requesting a chunk, possibly from a different
@@ -885,6 +1232,35 @@ type StateSyncConfig struct {
 
 	// The number of concurrent chunk and block fetchers to run (default: 4).
 	Fetchers int32 `mapstructure:"fetchers"`
+
+	// The options below apply to in-process applications implementing
+	// abci.StateSnapshotter, whose snapshots are managed by the node.
+
+	// Take a snapshot every snapshot-interval heights. 0 disables taking
+	// snapshots; snapshots offered by peers can still be restored.
+	SnapshotInterval uint64 `mapstructure:"snapshot-interval"`
+
+	// The number of most recent snapshots to keep (default: 2).
+	SnapshotKeepRecent uint32 `mapstructure:"snapshot-keep-recent"`
+
+	// The size of snapshot chunks in bytes (default: 10MB).
+	SnapshotChunkSize uint32 `mapstructure:"snapshot-chunk-size"`
+
+	// Directory in which managed snapshots are stored.
+	SnapshotDir string `mapstructure:"snapshot-dir"`
+}
+
+// SnapshotPath returns the full path to the managed snapshot directory
+func (cfg *StateSyncConfig) SnapshotPath() string {
+	return rootify(cfg.SnapshotDir, cfg.RootDir)
+}
+
+// TempPath returns the directory for state sync snapshot chunks.
+func (cfg *StateSyncConfig) TempPath() string {
+	if cfg.TempDir == "" {
+		return rootify(defaultDataDir, cfg.RootDir)
+	}
+	return cfg.TempDir
 }
 
 func (cfg *StateSyncConfig) TrustHashBytes() []byte {
@@ -903,6 +1279,9 @@ func DefaultStateSyncConfig() *StateSyncConfig {
 		DiscoveryTime:       15 * time.Second,
 		ChunkRequestTimeout: 15 * time.Second,
 		Fetchers:            4,
+		SnapshotKeepRecent:  2,
+		SnapshotChunkSize:   10 * 1024 * 1024,
+		SnapshotDir:         filepath.Join(defaultDataDir, "snapshots"),
 	}
 }
 
@@ -913,6 +1292,14 @@ func TestStateSyncConfig() *StateSyncConfig {
 
 // ValidateBasic performs basic validation.
 func (cfg *StateSyncConfig) ValidateBasic() error {
+	if cfg.SnapshotInterval > 0 && cfg.SnapshotKeepRecent == 0 {
+		return errors.New("snapshot-keep-recent must be positive when snapshot-interval is set")
+	}
+
+	if cfg.SnapshotChunkSize == 0 {
+		return errors.New("snapshot-chunk-size is required")
+	}
+
 	if !cfg.Enable {
 		return nil
 	}
@@ -963,6 +1350,35 @@ func (cfg *StateSyncConfig) ValidateBasic() error {
 	return nil
 }
 
+// BlockSyncConfig
+
+// BlockSyncConfig defines the configuration for the Bhojpur State block sync service
+type BlockSyncConfig struct {
+	RootDir string `mapstructure:"home"`
+
+	// Directory containing a block archive produced by "statectl archive export".
+	// When block sync starts and the directory exists, blocks are replayed from
+	// the archive, verifying every commit, before block sync continues with peers.
+	ArchiveDir string `mapstructure:"archive-dir"`
+}
+
+// DefaultBlockSyncConfig returns a default configuration for the block sync service
+func DefaultBlockSyncConfig() *BlockSyncConfig {
+	return &BlockSyncConfig{
+		ArchiveDir: filepath.Join(defaultDataDir, "block-archive"),
+	}
+}
+
+// TestBlockSyncConfig returns a default configuration for the block sync service
+func TestBlockSyncConfig() *BlockSyncConfig {
+	return DefaultBlockSyncConfig()
+}
+
+// ArchivePath returns the full path to the block archive directory
+func (cfg *BlockSyncConfig) ArchivePath() string {
+	return rootify(cfg.ArchiveDir, cfg.RootDir)
+}
+
 // ConsensusConfig
 
 // ConsensusConfig defines the configuration for the Bhojpur State consensus service,
@@ -982,6 +1398,15 @@ type ConsensusConfig struct {
 
 	DoubleSignCheckHeight int64 `mapstructure:"double-sign-check-height"`
 
+	// If non-zero, the node halts after committing this height, as if the
+	// application had scheduled an upgrade named UpgradeName at it.
+	HaltHeight  int64  `mapstructure:"halt-height"`
+	UpgradeName string `mapstructure:"upgrade-name"`
+
+	// Path to the JSON file written when the node halts for an upgrade. A
+	// binary that finds this file refuses to start unless it is the upgraded one.
+	UpgradeInfoPath string `mapstructure:"upgrade-info-file"`
+
 	// TODO: The following fields are all temporary overrides that should exist only
 	// for the duration of the v0.36 release. The below fields should be completely
 	// removed in the v0.37 release of Bhojpur State.
@@ -1037,6 +1462,7 @@ func DefaultConsensusConfig() *ConsensusConfig {
 		PeerGossipSleepDuration:     100 * time.Millisecond,
 		PeerQueryMaj23SleepDuration: 2000 * time.Millisecond,
 		DoubleSignCheckHeight:       int64(0),
+		UpgradeInfoPath:             filepath.Join(defaultDataDir, "upgrade-info.json"),
 	}
 }
 
@@ -1067,6 +1493,11 @@ func (cfg *ConsensusConfig) SetWalFile(walFile string) {
 	cfg.walFile = walFile
 }
 
+// UpgradeInfoFile returns the full path to the upgrade-info file
+func (cfg *ConsensusConfig) UpgradeInfoFile() string {
+	return rootify(cfg.UpgradeInfoPath, cfg.RootDir)
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *ConsensusConfig) ValidateBasic() error {
@@ -1097,6 +1528,12 @@ func (cfg *ConsensusConfig) ValidateBasic() error {
 	if cfg.DoubleSignCheckHeight < 0 {
 		return errors.New("double-sign-check-height can't be negative")
 	}
+	if cfg.HaltHeight < 0 {
+		return errors.New("halt-height can't be negative")
+	}
+	if cfg.HaltHeight > 0 && cfg.UpgradeName == "" {
+		return errors.New("upgrade-name must be set when halt-height is")
+	}
 	return nil
 }
 
@@ -1177,6 +1614,8 @@ func TestTxIndexConfig() *TxIndexConfig {
 
 // InstrumentationConfig defines the configuration for metrics reporting.
 type InstrumentationConfig struct {
+	RootDir string `mapstructure:"home"`
+
 	// When true, Prometheus metrics are served under /metrics on
 	// PrometheusListenAddr.
 	// Check out the documentation for the list of available metrics.
@@ -1193,8 +1632,30 @@ type InstrumentationConfig struct {
 
 	// Instrumentation namespace.
 	Namespace string `mapstructure:"namespace"`
+
+	// Exporter for OpenTelemetry traces of consensus steps, ABCI calls, block
+	// execution and RPC requests:
+	//   "" - tracing is disabled
+	//   "otlp" - spans are sent to TracingEndpoint using OTLP over HTTP
+	//   "file" - spans are appended to TracingFile in OTLP JSON format
+	TracingExporter string `mapstructure:"tracing-exporter"`
+
+	// URL of the traces endpoint of an OTLP collector.
+	TracingEndpoint string `mapstructure:"tracing-endpoint"`
+
+	// Path to the file traces are written to by the "file" exporter.
+	TracingFile string `mapstructure:"tracing-file"`
+
+	// Fraction of traces that are recorded, between 0 and 1.
+	TracingSampleRate float64 `mapstructure:"tracing-sample-rate"`
 }
 
+// Tracing exporters
+const (
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
 // DefaultInstrumentationConfig returns a default configuration for metrics
 // reporting.
 func DefaultInstrumentationConfig() *InstrumentationConfig {
@@ -1203,6 +1664,9 @@ func DefaultInstrumentationConfig() *InstrumentationConfig {
 		PrometheusListenAddr: ":26660",
 		MaxOpenConnections:   3,
 		Namespace:            "bhojpur",
+		TracingEndpoint:      "http://localhost:4318/v1/traces",
+		TracingFile:          filepath.Join(defaultDataDir, "traces.jsonl"),
+		TracingSampleRate:    1,
 	}
 }
 
@@ -1218,9 +1682,30 @@ func (cfg *InstrumentationConfig) ValidateBasic() error {
 	if cfg.MaxOpenConnections < 0 {
 		return errors.New("max-open-connections can't be negative")
 	}
+	switch cfg.TracingExporter {
+	case "", TracingExporterFile:
+	case TracingExporterOTLP:
+		if cfg.TracingEndpoint == "" {
+			return errors.New("tracing-endpoint must be set for the otlp exporter")
+		}
+	default:
+		return fmt.Errorf("unknown tracing-exporter %q (must be '', 'otlp' or 'file')", cfg.TracingExporter)
+	}
+	if cfg.TracingExporter == TracingExporterFile && cfg.TracingFile == "" {
+		return errors.New("tracing-file must be set for the file exporter")
+	}
+	if cfg.TracingSampleRate < 0 || cfg.TracingSampleRate > 1 {
+		return errors.New("tracing-sample-rate must be between 0 and 1")
+	}
 	return nil
 }
 
+// TracingFilePath returns the full path to the file written by the file
+// tracing exporter.
+func (cfg *InstrumentationConfig) TracingFilePath() string {
+	return rootify(cfg.TracingFile, cfg.RootDir)
+}
+
 // Utils
 
 // helper function to make config creation independent of root dir
@@ -52,6 +52,17 @@ func TestConfigValidateBasic(t *testing.T) {
 	// tamper with unsafe-propose-timeout-override
 	cfg.Consensus.UnsafeProposeTimeoutOverride = -10 * time.Second
 	assert.Error(t, cfg.ValidateBasic())
+
+	// the remote backend needs the [remote-db] section
+	cfg = DefaultConfig()
+	cfg.DBBackend = "remote"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.RemoteDB.Address = "10.0.0.2:26670"
+	cfg.RemoteDB.ClientCertificate = "config/remotedb-client.crt"
+	cfg.RemoteDB.ClientKey = "config/remotedb-client.key"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.RemoteDB.RootCA = "config/remotedb-ca.crt"
+	assert.NoError(t, cfg.ValidateBasic())
 }
 
 func TestTLSConfiguration(t *testing.T) {
@@ -76,6 +87,20 @@ func TestBaseConfigValidateBasic(t *testing.T) {
 	// tamper with log format
 	cfg.LogFormat = "invalid"
 	assert.Error(t, cfg.ValidateBasic())
+
+	// per-module log levels and sampling
+	cfg = TestBaseConfig()
+	cfg.LogLevel = "info,consensus=debug,p2p=error"
+	cfg.LogSampling = "mempool=10/1s"
+	assert.NoError(t, cfg.ValidateBasic())
+	cfg.LogLevel = "info,consensus=loud"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.LogLevel = DefaultLogLevel
+	cfg.LogSampling = "mempool=10"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.LogSampling = ""
+	cfg.LogFileMaxSize = -1
+	assert.Error(t, cfg.ValidateBasic())
 }
 
 func TestRPCConfigValidateBasic(t *testing.T) {
@@ -86,7 +111,9 @@ func TestRPCConfigValidateBasic(t *testing.T) {
 		"MaxOpenConnections",
 		"MaxSubscriptionClients",
 		"MaxSubscriptionsPerClient",
+		"MaxClientSubscriptions",
 		"TimeoutBroadcastTxCommit",
+		"ResponseCacheSize",
 		"MaxBodyBytes",
 		"MaxHeaderBytes",
 	}
@@ -96,6 +123,21 @@ func TestRPCConfigValidateBasic(t *testing.T) {
 		assert.Error(t, cfg.ValidateBasic())
 		reflect.ValueOf(cfg).Elem().FieldByName(fieldName).SetInt(0)
 	}
+
+	cfg = TestRPCConfig()
+	cfg.RateLimit = 10
+	cfg.RateLimitBurst = 0
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.RateLimitBurst = 10
+	assert.NoError(t, cfg.ValidateBasic())
+
+	costs, err := cfg.MethodCostWeights()
+	require.NoError(t, err)
+	assert.Equal(t, 10, costs["tx_search"])
+	for _, entry := range []string{"tx_search", "tx_search=0", "=5", "tx_search=x"} {
+		cfg.MethodCosts = []string{entry}
+		assert.Error(t, cfg.ValidateBasic(), entry)
+	}
 }
 
 func TestMempoolConfigValidateBasic(t *testing.T) {
@@ -119,6 +161,14 @@ func TestMempoolConfigValidateBasic(t *testing.T) {
 func TestStateSyncConfigValidateBasic(t *testing.T) {
 	cfg := TestStateSyncConfig()
 	require.NoError(t, cfg.ValidateBasic())
+
+	cfg.SnapshotInterval = 100
+	cfg.SnapshotKeepRecent = 0
+	require.Error(t, cfg.ValidateBasic())
+
+	cfg = TestStateSyncConfig()
+	cfg.SnapshotChunkSize = 0
+	require.Error(t, cfg.ValidateBasic())
 }
 
 func TestConsensusConfig_ValidateBasic(t *testing.T) {
@@ -141,6 +191,9 @@ func TestConsensusConfig_ValidateBasic(t *testing.T) {
 		"PeerQueryMaj23SleepDuration":                {func(c *ConsensusConfig) { c.PeerQueryMaj23SleepDuration = time.Second }, false},
 		"PeerQueryMaj23SleepDuration negative":       {func(c *ConsensusConfig) { c.PeerQueryMaj23SleepDuration = -1 }, true},
 		"DoubleSignCheckHeight negative":             {func(c *ConsensusConfig) { c.DoubleSignCheckHeight = -1 }, true},
+		"HaltHeight negative":                        {func(c *ConsensusConfig) { c.HaltHeight = -1 }, true},
+		"HaltHeight without UpgradeName":             {func(c *ConsensusConfig) { c.HaltHeight = 10 }, true},
+		"HaltHeight with UpgradeName":                {func(c *ConsensusConfig) { c.HaltHeight = 10; c.UpgradeName = "v2" }, false},
 	}
 	for desc, tc := range testcases {
 		tc := tc // appease linter
@@ -165,6 +218,42 @@ func TestInstrumentationConfigValidateBasic(t *testing.T) {
 	// tamper with maximum open connections
 	cfg.MaxOpenConnections = -1
 	assert.Error(t, cfg.ValidateBasic())
+
+	// tracing exporters
+	cfg = TestInstrumentationConfig()
+	for _, exporter := range []string{TracingExporterOTLP, TracingExporterFile} {
+		cfg.TracingExporter = exporter
+		assert.NoError(t, cfg.ValidateBasic())
+	}
+	cfg.TracingExporter = "jaeger"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.TracingExporter = TracingExporterOTLP
+	cfg.TracingEndpoint = ""
+	assert.Error(t, cfg.ValidateBasic())
+	cfg = TestInstrumentationConfig()
+	cfg.TracingSampleRate = 1.5
+	assert.Error(t, cfg.ValidateBasic())
+}
+
+func TestRocksDBConfigValidateBasic(t *testing.T) {
+	cfg := DefaultRocksDBConfig()
+	assert.NoError(t, cfg.ValidateBasic())
+
+	cfg.Compression = "brotli"
+	assert.Error(t, cfg.ValidateBasic())
+
+	cfg = DefaultRocksDBConfig()
+	cfg.Families["tx_index"] = &RocksDBFamilyConfig{Compression: "zstd", BlockCacheShare: 0.6}
+	assert.NoError(t, cfg.ValidateBasic())
+	assert.Equal(t, "zstd", cfg.Options().Family("tx_index").Compression)
+	assert.Equal(t, cfg.Compression, cfg.Options().Family("state").Compression)
+
+	// the shares of the block cache can't exceed it
+	cfg.Families["blockstore"] = &RocksDBFamilyConfig{BlockCacheShare: 0.6}
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.Families["blockstore"].BloomFilterBits = -1
+	cfg.Families["blockstore"].BlockCacheShare = 0
+	assert.Error(t, cfg.ValidateBasic())
 }
 
 func TestP2PConfigValidateBasic(t *testing.T) {
@@ -176,6 +265,7 @@ func TestP2PConfigValidateBasic(t *testing.T) {
 		"MaxPacketMsgPayloadSize",
 		"SendRate",
 		"RecvRate",
+		"PeerSendRate",
 	}
 
 	for _, fieldName := range fieldsToTest {
@@ -183,4 +273,15 @@ func TestP2PConfigValidateBasic(t *testing.T) {
 		assert.Error(t, cfg.ValidateBasic())
 		reflect.ValueOf(cfg).Elem().FieldByName(fieldName).SetInt(0)
 	}
+
+	cfg.ChannelSendRates = "0x40:1048576, 48:524288"
+	require.NoError(t, cfg.ValidateBasic())
+	rates, err := cfg.ChannelSendRateLimits()
+	require.NoError(t, err)
+	assert.Equal(t, map[uint16]int64{0x40: 1048576, 0x30: 524288}, rates)
+
+	for _, rates := range []string{"0x40", "0x40:0", "0x40:1,0x40:2", "blocksync:1", "0x10000:1"} {
+		cfg.ChannelSendRates = rates
+		assert.Error(t, cfg.ValidateBasic(), rates)
+	}
 }
@@ -24,6 +24,8 @@ import (
 	"context"
 
 	dbm "github.com/bhojpur/state/pkg/database"
+	"github.com/bhojpur/state/pkg/database/remote"
+	"github.com/bhojpur/state/pkg/database/remote/rpc"
 
 	"github.com/bhojpur/state/pkg/libs/log"
 	"github.com/bhojpur/state/pkg/libs/service"
@@ -42,8 +44,22 @@ type DBContext struct {
 type DBProvider func(*DBContext) (dbm.DB, error)
 
 // DefaultDBProvider returns a database using the DBBackend and DBDir
-// specified in the Config.
+// specified in the Config. With the remote backend, the database is hosted
+// under its ID on the server of the RemoteDB section. With the rocksdb
+// backend, the database is laid out and tuned by the RocksDB section.
 func DefaultDBProvider(ctx *DBContext) (dbm.DB, error) {
 	dbType := dbm.BackendType(ctx.Config.DBBackend)
-	return dbm.NewDB(ctx.ID, dbType, ctx.Config.DBDir())
+	if dbType == dbm.RemoteDBBackend {
+		return newRemoteDB(ctx.ID, ctx.Config.RemoteDB)
+	}
+	return dbm.NewDB(ctx.ID, dbType, ctx.Config.DBDir(),
+		dbm.WithRocksDBOptions(ctx.Config.RocksDB.Options()))
+}
+
+func newRemoteDB(name string, cfg *RemoteDBConfig) (dbm.DB, error) {
+	creds, err := rpc.ClientCredentials(cfg.ClientCertificateFile(), cfg.ClientKeyFile(), cfg.RootCAFile())
+	if err != nil {
+		return nil, err
+	}
+	return remote.NewRemoteDB(cfg.Address, name, creds)
 }
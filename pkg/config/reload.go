@@ -0,0 +1,190 @@
+package config
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// reloadableFields are the settings, keyed by their name in the config file,
+// that the node can apply without restarting.
+var reloadableFields = map[string]bool{
+	"log-level":                         true,
+	"log-sampling":                      true,
+	"mempool.size":                      true,
+	"mempool.max-txs-bytes":             true,
+	"mempool.max-tx-bytes":              true,
+	"mempool.recheck":                   true,
+	"mempool.keep-invalid-txs-in-cache": true,
+	"mempool.ttl-duration":              true,
+	"mempool.ttl-num-blocks":            true,
+	"rpc.cors-allowed-origins":          true,
+	"rpc.cors-allowed-methods":          true,
+	"rpc.cors-allowed-headers":          true,
+	"p2p.max-connections":               true,
+	"tx-index.indexer":                  true,
+	"tx-index.psql-conn":                true,
+}
+
+// IsReloadable reports whether the setting with the given key, such as
+// "mempool.size", can be changed without restarting the node.
+func IsReloadable(key string) bool {
+	return reloadableFields[key]
+}
+
+// FieldChange is a setting whose value differs between two configurations.
+type FieldChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+// ReloadReport describes the outcome of reloading the configuration of a
+// running node.
+type ReloadReport struct {
+	// Applied are the changes that took effect.
+	Applied []FieldChange `json:"applied"`
+
+	// Rejected are the changes that require a restart, and were ignored.
+	Rejected []FieldChange `json:"rejected"`
+}
+
+// Keys returns the keys of the given changes.
+func Keys(changes []FieldChange) []string {
+	keys := make([]string, len(changes))
+	for i, c := range changes {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// Diff returns the settings whose values differ between old and new, ordered
+// by key.
+func Diff(old, new *Config) []FieldChange {
+	oldFields, newFields := settingsOf(old), settingsOf(new)
+
+	var changes []FieldChange
+	for key, ov := range oldFields {
+		nv, ok := newFields[key]
+		if !ok || reflect.DeepEqual(ov.Interface(), nv.Interface()) {
+			continue
+		}
+		changes = append(changes, FieldChange{Key: key, Old: ov.Interface(), New: nv.Interface()})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// Reload returns a copy of cfg with the reloadable changes applied, and a
+// report of the applied and rejected changes. It does not modify cfg.
+func Reload(cfg *Config, changes []FieldChange) (*Config, *ReloadReport) {
+	next := cfg.clone()
+	fields := settingsOf(next)
+
+	report := &ReloadReport{}
+	for _, c := range changes {
+		field, ok := fields[c.Key]
+		if !ok || !IsReloadable(c.Key) {
+			report.Rejected = append(report.Rejected, c)
+			continue
+		}
+		field.Set(reflect.ValueOf(c.New))
+		report.Applied = append(report.Applied, c)
+	}
+	return next, report
+}
+
+// ReadConfigFile reads the config file of the node with the given home
+// directory, with defaults for the settings it omits, and validates it.
+// Unlike the configuration the node was started with, it does not include
+// settings given by flags or the environment.
+func ReadConfigFile(home string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(home, defaultConfigFilePath))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.SetRoot(home)
+	if err := cfg.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("error in config file: %w", err)
+	}
+	return cfg, nil
+}
+
+// clone returns a copy of cfg that shares no sections with it.
+func (cfg *Config) clone() *Config {
+	c := *cfg
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Ptr || field.IsNil() {
+			continue
+		}
+		section := reflect.New(field.Type().Elem())
+		section.Elem().Set(field.Elem())
+		field.Set(section)
+	}
+	return &c
+}
+
+// settingsOf returns the settable values of the settings of cfg, keyed by
+// their name in the config file.
+func settingsOf(cfg *Config) map[string]reflect.Value {
+	settings := make(map[string]reflect.Value)
+	collectSettings(reflect.ValueOf(cfg).Elem(), "", settings)
+	return settings
+}
+
+func collectSettings(v reflect.Value, prefix string, settings map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("mapstructure")
+		name := strings.Split(tag, ",")[0]
+		field := v.Field(i)
+		switch {
+		case strings.Contains(tag, ",squash"):
+			collectSettings(field, prefix, settings)
+		case name == "" || name == "-":
+			// Skip untagged fields and the remainder of unknown settings.
+		case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct:
+			if !field.IsNil() {
+				collectSettings(field.Elem(), prefix+name+".", settings)
+			}
+		default:
+			settings[prefix+name] = field
+		}
+	}
+}
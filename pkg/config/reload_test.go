@@ -0,0 +1,73 @@
+package config
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAndReload(t *testing.T) {
+	running := DefaultConfig()
+	next := DefaultConfig()
+	require.Empty(t, Diff(running, next))
+
+	next.LogLevel = "debug"
+	next.Mempool.Size = 10
+	next.RPC.CORSAllowedOrigins = []string{"*"}
+	next.Consensus.WalPath = "other/wal"
+
+	changes := Diff(running, next)
+	assert.Equal(t, []string{"consensus.wal-file", "log-level", "mempool.size", "rpc.cors-allowed-origins"}, Keys(changes))
+
+	cfg, report := Reload(running, changes)
+	assert.Equal(t, []string{"log-level", "mempool.size", "rpc.cors-allowed-origins"}, Keys(report.Applied))
+	assert.Equal(t, []string{"consensus.wal-file"}, Keys(report.Rejected))
+
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, 10, cfg.Mempool.Size)
+	assert.Equal(t, []string{"*"}, cfg.RPC.CORSAllowedOrigins)
+	assert.Equal(t, running.Consensus.WalPath, cfg.Consensus.WalPath)
+
+	// The running configuration is left untouched.
+	assert.Equal(t, DefaultConfig().LogLevel, running.LogLevel)
+	assert.Equal(t, DefaultConfig().Mempool.Size, running.Mempool.Size)
+}
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Mempool.Size = 1234
+	EnsureRoot(dir)
+	require.NoError(t, WriteConfigFile(dir, cfg))
+
+	read, err := ReadConfigFile(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1234, read.Mempool.Size)
+	assert.Equal(t, dir, read.RootDir)
+
+	cfg.Mempool.Size = -1
+	require.NoError(t, WriteConfigFile(dir, cfg))
+	_, err = ReadConfigFile(dir)
+	require.Error(t, err)
+}
@@ -23,6 +23,7 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -63,6 +64,12 @@ func EnsureRoot(rootDir string) {
 	}
 }
 
+// ConfigFilePath returns the path of the config file of the node with the
+// given home directory.
+func ConfigFilePath(rootDir string) string {
+	return filepath.Join(rootDir, defaultConfigFilePath)
+}
+
 // WriteConfigFile renders config using the template and writes it to configFilePath.
 // This function is called by cmd/client/commands/init.go
 func WriteConfigFile(rootDir string, config *Config) error {
@@ -75,13 +82,18 @@ func WriteConfigFile(rootDir string, config *Config) error {
 func (cfg *Config) WriteToTemplate(path string) error {
 	var buffer bytes.Buffer
 
-	if err := configTemplate.Execute(&buffer, cfg); err != nil {
+	if err := cfg.RenderTemplate(&buffer); err != nil {
 		return err
 	}
 
 	return writeFile(path, buffer.Bytes(), 0644)
 }
 
+// RenderTemplate writes the config to w in the default toml template.
+func (cfg *Config) RenderTemplate(w io.Writer) error {
+	return configTemplate.Execute(w, cfg)
+}
+
 func writeDefaultConfigFileIfNone(rootDir string) error {
 	configFilePath := filepath.Join(rootDir, defaultConfigFilePath)
 	if !bos.FileExists(configFilePath) {
@@ -100,6 +112,11 @@ const defaultConfigTemplate = `# This is a TOML config file.
 # "$HOME/.bhojpur" by default, but could be changed via $TMHOME env variable
 # or --home cmd flag.
 
+# NOTE: A running node re-reads this file on SIGHUP or an
+# "/unsafe_reload_config" RPC request. Changes to log-level, log-sampling,
+# the mempool limits, the RPC CORS settings, p2p.max-connections and the
+# tx-index section take effect immediately; other changes require a restart.
+
 #######################################################################
 ###                   Main Base Config Options                      ###
 #######################################################################
@@ -139,9 +156,12 @@ mode = "{{ .BaseConfig.Mode }}"
 #   - EXPERIMENTAL
 #   - requires gcc
 #   - use rocksdb build tag (go build -tags rocksdb)
+#   - tuned in the [rocksdb] section
 # * badgerdb (uses github.com/dgraph-io/badger)
 #   - EXPERIMENTAL
 #   - use badgerdb build tag (go build -tags badgerdb)
+# * remote (a remote database server, see the [remote-db] section)
+#   - EXPERIMENTAL
 db-backend = "{{ .BaseConfig.DBBackend }}"
 
 # Database directory
@@ -153,6 +173,23 @@ log-level = "{{ .BaseConfig.LogLevel }}"
 # Output format: 'plain' (colored text) or 'json'
 log-format = "{{ .BaseConfig.LogFormat }}"
 
+# Limits the number of debug and info messages logged by busy modules, as a
+# comma-separated list of module=burst/period entries. For example,
+# "mempool=10/1s" logs at most 10 messages with the same text per second for
+# the mempool module. Error messages are never dropped.
+log-sampling = "{{ .BaseConfig.LogSampling }}"
+
+# If set, log entries are also written in JSON format to this file, relative
+# to the home directory unless absolute. Set to "" to disable.
+log-file = "{{ js .BaseConfig.LogFile }}"
+
+# Size in bytes beyond which the log file is rotated.
+log-file-max-size = {{ .BaseConfig.LogFileMaxSize }}
+
+# Total size in bytes of the log file and its rotated files, beyond which the
+# oldest files are removed.
+log-file-max-total-size = {{ .BaseConfig.LogFileMaxTotalSize }}
+
 ##### additional base config options #####
 
 # Path to the JSON file containing the initial validator set and other meta data
@@ -164,6 +201,11 @@ node-key-file = "{{ js .BaseConfig.NodeKey }}"
 # Mechanism to connect to the ABCI application: socket | grpc
 abci = "{{ .BaseConfig.ABCI }}"
 
+# If set, every ABCI call is recorded with its request, response and timing to
+# this file, for replay against a fresh application with "statectl abci replay".
+# The recording is never pruned, so it should only be enabled while debugging.
+abci-record-file = "{{ js .BaseConfig.ABCIRecordFile }}"
+
 # If true, query the ABCI app on connecting to a new peer
 # so the app can decide if we should keep the connection or not
 filter-peers = {{ .BaseConfig.FilterPeers }}
@@ -240,6 +282,35 @@ max-subscription-clients = {{ .RPC.MaxSubscriptionClients }}
 # to the estimated maximum number of broadcast_tx_commit calls per block.
 max-subscriptions-per-client = {{ .RPC.MaxSubscriptionsPerClient }}
 
+# Maximum number of subscriptions a client can hold across all of its
+# websocket connections, where a client is identified by its API key or
+# its IP address.
+# 0 - unlimited.
+max-client-subscriptions = {{ .RPC.MaxClientSubscriptions }}
+
+# Number of RPC calls per second allowed for each client IP address, with
+# bursts of up to rate-limit-burst calls. Calls cost a number of tokens set
+# by method-costs. Rejected calls return an error with a retry-after hint.
+# 0 - unlimited.
+rate-limit = {{ .RPC.RateLimit }}
+rate-limit-burst = {{ .RPC.RateLimitBurst }}
+
+# HTTP header carrying the API key of a client. Clients presenting one of
+# the api-keys are rate limited by key, using api-key-rate-limit and
+# api-key-rate-limit-burst, instead of by IP address.
+api-key-header = "{{ .RPC.APIKeyHeader }}"
+api-keys = [{{ range .RPC.APIKeys }}{{ printf "%q, " . }}{{end}}]
+
+# Number of RPC calls per second allowed for each API key, with bursts of
+# up to api-key-rate-limit-burst calls.
+# 0 - unlimited.
+api-key-rate-limit = {{ .RPC.APIKeyRateLimit }}
+api-key-rate-limit-burst = {{ .RPC.APIKeyRateLimitBurst }}
+
+# Cost of RPC calls in rate limit tokens, as a list of "method=cost"
+# entries. Methods that are not listed cost a single token.
+method-costs = [{{ range .RPC.MethodCosts }}{{ printf "%q, " . }}{{end}}]
+
 # If true, disable the websocket interface to the RPC service.  This has
 # the effect of disabling the /subscribe, /unsubscribe, and /unsubscribe_all
 # methods for event subscription.
@@ -272,6 +343,11 @@ event-log-max-items = {{ .RPC.EventLogMaxItems }}
 # global HTTP write timeout, which applies to all connections and endpoints.
 timeout-broadcast-tx-commit = "{{ .RPC.TimeoutBroadcastTxCommit }}"
 
+# Maximum size, in bytes, of the cache of responses from block,
+# block_results, commit, header and validators for past heights, which
+# never change. 0 - disables the cache.
+response-cache-size = {{ .RPC.ResponseCacheSize }}
+
 # Maximum size of request body, in bytes
 max-body-bytes = {{ .RPC.MaxBodyBytes }}
 
@@ -340,6 +416,10 @@ max-incoming-connection-attempts = {{ .P2P.MaxIncomingConnectionAttempts }}
 # Set true to enable the peer-exchange reactor
 pex = {{ .P2P.PexReactor }}
 
+# Set true to enable the DHT peer discovery reactor, which finds peers by
+# node ID through a Kademlia-style distributed hash table
+dht = {{ .P2P.DHTReactor }}
+
 # Comma separated list of peer IDs to keep private (will not be gossiped to other peers)
 # Warning: IPs will be exposed at /net_info
 private-peer-ids = "{{ .P2P.PrivatePeerIDs }}"
@@ -367,6 +447,15 @@ send-rate = {{ .P2P.SendRate }}
 # TODO: Remove once MConnConnection is removed.
 recv-rate = {{ .P2P.RecvRate }}
 
+# Comma separated list of channel:rate pairs, limiting the bytes/second sent
+# on a channel across all peers, e.g. "0x40:1048576" to cap block sync at
+# 1 MB/s. The channel ID is decimal, or hexadecimal with a 0x prefix.
+channel-send-rates = "{{ .P2P.ChannelSendRates }}"
+
+# Rate at which messages can be sent to each peer across all channels, in
+# bytes/second. 0 means no limit.
+peer-send-rate = {{ .P2P.PeerSendRate }}
+
 
 #######################################################
 ###          Mempool Configuration Option          ###
@@ -448,9 +537,11 @@ trust-period = "{{ .StateSync.TrustPeriod }}"
 # Time to spend discovering snapshots before initiating a restore.
 discovery-time = "{{ .StateSync.DiscoveryTime }}"
 
-# Temporary directory for state sync snapshot chunks, defaults to os.TempDir().
-# The synchronizer will create a new, randomly named directory within this directory
-# and remove it when the sync is complete.
+# Directory for state sync snapshot chunks, defaults to the data directory.
+# The synchronizer keeps fetched chunks in a statesync-chunks directory within this
+# directory and removes it when the sync is complete. If the node is stopped during
+# a sync, the restore is resumed from these chunks on restart, so this should not be
+# a directory that is cleared on reboot.
 temp-dir = "{{ .StateSync.TempDir }}"
 
 # The timeout duration before re-requesting a chunk, possibly from a different
@@ -460,6 +551,34 @@ chunk-request-timeout = "{{ .StateSync.ChunkRequestTimeout }}"
 # The number of concurrent chunk and block fetchers to run (default: 4).
 fetchers = "{{ .StateSync.Fetchers }}"
 
+# The options below apply to in-process applications implementing
+# abci.StateSnapshotter, whose snapshots are taken, stored, pruned and
+# served by the node.
+
+# Take a snapshot every snapshot-interval heights. 0 disables taking snapshots;
+# snapshots offered by peers can still be restored.
+snapshot-interval = {{ .StateSync.SnapshotInterval }}
+
+# The number of most recent snapshots to keep (default: 2).
+snapshot-keep-recent = {{ .StateSync.SnapshotKeepRecent }}
+
+# The size of snapshot chunks in bytes (default: 10MB).
+snapshot-chunk-size = {{ .StateSync.SnapshotChunkSize }}
+
+# Directory in which managed snapshots are stored.
+snapshot-dir = "{{ js .StateSync.SnapshotDir }}"
+
+#######################################################
+###         Block Sync Configuration Options        ###
+#######################################################
+[blocksync]
+
+# Directory containing a block archive produced by "statectl archive export"
+# (or installed with "statectl archive import"). When block sync starts and
+# the directory exists, blocks are replayed from the archive, verifying every
+# commit, before block sync continues with peers over p2p.
+archive-dir = "{{ js .BlockSync.ArchiveDir }}"
+
 #######################################################
 ###         Consensus Configuration Options         ###
 #######################################################
@@ -481,6 +600,19 @@ create-empty-blocks-interval = "{{ .Consensus.CreateEmptyBlocksInterval }}"
 peer-gossip-sleep-duration = "{{ .Consensus.PeerGossipSleepDuration }}"
 peer-query-maj23-sleep-duration = "{{ .Consensus.PeerQueryMaj23SleepDuration }}"
 
+### Upgrade Options ###
+
+# If non-zero, the node stops after committing this height and writes the
+# upgrade-info file, as if the application had scheduled an upgrade named
+# upgrade-name at this height. The application can also schedule an upgrade
+# by returning an upgrade plan from FinalizeBlock.
+halt-height = {{ .Consensus.HaltHeight }}
+upgrade-name = "{{ .Consensus.UpgradeName }}"
+
+# Path to the JSON file written when the node halts for an upgrade. A binary
+# that finds this file refuses to start again unless it is the upgraded one.
+upgrade-info-file = "{{ js .Consensus.UpgradeInfoPath }}"
+
 ### Unsafe Timeout Overrides ###
 
 # These fields provide temporary overrides for the Timeout consensus parameters.
@@ -567,6 +699,89 @@ max-open-connections = {{ .Instrumentation.MaxOpenConnections }}
 
 # Instrumentation namespace
 namespace = "{{ .Instrumentation.Namespace }}"
+
+# Exporter for OpenTelemetry traces of consensus steps, ABCI calls, block
+# execution and RPC requests:
+#   "" - tracing is disabled
+#   "otlp" - spans are sent to tracing-endpoint using OTLP over HTTP (JSON)
+#   "file" - spans are appended to tracing-file in OTLP JSON format, one
+#            export request per line, for offline analysis
+tracing-exporter = "{{ .Instrumentation.TracingExporter }}"
+
+# URL of the traces endpoint of an OTLP collector
+tracing-endpoint = "{{ .Instrumentation.TracingEndpoint }}"
+
+# Path to the file written by the file exporter, relative to the home
+# directory unless absolute
+tracing-file = "{{ js .Instrumentation.TracingFile }}"
+
+# Fraction of traces that are recorded, between 0 and 1
+tracing-sample-rate = {{ .Instrumentation.TracingSampleRate }}
+
+#######################################################
+###       Remote Database Configuration Options     ###
+#######################################################
+[remote-db]
+
+# Address (host:port) of the remote database server used by the "remote"
+# db-backend, such as one run with "statectl db serve". Each database of the
+# node is hosted under its own name on the server.
+address = "{{ .RemoteDB.Address }}"
+
+# Path to the client certificate presented to the server, which must be
+# signed by a CA the server trusts
+client-certificate-file = "{{ js .RemoteDB.ClientCertificate }}"
+
+# Path to the key of the client certificate
+client-key-file = "{{ js .RemoteDB.ClientKey }}"
+
+# Path to the Root Certificate Authority used to verify the server certificate
+root-ca-file = "{{ js .RemoteDB.RootCA }}"
+
+#######################################################
+###          RocksDB Configuration Options          ###
+#######################################################
+[rocksdb]
+
+# If true, the databases of the node (blockstore, state, tx_index, ...) are
+# stored as column families of a single RocksDB instance, rocksdb.db in
+# db-dir, rather than as instances of their own. Families share the
+# write-ahead log and background threads, but are flushed, compacted and
+# tuned independently.
+# The layout of an existing db-dir can't be changed by setting this.
+column-families = {{ .RocksDB.ColumnFamilies }}
+
+# Size in bytes of the LRU block cache shared by the databases that have no
+# block-cache-share of their own.
+block-cache-size = {{ .RocksDB.BlockCacheSize }}
+
+# Block compression of databases without settings of their own, one of
+# "none", "snappy", "zlib", "lz4" or "zstd".
+compression = "{{ .RocksDB.Compression }}"
+
+# Bloom filter bits per key of databases without settings of their own.
+# 0 disables bloom filters.
+bloom-filter-bits = {{ .RocksDB.BloomFilterBits }}
+
+# Settings of individual databases, as tables named after the database, with
+# the keys:
+#   compression: as above (default "snappy")
+#   bloom-filter-bits: as above (default 0)
+#   block-cache-share: fraction of block-cache-size given to the database as
+#     a cache of its own; 0 uses the shared cache (default 0)
+# For example:
+#
+# [rocksdb.families.tx_index]
+# compression = "zstd"
+# bloom-filter-bits = 10
+# block-cache-share = 0.25
+{{- range $name, $family := .RocksDB.Families }}
+
+[rocksdb.families.{{ $name }}]
+compression = "{{ $family.Compression }}"
+bloom-filter-bits = {{ $family.BloomFilterBits }}
+block-cache-share = {{ $family.BlockCacheShare }}
+{{- end }}
 `
 
 /****** these are for test settings ***********/
@@ -34,7 +34,7 @@ import (
 // Register a test backend for PrefixDB as well, with some unrelated junk data
 func init() {
 	// nolint: errcheck
-	registerDBCreator("prefixdb", func(name, dir string) (DB, error) {
+	registerDBCreator("prefixdb", func(name, dir string, _ *Options) (DB, error) {
 		mdb := NewMemDB()
 		mdb.Set([]byte("a"), []byte{1})
 		mdb.Set([]byte("b"), []byte{2})
@@ -441,3 +441,105 @@ func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func TestDBSnapshot(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBSnapshot(t, dbType)
+		})
+	}
+}
+
+func testDBSnapshot(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+
+	snap, err := db.Snapshot()
+	if err == ErrSnapshotNotSupported {
+		t.Skip("snapshots not supported")
+	}
+	require.NoError(t, err)
+
+	// writes after the snapshot must not be visible through it
+	require.NoError(t, db.Set([]byte("a"), []byte{9}))
+	require.NoError(t, db.Delete([]byte("b")))
+	require.NoError(t, db.Set([]byte("c"), []byte{3}))
+
+	value, err := snap.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	ok, err := snap.Has([]byte("b"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = snap.Has([]byte("c"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = snap.Get(nil)
+	require.Equal(t, errKeyEmpty, err)
+
+	itr, err := snap.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	require.NoError(t, itr.Close())
+	require.Equal(t, []string{"a", "b"}, keys)
+
+	ritr, err := snap.ReverseIterator(nil, []byte("b"))
+	require.NoError(t, err)
+	keys = nil
+	for ; ritr.Valid(); ritr.Next() {
+		keys = append(keys, string(ritr.Key()))
+	}
+	require.NoError(t, ritr.Close())
+	require.Equal(t, []string{"a"}, keys)
+
+	require.NoError(t, snap.Close())
+	assertKeyValues(t, db, map[string][]byte{"a": {9}, "c": {3}})
+}
+
+func TestDBCheckpoint(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			testDBCheckpoint(t, dbType)
+		})
+	}
+}
+
+func testDBCheckpoint(t *testing.T, backend BackendType) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := t.TempDir()
+	db, err := NewDB(name, backend, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 2*checkpointBatchSize+1; i++ {
+		require.NoError(t, db.Set(int642Bytes(int64(i)), []byte{byte(i)}))
+	}
+
+	target := t.TempDir()
+	err = db.Checkpoint(target)
+	if err == ErrCheckpointNotSupported {
+		t.Skip("checkpoints not supported")
+	}
+	require.NoError(t, err)
+
+	// the target already exists now
+	require.Error(t, db.Checkpoint(target))
+
+	cdb, err := NewDB(name, backend, target)
+	require.NoError(t, err)
+	defer cdb.Close()
+	for i := 0; i < 2*checkpointBatchSize+1; i++ {
+		value, err := cdb.Get(int642Bytes(int64(i)))
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, value)
+	}
+}
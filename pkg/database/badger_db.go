@@ -26,6 +26,7 @@ package database
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -34,7 +35,7 @@ import (
 
 func init() { registerDBCreator(BadgerDBBackend, badgerDBCreator, true) }
 
-func badgerDBCreator(dbName, dir string) (DB, error) {
+func badgerDBCreator(dbName, dir string, _ *Options) (DB, error) {
 	return NewBadgerDB(dbName, dir)
 }
 
@@ -76,22 +77,27 @@ func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 		return nil, errKeyEmpty
 	}
 	var val []byte
-	err := b.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if err == badger.ErrKeyNotFound {
-			return nil
-		} else if err != nil {
-			return err
-		}
-		val, err = item.ValueCopy(nil)
-		if err == nil && val == nil {
-			val = []byte{}
-		}
+	err := b.db.View(func(txn *badger.Txn) (err error) {
+		val, err = badgerTxnGet(txn, key)
 		return err
 	})
 	return val, err
 }
 
+func badgerTxnGet(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	val, err := item.ValueCopy(nil)
+	if err == nil && val == nil {
+		val = []byte{}
+	}
+	return val, err
+}
+
 func (b *BadgerDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
 		return false, errKeyEmpty
@@ -156,7 +162,12 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	txn := b.db.NewTransaction(false)
+	return newBadgerDBIterator(b.db.NewTransaction(false), true, start, end, opts), nil
+}
+
+// newBadgerDBIterator creates an iterator within txn, which is discarded along with the
+// iterator if ownTxn is set.
+func newBadgerDBIterator(txn *badger.Txn, ownTxn bool, start, end []byte, opts badger.IteratorOptions) *badgerDBIterator {
 	iter := txn.NewIterator(opts)
 	iter.Rewind()
 	iter.Seek(start)
@@ -170,9 +181,10 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 		start:   start,
 		end:     end,
 
-		txn:  txn,
-		iter: iter,
-	}, nil
+		txn:    txn,
+		ownTxn: ownTxn,
+		iter:   iter,
+	}
 }
 
 func (b *BadgerDB) Iterator(start, end []byte) (Iterator, error) {
@@ -190,6 +202,41 @@ func (b *BadgerDB) Stats() map[string]string {
 	return nil
 }
 
+// Snapshot implements DB. A Badger snapshot is a read-only transaction, which sees the
+// database as of the moment it was opened.
+func (b *BadgerDB) Snapshot() (Snapshot, error) {
+	return &badgerDBSnapshot{txn: b.db.NewTransaction(false)}, nil
+}
+
+// Checkpoint implements DB. The database is streamed into a new Badger directory in dir
+// through Badger's backup format, which is read from a consistent snapshot.
+func (b *BadgerDB) Checkpoint(dir string) error {
+	opts := b.db.Opts()
+	path := filepath.Join(dir, filepath.Base(opts.Dir))
+	if err := checkpointTarget(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	dst, err := badger.Open(badger.DefaultOptions(path).WithLogger(nil))
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := b.db.Backup(pw, 0)
+		pw.CloseWithError(err)
+	}()
+	if err := dst.Load(pr, 256); err != nil {
+		pr.CloseWithError(err)
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 func (b *BadgerDB) NewBatch() Batch {
 	wb := &badgerDBBatch{
 		db:         b.db,
@@ -258,15 +305,18 @@ type badgerDBIterator struct {
 	reverse    bool
 	start, end []byte
 
-	txn  *badger.Txn
-	iter *badger.Iterator
+	txn    *badger.Txn
+	ownTxn bool
+	iter   *badger.Iterator
 
 	lastErr error
 }
 
 func (i *badgerDBIterator) Close() error {
 	i.iter.Close()
-	i.txn.Discard()
+	if i.ownTxn {
+		i.txn.Discard()
+	}
 	return nil
 }
 
@@ -313,3 +363,45 @@ func (i *badgerDBIterator) Value() []byte {
 	}
 	return val
 }
+
+type badgerDBSnapshot struct {
+	txn *badger.Txn
+}
+
+var _ Snapshot = (*badgerDBSnapshot)(nil)
+
+func (s *badgerDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return badgerTxnGet(s.txn, key)
+}
+
+func (s *badgerDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+func (s *badgerDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return newBadgerDBIterator(s.txn, false, start, end, badger.DefaultIteratorOptions), nil
+}
+
+func (s *badgerDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	return newBadgerDBIterator(s.txn, false, end, start, opts), nil
+}
+
+func (s *badgerDBSnapshot) Close() error {
+	s.txn.Discard()
+	return nil
+}
@@ -37,7 +37,7 @@ var (
 )
 
 func init() {
-	registerDBCreator(BoltDBBackend, func(name, dir string) (DB, error) {
+	registerDBCreator(BoltDBBackend, func(name, dir string, _ *Options) (DB, error) {
 		return NewBoltDB(name, dir)
 	}, false)
 }
@@ -200,6 +200,24 @@ func (bdb *BoltDB) NewBatch() Batch {
 	return newBoltDBBatch(bdb)
 }
 
+// Snapshot implements DB. Long-lived read transactions prevent bolt from reusing freed pages,
+// so snapshots are not offered; use Checkpoint for a consistent copy instead.
+func (bdb *BoltDB) Snapshot() (Snapshot, error) {
+	return nil, ErrSnapshotNotSupported
+}
+
+// Checkpoint implements DB. The database file is copied from within a read transaction,
+// which gives a consistent view while writes continue.
+func (bdb *BoltDB) Checkpoint(dir string) error {
+	dbPath := filepath.Join(dir, filepath.Base(bdb.db.Path()))
+	if err := checkpointTarget(dbPath); err != nil {
+		return err
+	}
+	return bdb.db.View(func(tx *bbolt.Tx) error {
+		return tx.CopyFile(dbPath, 0600)
+	})
+}
+
 // WARNING: Any concurrent writes or reads will block until the iterator is
 // closed.
 func (bdb *BoltDB) Iterator(start, end []byte) (Iterator, error) {
@@ -55,9 +55,15 @@ const (
 	RocksDBBackend BackendType = "rocksdb"
 
 	BadgerDBBackend BackendType = "badgerdb"
+	// RemoteDBBackend represents a database hosted by a remote database server
+	// (see the remote package)
+	//   - EXPERIMENTAL
+	//   - needs connection settings, so it is opened by config.DefaultDBProvider
+	//     rather than NewDB
+	RemoteDBBackend BackendType = "remote"
 )
 
-type dbCreator func(name string, dir string) (DB, error)
+type dbCreator func(name string, dir string, opts *Options) (DB, error)
 
 var backends = map[BackendType]dbCreator{}
 
@@ -70,7 +76,7 @@ func registerDBCreator(backend BackendType, creator dbCreator, force bool) {
 }
 
 // NewDB creates a new database of type backend with the given name.
-func NewDB(name string, backend BackendType, dir string) (DB, error) {
+func NewDB(name string, backend BackendType, dir string, opts ...Option) (DB, error) {
 	dbCreator, ok := backends[backend]
 	if !ok {
 		keys := make([]string, 0, len(backends))
@@ -81,7 +87,11 @@ func NewDB(name string, backend BackendType, dir string) (DB, error) {
 			backend, strings.Join(keys, ","))
 	}
 
-	db, err := dbCreator(name, dir)
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	db, err := dbCreator(name, dir, &o)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -31,14 +31,15 @@ import (
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
+	dbCreator := func(name string, dir string, _ *Options) (DB, error) {
 		return NewGoLevelDB(name, dir)
 	}
 	registerDBCreator(GoLevelDBBackend, dbCreator, false)
 }
 
 type GoLevelDB struct {
-	db *leveldb.DB
+	name string
+	db   *leveldb.DB
 }
 
 var _ DB = (*GoLevelDB)(nil)
@@ -54,7 +55,8 @@ func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB,
 		return nil, err
 	}
 	database := &GoLevelDB{
-		db: db,
+		name: name,
+		db:   db,
 	}
 	return database, nil
 }
@@ -212,3 +214,92 @@ func (db *GoLevelDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	itr := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
 	return newGoLevelDBIterator(itr, start, end, true), nil
 }
+
+// Snapshot implements DB.
+func (db *GoLevelDB) Snapshot() (Snapshot, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelDBSnapshot{snap: snap}, nil
+}
+
+// Checkpoint implements DB. goleveldb has no native checkpoints, so the contents of a
+// snapshot are copied into a new database.
+func (db *GoLevelDB) Checkpoint(dir string) error {
+	dbPath := filepath.Join(dir, db.name+".db")
+	if err := checkpointTarget(dbPath); err != nil {
+		return err
+	}
+	snap, err := db.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Close()
+
+	ldb, err := leveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		return err
+	}
+	dst := &GoLevelDB{name: db.name, db: ldb}
+	if err := copySnapshot(snap, dst); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// goLevelDBSnapshot wraps a goleveldb snapshot.
+type goLevelDBSnapshot struct {
+	snap *leveldb.Snapshot
+}
+
+var _ Snapshot = (*goLevelDBSnapshot)(nil)
+
+// Get implements Snapshot.
+func (s *goLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.snap.Get(key, nil)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// Has implements Snapshot.
+func (s *goLevelDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements Snapshot.
+func (s *goLevelDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snap.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return newGoLevelDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *goLevelDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snap.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return newGoLevelDBIterator(itr, start, end, true), nil
+}
+
+// Close implements Snapshot.
+func (s *goLevelDBSnapshot) Close() error {
+	s.snap.Release()
+	return nil
+}
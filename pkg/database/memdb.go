@@ -34,7 +34,7 @@ const (
 )
 
 func init() {
-	registerDBCreator(MemDBBackend, func(name, dir string) (DB, error) {
+	registerDBCreator(MemDBBackend, func(name, dir string, _ *Options) (DB, error) {
 		return NewMemDB(), nil
 	}, false)
 }
@@ -225,3 +225,49 @@ func (db *MemDB) ReverseIteratorNoMtx(start, end []byte) (Iterator, error) {
 	}
 	return newMemDBIteratorMtxChoice(db, start, end, true, false), nil
 }
+
+// Snapshot implements DB. The B-tree is cloned copy-on-write, so taking a snapshot is cheap
+// and later writes to the database copy only the nodes they touch.
+func (db *MemDB) Snapshot() (Snapshot, error) {
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	return &memDBSnapshot{db: &MemDB{btree: db.btree.Clone()}}, nil
+}
+
+// Checkpoint implements DB. An in-memory database has no files to checkpoint.
+func (db *MemDB) Checkpoint(dir string) error {
+	return ErrCheckpointNotSupported
+}
+
+// memDBSnapshot is a read-only view over a copy-on-write clone of a MemDB.
+type memDBSnapshot struct {
+	db *MemDB
+}
+
+var _ Snapshot = (*memDBSnapshot)(nil)
+
+// Get implements Snapshot.
+func (s *memDBSnapshot) Get(key []byte) ([]byte, error) {
+	return s.db.Get(key)
+}
+
+// Has implements Snapshot.
+func (s *memDBSnapshot) Has(key []byte) (bool, error) {
+	return s.db.Has(key)
+}
+
+// Iterator implements Snapshot.
+func (s *memDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	return s.db.Iterator(start, end)
+}
+
+// ReverseIterator implements Snapshot.
+func (s *memDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	return s.db.ReverseIterator(start, end)
+}
+
+// Close implements Snapshot.
+func (s *memDBSnapshot) Close() error {
+	return nil
+}
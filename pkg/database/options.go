@@ -0,0 +1,146 @@
+package database
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Options holds the backend specific settings of a database opened with
+// NewDB. Backends ignore the settings of other backends.
+type Options struct {
+	// RocksDB configures the rocksdb backend. A nil value opens each
+	// database as its own RocksDB instance with the default tuning.
+	RocksDB *RocksDBOptions
+}
+
+// Option sets an optional parameter on the Options of NewDB.
+type Option func(*Options)
+
+// WithRocksDBOptions sets the layout and tuning of rocksdb databases.
+func WithRocksDBOptions(opts *RocksDBOptions) Option {
+	return func(o *Options) { o.RocksDB = opts }
+}
+
+// RocksDB compression algorithms accepted by RocksDBFamilyOptions.
+const (
+	RocksDBNoCompression     = "none"
+	RocksDBSnappyCompression = "snappy"
+	RocksDBZlibCompression   = "zlib"
+	RocksDBLZ4Compression    = "lz4"
+	RocksDBZstdCompression   = "zstd"
+)
+
+// RocksDBSharedName is the name of the RocksDB instance that holds the
+// databases of a directory when they are laid out as column families.
+const RocksDBSharedName = "rocksdb"
+
+// RocksDBOptions configures the layout of rocksdb databases and the tuning
+// of their column families.
+type RocksDBOptions struct {
+	// ColumnFamilies stores every database of a directory as a column family
+	// of one RocksDB instance, named RocksDBSharedName, rather than as a
+	// RocksDB instance of its own. The families share the write-ahead log
+	// and background threads but are flushed and compacted independently.
+	ColumnFamilies bool
+
+	// BlockCacheSize is the size in bytes of the LRU block cache shared by
+	// the families that don't set a BlockCacheShare.
+	BlockCacheSize uint64
+
+	// Default tunes the families that have no entry in Families.
+	Default RocksDBFamilyOptions
+
+	// Families tunes the family of each database, by database name.
+	Families map[string]RocksDBFamilyOptions
+}
+
+// RocksDBFamilyOptions tunes a single column family.
+type RocksDBFamilyOptions struct {
+	// Compression is the block compression algorithm, one of "none",
+	// "snappy", "zlib", "lz4" or "zstd". Empty means snappy.
+	Compression string
+
+	// BloomFilterBits is the number of bloom filter bits per key. Zero
+	// disables bloom filters.
+	BloomFilterBits int
+
+	// BlockCacheShare is the fraction of BlockCacheSize given to the family
+	// as a block cache of its own. Zero uses the shared block cache.
+	BlockCacheShare float64
+}
+
+// DefaultRocksDBOptions returns the tuning rocksdb databases have always had:
+// one instance per database, a 1GB block cache, snappy compression and 10
+// bloom filter bits per key.
+func DefaultRocksDBOptions() *RocksDBOptions {
+	return &RocksDBOptions{
+		BlockCacheSize: 1 << 30,
+		Default: RocksDBFamilyOptions{
+			Compression:     RocksDBSnappyCompression,
+			BloomFilterBits: 10,
+		},
+	}
+}
+
+// Family returns the options of the family of the named database.
+func (o *RocksDBOptions) Family(name string) RocksDBFamilyOptions {
+	if fo, ok := o.Families[name]; ok {
+		return fo
+	}
+	return o.Default
+}
+
+// Validate performs basic validation of the options.
+func (o *RocksDBOptions) Validate() error {
+	if err := o.Default.Validate(); err != nil {
+		return fmt.Errorf("default family: %w", err)
+	}
+	var shares float64
+	for name, fo := range o.Families {
+		if err := fo.Validate(); err != nil {
+			return fmt.Errorf("family %q: %w", name, err)
+		}
+		shares += fo.BlockCacheShare
+	}
+	if shares > 1 {
+		return errors.New("block cache shares can't add up to more than 1")
+	}
+	return nil
+}
+
+// Validate performs basic validation of the family options.
+func (o RocksDBFamilyOptions) Validate() error {
+	switch o.Compression {
+	case "", RocksDBNoCompression, RocksDBSnappyCompression, RocksDBZlibCompression,
+		RocksDBLZ4Compression, RocksDBZstdCompression:
+	default:
+		return fmt.Errorf("unknown compression %q", o.Compression)
+	}
+	if o.BloomFilterBits < 0 {
+		return errors.New("bloom filter bits can't be negative")
+	}
+	if o.BlockCacheShare < 0 || o.BlockCacheShare > 1 {
+		return errors.New("block cache share must be between 0 and 1")
+	}
+	return nil
+}
@@ -222,3 +222,87 @@ func (pdb *PrefixDB) Stats() map[string]string {
 func (pdb *PrefixDB) prefixed(key []byte) []byte {
 	return append(cp(pdb.prefix), key...)
 }
+
+// Snapshot implements DB. The snapshot is taken of the underlying database and restricted to
+// the prefix.
+func (pdb *PrefixDB) Snapshot() (Snapshot, error) {
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+
+	snap, err := pdb.db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &prefixDBSnapshot{prefix: pdb.prefix, snap: snap}, nil
+}
+
+// Checkpoint implements DB. A checkpoint of the underlying database would include keys
+// outside the prefix, so it is not supported.
+func (pdb *PrefixDB) Checkpoint(dir string) error {
+	return ErrCheckpointNotSupported
+}
+
+// prefixDBSnapshot restricts a snapshot of the underlying database to a prefix.
+type prefixDBSnapshot struct {
+	prefix []byte
+	snap   Snapshot
+}
+
+var _ Snapshot = (*prefixDBSnapshot)(nil)
+
+// Get implements Snapshot.
+func (s *prefixDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return s.snap.Get(append(cp(s.prefix), key...))
+}
+
+// Has implements Snapshot.
+func (s *prefixDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return s.snap.Has(append(cp(s.prefix), key...))
+}
+
+// Iterator implements Snapshot.
+func (s *prefixDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	pstart, pend := s.domain(start, end)
+	itr, err := s.snap.Iterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.prefix, start, end, itr)
+}
+
+// ReverseIterator implements Snapshot.
+func (s *prefixDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	pstart, pend := s.domain(start, end)
+	ritr, err := s.snap.ReverseIterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.prefix, start, end, ritr)
+}
+
+// Close implements Snapshot.
+func (s *prefixDBSnapshot) Close() error {
+	return s.snap.Close()
+}
+
+func (s *prefixDBSnapshot) domain(start, end []byte) (pstart, pend []byte) {
+	pstart = append(cp(s.prefix), start...)
+	if end == nil {
+		pend = cpIncr(s.prefix)
+	} else {
+		pend = append(cp(s.prefix), end...)
+	}
+	return pstart, pend
+}
@@ -21,8 +21,10 @@ package remote
 // THE SOFTWARE.
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	v1 "github.com/bhojpur/state/pkg/api/v1/database"
 	db "github.com/bhojpur/state/pkg/database"
@@ -30,9 +32,21 @@ import (
 
 var errBatchClosed = errors.New("batch has been written or closed")
 
+// batchChunkSize is the number of operations sent per message of a batch
+// stream.
+const batchChunkSize = 1000
+
+// batch streams its operations to the server in chunks as they are added, so
+// that neither side needs to hold a large batch in a single message. The
+// server writes the batch once the stream is closed by Write or WriteSync,
+// and discards it if the stream is canceled by Close.
 type batch struct {
-	db  *RemoteDB
-	ops []*v1.Operation
+	db     *RemoteDB
+	ops    []*v1.Operation
+	closed bool
+
+	stream v1.DB_BatchWriteStreamClient
+	cancel context.CancelFunc
 }
 
 var _ db.Batch = (*batch)(nil)
@@ -46,59 +60,93 @@ func newBatch(rdb *RemoteDB) *batch {
 
 // Set implements Batch.
 func (b *batch) Set(key, value []byte) error {
-	if b.ops == nil {
+	if b.closed {
 		return errBatchClosed
 	}
 	op := &v1.Operation{
 		Entity: &v1.Entity{Key: key, Value: value},
 		Type:   v1.Operation_SET,
 	}
-	b.ops = append(b.ops, op)
-	return nil
+	return b.add(op)
 }
 
 // Delete implements Batch.
 func (b *batch) Delete(key []byte) error {
-	if b.ops == nil {
+	if b.closed {
 		return errBatchClosed
 	}
 	op := &v1.Operation{
 		Entity: &v1.Entity{Key: key},
 		Type:   v1.Operation_DELETE,
 	}
+	return b.add(op)
+}
+
+func (b *batch) add(op *v1.Operation) error {
 	b.ops = append(b.ops, op)
+	if len(b.ops) < batchChunkSize {
+		return nil
+	}
+	if err := b.send(false); err != nil {
+		return fmt.Errorf("remoteDB.BatchWriteStream: %w", err)
+	}
 	return nil
 }
 
-// Write implements Batch.
-func (b *batch) Write() error {
-	if b.ops == nil {
-		return errBatchClosed
+// send sends the pending operations to the server, opening the stream first
+// if needed.
+func (b *batch) send(sync bool) error {
+	if b.stream == nil {
+		ctx, cancel := context.WithCancel(b.db.ctx)
+		stream, err := b.db.dc.BatchWriteStream(ctx)
+		if err != nil {
+			cancel()
+			return err
+		}
+		b.stream, b.cancel = stream, cancel
 	}
-	_, err := b.db.dc.BatchWrite(b.db.ctx, &v1.Batch{Ops: b.ops})
-	if err != nil {
-		return fmt.Errorf("remoteDB.BatchWrite: %w", err)
+	err := b.stream.Send(&v1.Batch{Ops: b.ops, Sync: sync})
+	b.ops = []*v1.Operation{}
+	if err == io.EOF {
+		// the server ended the stream, its status tells why
+		_, err = b.stream.CloseAndRecv()
 	}
-	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
-	b.Close()
-	return nil
+	return err
+}
+
+// Write implements Batch.
+func (b *batch) Write() error {
+	return b.write(false)
 }
 
 // WriteSync implements Batch.
 func (b *batch) WriteSync() error {
-	if b.ops == nil {
+	return b.write(true)
+}
+
+func (b *batch) write(sync bool) error {
+	if b.closed {
 		return errBatchClosed
 	}
-	_, err := b.db.dc.BatchWriteSync(b.db.ctx, &v1.Batch{Ops: b.ops})
-	if err != nil {
-		return fmt.Errorf("RemoteDB.BatchWriteSync: %w", err)
-	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
-	return b.Close()
+	defer b.Close()
+
+	if err := b.send(sync); err != nil {
+		return fmt.Errorf("remoteDB.BatchWriteStream: %w", err)
+	}
+	if _, err := b.stream.CloseAndRecv(); err != nil {
+		return fmt.Errorf("remoteDB.BatchWriteStream: %w", err)
+	}
+	return nil
 }
 
-// Close implements Batch.
+// Close implements Batch. Closing a batch that has not been written discards
+// the operations already streamed to the server.
 func (b *batch) Close() error {
+	b.closed = true
 	b.ops = nil
+	if b.cancel != nil {
+		b.cancel()
+	}
 	return nil
 }
@@ -25,43 +25,51 @@ import (
 	"errors"
 	"fmt"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
 	v1 "github.com/bhojpur/state/pkg/api/v1/database"
 	db "github.com/bhojpur/state/pkg/database"
 	"github.com/bhojpur/state/pkg/database/remote/rpc"
 )
 
+// RemoteDB is a database hosted by a remote database server, see the rpc
+// package. The server may host several databases, each selected by name.
 type RemoteDB struct {
 	ctx context.Context
+	cc  *grpc.ClientConn
 	dc  v1.DBClient
 }
 
-func NewRemoteDB(serverAddr string, serverKey string) (*RemoteDB, error) {
-	return newRemoteDB(rpc.NewClient(serverAddr, serverKey))
-}
-
-func newRemoteDB(gdc v1.DBClient, err error) (*RemoteDB, error) {
+// NewRemoteDB connects to the database called name on the server at
+// serverAddr, securing the connection with creds, which should be created
+// with rpc.ClientCredentials.
+func NewRemoteDB(serverAddr, name string, creds credentials.TransportCredentials) (*RemoteDB, error) {
+	if err := rpc.ValidateDBName(name); err != nil {
+		return nil, err
+	}
+	cc, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, err
 	}
-	return &RemoteDB{dc: gdc, ctx: context.Background()}, nil
-}
-
-type Init struct {
-	Dir  string
-	Name string
-	Type string
-}
-
-func (rd *RemoteDB) InitRemote(in *Init) error {
-	_, err := rd.dc.Init(rd.ctx, &v1.Init{Dir: in.Dir, Type: in.Type, Name: in.Name})
-	return err
+	rd := &RemoteDB{
+		ctx: metadata.AppendToOutgoingContext(context.Background(), rpc.DBNameKey, name),
+		cc:  cc,
+		dc:  v1.NewDBClient(cc),
+	}
+	if _, err := rd.dc.Init(rd.ctx, &v1.Init{Name: name}); err != nil {
+		cc.Close()
+		return nil, fmt.Errorf("remoteDB.Init: %w", err)
+	}
+	return rd, nil
 }
 
 var _ db.DB = (*RemoteDB)(nil)
 
-// Close is a noop currently
+// Close closes the connection to the server.
 func (rd *RemoteDB) Close() error {
-	return nil
+	return rd.cc.Close()
 }
 
 func (rd *RemoteDB) Delete(key []byte) error {
@@ -134,6 +142,17 @@ func (rd *RemoteDB) Stats() map[string]string {
 	return stats.Data
 }
 
+// Snapshot is not supported over the remote protocol.
+func (rd *RemoteDB) Snapshot() (db.Snapshot, error) {
+	return nil, db.ErrSnapshotNotSupported
+}
+
+// Checkpoint is not supported over the remote protocol; checkpoint the database on the
+// server instead.
+func (rd *RemoteDB) Checkpoint(dir string) error {
+	return db.ErrCheckpointNotSupported
+}
+
 func (rd *RemoteDB) Iterator(start, end []byte) (db.Iterator, error) {
 	dic, err := rd.dc.Iterator(rd.ctx, &v1.Entity{Start: start, End: end})
 	if err != nil {
@@ -21,36 +21,52 @@ package remote_test
 // THE SOFTWARE.
 
 import (
+	"fmt"
 	"net"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	db "github.com/bhojpur/state/pkg/database"
 	"github.com/bhojpur/state/pkg/database/remote"
 	"github.com/bhojpur/state/pkg/database/remote/rpc"
 )
 
-func TestRemoteDB(t *testing.T) {
-	cert := "test.crt"
-	key := "test.key"
-	ln, err := net.Listen("tcp", "localhost:0")
+// startServer starts a server requiring client certificates, backed by store,
+// and returns its address.
+func startServer(t *testing.T, store db.DB) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	require.Nil(t, err, "expecting a port to have been assigned on which we can listen")
-	srv, err := rpc.NewServer(cert, key)
-	require.Nil(t, err)
-	defer srv.Stop()
+	creds, err := rpc.ServerCredentials("testdata/server.crt", "testdata/server.key", "testdata/ca.crt")
+	require.NoError(t, err)
+	srv := rpc.NewServer(store, creds)
+	t.Cleanup(srv.Stop)
 	go func() {
 		if err := srv.Serve(ln); err != nil {
 			panic(err)
 		}
 	}()
+	return ln.Addr().String()
+}
+
+func newClient(t *testing.T, addr, name string) *remote.RemoteDB {
+	t.Helper()
 
-	client, err := remote.NewRemoteDB(ln.Addr().String(), cert)
+	creds, err := rpc.ClientCredentials("testdata/client.crt", "testdata/client.key", "testdata/ca.crt")
+	require.NoError(t, err)
+	client, err := remote.NewRemoteDB(addr, name, creds)
 	require.Nil(t, err, "expecting a successful client creation")
-	dbName := "test-remote-db"
-	require.Nil(t, client.InitRemote(&remote.Init{Name: dbName, Type: "goleveldb"}))
-	defer os.RemoveAll(dbName + ".db")
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRemoteDB(t *testing.T) {
+	store, err := db.NewDB("test-remote-db", db.GoLevelDBBackend, t.TempDir())
+	require.NoError(t, err)
+	client := newClient(t, startServer(t, store), "test")
 
 	k1 := []byte("key-1")
 	v1, err := client.Get(k1)
@@ -185,3 +201,87 @@ func TestRemoteDB(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, rv5, v5, "expecting k5 to have been stored")
 }
+
+func TestRemoteDBNamespaces(t *testing.T) {
+	store := db.NewMemDB()
+	addr := startServer(t, store)
+	blocks := newClient(t, addr, "blockstore")
+	state := newClient(t, addr, "state")
+
+	require.NoError(t, blocks.Set([]byte("key"), []byte("block")))
+	require.NoError(t, state.Set([]byte("key"), []byte("state")))
+
+	value, err := blocks.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("block"), value)
+	value, err = state.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("state"), value)
+
+	// the databases are kept under their prefix in the store
+	value, err = store.Get([]byte("blockstore/key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("block"), value)
+
+	itr, err := state.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	require.NoError(t, itr.Close())
+	require.Equal(t, []string{"key"}, keys)
+
+	creds, err := rpc.ClientCredentials("testdata/client.crt", "testdata/client.key", "testdata/ca.crt")
+	require.NoError(t, err)
+	_, err = remote.NewRemoteDB(addr, "a/b", creds)
+	require.Error(t, err, "names with a slash would overlap other namespaces")
+}
+
+func TestRemoteDBClientAuth(t *testing.T) {
+	addr := startServer(t, db.NewMemDB())
+
+	// without a client certificate, the server rejects the connection
+	creds, err := rpc.ClientCredentials("", "", "testdata/ca.crt")
+	require.NoError(t, err)
+	_, err = remote.NewRemoteDB(addr, "test", creds)
+	require.Error(t, err)
+
+	// a client that does not trust the server refuses it
+	creds, err = rpc.ClientCredentials("testdata/client.crt", "testdata/client.key", "testdata/client.crt")
+	require.NoError(t, err)
+	_, err = remote.NewRemoteDB(addr, "test", creds)
+	require.Error(t, err)
+}
+
+func TestRemoteDBStreamedBatch(t *testing.T) {
+	client := newClient(t, startServer(t, db.NewMemDB()), "test")
+
+	const n = 2500 // several chunks
+	bat := client.NewBatch()
+	for i := 0; i < n; i++ {
+		require.NoError(t, bat.Set([]byte(fmt.Sprintf("key-%04d", i)), []byte{byte(i)}))
+	}
+	// streamed chunks are not written before the batch is
+	has, err := client.Has([]byte("key-0000"))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	require.NoError(t, bat.WriteSync())
+	require.Error(t, bat.Set([]byte("key"), []byte{1}))
+	require.NoError(t, bat.Close())
+
+	value, err := client.Get([]byte(fmt.Sprintf("key-%04d", n-1)))
+	require.NoError(t, err)
+	require.Equal(t, []byte{byte((n - 1) % 256)}, value)
+
+	// a closed batch discards the chunks it streamed
+	bat = client.NewBatch()
+	for i := 0; i < n; i++ {
+		require.NoError(t, bat.Delete([]byte(fmt.Sprintf("key-%04d", i))))
+	}
+	require.NoError(t, bat.Close())
+	has, err = client.Has([]byte("key-0000"))
+	require.NoError(t, err)
+	require.True(t, has)
+}
@@ -21,22 +21,82 @@ package rpc
 // THE SOFTWARE.
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
 	v1 "github.com/bhojpur/state/pkg/api/v1/database"
 )
 
-// NewClient creates a gRPC client connected to the bound gRPC server at serverAddr.
-// Use kind to set the level of security to either Secure or Insecure.
-func NewClient(serverAddr, serverCert string) (v1.DBClient, error) {
-	creds, err := credentials.NewClientTLSFromFile(serverCert, "")
+// NewClient creates a gRPC client connected to the bound gRPC server at serverAddr,
+// secured with creds.
+func NewClient(serverAddr string, creds credentials.TransportCredentials) (v1.DBClient, error) {
+	cc, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return nil, err
 	}
-	cc, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(creds))
+	return v1.NewDBClient(cc), nil
+}
+
+// ClientCredentials returns the transport credentials of a client that trusts
+// server certificates signed by the CA in caFile. If certFile and keyFile are
+// set, the client presents that certificate, as servers requiring client
+// authentication expect.
+func ClientCredentials(certFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	pool, err := loadCertPool(caFile)
 	if err != nil {
 		return nil, err
 	}
-	return v1.NewDBClient(cc), nil
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS13,
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load X.509 key pair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// ServerCredentials returns the transport credentials of a server presenting
+// the certificate in certFile. If clientCAFile is set, clients must present a
+// certificate signed by a CA in that file.
+func ServerCredentials(certFile, keyFile, clientCAFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load X.509 key pair: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		tlsConfig.ClientCAs = pool
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	bs, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bs) {
+		return nil, errors.New("failed to append CA certificates")
+	}
+	return pool, nil
 }
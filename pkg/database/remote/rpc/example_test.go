@@ -25,31 +25,42 @@ import (
 	"context"
 	"log"
 
+	"google.golang.org/grpc/metadata"
+
 	v1 "github.com/bhojpur/state/pkg/api/v1/database"
+	db "github.com/bhojpur/state/pkg/database"
 	grpcdb "github.com/bhojpur/state/pkg/database/remote/rpc"
 )
 
 func Example() {
 	addr := ":8998"
-	cert := "server.crt"
-	key := "server.key"
+	serverCreds, err := grpcdb.ServerCredentials("server.crt", "server.key", "ca.crt")
+	if err != nil {
+		log.Fatalf("ServerCredentials: %v", err)
+	}
+	store, err := db.NewDB("grpc-store", db.GoLevelDBBackend, ".")
+	if err != nil {
+		log.Fatalf("NewDB: %v", err)
+	}
 	go func() {
-		if err := grpcdb.ListenAndServe(addr, cert, key); err != nil {
+		if err := grpcdb.ListenAndServe(addr, store, serverCreds); err != nil {
 			log.Fatalf("BindServer: %v", err)
 		}
 	}()
 
-	client, err := grpcdb.NewClient(addr, cert)
+	clientCreds, err := grpcdb.ClientCredentials("client.crt", "client.key", "ca.crt")
+	if err != nil {
+		log.Fatalf("ClientCredentials: %v", err)
+	}
+	client, err := grpcdb.NewClient(addr, clientCreds)
 	if err != nil {
 		log.Fatalf("Failed to create grpcDB client: %v", err)
 	}
 
-	ctx := context.Background()
-	// 1. Initialize the DB
+	// 1. Initialize the DB, and name it in every request
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcdb.DBNameKey, "grpc-uno-test")
 	in := &v1.Init{
-		Type: "leveldb",
 		Name: "grpc-uno-test",
-		Dir:  ".",
 	}
 	if _, err := client.Init(ctx, in); err != nil {
 		log.Fatalf("Init error: %v", err)
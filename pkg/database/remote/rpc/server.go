@@ -22,77 +22,115 @@ package rpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	v1 "github.com/bhojpur/state/pkg/api/v1/database"
 	db "github.com/bhojpur/state/pkg/database"
 )
 
+// DBNameKey is the gRPC metadata key naming the database a request operates on.
+const DBNameKey = "x-db-name"
+
 // ListenAndServe is a blocking function that sets up a gRPC based
 // server at the address supplied, with the gRPC options passed in.
 // Normally in usage, invoke it in a goroutine like you would for http.ListenAndServe.
-func ListenAndServe(addr, cert, key string, opts ...grpc.ServerOption) error {
+func ListenAndServe(addr string, store db.DB, creds credentials.TransportCredentials, opts ...grpc.ServerOption) error {
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
-	srv, err := NewServer(cert, key, opts...)
-	if err != nil {
-		return err
-	}
-	return srv.Serve(ln)
+	return NewServer(store, creds, opts...).Serve(ln)
 }
 
-func NewServer(cert, key string, opts ...grpc.ServerOption) (*grpc.Server, error) {
-	creds, err := credentials.NewServerTLSFromFile(cert, key)
-	if err != nil {
-		return nil, err
-	}
+// NewServer creates a gRPC server hosting named databases, which are kept in
+// store under the prefix "<name>/". Connections are secured with creds, which
+// should be created with ServerCredentials.
+func NewServer(store db.DB, creds credentials.TransportCredentials, opts ...grpc.ServerOption) *grpc.Server {
 	opts = append(opts, grpc.Creds(creds))
 	srv := grpc.NewServer(opts...)
-	v1.RegisterDBServer(srv, new(server))
-	return srv, nil
+	v1.RegisterDBServer(srv, &server{
+		store: store,
+		dbs:   make(map[string]db.DB),
+	})
+	return srv
+}
+
+// ValidateDBName returns an error if name cannot name a database of a server.
+// Names may not contain "/", so that the prefixes of two databases never
+// overlap.
+func ValidateDBName(name string) error {
+	if name == "" {
+		return errors.New("database name can't be empty")
+	}
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("database name %q can't contain \"/\"", name)
+	}
+	return nil
 }
 
 type server struct {
 	v1.UnimplementedDBServer
-	mu sync.Mutex
-	db db.DB
+	store db.DB
+
+	mu  sync.Mutex
+	dbs map[string]db.DB
 }
 
 var _ v1.DBServer = (*server)(nil)
 
-// Init initializes the server's database. Only one type of database
-// can be initialized per server.
-//
-// Dir is the directory on the file system in which the DB will be stored(if backed by disk) (TODO: remove)
-//
-// Name is representative filesystem entry's basepath
-//
-// Type can be either one of:
-//  * cleveldb (if built with gcc enabled)
-//  * fsdb
-//  * memdB
-//  * goleveldb
-func (s *server) Init(ctx context.Context, in *v1.Init) (*v1.Entity, error) {
+// open returns the database with the given name, namespaced within the store.
+func (s *server) open(name string) (db.DB, error) {
+	if err := ValidateDBName(name); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var err error
-	s.db, err = db.NewDB(in.Name, db.BackendType(in.Type), in.Dir)
-	if err != nil {
+	ndb, ok := s.dbs[name]
+	if !ok {
+		ndb = db.NewPrefixDB(s.store, []byte(name+"/"))
+		s.dbs[name] = ndb
+	}
+	return ndb, nil
+}
+
+// db returns the database named by the metadata of the request.
+func (s *server) db(ctx context.Context) (db.DB, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	names := md.Get(DBNameKey)
+	if len(names) != 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "requests must name exactly one database in %q", DBNameKey)
+	}
+	return s.open(names[0])
+}
+
+// Init opens the named database of the server. Type and Dir are ignored, as
+// all databases of the server are kept in its store.
+func (s *server) Init(ctx context.Context, in *v1.Init) (*v1.Entity, error) {
+	if _, err := s.open(in.Name); err != nil {
 		return nil, err
 	}
 	return &v1.Entity{CreatedAt: time.Now().Unix()}, nil
 }
 
 func (s *server) Delete(ctx context.Context, in *v1.Entity) (*v1.Nothing, error) {
-	err := s.db.Delete(in.Key)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = ndb.Delete(in.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +140,11 @@ func (s *server) Delete(ctx context.Context, in *v1.Entity) (*v1.Nothing, error)
 var nothing = new(v1.Nothing)
 
 func (s *server) DeleteSync(ctx context.Context, in *v1.Entity) (*v1.Nothing, error) {
-	err := s.db.DeleteSync(in.Key)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = ndb.DeleteSync(in.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +152,11 @@ func (s *server) DeleteSync(ctx context.Context, in *v1.Entity) (*v1.Nothing, er
 }
 
 func (s *server) Get(ctx context.Context, in *v1.Entity) (*v1.Entity, error) {
-	value, err := s.db.Get(in.Key)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := ndb.Get(in.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +168,7 @@ func (s *server) GetStream(ds v1.DB_GetStreamServer) error {
 	responsesChan := make(chan *v1.Entity)
 	go func() {
 		defer close(responsesChan)
-		ctx := context.Background()
+		ctx := ds.Context()
 		for {
 			in, err := ds.Recv()
 			if err != nil {
@@ -155,7 +201,11 @@ func (s *server) GetStream(ds v1.DB_GetStreamServer) error {
 }
 
 func (s *server) Has(ctx context.Context, in *v1.Entity) (*v1.Entity, error) {
-	exists, err := s.db.Has(in.Key)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := ndb.Has(in.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +213,11 @@ func (s *server) Has(ctx context.Context, in *v1.Entity) (*v1.Entity, error) {
 }
 
 func (s *server) Set(ctx context.Context, in *v1.Entity) (*v1.Nothing, error) {
-	err := s.db.Set(in.Key, in.Value)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = ndb.Set(in.Key, in.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +225,11 @@ func (s *server) Set(ctx context.Context, in *v1.Entity) (*v1.Nothing, error) {
 }
 
 func (s *server) SetSync(ctx context.Context, in *v1.Entity) (*v1.Nothing, error) {
-	err := s.db.SetSync(in.Key, in.Value)
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = ndb.SetSync(in.Key, in.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +237,11 @@ func (s *server) SetSync(ctx context.Context, in *v1.Entity) (*v1.Nothing, error
 }
 
 func (s *server) Iterator(query *v1.Entity, dis v1.DB_IteratorServer) error {
-	it, err := s.db.Iterator(query.Start, query.End)
+	ndb, err := s.db(dis.Context())
+	if err != nil {
+		return err
+	}
+	it, err := ndb.Iterator(query.Start, query.End)
 	if err != nil {
 		return err
 	}
@@ -211,7 +273,11 @@ func (s *server) handleIterator(it db.Iterator, sendFunc func(*v1.Iterator) erro
 }
 
 func (s *server) ReverseIterator(query *v1.Entity, dis v1.DB_ReverseIteratorServer) error {
-	it, err := s.db.ReverseIterator(query.Start, query.End)
+	ndb, err := s.db(dis.Context())
+	if err != nil {
+		return err
+	}
+	it, err := ndb.ReverseIterator(query.Start, query.End)
 	if err != nil {
 		return err
 	}
@@ -219,8 +285,12 @@ func (s *server) ReverseIterator(query *v1.Entity, dis v1.DB_ReverseIteratorServ
 	return s.handleIterator(it, dis.Send)
 }
 
-func (s *server) Stats(context.Context, *v1.Nothing) (*v1.Stats, error) {
-	stats := s.db.Stats()
+func (s *server) Stats(ctx context.Context, _ *v1.Nothing) (*v1.Stats, error) {
+	ndb, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := ndb.Stats()
 	return &v1.Stats{Data: stats, TimeAt: time.Now().Unix()}, nil
 }
 
@@ -233,32 +303,73 @@ func (s *server) BatchWriteSync(c context.Context, b *v1.Batch) (*v1.Nothing, er
 }
 
 func (s *server) batchWrite(c context.Context, b *v1.Batch, sync bool) (*v1.Nothing, error) {
-	bat := s.db.NewBatch()
+	ndb, err := s.db(c)
+	if err != nil {
+		return nil, err
+	}
+	bat := ndb.NewBatch()
+	defer bat.Close()
+	if err := addOps(bat, b.Ops); err != nil {
+		return nil, err
+	}
+	if err := writeBatch(bat, sync); err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
+
+// BatchWriteStream writes the operations of all messages received on the
+// stream as one batch, once the client closes the stream. If the stream
+// fails before, nothing is written.
+func (s *server) BatchWriteStream(bs v1.DB_BatchWriteStreamServer) error {
+	ndb, err := s.db(bs.Context())
+	if err != nil {
+		return err
+	}
+	bat := ndb.NewBatch()
 	defer bat.Close()
-	for _, op := range b.Ops {
+
+	sync := false
+	for {
+		b, err := bs.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := addOps(bat, b.Ops); err != nil {
+			return err
+		}
+		sync = b.Sync
+	}
+	if err := writeBatch(bat, sync); err != nil {
+		return err
+	}
+	return bs.SendAndClose(nothing)
+}
+
+func addOps(bat db.Batch, ops []*v1.Operation) error {
+	for _, op := range ops {
 		switch op.Type {
 		case v1.Operation_SET:
 			err := bat.Set(op.Entity.Key, op.Entity.Value)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case v1.Operation_DELETE:
 			err := bat.Delete(op.Entity.Key)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+func writeBatch(bat db.Batch, sync bool) error {
 	if sync {
-		err := bat.WriteSync()
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		err := bat.Write()
-		if err != nil {
-			return nil, err
-		}
+		return bat.WriteSync()
 	}
-	return nothing, nil
+	return bat.Write()
 }
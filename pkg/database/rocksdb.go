@@ -26,21 +26,32 @@ package database
 import (
 	"fmt"
 	"path/filepath"
-	"runtime"
 
 	"github.com/bhojpur/gorocksdb"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewRocksDB(name, dir)
+	dbCreator := func(name string, dir string, opts *Options) (DB, error) {
+		switch {
+		case opts.RocksDB == nil:
+			return NewRocksDB(name, dir)
+		case opts.RocksDB.ColumnFamilies:
+			return NewRocksDBColumnFamily(name, dir, opts.RocksDB)
+		default:
+			return NewRocksDBWithTuning(name, dir, opts.RocksDB)
+		}
 	}
 	registerDBCreator(RocksDBBackend, dbCreator, false)
 }
 
-// RocksDB is a RocksDB backend.
+// RocksDB is a RocksDB backend. It is either a RocksDB instance of its own,
+// or a column family of an instance shared with the other databases of its
+// directory.
 type RocksDB struct {
+	name   string
+	inst   *rocksDBInstance
 	db     *gorocksdb.DB
+	cf     *gorocksdb.ColumnFamilyHandle
 	ro     *gorocksdb.ReadOptions
 	wo     *gorocksdb.WriteOptions
 	woSync *gorocksdb.WriteOptions
@@ -52,38 +63,84 @@ func NewRocksDB(name string, dir string) (*RocksDB, error) {
 	// default rocksdb option, good enough for most cases, including heavy workloads.
 	// 1GB table cache, 512MB write buffer(may use 50% more on heavy workloads).
 	// compression: snappy as default, need to -lsnappy to enable.
-	bbto := gorocksdb.NewDefaultBlockBasedTableOptions()
-	bbto.SetBlockCache(gorocksdb.NewLRUCache(1 << 30))
-	bbto.SetFilterPolicy(gorocksdb.NewBloomFilter(10))
+	return NewRocksDBWithTuning(name, dir, DefaultRocksDBOptions())
+}
 
-	opts := gorocksdb.NewDefaultOptions()
-	opts.SetBlockBasedTableFactory(bbto)
-	// SetMaxOpenFiles to 4096 seems to provide a reliable performance boost
-	opts.SetMaxOpenFiles(4096)
-	opts.SetCreateIfMissing(true)
-	opts.IncreaseParallelism(runtime.NumCPU())
-	// 1.5GB maximum memory use for writebuffer.
-	opts.OptimizeLevelStyleCompaction(512 * 1024 * 1024)
-	return NewRocksDBWithOptions(name, dir, opts)
+// NewRocksDBWithTuning opens the named database as a RocksDB instance of its
+// own, tuned by the family options of name. opts.ColumnFamilies is ignored.
+func NewRocksDBWithTuning(name string, dir string, opts *RocksDBOptions) (*RocksDB, error) {
+	inst := newRocksDBInstance(filepath.Join(dir, name+".db"), opts)
+	if err := inst.open([]string{defaultColumnFamily}, name); err != nil {
+		inst.destroy()
+		return nil, err
+	}
+	return newRocksDB(name, inst, inst.families[defaultColumnFamily]), nil
 }
 
 func NewRocksDBWithOptions(name string, dir string, opts *gorocksdb.Options) (*RocksDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
-	db, err := gorocksdb.OpenDb(opts, dbPath)
+	db, cfs, err := gorocksdb.OpenDbColumnFamilies(opts, dbPath,
+		[]string{defaultColumnFamily}, []*gorocksdb.Options{opts})
 	if err != nil {
 		return nil, err
 	}
+	inst := &rocksDBInstance{
+		path:     dbPath,
+		db:       db,
+		families: map[string]*gorocksdb.ColumnFamilyHandle{defaultColumnFamily: cfs[0]},
+	}
+	return newRocksDB(name, inst, cfs[0]), nil
+}
+
+// NewRocksDBColumnFamily opens the named database as a column family of the
+// RocksDB instance named RocksDBSharedName in dir, creating the instance and
+// the family as needed. The instance is opened with the options of the first
+// family opened from it, and closed with the last.
+func NewRocksDBColumnFamily(name string, dir string, opts *RocksDBOptions) (*RocksDB, error) {
+	path := filepath.Join(dir, RocksDBSharedName+".db")
+
+	sharedRocksDBs.mtx.Lock()
+	defer sharedRocksDBs.mtx.Unlock()
+
+	inst, ok := sharedRocksDBs.instances[path]
+	if !ok {
+		names, err := rocksDBColumnFamilies(path)
+		if err != nil {
+			return nil, err
+		}
+		inst = newRocksDBInstance(path, opts)
+		if err := inst.open(names, defaultColumnFamily); err != nil {
+			inst.destroy()
+			return nil, err
+		}
+		inst.shared = true
+		sharedRocksDBs.instances[path] = inst
+	}
+	cf, err := inst.family(name)
+	if err != nil {
+		if inst.refs == 0 {
+			inst.closeShared()
+		}
+		return nil, err
+	}
+	inst.refs++
+	return newRocksDB(name, inst, cf), nil
+}
+
+func newRocksDB(name string, inst *rocksDBInstance, cf *gorocksdb.ColumnFamilyHandle) *RocksDB {
 	ro := gorocksdb.NewDefaultReadOptions()
 	wo := gorocksdb.NewDefaultWriteOptions()
 	woSync := gorocksdb.NewDefaultWriteOptions()
 	woSync.SetSync(true)
-	database := &RocksDB{
-		db:     db,
+	return &RocksDB{
+		name:   name,
+		inst:   inst,
+		db:     inst.db,
+		cf:     cf,
 		ro:     ro,
 		wo:     wo,
 		woSync: woSync,
 	}
-	return database, nil
 }
 
 // Get implements DB.
@@ -91,7 +148,7 @@ func (db *RocksDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
-	res, err := db.db.Get(db.ro, key)
+	res, err := db.db.GetCF(db.ro, db.cf, key)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +172,7 @@ func (db *RocksDB) Set(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	err := db.db.Put(db.wo, key, value)
+	err := db.db.PutCF(db.wo, db.cf, key, value)
 	if err != nil {
 		return err
 	}
@@ -130,7 +187,7 @@ func (db *RocksDB) SetSync(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	err := db.db.Put(db.woSync, key, value)
+	err := db.db.PutCF(db.woSync, db.cf, key, value)
 	if err != nil {
 		return err
 	}
@@ -142,7 +199,7 @@ func (db *RocksDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	err := db.db.Delete(db.wo, key)
+	err := db.db.DeleteCF(db.wo, db.cf, key)
 	if err != nil {
 		return err
 	}
@@ -154,7 +211,7 @@ func (db *RocksDB) DeleteSync(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	err := db.db.Delete(db.woSync, key)
+	err := db.db.DeleteCF(db.woSync, db.cf, key)
 	if err != nil {
 		return nil
 	}
@@ -170,7 +227,7 @@ func (db *RocksDB) Close() error {
 	db.ro.Destroy()
 	db.wo.Destroy()
 	db.woSync.Destroy()
-	db.db.Close()
+	db.inst.release()
 	return nil
 }
 
@@ -189,12 +246,27 @@ func (db *RocksDB) Print() error {
 	return nil
 }
 
-// Stats implements DB.
+// rocksDBProperties are the RocksDB properties reported by Stats. All but
+// rocksdb.stats are numeric.
+var rocksDBProperties = []string{
+	"rocksdb.stats",
+	"rocksdb.estimate-num-keys",
+	"rocksdb.estimate-live-data-size",
+	"rocksdb.total-sst-files-size",
+	"rocksdb.cur-size-all-mem-tables",
+	"rocksdb.estimate-table-readers-mem",
+	"rocksdb.block-cache-usage",
+	"rocksdb.estimate-pending-compaction-bytes",
+	"rocksdb.num-running-compactions",
+	"rocksdb.num-running-flushes",
+}
+
+// Stats implements DB. The properties are those of the column family of the
+// database.
 func (db *RocksDB) Stats() map[string]string {
-	keys := []string{"rocksdb.stats"}
-	stats := make(map[string]string, len(keys))
-	for _, key := range keys {
-		stats[key] = db.db.GetProperty(key)
+	stats := make(map[string]string, len(rocksDBProperties))
+	for _, key := range rocksDBProperties {
+		stats[key] = db.db.GetPropertyCF(key, db.cf)
 	}
 	return stats
 }
@@ -209,7 +281,7 @@ func (db *RocksDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	itr := db.db.NewIterator(db.ro)
+	itr := db.db.NewIteratorCF(db.ro, db.cf)
 	return newRocksDBIterator(itr, start, end, false), nil
 }
 
@@ -218,6 +290,86 @@ func (db *RocksDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	itr := db.db.NewIterator(db.ro)
+	itr := db.db.NewIteratorCF(db.ro, db.cf)
+	return newRocksDBIterator(itr, start, end, true), nil
+}
+
+// Snapshot implements DB.
+func (db *RocksDB) Snapshot() (Snapshot, error) {
+	snap := db.db.NewSnapshot()
+	ro := gorocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+	return &rocksDBSnapshot{db: db.db, cf: db.cf, snap: snap, ro: ro}, nil
+}
+
+// Checkpoint implements DB. RocksDB checkpoints hard-link the immutable SST files when dir is
+// on the same filesystem as the database, and copy them otherwise. The checkpoint of a column
+// family holds every family of its instance; use a CheckpointSet to write it once for all of
+// them.
+func (db *RocksDB) Checkpoint(dir string) error {
+	return db.inst.checkpoint(dir)
+}
+
+// checkpointSource implements sharedCheckpointer.
+func (db *RocksDB) checkpointSource() interface{} {
+	if db.inst.shared {
+		return db.inst
+	}
+	return nil
+}
+
+// rocksDBSnapshot wraps a RocksDB snapshot and the read options that select it.
+type rocksDBSnapshot struct {
+	db   *gorocksdb.DB
+	cf   *gorocksdb.ColumnFamilyHandle
+	snap *gorocksdb.Snapshot
+	ro   *gorocksdb.ReadOptions
+}
+
+var _ Snapshot = (*rocksDBSnapshot)(nil)
+
+// Get implements Snapshot.
+func (s *rocksDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.db.GetCF(s.ro, s.cf, key)
+	if err != nil {
+		return nil, err
+	}
+	return moveSliceToBytes(res), nil
+}
+
+// Has implements Snapshot.
+func (s *rocksDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements Snapshot.
+func (s *rocksDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.NewIteratorCF(s.ro, s.cf)
+	return newRocksDBIterator(itr, start, end, false), nil
+}
+
+// ReverseIterator implements Snapshot.
+func (s *rocksDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.db.NewIteratorCF(s.ro, s.cf)
 	return newRocksDBIterator(itr, start, end, true), nil
 }
+
+// Close implements Snapshot.
+func (s *rocksDBSnapshot) Close() error {
+	s.ro.Destroy()
+	s.db.ReleaseSnapshot(s.snap)
+	return nil
+}
@@ -50,7 +50,7 @@ func (b *rocksDBBatch) Set(key, value []byte) error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
-	b.batch.Put(key, value)
+	b.batch.PutCF(b.db.cf, key, value)
 	return nil
 }
 
@@ -62,7 +62,7 @@ func (b *rocksDBBatch) Delete(key []byte) error {
 	if b.batch == nil {
 		return errBatchClosed
 	}
-	b.batch.Delete(key)
+	b.batch.DeleteCF(b.db.cf, key)
 	return nil
 }
 
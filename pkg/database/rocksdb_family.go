@@ -0,0 +1,233 @@
+//go:build rocksdb
+// +build rocksdb
+
+package database
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/bhojpur/gorocksdb"
+)
+
+// defaultColumnFamily is the column family every RocksDB instance has.
+const defaultColumnFamily = "default"
+
+// rocksDBNumLevels is the default number of LSM levels of a column family.
+const rocksDBNumLevels = 7
+
+// sharedRocksDBs holds the open RocksDB instances whose column families are
+// databases, by path.
+var sharedRocksDBs = struct {
+	mtx       sync.Mutex
+	instances map[string]*rocksDBInstance
+}{instances: make(map[string]*rocksDBInstance)}
+
+// rocksDBInstance is an open RocksDB instance with the column families opened
+// from it.
+type rocksDBInstance struct {
+	path     string
+	opts     *RocksDBOptions
+	shared   bool
+	db       *gorocksdb.DB
+	families map[string]*gorocksdb.ColumnFamilyHandle
+
+	// refs counts the open databases of a shared instance.
+	refs int
+
+	// cache is the block cache shared by the families, options and caches
+	// are freed once the instance is closed.
+	cache   *gorocksdb.Cache
+	options []*gorocksdb.Options
+	caches  []*gorocksdb.Cache
+}
+
+func newRocksDBInstance(path string, opts *RocksDBOptions) *rocksDBInstance {
+	cache := gorocksdb.NewLRUCache(opts.BlockCacheSize)
+	return &rocksDBInstance{
+		path:     path,
+		opts:     opts,
+		families: make(map[string]*gorocksdb.ColumnFamilyHandle),
+		cache:    cache,
+		caches:   []*gorocksdb.Cache{cache},
+	}
+}
+
+// rocksDBColumnFamilies returns the column families of the RocksDB instance
+// at path, which may not exist yet.
+func rocksDBColumnFamilies(path string) ([]string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return []string{defaultColumnFamily}, nil
+	}
+	opts := gorocksdb.NewDefaultOptions()
+	defer opts.Destroy()
+	return gorocksdb.ListColumnFamilies(opts, path)
+}
+
+// open opens the instance with the given column families. The default family
+// and the instance itself are tuned by the family options of tuneDefaultAs.
+func (inst *rocksDBInstance) open(names []string, tuneDefaultAs string) error {
+	dbOpts := inst.familyOptions(inst.opts.Family(tuneDefaultAs))
+	dbOpts.SetCreateIfMissing(true)
+	dbOpts.SetCreateIfMissingColumnFamilies(true)
+
+	cfOpts := make([]*gorocksdb.Options, len(names))
+	for i, name := range names {
+		if name == defaultColumnFamily {
+			cfOpts[i] = dbOpts
+		} else {
+			cfOpts[i] = inst.familyOptions(inst.opts.Family(name))
+		}
+	}
+	db, cfs, err := gorocksdb.OpenDbColumnFamilies(dbOpts, inst.path, names, cfOpts)
+	if err != nil {
+		return err
+	}
+	inst.db = db
+	for i, name := range names {
+		inst.families[name] = cfs[i]
+	}
+	return nil
+}
+
+// family returns the named column family, creating it if it doesn't exist.
+// The caller must hold sharedRocksDBs.mtx.
+func (inst *rocksDBInstance) family(name string) (*gorocksdb.ColumnFamilyHandle, error) {
+	if cf, ok := inst.families[name]; ok {
+		return cf, nil
+	}
+	cf, err := inst.db.CreateColumnFamily(inst.familyOptions(inst.opts.Family(name)), name)
+	if err != nil {
+		return nil, err
+	}
+	inst.families[name] = cf
+	return cf, nil
+}
+
+// familyOptions returns the RocksDB options of a column family tuned by fo.
+func (inst *rocksDBInstance) familyOptions(fo RocksDBFamilyOptions) *gorocksdb.Options {
+	cache := inst.cache
+	if fo.BlockCacheShare > 0 {
+		cache = gorocksdb.NewLRUCache(uint64(fo.BlockCacheShare * float64(inst.opts.BlockCacheSize)))
+		inst.caches = append(inst.caches, cache)
+	}
+	bbto := gorocksdb.NewDefaultBlockBasedTableOptions()
+	bbto.SetBlockCache(cache)
+	if fo.BloomFilterBits > 0 {
+		bbto.SetFilterPolicy(gorocksdb.NewBloomFilter(fo.BloomFilterBits))
+	}
+
+	opts := gorocksdb.NewDefaultOptions()
+	opts.SetBlockBasedTableFactory(bbto)
+	// SetMaxOpenFiles to 4096 seems to provide a reliable performance boost
+	opts.SetMaxOpenFiles(4096)
+	opts.IncreaseParallelism(runtime.NumCPU())
+	// 512MB write buffer per family (may use 50% more on heavy workloads).
+	opts.OptimizeLevelStyleCompaction(512 * 1024 * 1024)
+	// OptimizeLevelStyleCompaction leaves the two lowest levels uncompressed
+	// and picks a compression for the others; use the configured one instead.
+	compression := rocksDBCompression(fo.Compression)
+	levels := make([]gorocksdb.CompressionType, rocksDBNumLevels)
+	for i := range levels {
+		levels[i] = gorocksdb.NoCompression
+		if i >= 2 {
+			levels[i] = compression
+		}
+	}
+	opts.SetCompressionPerLevel(levels)
+	opts.SetCompression(compression)
+
+	inst.options = append(inst.options, opts)
+	return opts
+}
+
+func rocksDBCompression(name string) gorocksdb.CompressionType {
+	switch name {
+	case RocksDBNoCompression:
+		return gorocksdb.NoCompression
+	case RocksDBZlibCompression:
+		return gorocksdb.ZLibCompression
+	case RocksDBLZ4Compression:
+		return gorocksdb.LZ4Compression
+	case RocksDBZstdCompression:
+		return gorocksdb.ZSTDCompression
+	default:
+		return gorocksdb.SnappyCompression
+	}
+}
+
+// checkpoint writes a checkpoint of the instance into dir.
+func (inst *rocksDBInstance) checkpoint(dir string) error {
+	path := filepath.Join(dir, filepath.Base(inst.path))
+	if err := checkpointTarget(path); err != nil {
+		return err
+	}
+	cp, err := inst.db.NewCheckpoint()
+	if err != nil {
+		return err
+	}
+	defer cp.Destroy()
+	// A log size of 0 flushes the memtables first, so the checkpoint needs no WAL replay.
+	return cp.CreateCheckpoint(path, 0)
+}
+
+// release closes the instance once its last database is closed.
+func (inst *rocksDBInstance) release() {
+	if !inst.shared {
+		inst.close()
+		return
+	}
+	sharedRocksDBs.mtx.Lock()
+	defer sharedRocksDBs.mtx.Unlock()
+	inst.refs--
+	if inst.refs == 0 {
+		inst.closeShared()
+	}
+}
+
+// closeShared closes a shared instance. The caller must hold
+// sharedRocksDBs.mtx.
+func (inst *rocksDBInstance) closeShared() {
+	delete(sharedRocksDBs.instances, inst.path)
+	inst.close()
+}
+
+func (inst *rocksDBInstance) close() {
+	for _, cf := range inst.families {
+		cf.Destroy()
+	}
+	inst.db.Close()
+	inst.destroy()
+}
+
+// destroy frees the options and caches of the instance.
+func (inst *rocksDBInstance) destroy() {
+	for _, opts := range inst.options {
+		opts.Destroy()
+	}
+	for _, cache := range inst.caches {
+		cache.Destroy()
+	}
+}
@@ -26,6 +26,7 @@ package database
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,4 +54,59 @@ func TestRocksDBStats(t *testing.T) {
 	assert.NotEmpty(t, db.Stats())
 }
 
-// TODO: Add tests for rocksdb
+func TestRocksDBColumnFamilies(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultRocksDBOptions()
+	opts.ColumnFamilies = true
+	opts.Families = map[string]RocksDBFamilyOptions{
+		"tx_index": {Compression: RocksDBZstdCompression, BloomFilterBits: 10, BlockCacheShare: 0.25},
+	}
+
+	open := func(name string) DB {
+		db, err := NewDB(name, RocksDBBackend, dir, WithRocksDBOptions(opts))
+		require.NoError(t, err)
+		return db
+	}
+	state, txIndex := open("state"), open("tx_index")
+	require.NoError(t, state.Set([]byte("key"), []byte("state")))
+	require.NoError(t, txIndex.Set([]byte("key"), []byte("tx_index")))
+	assert.Contains(t, txIndex.Stats(), "rocksdb.estimate-num-keys")
+
+	// both databases live in one instance
+	assert.DirExists(t, filepath.Join(dir, RocksDBSharedName+".db"))
+	assert.NoDirExists(t, filepath.Join(dir, "state.db"))
+
+	// a checkpoint set writes the instance once for both databases
+	backup := t.TempDir()
+	checkpoints := NewCheckpointSet(backup)
+	require.NoError(t, checkpoints.Checkpoint(state))
+	require.NoError(t, checkpoints.Checkpoint(txIndex))
+
+	// a later backup into the same dir fails rather than writing nothing
+	require.Error(t, NewCheckpointSet(backup).Checkpoint(state))
+	require.Error(t, state.Checkpoint(backup))
+
+	// once the earlier backup is removed, the instance is written again
+	again := t.TempDir()
+	require.NoError(t, NewCheckpointSet(again).Checkpoint(txIndex))
+	require.NoError(t, os.RemoveAll(again))
+	require.NoError(t, NewCheckpointSet(again).Checkpoint(state))
+	assert.DirExists(t, filepath.Join(again, RocksDBSharedName+".db"))
+
+	require.NoError(t, state.Close())
+	require.NoError(t, txIndex.Close())
+
+	// the families are reopened with their data
+	for _, d := range []string{dir, backup} {
+		dir = d
+		state, txIndex = open("state"), open("tx_index")
+		value, err := state.Get([]byte("key"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("state"), value)
+		value, err = txIndex.Get([]byte("key"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("tx_index"), value)
+		require.NoError(t, state.Close())
+		require.NoError(t, txIndex.Close())
+	}
+}
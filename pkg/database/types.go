@@ -31,6 +31,14 @@ var (
 
 	// errValueNil is returned when attempting to set a nil value.
 	errValueNil = errors.New("value cannot be nil")
+
+	// ErrSnapshotNotSupported is returned by backends that cannot provide a consistent
+	// read-only view of the database.
+	ErrSnapshotNotSupported = errors.New("snapshots are not supported by this backend")
+
+	// ErrCheckpointNotSupported is returned by backends that cannot write a consistent
+	// copy of the database to another directory, such as in-memory databases.
+	ErrCheckpointNotSupported = errors.New("checkpoints are not supported by this backend")
 )
 
 // DB is the main interface for all database backends. DBs are concurrency-safe. Callers must call
@@ -88,6 +96,42 @@ type DB interface {
 
 	// Stats returns a map of property values for all keys and the size of the cache.
 	Stats() map[string]string
+
+	// Snapshot returns a read-only, point-in-time view of the database. Writes made after the
+	// snapshot was taken are not visible through it. The caller must call Close when done.
+	Snapshot() (Snapshot, error)
+
+	// Checkpoint writes a consistent copy of the database into dir, under the same name the
+	// database has in its own directory, so that dir can be used as a database directory as
+	// is. Engines that support it hard-link immutable files rather than copying them. The
+	// target must not already exist.
+	Checkpoint(dir string) error
+}
+
+// Snapshot is a read-only, point-in-time view of a DB. Snapshots are concurrency-safe and
+// are not affected by writes to the DB they were taken from. Callers must call Close on the
+// snapshot when done, and must close all iterators before closing the snapshot.
+//
+// As with DB, keys and values should be considered read-only.
+type Snapshot interface {
+	// Get fetches the value of the given key, or nil if it does not exist.
+	// CONTRACT: key, value readonly []byte
+	Get([]byte) ([]byte, error)
+
+	// Has checks if a key exists.
+	// CONTRACT: key, value readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Iterator returns an iterator over a domain of keys, in ascending order, with the same
+	// semantics as DB.Iterator.
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending order, with the
+	// same semantics as DB.ReverseIterator.
+	ReverseIterator(start, end []byte) (Iterator, error)
+
+	// Close releases the snapshot.
+	Close() error
 }
 
 // Batch represents a group of writes. They may or may not be written atomically depending on the
@@ -22,9 +22,13 @@ package database
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 )
 
+// checkpointBatchSize is the number of keys written per batch by copySnapshot.
+const checkpointBatchSize = 1000
+
 func cp(bz []byte) (ret []byte) {
 	ret = make([]byte, len(bz))
 	copy(ret, bz)
@@ -69,3 +73,81 @@ func FileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
 }
+
+// A CheckpointSet writes checkpoints of several databases into one directory. Databases that
+// share their files, such as the column families of one RocksDB instance, are written once.
+// A set is meant for a single backup: a database checkpointed into a directory again by a
+// new set fails, as the target already exists.
+type CheckpointSet struct {
+	dir     string
+	written map[interface{}]bool
+}
+
+// NewCheckpointSet returns a CheckpointSet writing into dir.
+func NewCheckpointSet(dir string) *CheckpointSet {
+	return &CheckpointSet{dir: dir, written: make(map[interface{}]bool)}
+}
+
+// Checkpoint writes a checkpoint of db, as by DB.Checkpoint, unless the files of db were
+// already written by the set.
+func (s *CheckpointSet) Checkpoint(db DB) error {
+	if sc, ok := db.(sharedCheckpointer); ok {
+		if source := sc.checkpointSource(); source != nil {
+			if s.written[source] {
+				return nil
+			}
+			if err := db.Checkpoint(s.dir); err != nil {
+				return err
+			}
+			s.written[source] = true
+			return nil
+		}
+	}
+	return db.Checkpoint(s.dir)
+}
+
+// sharedCheckpointer is implemented by databases whose checkpoint may hold other databases
+// too.
+type sharedCheckpointer interface {
+	// checkpointSource returns a value identifying the files a checkpoint of the database is
+	// written from, if they are shared with other databases, or nil otherwise.
+	checkpointSource() interface{}
+}
+
+// copySnapshot writes every key of snap into dst, for backends without native checkpoints.
+func copySnapshot(snap Snapshot, dst DB) error {
+	itr, err := snap.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	batch := dst.NewBatch()
+	defer func() { batch.Close() }()
+	n := 0
+	for ; itr.Valid(); itr.Next() {
+		if err := batch.Set(itr.Key(), itr.Value()); err != nil {
+			return err
+		}
+		n++
+		if n%checkpointBatchSize == 0 {
+			if err := batch.WriteSync(); err != nil {
+				return err
+			}
+			batch.Close()
+			batch = dst.NewBatch()
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	return batch.WriteSync()
+}
+
+// checkpointTarget returns an error if path, the target of a checkpoint, already exists.
+func checkpointTarget(path string) error {
+	if FileExists(path) {
+		return fmt.Errorf("checkpoint target %s already exists", path)
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -34,6 +35,55 @@ var _ Logger = (*defaultLogger)(nil)
 
 type defaultLogger struct {
 	zerolog.Logger
+
+	// settings are shared by all loggers derived from the same logger, so
+	// that level changes at runtime apply to each of them. Loggers without
+	// settings filter messages by the level of the zerolog logger only.
+	settings *loggerSettings
+	module   string
+}
+
+// loggerSettings holds the levels and sampling rules of a family of loggers.
+type loggerSettings struct {
+	mtx     sync.RWMutex
+	levels  *moduleLevels
+	sampler *sampler
+}
+
+func (s *loggerSettings) get() (*moduleLevels, *sampler) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.levels, s.sampler
+}
+
+func (s *loggerSettings) set(levels *moduleLevels, smp *sampler) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.levels = levels
+	s.sampler = smp
+}
+
+// Option sets an optional parameter of a logger created by NewDefaultLogger.
+type Option func(*loggerOptions)
+
+type loggerOptions struct {
+	sampling string
+	writers  []io.Writer
+}
+
+// WithSampling limits the number of Debug and Info messages logged by hot
+// paths, as specified by a comma-separated list of module=burst/period
+// entries. For example, "mempool=10/1s" logs at most 10 messages with the
+// same text per second for the mempool module. Error messages are never
+// dropped.
+func WithSampling(spec string) Option {
+	return func(opts *loggerOptions) { opts.sampling = spec }
+}
+
+// WithJSONWriter additionally writes log entries to w in JSON format, e.g. to
+// a rotating autofile.Group.
+func WithJSONWriter(w io.Writer) Option {
+	return func(opts *loggerOptions) { opts.writers = append(opts.writers, w) }
 }
 
 // NewDefaultLogger returns a default logger that can be used within Bhojpur State
@@ -41,10 +91,19 @@ type defaultLogger struct {
 // zerolog logger that supports typical log levels along with JSON and plain/text
 // log formats.
 //
+// The level is either a single log level, or a comma-separated list of a log
+// level and per-module overrides, e.g. "info,consensus=debug,p2p=error".
+// Modules are identified by the "module" key passed to With.
+//
 // Since zerolog supports typed structured logging and it is difficult to reflect
 // that in a generic interface, all logging methods accept a series of key/value
 // pair tuples, where the key must be a string.
-func NewDefaultLogger(format, level string) (Logger, error) {
+func NewDefaultLogger(format, level string, options ...Option) (Logger, error) {
+	var opts loggerOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
 	var logWriter io.Writer
 	switch strings.ToLower(format) {
 	case LogFormatPlain, LogFormatText:
@@ -67,47 +126,94 @@ func NewDefaultLogger(format, level string) (Logger, error) {
 		return nil, fmt.Errorf("unsupported log format: %s", format)
 	}
 
-	logLevel, err := zerolog.ParseLevel(level)
+	levels, err := parseLevels(level)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseSampling(opts.sampling)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse log level (%s): %w", level, err)
+		return nil, err
+	}
+	settings := &loggerSettings{levels: levels}
+	if len(rules) > 0 {
+		settings.sampler = newSampler(rules)
+	}
+
+	if len(opts.writers) > 0 {
+		logWriter = zerolog.MultiLevelWriter(append([]io.Writer{logWriter}, opts.writers...)...)
 	}
 
 	// make the writer thread-safe
 	logWriter = newSyncWriter(logWriter)
 
 	return &defaultLogger{
-		Logger: zerolog.New(logWriter).Level(logLevel).With().Timestamp().Logger(),
+		Logger:   zerolog.New(logWriter).With().Timestamp().Logger(),
+		settings: settings,
 	}, nil
 }
 
 func (l defaultLogger) Info(msg string, keyVals ...interface{}) {
-	l.Logger.Info().Fields(getLogFields(keyVals...)).Msg(msg)
+	l.log(zerolog.InfoLevel, msg, keyVals)
 }
 
 func (l defaultLogger) Error(msg string, keyVals ...interface{}) {
-	l.Logger.Error().Fields(getLogFields(keyVals...)).Msg(msg)
+	l.log(zerolog.ErrorLevel, msg, keyVals)
 }
 
 func (l defaultLogger) Debug(msg string, keyVals ...interface{}) {
-	l.Logger.Debug().Fields(getLogFields(keyVals...)).Msg(msg)
+	l.log(zerolog.DebugLevel, msg, keyVals)
+}
+
+func (l defaultLogger) log(level zerolog.Level, msg string, keyVals []interface{}) {
+	var dropped int
+	if l.settings != nil {
+		levels, smp := l.settings.get()
+		if level < levels.level(l.module) {
+			return
+		}
+		if smp != nil && level < zerolog.ErrorLevel {
+			var ok bool
+			if ok, dropped = smp.sample(l.module, msg); !ok {
+				return
+			}
+		}
+	}
+
+	fields := getLogFields(keyVals...)
+	if dropped > 0 {
+		if fields == nil {
+			fields = make(map[string]interface{}, 1)
+		}
+		fields["sampled_out"] = dropped
+	}
+	l.Logger.WithLevel(level).Fields(fields).Msg(msg)
 }
 
 func (l defaultLogger) With(keyVals ...interface{}) Logger {
+	module := l.module
+	for i := 0; i+1 < len(keyVals); i += 2 {
+		if keyVals[i] == moduleKey {
+			module = fmt.Sprint(keyVals[i+1])
+		}
+	}
+
 	return &defaultLogger{
-		Logger: l.Logger.With().Fields(getLogFields(keyVals...)).Logger(),
+		Logger:   l.Logger.With().Fields(getLogFields(keyVals...)).Logger(),
+		settings: l.settings,
+		module:   module,
 	}
 }
 
 // OverrideWithNewLogger replaces an existing logger's internal with
 // a new logger, and makes it possible to reconfigure an existing
 // logger that has already been propagated to callers.
-func OverrideWithNewLogger(logger Logger, format, level string) error {
+func OverrideWithNewLogger(logger Logger, format, level string, options ...Option) error {
 	ol, ok := logger.(*defaultLogger)
 	if !ok {
 		return fmt.Errorf("logger %T cannot be overridden", logger)
 	}
 
-	newLogger, err := NewDefaultLogger(format, level)
+	newLogger, err := NewDefaultLogger(format, level, options...)
 	if err != nil {
 		return err
 	}
@@ -117,6 +223,74 @@ func OverrideWithNewLogger(logger Logger, format, level string) error {
 	}
 
 	ol.Logger = nl.Logger
+	if ol.settings == nil {
+		ol.settings = nl.settings
+	} else {
+		ol.settings.set(nl.settings.get())
+	}
+	return nil
+}
+
+// SetLevel changes the log levels of logger, and of all loggers sharing its
+// origin, to those given by the level specification, which has the format
+// accepted by NewDefaultLogger.
+func SetLevel(logger Logger, level string) error {
+	settings := settingsOf(logger)
+	if settings == nil {
+		return fmt.Errorf("logger %T does not support changing levels", logger)
+	}
+	levels, err := parseLevels(level)
+	if err != nil {
+		return err
+	}
+
+	settings.mtx.Lock()
+	defer settings.mtx.Unlock()
+	settings.levels = levels
+	return nil
+}
+
+// SetSampling changes the sampling rules of logger, and of all loggers sharing
+// its origin, to those given by spec, which has the format accepted by
+// WithSampling. An empty spec disables sampling.
+func SetSampling(logger Logger, spec string) error {
+	settings := settingsOf(logger)
+	if settings == nil {
+		return fmt.Errorf("logger %T does not support sampling", logger)
+	}
+	rules, err := parseSampling(spec)
+	if err != nil {
+		return err
+	}
+	var smp *sampler
+	if len(rules) > 0 {
+		smp = newSampler(rules)
+	}
+
+	settings.mtx.Lock()
+	defer settings.mtx.Unlock()
+	settings.sampler = smp
+	return nil
+}
+
+// GetLevel returns the level specification of logger, in the format accepted
+// by NewDefaultLogger.
+func GetLevel(logger Logger) (string, error) {
+	settings := settingsOf(logger)
+	if settings == nil {
+		return "", fmt.Errorf("logger %T does not support changing levels", logger)
+	}
+	levels, _ := settings.get()
+	return levels.String(), nil
+}
+
+func settingsOf(logger Logger) *loggerSettings {
+	switch l := logger.(type) {
+	case *defaultLogger:
+		return l.settings
+	case defaultLogger:
+		return l.settings
+	}
 	return nil
 }
 
@@ -21,6 +21,9 @@ package log_test
 // THE SOFTWARE.
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/bhojpur/state/pkg/libs/log"
@@ -63,3 +66,96 @@ func TestNewDefaultLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultLogger_ModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := log.NewDefaultLogger(log.LogFormatJSON, "info,consensus=debug,p2p=error", log.WithJSONWriter(&buf))
+	require.NoError(t, err)
+
+	consensus := logger.With("module", "consensus")
+	p2p := logger.With("module", "p2p")
+	mempool := logger.With("module", "mempool").With("peer", "abc")
+
+	consensus.Debug("consensus debug")
+	p2p.Info("p2p info")
+	p2p.Error("p2p error")
+	mempool.Debug("mempool debug")
+	mempool.Info("mempool info")
+	require.Equal(t, []string{"consensus debug", "p2p error", "mempool info"}, logMessages(t, &buf))
+
+	level, err := log.GetLevel(mempool)
+	require.NoError(t, err)
+	require.Equal(t, "info,consensus=debug,p2p=error", level)
+
+	// Changing the level through any derived logger applies to all of them.
+	require.NoError(t, log.SetLevel(p2p, "error,mempool=debug"))
+	consensus.Info("consensus info")
+	mempool.Debug("mempool debug")
+	require.Equal(t, []string{"mempool debug"}, logMessages(t, &buf))
+
+	require.Error(t, log.SetLevel(logger, "consensus=loud"))
+	require.Error(t, log.SetLevel(log.NewNopLogger(), "info"))
+}
+
+func TestDefaultLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := log.NewDefaultLogger(log.LogFormatJSON, "debug",
+		log.WithSampling("mempool=2/1h"), log.WithJSONWriter(&buf))
+	require.NoError(t, err)
+
+	mempool := logger.With("module", "mempool")
+	consensus := logger.With("module", "consensus")
+	for i := 0; i < 5; i++ {
+		mempool.Debug("gossip")
+		mempool.Error("failure")
+		consensus.Debug("step")
+	}
+	mempool.Info("other")
+
+	counts := make(map[string]int)
+	for _, msg := range logMessages(t, &buf) {
+		counts[msg]++
+	}
+	require.Equal(t, map[string]int{"gossip": 2, "failure": 5, "step": 5, "other": 1}, counts)
+
+	require.NoError(t, log.SetSampling(consensus, ""))
+	mempool.Debug("gossip")
+	require.Equal(t, []string{"gossip"}, logMessages(t, &buf))
+	require.Error(t, log.SetSampling(logger, "mempool"))
+}
+
+func TestValidateLevelAndSampling(t *testing.T) {
+	require.NoError(t, log.ValidateLevel("info"))
+	require.NoError(t, log.ValidateLevel("info, consensus=debug,p2p=error"))
+	require.NoError(t, log.ValidateLevel("*=error"))
+	require.Error(t, log.ValidateLevel("verbose"))
+	require.Error(t, log.ValidateLevel("=debug"))
+	require.Error(t, log.ValidateLevel("p2p="))
+
+	require.NoError(t, log.ValidateSampling(""))
+	require.NoError(t, log.ValidateSampling("mempool=10/1s,p2p=100/1m"))
+	require.Error(t, log.ValidateSampling("mempool"))
+	require.Error(t, log.ValidateSampling("mempool=10"))
+	require.Error(t, log.ValidateSampling("mempool=x/1s"))
+	require.Error(t, log.ValidateSampling("mempool=10/0s"))
+}
+
+// logMessages returns the messages of the JSON log entries in buf, and resets
+// buf.
+func logMessages(t *testing.T, buf *bytes.Buffer) []string {
+	t.Helper()
+
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry struct {
+			Message string `json:"message"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		msgs = append(msgs, entry.Message)
+	}
+	buf.Reset()
+	return msgs
+}
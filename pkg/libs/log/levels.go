@@ -0,0 +1,233 @@
+package log
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// moduleKey is the key under which loggers are tagged with the module they
+// are used by, e.g. logger.With("module", "consensus").
+const moduleKey = "module"
+
+// moduleLevels holds the log level of each module, along with the level of
+// modules without an explicit setting.
+type moduleLevels struct {
+	defaultLevel zerolog.Level
+	modules      map[string]zerolog.Level
+}
+
+// parseLevels parses a log level specification, a comma-separated list of
+// either a level, which applies to all modules without an explicit setting,
+// or module=level pairs, e.g. "info,consensus=debug,p2p=error". The module
+// name "*" is equivalent to leaving out the module.
+func parseLevels(spec string) (*moduleLevels, error) {
+	ml := &moduleLevels{
+		defaultLevel: zerolog.InfoLevel,
+		modules:      make(map[string]zerolog.Level),
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		module, level := "*", item
+		if i := strings.Index(item, "="); i >= 0 {
+			module, level = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+			if module == "" {
+				return nil, fmt.Errorf("missing module name in %q", item)
+			}
+		}
+
+		lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+		if err != nil || level == "" {
+			return nil, fmt.Errorf("failed to parse log level (%s): unknown level %q", spec, level)
+		}
+		if module == "*" {
+			ml.defaultLevel = lvl
+		} else {
+			ml.modules[module] = lvl
+		}
+	}
+	return ml, nil
+}
+
+// level returns the log level of the given module.
+func (ml *moduleLevels) level(module string) zerolog.Level {
+	if lvl, ok := ml.modules[module]; ok {
+		return lvl
+	}
+	return ml.defaultLevel
+}
+
+// String returns the specification of ml, in the format parsed by
+// parseLevels.
+func (ml *moduleLevels) String() string {
+	items := make([]string, 0, len(ml.modules)+1)
+	items = append(items, ml.defaultLevel.String())
+	modules := make([]string, 0, len(ml.modules))
+	for module := range ml.modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		items = append(items, module+"="+ml.modules[module].String())
+	}
+	return strings.Join(items, ",")
+}
+
+// ValidateLevel reports whether spec is a valid log level specification,
+// either a single level such as "info", or a comma-separated list of a level
+// and module=level overrides such as "info,consensus=debug,p2p=error".
+func ValidateLevel(spec string) error {
+	_, err := parseLevels(spec)
+	return err
+}
+
+// samplingRule limits the number of messages logged by a module: at most
+// burst Debug or Info messages with the same text are logged per period.
+type samplingRule struct {
+	burst  int
+	period time.Duration
+}
+
+// parseSampling parses a log sampling specification, a comma-separated list
+// of module=burst/period entries, e.g. "mempool=10/1s,p2p=100/1m".
+func parseSampling(spec string) (map[string]samplingRule, error) {
+	rules := make(map[string]samplingRule)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid log sampling %q: expected module=burst/period", item)
+		}
+		limit := strings.SplitN(parts[1], "/", 2)
+		if len(limit) != 2 {
+			return nil, fmt.Errorf("invalid log sampling %q: expected module=burst/period", item)
+		}
+		burst, err := strconv.Atoi(strings.TrimSpace(limit[0]))
+		if err != nil || burst < 0 {
+			return nil, fmt.Errorf("invalid log sampling burst in %q", item)
+		}
+		period, err := time.ParseDuration(strings.TrimSpace(limit[1]))
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid log sampling period in %q", item)
+		}
+		rules[strings.TrimSpace(parts[0])] = samplingRule{burst: burst, period: period}
+	}
+	return rules, nil
+}
+
+// ValidateSampling reports whether spec is a valid log sampling
+// specification, a comma-separated list of module=burst/period entries such
+// as "mempool=10/1s".
+func ValidateSampling(spec string) error {
+	_, err := parseSampling(spec)
+	return err
+}
+
+type samplingKey struct {
+	module string
+	msg    string
+}
+
+type samplingCounter struct {
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// sampler drops Debug and Info messages of modules that log the same message
+// more often than permitted by their sampling rule. The number of dropped
+// messages is reported with the first message logged after them.
+type sampler struct {
+	rules map[string]samplingRule
+	now   func() time.Time
+
+	mtx      sync.Mutex
+	counters map[samplingKey]*samplingCounter
+	pruned   time.Time
+}
+
+func newSampler(rules map[string]samplingRule) *sampler {
+	return &sampler{
+		rules:    rules,
+		now:      time.Now,
+		counters: make(map[samplingKey]*samplingCounter),
+	}
+}
+
+// sample reports whether a message should be logged, and if so, how many
+// preceding messages with the same text were dropped.
+func (s *sampler) sample(module, msg string) (bool, int) {
+	rule, ok := s.rules[module]
+	if !ok {
+		return true, 0
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.now()
+	s.prune(now, rule.period)
+	key := samplingKey{module: module, msg: msg}
+	c, ok := s.counters[key]
+	if !ok {
+		c = &samplingCounter{start: now}
+		s.counters[key] = c
+	}
+	if now.Sub(c.start) >= rule.period {
+		c.start = now
+		c.count = 0
+	}
+	c.count++
+	if c.count > rule.burst {
+		c.dropped++
+		return false, 0
+	}
+	dropped := c.dropped
+	c.dropped = 0
+	return true, dropped
+}
+
+// prune removes counters without dropped messages whose period has ended, so
+// that messages with varying text do not accumulate counters.
+func (s *sampler) prune(now time.Time, period time.Duration) {
+	if now.Sub(s.pruned) < period {
+		return
+	}
+	s.pruned = now
+	for key, c := range s.counters {
+		if c.dropped == 0 && now.Sub(c.start) >= s.rules[key.module].period {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package trace
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package trace records the timing of operations as spans, which are grouped
+// into traces and exported in the OpenTelemetry protocol (OTLP) format.
+//
+// Spans are started from a Tracer, or as children of the span found in a
+// context with StartSpan. The span context of a trace is propagated across
+// processes using the W3C traceparent format, both in HTTP headers and gRPC
+// metadata. All methods of a nil *Tracer and a nil *Span are no-ops, so that
+// instrumented code need not check whether tracing is enabled.
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// IsValid reports whether id is non-zero.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether id is non-zero.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext identifies a span, and carries the sampling decision of its
+// trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether sc identifies a span.
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// Attribute is a key/value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span records a single timed operation.
+type Span struct {
+	tracer *Tracer
+	name   string
+	sc     SpanContext
+	parent SpanID
+
+	mtx    sync.Mutex
+	start  time.Time
+	end    time.Time
+	attrs  []Attribute
+	errMsg string
+	ended  bool
+}
+
+// SpanContext returns the span context of s.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes adds key/value pairs describing s, in the same form as taken
+// by the log.Logger methods.
+func (s *Span) SetAttributes(keyVals ...interface{}) {
+	if s == nil {
+		return
+	}
+	attrs := makeAttributes(keyVals)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks s as failed with err, if err is non-nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.errMsg = err.Error()
+}
+
+// End completes s, and queues it for export if its trace is sampled. Calls
+// after the first have no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mtx.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+// data returns an immutable copy of the recorded state of s.
+func (s *Span) data() SpanData {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return SpanData{
+		Name:         s.name,
+		TraceID:      s.sc.TraceID,
+		SpanID:       s.sc.SpanID,
+		ParentSpanID: s.parent,
+		Start:        s.start,
+		End:          s.end,
+		Attributes:   append([]Attribute(nil), s.attrs...),
+		Error:        s.errMsg,
+	}
+}
+
+func makeAttributes(keyVals []interface{}) []Attribute {
+	attrs := make([]Attribute, 0, len(keyVals)/2)
+	for i := 0; i+1 < len(keyVals); i += 2 {
+		attrs = append(attrs, Attribute{Key: fmt.Sprint(keyVals[i]), Value: keyVals[i+1]})
+	}
+	return attrs
+}
+
+const (
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	maxQueuedSpans       = 8192
+)
+
+// Tracer starts spans and exports them in batches.
+type Tracer struct {
+	service  string
+	attrs    []Attribute
+	exporter Exporter
+	ratio    float64
+
+	batchSize     int
+	flushInterval time.Duration
+
+	mtx     sync.Mutex
+	queue   []SpanData
+	dropped int
+	rng     *rand.Rand
+
+	flushCh chan chan struct{}
+	doneCh  chan struct{}
+	stopped sync.Once
+}
+
+// TracerOption sets an optional parameter of a Tracer.
+type TracerOption func(*Tracer)
+
+// WithSampleRatio sets the fraction of traces that are recorded. The default
+// is to record all traces. The sampling decision of a remote parent takes
+// precedence.
+func WithSampleRatio(ratio float64) TracerOption {
+	return func(t *Tracer) { t.ratio = ratio }
+}
+
+// WithResource adds key/value pairs describing the traced process to the
+// exported spans, e.g. the node moniker.
+func WithResource(keyVals ...interface{}) TracerOption {
+	return func(t *Tracer) { t.attrs = append(t.attrs, makeAttributes(keyVals)...) }
+}
+
+// WithBatching sets the number of spans at which the tracer exports a batch,
+// and the interval at which it exports spans regardless.
+func WithBatching(size int, interval time.Duration) TracerOption {
+	return func(t *Tracer) {
+		t.batchSize = size
+		t.flushInterval = interval
+	}
+}
+
+// NewTracer returns a tracer for the named service that exports spans to
+// exporter. Shutdown must be called to export the remaining spans and release
+// the resources of the tracer.
+func NewTracer(service string, exporter Exporter, options ...TracerOption) *Tracer {
+	var seed int64
+	_ = binary.Read(crand.Reader, binary.LittleEndian, &seed)
+
+	t := &Tracer{
+		service:       service,
+		exporter:      exporter,
+		ratio:         1,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		rng:           rand.New(rand.NewSource(seed)), // nolint:gosec // not used for security
+		flushCh:       make(chan chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(t)
+	}
+	t.attrs = append([]Attribute{{Key: "service.name", Value: service}}, t.attrs...)
+
+	go t.exportRoutine()
+	return t
+}
+
+// Start starts a span named name, as a child of the span in ctx if any. It
+// returns the span along with a copy of ctx containing it.
+func (t *Tracer) Start(ctx context.Context, name string, keyVals ...interface{}) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	t.mtx.Lock()
+	sc := SpanContext{Sampled: parent.Sampled}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+	} else {
+		t.rng.Read(sc.TraceID[:])
+		sc.Sampled = t.ratio >= 1 || t.rng.Float64() < t.ratio
+	}
+	t.rng.Read(sc.SpanID[:])
+	t.mtx.Unlock()
+
+	s := &Span{
+		tracer: t,
+		name:   name,
+		sc:     sc,
+		parent: parent.SpanID,
+		start:  time.Now(),
+		attrs:  makeAttributes(keyVals),
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Flush exports all queued spans, waiting until done or ctx ends.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case t.flushCh <- done:
+	case <-t.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports all queued spans and stops the tracer and its exporter.
+// Spans ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	err := t.Flush(ctx)
+	t.stopped.Do(func() { close(t.doneCh) })
+	if serr := t.exporter.Shutdown(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	data := s.data()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if len(t.queue) >= maxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, data)
+	if len(t.queue) == t.batchSize {
+		select {
+		case t.flushCh <- nil:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) exportRoutine() {
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.doneCh:
+			return
+		case <-ticker.C:
+			t.export()
+		case done := <-t.flushCh:
+			t.export()
+			if done != nil {
+				close(done)
+			}
+		}
+	}
+}
+
+func (t *Tracer) export() {
+	t.mtx.Lock()
+	spans := t.queue
+	t.queue = nil
+	dropped := t.dropped
+	t.dropped = 0
+	t.mtx.Unlock()
+
+	if len(spans) == 0 && dropped == 0 {
+		return
+	}
+	batch := &Batch{
+		Service:  t.service,
+		Resource: t.attrs,
+		Spans:    spans,
+		Dropped:  dropped,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = t.exporter.ExportSpans(ctx, batch) // exporters are best-effort
+}
+
+type spanKey struct{}
+type tracerKey struct{}
+type remoteKey struct{}
+
+// ContextWithTracer returns a copy of ctx in which spans started with
+// StartSpan are recorded by t, unless ctx contains a span.
+func ContextWithTracer(ctx context.Context, t *Tracer) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx in which sc, received
+// from another process, is the parent of spans started from ctx.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanFromContext returns the span in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the span context of the span in ctx, or of
+// the remote parent in ctx if there is no span.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// StartSpan starts a span named name, as a child of the span in ctx, or as a
+// root span of the tracer in ctx. If ctx contains neither, it returns ctx and
+// a nil span.
+func StartSpan(ctx context.Context, name string, keyVals ...interface{}) (context.Context, *Span) {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.tracer.Start(ctx, name, keyVals...)
+	}
+	t, _ := ctx.Value(tracerKey{}).(*Tracer)
+	return t.Start(ctx, name, keyVals...)
+}
+
+// ContextWithSpan returns a copy of ctx containing s, so that spans started
+// from it are children of s. If s is nil, it returns ctx.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, s)
+}
@@ -0,0 +1,213 @@
+package trace_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/bhojpur/state/pkg/libs/trace"
+)
+
+type memExporter struct {
+	mtx   sync.Mutex
+	spans []trace.SpanData
+}
+
+func (e *memExporter) ExportSpans(ctx context.Context, batch *trace.Batch) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = append(e.spans, batch.Spans...)
+	return nil
+}
+
+func (e *memExporter) Shutdown(ctx context.Context) error { return nil }
+
+func (e *memExporter) byName() map[string]trace.SpanData {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	spans := make(map[string]trace.SpanData)
+	for _, s := range e.spans {
+		spans[s.Name] = s
+	}
+	return spans
+}
+
+func TestTracer_Spans(t *testing.T) {
+	ctx := context.Background()
+	exp := &memExporter{}
+	tracer := trace.NewTracer("test", exp)
+
+	ctx, root := tracer.Start(ctx, "root", "height", int64(3))
+	_, child := trace.StartSpan(ctx, "child")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End() // no effect
+
+	// Without a span or tracer in the context, no span is started.
+	_, none := trace.StartSpan(context.Background(), "none")
+	require.Nil(t, none)
+	none.SetAttributes("ignored", true)
+	none.End()
+
+	require.NoError(t, tracer.Shutdown(ctx))
+	spans := exp.byName()
+	require.Len(t, spans, 2)
+	require.Equal(t, spans["root"].TraceID, spans["child"].TraceID)
+	require.Equal(t, spans["root"].SpanID, spans["child"].ParentSpanID)
+	require.False(t, spans["root"].ParentSpanID.IsValid())
+	require.Equal(t, "boom", spans["child"].Error)
+	require.Equal(t, []trace.Attribute{{Key: "height", Value: int64(3)}}, spans["root"].Attributes)
+	require.False(t, spans["root"].End.Before(spans["child"].End))
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	ctx := context.Background()
+	exp := &memExporter{}
+	tracer := trace.NewTracer("test", exp, trace.WithSampleRatio(0))
+
+	ctx1, root := tracer.Start(ctx, "root")
+	_, child := trace.StartSpan(ctx1, "child")
+	require.False(t, child.SpanContext().Sampled)
+	child.End()
+	root.End()
+
+	// A sampled remote parent takes precedence.
+	remote := trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}, Sampled: true}
+	_, span := tracer.Start(trace.ContextWithRemoteSpanContext(ctx, remote), "remote")
+	span.End()
+
+	require.NoError(t, tracer.Shutdown(ctx))
+	spans := exp.byName()
+	require.Len(t, spans, 1)
+	require.Equal(t, remote.TraceID, spans["remote"].TraceID)
+	require.Equal(t, remote.SpanID, spans["remote"].ParentSpanID)
+}
+
+func TestTraceparent(t *testing.T) {
+	sc := trace.SpanContext{TraceID: trace.TraceID{0xab, 1}, SpanID: trace.SpanID{0xcd, 2}, Sampled: true}
+	tp := sc.Traceparent()
+	require.Equal(t, "00-ab010000000000000000000000000000-cd02000000000000-01", tp)
+	parsed, err := trace.ParseTraceparent(tp)
+	require.NoError(t, err)
+	require.Equal(t, sc, parsed)
+
+	for _, invalid := range []string{
+		"",
+		"00-ab01-cd02-01",
+		"00-00000000000000000000000000000000-cd02000000000000-01",
+		"00-AB010000000000000000000000000000-cd02000000000000-01",
+		"ff-ab010000000000000000000000000000-cd02000000000000-01",
+		"00-ab010000000000000000000000000000-cd02000000000000-01-extra",
+	} {
+		_, err := trace.ParseTraceparent(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	ctx := context.Background()
+	tracer := trace.NewTracer("test", &memExporter{})
+	defer func() { require.NoError(t, tracer.Shutdown(ctx)) }()
+	ctx, span := tracer.Start(ctx, "caller")
+	defer span.End()
+
+	// HTTP: the handler sees the caller's span context as the remote parent.
+	var got trace.SpanContext
+	handler := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, s := trace.StartSpan(r.Context(), "handler")
+		defer s.End()
+		got = trace.SpanContextFromContext(r.Context())
+		require.Equal(t, span.SpanContext().TraceID, s.SpanContext().TraceID)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	trace.InjectHTTP(ctx, req.Header)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, span.SpanContext(), got)
+
+	// gRPC: the client interceptor sends the span context as metadata.
+	client := trace.UnaryClientInterceptor()
+	server := trace.UnaryServerInterceptor()
+	err := client(ctx, "/abci/Info", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			sctx := metadata.NewIncomingContext(context.Background(), md)
+			_, err := server(sctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = trace.SpanContextFromContext(ctx)
+				return nil, nil
+			})
+			return err
+		})
+	require.NoError(t, err)
+	require.Equal(t, span.SpanContext(), got)
+}
+
+func TestFileExporter(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	tracer := trace.NewTracer("test", trace.NewFileExporter(&buf), trace.WithResource("moniker", "node0"))
+	_, span := tracer.Start(ctx, "consensus.height", "height", 5)
+	span.End()
+	require.NoError(t, tracer.Shutdown(ctx))
+
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value map[string]interface{}
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID    string `json:"traceId"`
+					Name       string
+					Attributes []struct {
+						Key   string
+						Value map[string]interface{}
+					}
+				}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &req))
+	require.Len(t, req.ResourceSpans, 1)
+	rs := req.ResourceSpans[0]
+	require.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	require.Equal(t, "test", rs.Resource.Attributes[0].Value["stringValue"])
+	require.Equal(t, "node0", rs.Resource.Attributes[1].Value["stringValue"])
+	spans := rs.ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	require.Equal(t, "consensus.height", spans[0].Name)
+	require.Equal(t, span.SpanContext().TraceID.String(), spans[0].TraceID)
+	require.Equal(t, "5", spans[0].Attributes[0].Value["intValue"])
+}
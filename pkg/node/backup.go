@@ -0,0 +1,99 @@
+package node
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/bhojpur/state/pkg/config"
+	dbm "github.com/bhojpur/state/pkg/database"
+)
+
+// dbRegistry records the databases a node opens through its DBProvider, so
+// that they can be checkpointed while the node runs.
+type dbRegistry struct {
+	mtx sync.Mutex
+	dbs map[string]dbm.DB
+}
+
+func newDBRegistry() *dbRegistry {
+	return &dbRegistry{dbs: make(map[string]dbm.DB)}
+}
+
+// wrap returns a DBProvider that records every database opened through
+// provider. A database opened again under the same ID replaces the earlier
+// one.
+func (r *dbRegistry) wrap(provider config.DBProvider) config.DBProvider {
+	return func(ctx *config.DBContext) (dbm.DB, error) {
+		db, err := provider(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.mtx.Lock()
+		r.dbs[ctx.ID] = db
+		r.mtx.Unlock()
+		return db, nil
+	}
+}
+
+// checkpoint writes a consistent copy of every recorded database into dir,
+// and returns the IDs of the databases written.
+func (r *dbRegistry) checkpoint(ctx context.Context, dir string) ([]string, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	ids := make([]string, 0, len(r.dbs))
+	for id := range r.dbs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	checkpoints := dbm.NewCheckpointSet(dir)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := checkpoints.Checkpoint(r.dbs[id]); err != nil {
+			return nil, fmt.Errorf("checkpointing %q: %w", id, err)
+		}
+	}
+	return ids, nil
+}
+
+// Backup writes a consistent copy of every database of the node into dir,
+// which can afterwards be used as the db-dir of a node. Each database is
+// copied at its own point in time, so the block store may run ahead of the
+// state store by a block, as it may after a crash.
+func (n *nodeImpl) Backup(ctx context.Context, dir string) ([]string, error) {
+	ids, err := n.dbRegistry.checkpoint(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	n.logger.Info("backed up databases", "dir", dir, "databases", ids)
+	return ids, nil
+}
@@ -28,6 +28,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +41,7 @@ import (
 	"github.com/bhojpur/state/internal/evidence"
 	"github.com/bhojpur/state/internal/mempool"
 	"github.com/bhojpur/state/internal/p2p"
+	"github.com/bhojpur/state/internal/p2p/dht"
 	"github.com/bhojpur/state/internal/p2p/pex"
 	"github.com/bhojpur/state/internal/proxy"
 	rpccore "github.com/bhojpur/state/internal/rpc/core"
@@ -52,6 +54,7 @@ import (
 	abci "github.com/bhojpur/state/pkg/abci/types"
 	"github.com/bhojpur/state/pkg/config"
 	"github.com/bhojpur/state/pkg/crypto"
+	dbm "github.com/bhojpur/state/pkg/database"
 	"github.com/bhojpur/state/pkg/libs/log"
 	"github.com/bhojpur/state/pkg/libs/service"
 	libtime "github.com/bhojpur/state/pkg/libs/time"
@@ -71,10 +74,15 @@ type nodeImpl struct {
 
 	// config
 	config        *config.Config
+	fileConfig    *config.Config // config file as last read, if available
+	reloadMtx     sync.Mutex
+	dbProvider    config.DBProvider
+	dbRegistry    *dbRegistry
 	genesisDoc    *types.GenesisDoc   // initial validator set
 	privValidator types.PrivValidator // local node's validator key
 
 	// network
+	transport   *p2p.MConnTransport
 	peerManager *p2p.PeerManager
 	router      *p2p.Router
 	nodeInfo    types.NodeInfo
@@ -87,11 +95,13 @@ type nodeImpl struct {
 	blockStore     *store.BlockStore // store the blockchain to disk
 	evPool         *evidence.Pool
 	indexerService *indexer.Service
+	upgrader       *sm.Upgrader
 	services       []service.Service
 	rpcListeners   []net.Listener // rpc servers
 	shutdownOps    closer
 	rpcEnv         *rpccore.Environment
 	prometheusSrv  *http.Server
+	dbMetrics      *dbm.Metrics
 }
 
 // newDefaultNode returns a Bhojpur State node with default settings for the
@@ -153,6 +163,10 @@ func makeNode(
 
 	closers := []closer{convertCancelCloser(cancel)}
 
+	// record the databases of the node, so that they can be backed up
+	dbRegistry := newDBRegistry()
+	dbProvider = dbRegistry.wrap(dbProvider)
+
 	blockStore, stateDB, dbCloser, err := initDBs(cfg, dbProvider)
 	if err != nil {
 		return nil, combineCloseError(err, dbCloser)
@@ -177,6 +191,25 @@ func makeNode(
 
 	nodeMetrics := defaultMetricsProvider(cfg.Instrumentation)(genDoc.ChainID)
 
+	tracer, err := createTracer(cfg, genDoc.ChainID)
+	if err != nil {
+		return nil, combineCloseError(err, makeCloser(closers))
+	}
+	// The wrappers below hide the in-process application, if any.
+	localApp := abciclient.LocalApplication(client)
+	if tracer != nil {
+		client = abciclient.NewTracingClient(client)
+		closers = append(closers, func() error { return tracer.Shutdown(context.Background()) })
+	}
+	if path := cfg.ABCIRecordFilePath(); path != "" {
+		client, err = abciclient.NewRecordingClient(ctx, logger.With("module", "abci-recorder"), client, path)
+		if err != nil {
+			return nil, combineCloseError(
+				fmt.Errorf("failed to open ABCI record file %q: %w", path, err),
+				makeCloser(closers))
+		}
+	}
+
 	proxyApp := proxy.New(client, logger.With("module", "proxy"), nodeMetrics.proxy)
 	eventBus := eventbus.NewDefault(logger.With("module", "events"))
 
@@ -223,7 +256,7 @@ func makeNode(
 		}
 	}
 
-	peerManager, peerCloser, err := createPeerManager(cfg, dbProvider, nodeKey.ID)
+	peerManager, peerCloser, err := createPeerManager(cfg, dbProvider, nodeKey)
 	closers = append(closers, peerCloser)
 	if err != nil {
 		return nil, combineCloseError(
@@ -234,10 +267,15 @@ func makeNode(
 	// TODO construct node here:
 	node := &nodeImpl{
 		config:        cfg,
+		fileConfig:    readConfigFile(cfg, logger),
+		dbProvider:    dbProvider,
+		dbRegistry:    dbRegistry,
+		dbMetrics:     nodeMetrics.database,
 		logger:        logger,
 		genesisDoc:    genDoc,
 		privValidator: privValidator,
 
+		transport:   createTransport(logger, cfg),
 		peerManager: peerManager,
 		nodeKey:     nodeKey,
 
@@ -265,15 +303,22 @@ func makeNode(
 			EventLog:   eventLog,
 			Logger:     logger.With("module", "rpc"),
 			Config:     *cfg.RPC,
+			Tracer:     tracer,
 		},
 	}
 
-	node.router, err = createRouter(logger, nodeMetrics.p2p, node.NodeInfo, nodeKey, peerManager, cfg, proxyApp)
+	node.rpcEnv.ReloadConfig = node.ReloadConfig
+	node.rpcEnv.Backup = node.Backup
+
+	node.router, err = createRouter(logger, nodeMetrics.p2p, node.NodeInfo, nodeKey, peerManager,
+		node.transport, cfg, proxyApp)
 	if err != nil {
 		return nil, combineCloseError(
 			fmt.Errorf("failed to create router: %w", err),
 			makeCloser(closers))
 	}
+	node.rpcEnv.Traffic = node.router
+	node.rpcEnv.P2PCapture = node.router
 
 	evReactor, evPool, edbCloser, err := createEvidenceReactor(logger, cfg, dbProvider,
 		stateStore, blockStore, peerManager.Subscribe, node.router.OpenChannel, nodeMetrics.evidence, eventBus)
@@ -290,6 +335,15 @@ func makeNode(
 	node.rpcEnv.Mempool = mp
 	node.services = append(node.services, mpReactor)
 
+	upgrader, err := sm.NewUpgrader(cfg.Consensus.UpgradeInfoFile(), sm.UpgradePlan{
+		Name:   cfg.Consensus.UpgradeName,
+		Height: cfg.Consensus.HaltHeight,
+	})
+	if err != nil {
+		return nil, combineCloseError(fmt.Errorf("loading upgrade info: %w", err), makeCloser(closers))
+	}
+	node.upgrader = upgrader
+
 	// make block executor for consensus and blockchain reactors to execute blocks
 	blockExec := sm.NewBlockExecutor(
 		stateStore,
@@ -300,6 +354,7 @@ func makeNode(
 		blockStore,
 		eventBus,
 		nodeMetrics.state,
+		sm.BlockExecutorWithUpgrader(upgrader),
 	)
 
 	// Determine whether we should attempt state sync.
@@ -323,6 +378,7 @@ func makeNode(
 		evPool,
 		eventBus,
 		consensus.StateMetrics(nodeMetrics.consensus),
+		consensus.StateTracer(tracer),
 		consensus.SkipStateStoreBootstrap,
 	)
 	if err != nil {
@@ -355,6 +411,7 @@ func makeNode(
 		blockSync && !stateSync,
 		nodeMetrics.consensus,
 		eventBus,
+		blocksync.WithArchive(cfg.BlockSync.ArchivePath()),
 	)
 	node.services = append(node.services, bcReactor)
 	node.rpcEnv.BlockSyncReactor = bcReactor
@@ -371,6 +428,33 @@ func makeNode(
 		node.services = append(node.services, pex.NewReactor(logger, peerManager, node.router.OpenChannel, peerManager.Subscribe))
 	}
 
+	if cfg.P2P.DHTReactor {
+		dhtReactor, err := dht.NewReactor(logger, peerManager, node.router.OpenChannel, peerManager.Subscribe)
+		if err != nil {
+			return nil, combineCloseError(fmt.Errorf("failed to create DHT reactor: %w", err), makeCloser(closers))
+		}
+		node.services = append(node.services, dhtReactor)
+	}
+
+	// If the in-process application lets the node manage its snapshots, serve
+	// and restore them through the snapshot manager.
+	stateSyncConn := proxyApp
+	if app, ok := abci.AsStateSnapshotter(localApp); ok {
+		snapshotStore, err := statesync.NewSnapshotStore(cfg.StateSync.SnapshotPath())
+		if err != nil {
+			return nil, combineCloseError(fmt.Errorf("failed to open snapshot store: %w", err), makeCloser(closers))
+		}
+		snapshotManager := statesync.NewSnapshotManager(
+			logger.With("module", "snapshots"),
+			*cfg.StateSync,
+			app,
+			snapshotStore,
+			eventBus,
+		)
+		node.services = append(node.services, snapshotManager)
+		stateSyncConn = snapshotManager.Client(proxyApp)
+	}
+
 	// Set up state sync reactor, and schedule a sync if requested.
 	// FIXME The way we do phased startups (e.g. replay -> block sync -> consensus) is very messy,
 	// we should clean this whole thing up. See:
@@ -379,12 +463,12 @@ func makeNode(
 		genDoc.InitialHeight,
 		*cfg.StateSync,
 		logger.With("module", "statesync"),
-		proxyApp,
+		stateSyncConn,
 		node.router.OpenChannel,
 		peerManager.Subscribe,
 		stateStore,
 		blockStore,
-		cfg.StateSync.TempDir,
+		cfg.StateSync.TempPath(),
 		nodeMetrics.statesync,
 		eventBus,
 		// the post-sync operation
@@ -452,6 +536,15 @@ func (n *nodeImpl) OnStart(ctx context.Context) error {
 		return fmt.Errorf("cannot load state: %w", err)
 	}
 
+	// Refuse to continue past a scheduled upgrade with the old binary.
+	info, err := n.rpcEnv.ProxyApp.Info(ctx, &proxy.RequestInfo)
+	if err != nil {
+		return fmt.Errorf("error calling Info: %w", err)
+	}
+	if err := n.upgrader.CheckStartup(state, info.AppVersion); err != nil {
+		return err
+	}
+
 	logNodeStartupInfo(state, n.rpcEnv.PubKey, n.logger, n.config.Mode)
 
 	// TODO: Fetch and provide real options and do proper p2p bootstrapping.
@@ -509,6 +602,7 @@ func (n *nodeImpl) OnStart(ctx context.Context) error {
 
 	if n.config.Instrumentation.Prometheus && n.config.Instrumentation.PrometheusListenAddr != "" {
 		n.prometheusSrv = n.startPrometheusServer(ctx, n.config.Instrumentation.PrometheusListenAddr)
+		go n.dbRegistry.recordStats(ctx, n.dbMetrics, dbStatsInterval)
 	}
 
 	// Start the transport.
@@ -657,6 +751,7 @@ func defaultGenesisDocProviderFunc(cfg *config.Config) genesisDocProvider {
 
 type nodeMetrics struct {
 	consensus *consensus.Metrics
+	database  *dbm.Metrics
 	eventlog  *eventlog.Metrics
 	indexer   *indexer.Metrics
 	mempool   *mempool.Metrics
@@ -677,6 +772,7 @@ func defaultMetricsProvider(cfg *config.InstrumentationConfig) metricsProvider {
 		if cfg.Prometheus {
 			return &nodeMetrics{
 				consensus: consensus.PrometheusMetrics(cfg.Namespace, "chain_id", chainID),
+				database:  dbm.PrometheusMetrics(cfg.Namespace, "chain_id", chainID),
 				eventlog:  eventlog.PrometheusMetrics(cfg.Namespace, "chain_id", chainID),
 				indexer:   indexer.PrometheusMetrics(cfg.Namespace, "chain_id", chainID),
 				mempool:   mempool.PrometheusMetrics(cfg.Namespace, "chain_id", chainID),
@@ -689,6 +785,7 @@ func defaultMetricsProvider(cfg *config.InstrumentationConfig) metricsProvider {
 		}
 		return &nodeMetrics{
 			consensus: consensus.NopMetrics(),
+			database:  dbm.NopMetrics(),
 			indexer:   indexer.NopMetrics(),
 			mempool:   mempool.NopMetrics(),
 			p2p:       p2p.NopMetrics(),
@@ -730,7 +827,16 @@ func loadStateFromDBOrGenesisDocProvider(stateStore sm.Store, genDoc *types.Gene
 
 func getRouterConfig(conf *config.Config, appClient abciclient.Client) p2p.RouterOptions {
 	opts := p2p.RouterOptions{
-		QueueType: conf.P2P.QueueType,
+		QueueType:    conf.P2P.QueueType,
+		PeerSendRate: conf.P2P.PeerSendRate,
+	}
+
+	// the rates were validated along with the config
+	if rates, err := conf.P2P.ChannelSendRateLimits(); err == nil && len(rates) > 0 {
+		opts.ChannelSendRates = make(map[p2p.ChannelID]int64, len(rates))
+		for chID, rate := range rates {
+			opts.ChannelSendRates[p2p.ChannelID(chID)] = rate
+		}
 	}
 
 	if conf.FilterPeers && appClient != nil {
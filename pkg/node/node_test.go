@@ -24,6 +24,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net"
 	"os"
@@ -173,6 +174,70 @@ func TestNodeSetAppVersion(t *testing.T) {
 	assert.Equal(t, n.nodeInfo.ProtocolVersion.App, appVersion)
 }
 
+// snapshotterApp is a kvstore application that lets the node manage its
+// snapshots.
+type snapshotterApp struct {
+	*kvstore.Application
+	exported chan uint64
+}
+
+func (app *snapshotterApp) ExportState(_ context.Context, height uint64, w io.Writer) error {
+	select {
+	case app.exported <- height:
+	default:
+	}
+	_, err := fmt.Fprintf(w, "state at height %d", height)
+	return err
+}
+
+func (*snapshotterApp) RestoreState(context.Context, uint64, []byte, io.Reader) error {
+	return errors.New("not supported")
+}
+
+func TestNodeSnapshotsWithTracing(t *testing.T) {
+	cfg, err := config.ResetTestRoot(t.TempDir(), "node_snapshots_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(cfg.RootDir)
+
+	// the client of the application is wrapped to trace and record calls
+	cfg.Instrumentation.TracingExporter = config.TracingExporterFile
+	cfg.ABCIRecordFile = "data/abci.record"
+	cfg.StateSync.SnapshotInterval = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewNopLogger()
+
+	nodeKey, err := types.LoadOrGenNodeKey(cfg.NodeKeyFile())
+	require.NoError(t, err)
+	pval, err := makeDefaultPrivval(cfg)
+	require.NoError(t, err)
+
+	app := &snapshotterApp{Application: kvstore.NewApplication(), exported: make(chan uint64, 1)}
+	ns, err := makeNode(ctx, cfg, pval, nodeKey, abciclient.NewLocalClient(logger, app),
+		defaultGenesisDocProviderFunc(cfg), config.DefaultDBProvider, logger)
+	require.NoError(t, err)
+	n, ok := ns.(*nodeImpl)
+	require.True(t, ok)
+	t.Cleanup(func() {
+		cancel()
+		if n.IsRunning() {
+			n.Wait()
+		}
+	})
+
+	require.NoError(t, n.Start(ctx))
+
+	// the node takes snapshots of the application
+	select {
+	case height := <-app.exported:
+		assert.EqualValues(t, 1, height)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a snapshot")
+	}
+}
+
 func TestNodeSetPrivValTCP(t *testing.T) {
 	addr := "tcp://" + testFreeAddr(t)
 
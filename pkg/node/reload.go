@@ -0,0 +1,163 @@
+package node
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bhojpur/state/internal/mempool"
+	"github.com/bhojpur/state/internal/state/indexer"
+	"github.com/bhojpur/state/internal/state/indexer/sink"
+	"github.com/bhojpur/state/pkg/config"
+	"github.com/bhojpur/state/pkg/libs/log"
+)
+
+// readConfigFile returns the config file of the node, or nil if it can't be
+// read. It is the baseline for reloading the file, so that settings given by
+// flags or the environment are not mistaken for changes to the file.
+func readConfigFile(cfg *config.Config, logger log.Logger) *config.Config {
+	fileConfig, err := config.ReadConfigFile(cfg.RootDir)
+	if err != nil {
+		logger.Debug("config file not available as baseline for reloading", "err", err)
+		return nil
+	}
+	return fileConfig
+}
+
+// ReloadConfig re-reads the config file of the node, and applies the settings
+// changed since it was last read to the running services, if they can take
+// effect without a restart. Changed settings that require a restart are
+// reported as rejected, and keep their running values.
+func (n *nodeImpl) ReloadConfig(ctx context.Context) (*config.ReloadReport, error) {
+	n.reloadMtx.Lock()
+	defer n.reloadMtx.Unlock()
+
+	next, err := config.ReadConfigFile(n.config.RootDir)
+	if err != nil {
+		return nil, err
+	}
+	base := n.fileConfig
+	if base == nil {
+		base = n.config
+	}
+
+	cfg, report := config.Reload(n.config, config.Diff(base, next))
+	if err := cfg.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid configuration after reload: %w", err)
+	}
+	if err := n.applyConfig(cfg, report.Applied); err != nil {
+		return nil, err
+	}
+
+	n.config = cfg
+	// Rejected changes are left out of the baseline, so that they are
+	// reported again until the node is restarted.
+	n.fileConfig, _ = config.Reload(base, report.Applied)
+
+	if len(report.Applied) > 0 {
+		n.logger.Info("reloaded configuration", "applied", config.Keys(report.Applied))
+	}
+	if len(report.Rejected) > 0 {
+		n.logger.Error("changed settings require a restart", "rejected", config.Keys(report.Rejected))
+	}
+	return report, nil
+}
+
+// applyConfig applies the settings of cfg that changed to the running
+// services. Changes that can fail are applied first, so that nothing is
+// applied if they do.
+func (n *nodeImpl) applyConfig(cfg *config.Config, changes []config.FieldChange) error {
+	changed := func(prefix string) bool {
+		for _, c := range changes {
+			if strings.HasPrefix(c.Key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if changed("p2p.") {
+		if err := n.setMaxConnections(cfg.P2P.MaxConnections); err != nil {
+			return fmt.Errorf("failed to change p2p.max-connections: %w", err)
+		}
+	}
+	if changed("tx-index.") {
+		if err := n.reloadIndexer(cfg); err != nil {
+			if changed("p2p.") {
+				_ = n.setMaxConnections(n.config.P2P.MaxConnections)
+			}
+			return fmt.Errorf("failed to change indexer: %w", err)
+		}
+	}
+	if changed("mempool.") {
+		if txmp, ok := n.rpcEnv.Mempool.(*mempool.TxMempool); ok {
+			txmp.SetConfig(cfg.Mempool)
+		}
+	}
+	if changed("rpc.") {
+		n.rpcEnv.SetCORS(cfg.RPC)
+	}
+	if changed("log-") {
+		// Both settings were validated by cfg.ValidateBasic.
+		_ = log.SetLevel(n.logger, cfg.LogLevel)
+		_ = log.SetSampling(n.logger, cfg.LogSampling)
+	}
+	return nil
+}
+
+// setMaxConnections changes the number of peers the node connects to, with
+// the defaults of createPeerManager.
+func (n *nodeImpl) setMaxConnections(maxConnections uint16) error {
+	maxConns := maxConnections
+	if maxConns == 0 {
+		maxConns = 64
+	}
+	if err := n.peerManager.SetMaxConnected(maxConns); err != nil {
+		return err
+	}
+	n.transport.SetMaxAcceptedConnections(uint32(maxConnections))
+	return nil
+}
+
+// reloadIndexer replaces the event sinks of the node by those configured in
+// cfg. If they can't be opened, the node keeps indexing with the sinks of the
+// running configuration.
+func (n *nodeImpl) reloadIndexer(cfg *config.Config) error {
+	var sinks []indexer.EventSink
+	err := n.indexerService.ReplaceEventSinks(func() ([]indexer.EventSink, error) {
+		var err error
+		sinks, err = sink.EventSinksFromConfig(cfg, n.dbProvider, n.genesisDoc.ChainID)
+		if err == nil {
+			return sinks, nil
+		}
+		var ferr error
+		sinks, ferr = sink.EventSinksFromConfig(n.config, n.dbProvider, n.genesisDoc.ChainID)
+		if ferr != nil {
+			n.logger.Error("failed to reopen event sinks, indexing is disabled", "err", ferr)
+		}
+		return sinks, err
+	})
+	n.eventSinks = sinks
+	n.rpcEnv.SetEventSinks(sinks)
+	return err
+}
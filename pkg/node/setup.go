@@ -25,6 +25,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -37,6 +39,7 @@ import (
 	"github.com/bhojpur/state/internal/mempool"
 	"github.com/bhojpur/state/internal/p2p"
 	"github.com/bhojpur/state/internal/p2p/conn"
+	"github.com/bhojpur/state/internal/p2p/dht"
 	"github.com/bhojpur/state/internal/p2p/pex"
 	sm "github.com/bhojpur/state/internal/state"
 	"github.com/bhojpur/state/internal/state/indexer"
@@ -49,6 +52,7 @@ import (
 	libnet "github.com/bhojpur/state/pkg/libs/net"
 	"github.com/bhojpur/state/pkg/libs/service"
 	libstrings "github.com/bhojpur/state/pkg/libs/strings"
+	"github.com/bhojpur/state/pkg/libs/trace"
 	"github.com/bhojpur/state/pkg/privval"
 	privrpc "github.com/bhojpur/state/pkg/privval/grpc"
 	"github.com/bhojpur/state/pkg/types"
@@ -114,6 +118,35 @@ func initDBs(
 	return blockStore, stateDB, makeCloser(closers), nil
 }
 
+// createTracer returns the tracer configured by cfg.Instrumentation, or nil
+// if tracing is disabled.
+func createTracer(cfg *config.Config, chainID string) (*trace.Tracer, error) {
+	var exporter trace.Exporter
+	switch cfg.Instrumentation.TracingExporter {
+	case "":
+		return nil, nil
+	case config.TracingExporterOTLP:
+		exporter = trace.NewOTLPExporter(cfg.Instrumentation.TracingEndpoint, nil)
+	case config.TracingExporterFile:
+		path := cfg.Instrumentation.TracingFilePath()
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("creating tracing directory: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("opening tracing file: %w", err)
+		}
+		exporter = trace.NewFileExporter(f)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Instrumentation.TracingExporter)
+	}
+
+	return trace.NewTracer("bhojpur-state", exporter,
+		trace.WithSampleRatio(cfg.Instrumentation.TracingSampleRate),
+		trace.WithResource("service.instance.id", cfg.Moniker, "chain_id", chainID),
+	), nil
+}
+
 func logNodeStartupInfo(state sm.State, pubKey crypto.PubKey, logger log.Logger, mode string) {
 	// Log the version info.
 	logger.Info("Version info",
@@ -223,10 +256,10 @@ func createEvidenceReactor(
 func createPeerManager(
 	cfg *config.Config,
 	dbProvider config.DBProvider,
-	nodeID types.NodeID,
+	nodeKey types.NodeKey,
 ) (*p2p.PeerManager, closer, error) {
 
-	selfAddr, err := p2p.ParseNodeAddress(nodeID.AddressString(cfg.P2P.ExternalAddress))
+	selfAddr, err := p2p.ParseNodeAddress(nodeKey.ID.AddressString(cfg.P2P.ExternalAddress))
 	if err != nil {
 		return nil, func() error { return nil }, fmt.Errorf("couldn't parse ExternalAddress %q: %w", cfg.P2P.ExternalAddress, err)
 	}
@@ -247,6 +280,7 @@ func createPeerManager(
 
 	options := p2p.PeerManagerOptions{
 		SelfAddress:            selfAddr,
+		PrivKey:                nodeKey.PrivKey,
 		MaxConnected:           maxConns,
 		MaxConnectedUpgrade:    4,
 		MaxPeers:               1000,
@@ -281,7 +315,7 @@ func createPeerManager(
 		return nil, func() error { return nil }, fmt.Errorf("unable to initialize peer store: %w", err)
 	}
 
-	peerManager, err := p2p.NewPeerManager(nodeID, peerDB, options)
+	peerManager, err := p2p.NewPeerManager(nodeKey.ID, peerDB, options)
 	if err != nil {
 		return nil, peerDB.Close, fmt.Errorf("failed to create peer manager: %w", err)
 	}
@@ -295,30 +329,33 @@ func createPeerManager(
 	return peerManager, peerDB.Close, nil
 }
 
+func createTransport(logger log.Logger, cfg *config.Config) *p2p.MConnTransport {
+	transportConf := conn.DefaultMConnConfig()
+	transportConf.FlushThrottle = cfg.P2P.FlushThrottleTimeout
+	transportConf.SendRate = cfg.P2P.SendRate
+	transportConf.RecvRate = cfg.P2P.RecvRate
+	transportConf.MaxPacketMsgPayloadSize = cfg.P2P.MaxPacketMsgPayloadSize
+	return p2p.NewMConnTransport(
+		logger.With("module", "p2p"), transportConf, []*p2p.ChannelDescriptor{},
+		p2p.MConnTransportOptions{
+			MaxAcceptedConnections: uint32(cfg.P2P.MaxConnections),
+		},
+	)
+}
+
 func createRouter(
 	logger log.Logger,
 	p2pMetrics *p2p.Metrics,
 	nodeInfoProducer func() *types.NodeInfo,
 	nodeKey types.NodeKey,
 	peerManager *p2p.PeerManager,
+	transport p2p.Transport,
 	cfg *config.Config,
 	appClient abciclient.Client,
 ) (*p2p.Router, error) {
 
 	p2pLogger := logger.With("module", "p2p")
 
-	transportConf := conn.DefaultMConnConfig()
-	transportConf.FlushThrottle = cfg.P2P.FlushThrottleTimeout
-	transportConf.SendRate = cfg.P2P.SendRate
-	transportConf.RecvRate = cfg.P2P.RecvRate
-	transportConf.MaxPacketMsgPayloadSize = cfg.P2P.MaxPacketMsgPayloadSize
-	transport := p2p.NewMConnTransport(
-		p2pLogger, transportConf, []*p2p.ChannelDescriptor{},
-		p2p.MConnTransportOptions{
-			MaxAcceptedConnections: uint32(cfg.P2P.MaxConnections),
-		},
-	)
-
 	ep, err := p2p.NewEndpoint(nodeKey.ID.AddressString(cfg.P2P.ListenAddress))
 	if err != nil {
 		return nil, err
@@ -382,6 +419,9 @@ func makeNodeInfo(
 	if cfg.P2P.PexReactor {
 		nodeInfo.Channels = append(nodeInfo.Channels, pex.PexChannel)
 	}
+	if cfg.P2P.DHTReactor {
+		nodeInfo.Channels = append(nodeInfo.Channels, dht.DHTChannel)
+	}
 
 	nodeInfo.ListenAddr = cfg.P2P.ExternalAddress
 	if nodeInfo.ListenAddr == "" {
@@ -0,0 +1,112 @@
+package eventstream
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/bhojpur/state/internal/libs/tempfile"
+)
+
+// A Checkpoint is the progress of a Consumer.
+type Checkpoint struct {
+	// The cursor of the last delivered event, or "" to start at the
+	// beginning of the event log.
+	Cursor string `json:"cursor"`
+
+	// The highest block height of the delivered events, which is where
+	// backfilling starts if the cursor falls out of the event log.
+	Height int64 `json:"height,string"`
+}
+
+// A CheckpointStore persists the checkpoint of a Consumer.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or the zero checkpoint if none was
+	// saved yet.
+	Load() (Checkpoint, error)
+
+	// Save replaces the saved checkpoint. Once Save returns, the checkpoint
+	// must survive a crash.
+	Save(Checkpoint) error
+}
+
+// FileCheckpointStore stores a checkpoint as JSON in a file, which is
+// written synchronously and replaced atomically on each save.
+type FileCheckpointStore struct {
+	path string
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// NewFileCheckpointStore returns a checkpoint store backed by the file at
+// path, which is created on the first save.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load implements CheckpointStore.
+func (s *FileCheckpointStore) Load() (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	} else if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// Save implements CheckpointStore.
+func (s *FileCheckpointStore) Save(cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(s.path, data, 0600)
+}
+
+// MemoryCheckpointStore keeps a checkpoint in memory, for consumers that need
+// not resume after a restart, and for tests.
+type MemoryCheckpointStore struct {
+	mtx sync.Mutex
+	cp  Checkpoint
+}
+
+var _ CheckpointStore = (*MemoryCheckpointStore)(nil)
+
+// Load implements CheckpointStore.
+func (s *MemoryCheckpointStore) Load() (Checkpoint, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cp, nil
+}
+
+// Save implements CheckpointStore.
+func (s *MemoryCheckpointStore) Save(cp Checkpoint) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cp = cp
+	return nil
+}
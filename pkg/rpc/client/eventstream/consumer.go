@@ -0,0 +1,373 @@
+package eventstream
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+)
+
+// BackfillEvent is the event label of the records a Consumer delivers for the
+// blocks it backfills. Their data is the ResultBlockResults of the block.
+const BackfillEvent = "BlockResults"
+
+// ConsumerClient is the subset of the RPC client interface consumed by
+// Consumer.
+type ConsumerClient interface {
+	Client
+	BlockResults(ctx context.Context, height *int64) (*coretypes.ResultBlockResults, error)
+}
+
+// A Record is an event delivered by a Consumer to its sink.
+type Record struct {
+	// The cursor of the event in the event log of the node, or "" for the
+	// records of backfilled blocks.
+	Cursor string `json:"cursor,omitempty"`
+
+	// The height of the block the event belongs to, or 0 if the event data
+	// has no height.
+	Height int64 `json:"height,string"`
+
+	// The event label of the record (for example, "Tx"), or BackfillEvent.
+	Event string `json:"event"`
+
+	// The encoded event data, as in EventItem, or the ResultBlockResults of
+	// a backfilled block.
+	Data json.RawMessage `json:"data"`
+}
+
+// ID returns an identifier of the record, which is the same each time the
+// record is delivered, so that sinks can discard records delivered twice.
+func (r *Record) ID() string {
+	if r.Cursor != "" {
+		return r.Cursor
+	}
+	return fmt.Sprintf("%s/%d", BackfillEvent, r.Height)
+}
+
+// A Consumer delivers the events matching a query to a sink, and persists
+// its progress so that it resumes where it left off when restarted.
+//
+// Delivery is at-least-once: the checkpoint is only saved once the sink has
+// accepted a record, and failed deliveries are retried until they succeed, so
+// a record may be delivered more than once but is never skipped. If the
+// consumer falls behind the event log of the node, it backfills the blocks it
+// may have missed from the "block_results" method, starting at the height of
+// the newest event it processed, and then resumes after the newest event the
+// log held when the backfill started. If the log moved past that event in the
+// meantime, the blocks committed since are backfilled in turn. Backfilled blocks are delivered as records with event BackfillEvent, which
+// hold the results of every transaction of the block, regardless of the
+// query.
+type Consumer struct {
+	client      ConsumerClient
+	query       string
+	sink        Sink
+	checkpoints CheckpointStore
+	opts        *ConsumerOptions
+	logger      log.Logger
+}
+
+// NewConsumer constructs a new consumer of the events matching query. If
+// opts == nil, the consumer uses default values as described by
+// ConsumerOptions. This function will panic if cli, sink or store is nil.
+func NewConsumer(cli ConsumerClient, query string, sink Sink, store CheckpointStore, opts *ConsumerOptions) *Consumer {
+	if cli == nil || sink == nil || store == nil {
+		panic("eventstream: nil client, sink or checkpoint store")
+	}
+	logger := log.NewNopLogger()
+	if opts != nil && opts.Logger != nil {
+		logger = opts.Logger
+	}
+	return &Consumer{
+		client:      cli,
+		query:       query,
+		sink:        sink,
+		checkpoints: store,
+		opts:        opts,
+		logger:      logger,
+	}
+}
+
+// Run delivers events to the sink until ctx ends, resuming from the last
+// saved checkpoint. Failed requests to the node, deliveries and checkpoint
+// saves are retried with exponential backoff. Run returns an error if the
+// checkpoint can't be loaded, or if the consumer falls behind the event log
+// before it processed any event with a block height to backfill from.
+func (c *Consumer) Run(ctx context.Context) error {
+	cp, err := c.checkpoints.Load()
+	if err != nil {
+		return fmt.Errorf("loading checkpoint: %w", err)
+	}
+
+	backoff := c.opts.retryInterval()
+	for {
+		stream := New(c.client, c.query, &StreamOptions{
+			BatchSize:  c.opts.batchSize(),
+			ResumeFrom: cp.Cursor,
+			WaitTime:   c.opts.waitTime(),
+		})
+		err := stream.Run(ctx, func(itm *coretypes.EventItem) error {
+			backoff = c.opts.retryInterval()
+			rec := &Record{
+				Cursor: itm.Cursor,
+				Height: eventHeight(itm.Data),
+				Event:  itm.Event,
+				Data:   itm.Data,
+			}
+			next := cp
+			next.Cursor = itm.Cursor
+			if rec.Height > next.Height {
+				next.Height = rec.Height
+			}
+			if err := c.deliver(ctx, rec, next); err != nil {
+				return err
+			}
+			cp = next
+			return nil
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var missed *MissedItemsError
+		if errors.As(err, &missed) {
+			c.logger.Info("fell behind the event log",
+				"newest_seen", missed.NewestSeen, "oldest_present", missed.OldestPresent)
+			if err := c.backfill(ctx, &cp, missed); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.logger.Error("failed to read events, retrying", "err", err, "wait", backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = c.opts.nextRetryInterval(backoff)
+	}
+}
+
+// backfill delivers the results of the blocks from the height of cp to the
+// latest one, and moves cp to the newest event in the log before the
+// backfill. The events of the blocks up to the latest one are either older
+// than that event or in the log after it, so resuming there skips none.
+func (c *Consumer) backfill(ctx context.Context, cp *Checkpoint, missed *MissedItemsError) error {
+	if cp.Height == 0 {
+		return fmt.Errorf("unable to backfill, no event with a block height was processed: %w", missed)
+	}
+
+	// The newest event must be read before the latest block, so that the
+	// latest block is at least as high as the block of that event.
+	var newest string
+	if err := c.retry(ctx, "read the event log", func() error {
+		rsp, err := c.client.Events(ctx, &coretypes.RequestEvents{MaxItems: 1})
+		if err != nil {
+			return err
+		}
+		newest = rsp.Newest
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var latest *coretypes.ResultBlockResults
+	if err := c.retry(ctx, "fetch the latest block results", func() (err error) {
+		latest, err = c.client.BlockResults(ctx, nil)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info("backfilling blocks", "from", cp.Height, "to", latest.Height)
+	for height := cp.Height; height <= latest.Height; height++ {
+		res := latest
+		if height != latest.Height {
+			h := height
+			if err := c.retry(ctx, "fetch block results", func() (err error) {
+				res, err = c.client.BlockResults(ctx, &h)
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+
+		next := *cp
+		next.Height = height
+		if err := c.deliver(ctx, &Record{Height: height, Event: BackfillEvent, Data: data}, next); err != nil {
+			return err
+		}
+		*cp = next
+	}
+
+	// If the log moved past the newest event while backfilling, the stream
+	// reports the missed events and the blocks committed since are backfilled.
+	next := *cp
+	next.Cursor = newest
+	if err := c.retry(ctx, "save checkpoint", func() error { return c.checkpoints.Save(next) }); err != nil {
+		return err
+	}
+	*cp = next
+	return nil
+}
+
+// deliver delivers rec to the sink, and then saves cp, retrying each until
+// it succeeds or ctx ends.
+func (c *Consumer) deliver(ctx context.Context, rec *Record, cp Checkpoint) error {
+	if err := c.retry(ctx, "deliver record", func() error { return c.sink.Deliver(ctx, rec) }); err != nil {
+		return err
+	}
+	return c.retry(ctx, "save checkpoint", func() error { return c.checkpoints.Save(cp) })
+}
+
+// retry calls f until it succeeds, backing off exponentially between calls,
+// and returns an error only if ctx ends.
+func (c *Consumer) retry(ctx context.Context, what string, f func() error) error {
+	wait := c.opts.retryInterval()
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logger.Error("failed to "+what+", retrying", "err", err, "wait", wait)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+		wait = c.opts.nextRetryInterval(wait)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// eventHeight returns the block height of encoded event data, or 0 if it has
+// none. Block events carry it in their header, others in a height field.
+func eventHeight(data json.RawMessage) int64 {
+	var event struct {
+		Value struct {
+			Height jsonInt64 `json:"height"`
+			Header struct {
+				Height jsonInt64 `json:"height"`
+			} `json:"header"`
+			Block struct {
+				Header struct {
+					Height jsonInt64 `json:"height"`
+				} `json:"header"`
+			} `json:"block"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0
+	}
+	for _, h := range []jsonInt64{event.Value.Height, event.Value.Header.Height, event.Value.Block.Header.Height} {
+		if h > 0 {
+			return int64(h)
+		}
+	}
+	return 0
+}
+
+// jsonInt64 decodes an integer encoded as either a JSON number or string, and
+// ignores other values.
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*i = jsonInt64(v)
+	}
+	return nil
+}
+
+// ConsumerOptions are optional settings for a Consumer value. A nil
+// *ConsumerOptions is ready for use and provides default values as described.
+type ConsumerOptions struct {
+	// How many items to request per call to the service, as in StreamOptions.
+	BatchSize int
+
+	// Specifies the long poll interval, as in StreamOptions.
+	WaitTime time.Duration
+
+	// The initial interval between retries of failed operations, which
+	// doubles with each failed attempt up to MaxRetryInterval. The defaults
+	// are 1 second and 1 minute.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	// If set, failures and backfills are logged here.
+	Logger log.Logger
+}
+
+func (o *ConsumerOptions) batchSize() int {
+	if o == nil {
+		return 0
+	}
+	return o.BatchSize
+}
+
+func (o *ConsumerOptions) waitTime() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.WaitTime
+}
+
+func (o *ConsumerOptions) retryInterval() time.Duration {
+	if o == nil || o.RetryInterval <= 0 {
+		return time.Second
+	}
+	return o.RetryInterval
+}
+
+func (o *ConsumerOptions) nextRetryInterval(d time.Duration) time.Duration {
+	max := time.Minute
+	if o != nil && o.MaxRetryInterval > 0 {
+		max = o.MaxRetryInterval
+	}
+	if d *= 2; d > max {
+		return max
+	}
+	return d
+}
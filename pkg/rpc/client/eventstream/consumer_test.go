@@ -0,0 +1,315 @@
+package eventstream_test
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bhojpur/state/pkg/rpc/client/eventstream"
+	"github.com/bhojpur/state/pkg/rpc/coretypes"
+)
+
+func TestConsumer_deliverAndResume(t *testing.T) {
+	cli := newFakeClient()
+	for h := int64(1); h <= 3; h++ {
+		cli.publish(h)
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	broker := eventstream.NewMemoryBroker()
+	sink := &flakySink{Sink: eventstream.NewBrokerSink(broker, "events"), failures: 2}
+	runConsumer(t, cli, sink, eventstream.NewFileCheckpointStore(path), broker, 3)
+
+	// Failed deliveries are retried, and the checkpoint records the progress.
+	if diff := cmp.Diff([]string{"c01", "c02", "c03"}, messageKeys(broker)); diff != "" {
+		t.Errorf("Wrong records: (-want, +got)\n%s", diff)
+	}
+	cp, err := eventstream.NewFileCheckpointStore(path).Load()
+	if err != nil {
+		t.Fatalf("Loading checkpoint: %v", err)
+	}
+	if want := (eventstream.Checkpoint{Cursor: "c03", Height: 3}); cp != want {
+		t.Errorf("Wrong checkpoint: got %+v, want %+v", cp, want)
+	}
+
+	// A restarted consumer resumes after the checkpoint.
+	cli.publish(4)
+	broker = eventstream.NewMemoryBroker()
+	runConsumer(t, cli, eventstream.NewBrokerSink(broker, "events"), eventstream.NewFileCheckpointStore(path), broker, 1)
+	if diff := cmp.Diff([]string{"c04"}, messageKeys(broker)); diff != "" {
+		t.Errorf("Wrong records after restart: (-want, +got)\n%s", diff)
+	}
+}
+
+func TestConsumer_backfill(t *testing.T) {
+	cli := newFakeClient()
+	for h := int64(1); h <= 6; h++ {
+		cli.publish(h)
+	}
+	// Heights 3 and 4 fall out of the event log window.
+	cli.expire(4)
+
+	store := &eventstream.MemoryCheckpointStore{}
+	if err := store.Save(eventstream.Checkpoint{Cursor: "c02", Height: 2}); err != nil {
+		t.Fatal(err)
+	}
+	broker := eventstream.NewMemoryBroker()
+	runConsumer(t, cli, eventstream.NewBrokerSink(broker, "events"), store, broker, 5)
+
+	// The blocks from the last processed height to the latest one are
+	// backfilled, and the consumer resumes after the events they cover.
+	want := []string{
+		"BlockResults/2", "BlockResults/3", "BlockResults/4", "BlockResults/5", "BlockResults/6",
+	}
+	if diff := cmp.Diff(want, messageKeys(broker)); diff != "" {
+		t.Errorf("Wrong records: (-want, +got)\n%s", diff)
+	}
+	cp, err := store.Load()
+	if err != nil {
+		t.Fatalf("Loading checkpoint: %v", err)
+	}
+	if want := (eventstream.Checkpoint{Cursor: "c06", Height: 6}); cp != want {
+		t.Errorf("Wrong checkpoint: got %+v, want %+v", cp, want)
+	}
+
+	var rec eventstream.Record
+	if err := json.Unmarshal(broker.Messages("events")[1].Value, &rec); err != nil {
+		t.Fatalf("Decoding record: %v", err)
+	}
+	var res coretypes.ResultBlockResults
+	if err := json.Unmarshal(rec.Data, &res); err != nil || res.Height != 3 || rec.Event != eventstream.BackfillEvent {
+		t.Errorf("Wrong backfilled record: %+v (%v)", rec, err)
+	}
+}
+
+func TestConsumer_backfillFallsBehind(t *testing.T) {
+	cli := newFakeClient()
+	for h := int64(1); h <= 6; h++ {
+		cli.publish(h)
+	}
+	cli.expire(4)
+
+	// While block 3 is backfilled, blocks 7 to 9 are committed and the log
+	// moves past all the events it held when the backfill started.
+	var once sync.Once
+	cli.beforeBlockResults = func(h int64) {
+		if h != 3 {
+			return
+		}
+		once.Do(func() {
+			for h := int64(7); h <= 9; h++ {
+				cli.publish(h)
+			}
+			cli.expire(8)
+		})
+	}
+
+	store := &eventstream.MemoryCheckpointStore{}
+	if err := store.Save(eventstream.Checkpoint{Cursor: "c02", Height: 2}); err != nil {
+		t.Fatal(err)
+	}
+	broker := eventstream.NewMemoryBroker()
+	runConsumer(t, cli, eventstream.NewBrokerSink(broker, "events"), store, broker, 9)
+
+	// The blocks committed during the first backfill are backfilled too.
+	want := []string{
+		"BlockResults/2", "BlockResults/3", "BlockResults/4", "BlockResults/5", "BlockResults/6",
+		"BlockResults/6", "BlockResults/7", "BlockResults/8", "BlockResults/9",
+	}
+	if diff := cmp.Diff(want, messageKeys(broker)); diff != "" {
+		t.Errorf("Wrong records: (-want, +got)\n%s", diff)
+	}
+	cp, err := store.Load()
+	if err != nil {
+		t.Fatalf("Loading checkpoint: %v", err)
+	}
+	if want := (eventstream.Checkpoint{Cursor: "c09", Height: 9}); cp != want {
+		t.Errorf("Wrong checkpoint: got %+v, want %+v", cp, want)
+	}
+}
+
+func TestConsumer_noBackfillHeight(t *testing.T) {
+	cli := newFakeClient()
+	for h := int64(1); h <= 3; h++ {
+		cli.publish(h)
+	}
+	cli.expire(2)
+
+	store := &eventstream.MemoryCheckpointStore{}
+	if err := store.Save(eventstream.Checkpoint{Cursor: "c01"}); err != nil {
+		t.Fatal(err)
+	}
+	c := eventstream.NewConsumer(cli, "", eventstream.NewBrokerSink(eventstream.NewMemoryBroker(), "events"), store, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var missed *eventstream.MissedItemsError
+	if err := c.Run(ctx); !errors.As(err, &missed) {
+		t.Errorf("Wrong error: got %v, want %T", err, missed)
+	}
+}
+
+// runConsumer runs a consumer until n messages were published to broker.
+func runConsumer(
+	t *testing.T,
+	cli *fakeClient,
+	sink eventstream.Sink,
+	store eventstream.CheckpointStore,
+	broker *eventstream.MemoryBroker,
+	n int,
+) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := eventstream.NewConsumer(cli, "", sink, store, &eventstream.ConsumerOptions{
+		RetryInterval: time.Millisecond,
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- c.Run(ctx) }()
+
+	for len(broker.Messages("events")) < n {
+		select {
+		case err := <-errc:
+			t.Fatalf("Consumer stopped: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for %d records, got %d", n, len(broker.Messages("events")))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Consumer failed: %v", err)
+	}
+}
+
+func messageKeys(broker *eventstream.MemoryBroker) []string {
+	var keys []string
+	for _, msg := range broker.Messages("events") {
+		keys = append(keys, msg.Key)
+	}
+	return keys
+}
+
+// flakySink fails the given number of deliveries before delivering records.
+type flakySink struct {
+	eventstream.Sink
+	failures int
+}
+
+func (s *flakySink) Deliver(ctx context.Context, rec *eventstream.Record) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	return s.Sink.Deliver(ctx, rec)
+}
+
+// fakeClient serves an event log with one event per block height, and the
+// results of those blocks.
+type fakeClient struct {
+	mtx    sync.Mutex
+	items  []*coretypes.EventItem // oldest first
+	latest int64
+
+	// If set, called with the height of each block results request.
+	beforeBlockResults func(h int64)
+}
+
+func newFakeClient() *fakeClient { return &fakeClient{} }
+
+// publish adds the event of a new block at height h.
+func (c *fakeClient) publish(h int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.items = append(c.items, &coretypes.EventItem{
+		Cursor: fmt.Sprintf("c%02d", h),
+		Event:  "NewBlockHeader",
+		Data:   json.RawMessage(fmt.Sprintf(`{"type":"bhojpur/event/NewBlockHeader","value":{"header":{"height":"%d"}}}`, h)),
+	})
+	c.latest = h
+}
+
+// expire removes the events up to height h from the log.
+func (c *fakeClient) expire(h int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	last := fmt.Sprintf("c%02d", h)
+	for len(c.items) != 0 && c.items[0].Cursor <= last {
+		c.items = c.items[1:]
+	}
+}
+
+func (c *fakeClient) Events(ctx context.Context, req *coretypes.RequestEvents) (*coretypes.ResultEvents, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	rsp := &coretypes.ResultEvents{}
+	if len(c.items) == 0 {
+		return rsp, nil
+	}
+	rsp.Oldest = c.items[0].Cursor
+	rsp.Newest = c.items[len(c.items)-1].Cursor
+	for i := len(c.items) - 1; i >= 0; i-- {
+		itm := c.items[i]
+		if itm.Cursor <= req.After || (req.Before != "" && itm.Cursor >= req.Before) {
+			continue
+		}
+		if len(rsp.Items) == req.MaxItems {
+			rsp.More = true
+			break
+		}
+		rsp.Items = append(rsp.Items, itm)
+	}
+	if len(rsp.Items) == 0 {
+		// Stand in for the long poll of the service.
+		c.mtx.Unlock()
+		defer c.mtx.Lock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return rsp, nil
+}
+
+func (c *fakeClient) BlockResults(ctx context.Context, height *int64) (*coretypes.ResultBlockResults, error) {
+	if height != nil && c.beforeBlockResults != nil {
+		c.beforeBlockResults(*height)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	h := c.latest
+	if height != nil {
+		h = *height
+	}
+	return &coretypes.ResultBlockResults{Height: h}, nil
+}
@@ -22,7 +22,8 @@ package eventstream
 
 // It implements a convenience client for the Events method
 // of the Bhojpur State RPC service, allowing clients to observe a resumable
-// stream of events matching a query.
+// stream of events matching a query. A Consumer builds on a Stream to deliver
+// the events to a sink at least once, persisting its progress across restarts.
 
 import (
 	"context"
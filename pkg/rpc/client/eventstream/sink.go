@@ -0,0 +1,195 @@
+package eventstream
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// A Sink is the output of a Consumer.
+type Sink interface {
+	// Deliver outputs rec, and returns nil only once rec is stored durably
+	// or acknowledged by its receiver. A record whose delivery failed is
+	// delivered again, and a record may also be delivered again after the
+	// consumer restarts, so receivers should discard records with an ID they
+	// have seen.
+	Deliver(ctx context.Context, rec *Record) error
+}
+
+// FileSink appends records to a file as lines of JSON.
+type FileSink struct {
+	mtx  sync.Mutex
+	file *os.File
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink opens the file at path for appending, creating it if need be.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: f}, nil
+}
+
+// Deliver implements Sink. It syncs the file after each record.
+func (s *FileSink) Deliver(ctx context.Context, rec *Record) error {
+	bz, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.file.Write(append(bz, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.file.Close()
+}
+
+// WebhookSink posts each record as JSON to a URL. The ID of the record is
+// sent in the Idempotency-Key header.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ Sink = (*WebhookSink)(nil)
+
+// NewWebhookSink returns a sink that posts records to url with client, or
+// with http.DefaultClient if client is nil.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookSink{url: url, client: client}
+}
+
+// Deliver implements Sink. The record is delivered once the receiver
+// responds with a 2xx status.
+func (s *WebhookSink) Deliver(ctx context.Context, rec *Record) error {
+	bz, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(bz))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", rec.ID())
+
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	_, _ = io.Copy(io.Discard, rsp.Body)
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %s", s.url, rsp.Status)
+	}
+	return nil
+}
+
+// A Publisher publishes messages to the topics of a message broker.
+type Publisher interface {
+	// Publish publishes a message with the given key to topic, and returns
+	// once the broker acknowledged it.
+	Publish(ctx context.Context, topic, key string, value []byte) error
+}
+
+// BrokerSink publishes each record as JSON to a topic of a message broker,
+// keyed by the ID of the record.
+type BrokerSink struct {
+	publisher Publisher
+	topic     string
+}
+
+var _ Sink = (*BrokerSink)(nil)
+
+// NewBrokerSink returns a sink that publishes records to topic.
+func NewBrokerSink(publisher Publisher, topic string) *BrokerSink {
+	return &BrokerSink{publisher: publisher, topic: topic}
+}
+
+// Deliver implements Sink.
+func (s *BrokerSink) Deliver(ctx context.Context, rec *Record) error {
+	bz, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, s.topic, rec.ID(), bz)
+}
+
+// A BrokerMessage is a message published to a MemoryBroker.
+type BrokerMessage struct {
+	Key   string
+	Value []byte
+}
+
+// MemoryBroker is an in-memory message broker. It stands in for a real
+// broker in tests and local setups, and keeps every message published to a
+// topic.
+type MemoryBroker struct {
+	mtx    sync.Mutex
+	topics map[string][]BrokerMessage
+}
+
+var _ Publisher = (*MemoryBroker)(nil)
+
+// NewMemoryBroker returns an empty broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{topics: make(map[string][]BrokerMessage)}
+}
+
+// Publish implements Publisher.
+func (b *MemoryBroker) Publish(ctx context.Context, topic, key string, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.topics[topic] = append(b.topics[topic], BrokerMessage{Key: key, Value: value})
+	return nil
+}
+
+// Messages returns the messages published to topic, in order.
+func (b *MemoryBroker) Messages(topic string) []BrokerMessage {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]BrokerMessage(nil), b.topics[topic]...)
+}
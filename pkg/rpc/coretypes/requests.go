@@ -109,6 +109,23 @@ type RequestABCIQuery struct {
 	Prove  bool           `json:"prove"`
 }
 
+type RequestSetLogLevel struct {
+	Level string `json:"level"`
+}
+
+type RequestBackup struct {
+	Dir string `json:"dir"`
+}
+
+type RequestP2PCapture struct {
+	// Path is the head file of the capture on the node's host. If empty, a
+	// running capture is stopped.
+	Path string `json:"path"`
+
+	// Capture for up to this long, or until stopped if zero.
+	Duration time.Duration `json:"duration"`
+}
+
 type RequestBroadcastEvidence struct {
 	Evidence types.Evidence
 }
@@ -198,6 +198,9 @@ type ResultNetInfo struct {
 	Listeners []string `json:"listeners"`
 	NPeers    int      `json:"n_peers,string"`
 	Peers     []Peer   `json:"peers"`
+
+	// bytes sent and received on each channel since the node started
+	Channels []ChannelTraffic `json:"channels,omitempty"`
 }
 
 // Log from dialing seeds
@@ -214,6 +217,19 @@ type ResultDialPeers struct {
 type Peer struct {
 	ID  types.NodeID `json:"node_id"`
 	URL string       `json:"url"`
+
+	// bytes sent to and received from the peer since it connected, in total
+	// and on each channel
+	SendBytes int64            `json:"send_bytes,string"`
+	RecvBytes int64            `json:"recv_bytes,string"`
+	Channels  []ChannelTraffic `json:"channels,omitempty"`
+}
+
+// ChannelTraffic is the number of bytes sent and received on a p2p channel.
+type ChannelTraffic struct {
+	ID        uint16 `json:"id"`
+	SendBytes int64  `json:"send_bytes,string"`
+	RecvBytes int64  `json:"recv_bytes,string"`
 }
 
 // Validators for a height.
@@ -317,6 +333,41 @@ type ResultBroadcastEvidence struct {
 	Hash []byte `json:"hash"`
 }
 
+// ResultUnsafeSetLogLevel reports the log levels in effect after a
+// "/unsafe_set_log_level" request.
+type ResultUnsafeSetLogLevel struct {
+	Level string `json:"level"`
+}
+
+// ConfigChange is a setting whose value was changed in the config file.
+type ConfigChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// ResultUnsafeReloadConfig reports the outcome of a "/unsafe_reload_config"
+// request. Applied settings took effect, rejected ones require a restart.
+type ResultUnsafeReloadConfig struct {
+	Applied  []ConfigChange `json:"applied"`
+	Rejected []ConfigChange `json:"rejected"`
+}
+
+// ResultUnsafeBackup lists the databases written by an "/unsafe_backup"
+// request into Dir.
+type ResultUnsafeBackup struct {
+	Dir       string   `json:"dir"`
+	Databases []string `json:"databases"`
+}
+
+// ResultUnsafeP2PCapture reports the capture started by an
+// "/unsafe_p2p_capture" request. Until is zero if the capture runs until
+// stopped, and Path is empty if the request stopped the capture.
+type ResultUnsafeP2PCapture struct {
+	Path  string    `json:"path"`
+	Until time.Time `json:"until"`
+}
+
 // empty results
 type (
 	ResultUnsafeFlushMempool struct{}
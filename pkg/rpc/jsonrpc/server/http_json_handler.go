@@ -28,6 +28,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/bhojpur/state/pkg/libs/log"
 	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
@@ -68,6 +69,7 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, logger log.Logger) http.Han
 		}
 
 		var responses []rpctypes.RPCResponse
+		var retryAfter time.Duration
 		for _, req := range requests {
 			// Ignore notifications, which this service does not support.
 			if req.IsNotification() {
@@ -81,12 +83,20 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, logger log.Logger) http.Han
 				continue
 			}
 
+			if wait := rateLimitWait(hreq.Context(), req.Method); wait > 0 {
+				if wait > retryAfter {
+					retryAfter = wait
+				}
+				responses = append(responses, rateLimitError(req, req.Method, wait))
+				continue
+			}
+
 			req := req
 			ctx := rpctypes.WithCallInfo(hreq.Context(), &rpctypes.CallInfo{
 				RPCRequest:  &req,
 				HTTPRequest: hreq,
 			})
-			result, err := rpcFunc.Call(ctx, req.Params)
+			result, _, err := rpcFunc.call(ctx, req.Method, req.Params)
 			if err != nil {
 				responses = append(responses, req.MakeError(err))
 			} else {
@@ -97,6 +107,9 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, logger log.Logger) http.Han
 		if len(responses) == 0 {
 			return
 		}
+		if retryAfter > 0 {
+			setRetryAfter(w, retryAfter)
+		}
 		writeRPCResponse(w, logger, responses...)
 	}
 }
@@ -38,7 +38,7 @@ import (
 const uriReqID = -1
 
 // convert from a function name to the http handler
-func makeHTTPHandler(rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+func makeHTTPHandler(name string, rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx := rpctypes.WithCallInfo(req.Context(), &rpctypes.CallInfo{
 			HTTPRequest: req,
@@ -51,12 +51,26 @@ func makeHTTPHandler(rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWrit
 			return
 		}
 		jreq := rpctypes.NewRequest(uriReqID)
-		result, err := rpcFunc.Call(ctx, args)
-		if err == nil {
-			writeHTTPResponse(w, logger, jreq.MakeResponse(result))
-		} else {
+		if wait := rateLimitWait(ctx, name); wait > 0 {
+			setRetryAfter(w, wait)
+			writeHTTPResponse(w, logger, rateLimitError(jreq, name, wait))
+			return
+		}
+		result, etag, err := rpcFunc.call(ctx, name, args)
+		if err != nil {
 			writeHTTPResponse(w, logger, jreq.MakeError(err))
+			return
 		}
+		if etag != "" {
+			// The result is immutable, so clients may cache it indefinitely.
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			if etagMatch(req.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		writeHTTPResponse(w, logger, jreq.MakeResponse(result))
 	}
 }
 
@@ -110,6 +124,17 @@ func parseURLParams(args []argInfo, req *http.Request) ([]byte, error) {
 	return json.Marshal(params)
 }
 
+// etagMatch reports whether an If-None-Match header value matches etag.
+func etagMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // isQuotedString reports whether s is enclosed in double quotes.
 func isQuotedString(s string) bool {
 	return len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)
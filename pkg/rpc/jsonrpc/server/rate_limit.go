@@ -0,0 +1,280 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
+)
+
+// bucketIdleTimeout is how long a full token bucket is kept after its last use.
+const bucketIdleTimeout = time.Minute
+
+// RateLimitConfig configures per-client rate limits of the RPC server. A client
+// is identified by its API key, if it presents one of the configured keys, and
+// by its IP address otherwise.
+type RateLimitConfig struct {
+	// Rate is the number of tokens per second added to the bucket of each IP
+	// address, and Burst is the bucket size. A zero Rate disables the limit.
+	Rate  float64
+	Burst int
+
+	// APIKeyHeader is the HTTP header carrying the API key of a client.
+	APIKeyHeader string
+	// APIKeys lists the accepted API keys. Unknown keys are ignored.
+	APIKeys []string
+	// KeyRate and KeyBurst configure the bucket of each API key. A zero
+	// KeyRate disables the limit for clients with an API key.
+	KeyRate  float64
+	KeyBurst int
+
+	// MethodCosts maps RPC method names to the number of tokens a call
+	// costs. Methods that are not listed cost a single token. Costs larger
+	// than the bucket size are capped to it.
+	MethodCosts map[string]int
+}
+
+// RateLimiter enforces token-bucket rate limits for clients of the RPC server,
+// and keeps track of the websocket connections of each client.
+type RateLimiter struct {
+	cfg  RateLimitConfig
+	keys map[string]bool
+	now  func() time.Time
+
+	mtx       sync.Mutex
+	buckets   map[string]*tokenBucket
+	conns     map[string]map[string]bool // websocket connections by client
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a rate limiter for the given configuration.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	keys := make(map[string]bool, len(cfg.APIKeys))
+	for _, key := range cfg.APIKeys {
+		keys[key] = true
+	}
+	return &RateLimiter{
+		cfg:     cfg,
+		keys:    keys,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+		conns:   make(map[string]map[string]bool),
+	}
+}
+
+// Handler wraps an HTTP handler, identifying the client of each request so that
+// the RPC calls made with it are rate limited.
+func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withRateLimitClient(r.Context(), rl.client(r))))
+	})
+}
+
+// client identifies the client of an HTTP request.
+func (rl *RateLimiter) client(r *http.Request) *rateLimitClient {
+	if rl.cfg.APIKeyHeader != "" {
+		if key := r.Header.Get(rl.cfg.APIKeyHeader); key != "" && rl.keys[key] {
+			return &rateLimitClient{limiter: rl, id: "key:" + key, keyed: true}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return &rateLimitClient{limiter: rl, id: "ip:" + host}
+}
+
+// wait charges a client for a call of method, returning zero if the call is
+// allowed, or how long the client must wait before retrying it otherwise.
+func (rl *RateLimiter) wait(client *rateLimitClient, method string) time.Duration {
+	rate, burst := rl.cfg.Rate, rl.cfg.Burst
+	if client.keyed {
+		rate, burst = rl.cfg.KeyRate, rl.cfg.KeyBurst
+	}
+	if rate <= 0 {
+		return 0
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	cost := 1
+	if c, ok := rl.cfg.MethodCosts[method]; ok && c > 0 {
+		cost = c
+	}
+	if cost > burst {
+		cost = burst
+	}
+
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	now := rl.now()
+	rl.prune(now)
+	bucket, ok := rl.buckets[client.id]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now, rate: rate, burst: float64(burst)}
+		rl.buckets[client.id] = bucket
+	}
+	return bucket.take(now, float64(cost))
+}
+
+// prune removes buckets that have been refilled and idle for a while. The
+// caller must hold the mutex lock.
+func (rl *RateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < bucketIdleTimeout {
+		return
+	}
+	rl.lastPrune = now
+	for id, bucket := range rl.buckets {
+		if now.Sub(bucket.last) >= bucketIdleTimeout && bucket.refill(now) >= bucket.burst {
+			delete(rl.buckets, id)
+		}
+	}
+}
+
+// addConn registers a websocket connection of a client.
+func (rl *RateLimiter) addConn(client, remoteAddr string) {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	if rl.conns[client] == nil {
+		rl.conns[client] = make(map[string]bool)
+	}
+	rl.conns[client][remoteAddr] = true
+}
+
+// removeConn unregisters a websocket connection of a client.
+func (rl *RateLimiter) removeConn(client, remoteAddr string) {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	delete(rl.conns[client], remoteAddr)
+	if len(rl.conns[client]) == 0 {
+		delete(rl.conns, client)
+	}
+}
+
+// tokenBucket is a token bucket that is refilled at a constant rate.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// refill adds the tokens accumulated since the last refill, returning the
+// current number of tokens.
+func (b *tokenBucket) refill(now time.Time) float64 {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	return b.tokens
+}
+
+// take takes cost tokens from the bucket if available, returning zero.
+// Otherwise it returns how long it takes until enough tokens are available.
+func (b *tokenBucket) take(now time.Time, cost float64) time.Duration {
+	if tokens := b.refill(now); tokens < cost {
+		return time.Duration((cost - tokens) / b.rate * float64(time.Second))
+	}
+	b.tokens -= cost
+	return 0
+}
+
+// rateLimitClient is a client of the RPC server, as identified by a rate limiter.
+type rateLimitClient struct {
+	limiter *RateLimiter
+	id      string
+	keyed   bool // identified by API key rather than IP address
+}
+
+type rateLimitClientKey struct{}
+
+// withRateLimitClient returns a child context of ctx with the client attached.
+func withRateLimitClient(ctx context.Context, client *rateLimitClient) context.Context {
+	if client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimitClientKey{}, client)
+}
+
+// getRateLimitClient returns the client attached to ctx, or nil if the request
+// is not rate limited.
+func getRateLimitClient(ctx context.Context) *rateLimitClient {
+	if v := ctx.Value(rateLimitClientKey{}); v != nil {
+		return v.(*rateLimitClient)
+	}
+	return nil
+}
+
+// rateLimitWait charges the client of ctx for a call of method, returning zero
+// if the call is allowed, or how long the client must wait before retrying.
+func rateLimitWait(ctx context.Context, method string) time.Duration {
+	client := getRateLimitClient(ctx)
+	if client == nil {
+		return 0
+	}
+	return client.limiter.wait(client, method)
+}
+
+// rateLimitError constructs the error response for a rate-limited call of
+// req, including a hint when to retry.
+func rateLimitError(req rpctypes.RPCRequest, method string, wait time.Duration) rpctypes.RPCResponse {
+	return req.MakeErrorf(rpctypes.CodeRateLimited,
+		"rate limit exceeded for %s, retry after %v", method, wait.Round(time.Millisecond))
+}
+
+// setRetryAfter sets the Retry-After header of a response, in whole seconds.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+// ClientConnections returns the remote addresses of the open websocket
+// connections of the client making the call in ctx, such that limits can be
+// applied across all connections of a client. Without a rate limiter each
+// connection is its own client, and only the remote address of the call is
+// returned.
+func ClientConnections(ctx context.Context) []string {
+	client := getRateLimitClient(ctx)
+	if client == nil {
+		if addr := rpctypes.GetCallInfo(ctx).RemoteAddr(); addr != "" {
+			return []string{addr}
+		}
+		return nil
+	}
+
+	rl := client.limiter
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	addrs := make([]string, 0, len(rl.conns[client.id]))
+	for addr := range rl.conns[client.id] {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
@@ -0,0 +1,120 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	rl := NewRateLimiter(RateLimitConfig{
+		Rate:        2,
+		Burst:       4,
+		MethodCosts: map[string]int{"c": 3, "block": 10},
+	})
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	client := &rateLimitClient{limiter: rl, id: "ip:1.2.3.4"}
+
+	assert.Zero(t, rl.wait(client, "c"))
+	assert.Zero(t, rl.wait(client, "other"))
+	assert.Equal(t, 1500*time.Millisecond, rl.wait(client, "c"))
+
+	// Costs are capped to the burst size.
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Second, rl.wait(client, "block"))
+	now = now.Add(time.Second)
+	assert.Zero(t, rl.wait(client, "block"))
+
+	// Other clients have their own bucket.
+	assert.Zero(t, rl.wait(&rateLimitClient{limiter: rl, id: "ip:5.6.7.8"}, "c"))
+
+	// Clients with an API key are unlimited without a key rate.
+	keyed := &rateLimitClient{limiter: rl, id: "key:k", keyed: true}
+	for i := 0; i < 10; i++ {
+		assert.Zero(t, rl.wait(keyed, "block"))
+	}
+}
+
+func TestRateLimiter_Handler(t *testing.T) {
+	rl := NewRateLimiter(RateLimitConfig{
+		Rate:         0.001,
+		Burst:        1,
+		APIKeyHeader: "X-API-Key",
+		APIKeys:      []string{"secret"},
+		KeyRate:      0.001,
+		KeyBurst:     2,
+	})
+	handler := rl.Handler(testMux())
+
+	call := func(key string) (*http.Response, rpctypes.RPCResponse) {
+		body := strings.NewReader(`{"jsonrpc": "2.0", "id": 0, "method": "c", "params": {"s": "x", "i": "1"}}`)
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/", body)
+		req.RemoteAddr = "1.2.3.4:1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		res := rec.Result()
+		defer res.Body.Close()
+
+		bz, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		var resp rpctypes.RPCResponse
+		require.NoError(t, json.Unmarshal(bz, &resp))
+		return res, resp
+	}
+
+	res, resp := call("")
+	require.Nil(t, resp.Error)
+	assert.Empty(t, res.Header.Get("Retry-After"))
+
+	res, resp = call("")
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, int(rpctypes.CodeRateLimited), resp.Error.Code)
+	assert.Contains(t, resp.Error.Data, "retry after")
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+
+	// Unknown API keys are limited by IP address.
+	_, resp = call("unknown")
+	require.NotNil(t, resp.Error)
+
+	// Known API keys have their own limit.
+	for i := 0; i < 2; i++ {
+		_, resp = call("secret")
+		require.Nil(t, resp.Error)
+	}
+	_, resp = call("secret")
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, int(rpctypes.CodeRateLimited), resp.Error.Code)
+}
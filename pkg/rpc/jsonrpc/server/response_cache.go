@@ -0,0 +1,133 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// CachePolicy reports whether the result of a call with the given decoded
+// parameters is immutable, such that it can be served from a response cache.
+// The parameters are a pointer to the parameter struct of the RPC function.
+type CachePolicy func(ctx context.Context, params interface{}) bool
+
+// ResponseCache is a size-bounded LRU cache of encoded RPC results, keyed by
+// method and canonical parameters. It is safe for concurrent use.
+type ResponseCache struct {
+	mtx      sync.Mutex
+	maxBytes int64
+	size     int64
+	cacheMap map[string]*list.Element
+	list     *list.List
+}
+
+// cachedResponse is an entry of the response cache.
+type cachedResponse struct {
+	key    string
+	result []byte
+	etag   string
+}
+
+// NewResponseCache creates a response cache holding up to maxBytes of keys and
+// encoded results.
+func NewResponseCache(maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		cacheMap: make(map[string]*list.Element),
+		list:     list.New(),
+	}
+}
+
+// get returns the cached response for key, if any.
+func (c *ResponseCache) get(key string) (*cachedResponse, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.cacheMap[key]
+	if !ok {
+		return nil, false
+	}
+	c.list.MoveToBack(e)
+	return e.Value.(*cachedResponse), true
+}
+
+// add caches the encoded result for key, evicting the least recently used
+// entries as needed, and returns the entity tag of the result. Results that
+// exceed the cache size are not cached.
+func (c *ResponseCache) add(key string, result []byte) string {
+	hash := sha256.Sum256(result)
+	entry := &cachedResponse{
+		key:    key,
+		result: result,
+		etag:   `"` + hex.EncodeToString(hash[:16]) + `"`,
+	}
+	size := entry.size()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if size > c.maxBytes {
+		return entry.etag
+	}
+	if e, ok := c.cacheMap[key]; ok {
+		c.remove(e)
+	}
+	for c.size+size > c.maxBytes {
+		c.remove(c.list.Front())
+	}
+
+	c.cacheMap[key] = c.list.PushBack(entry)
+	c.size += size
+	return entry.etag
+}
+
+// remove removes an element from the cache. The caller must hold the mutex lock.
+func (c *ResponseCache) remove(e *list.Element) {
+	entry := c.list.Remove(e).(*cachedResponse)
+	delete(c.cacheMap, entry.key)
+	c.size -= entry.size()
+}
+
+// Purge removes all entries from the cache, e.g. after a rollback changed
+// results that were considered immutable.
+func (c *ResponseCache) Purge() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.cacheMap = make(map[string]*list.Element)
+	c.list.Init()
+	c.size = 0
+}
+
+// Len returns the number of cached responses.
+func (c *ResponseCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.list.Len()
+}
+
+func (r *cachedResponse) size() int64 {
+	return int64(len(r.key) + len(r.result))
+}
@@ -0,0 +1,171 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/libs/trace"
+)
+
+func TestResponseCache_Evict(t *testing.T) {
+	cache := NewResponseCache(30)
+
+	etag := cache.add("a", []byte("0123456789"))
+	assert.NotEmpty(t, etag)
+	cache.add("b", []byte("0123456789"))
+	assert.Equal(t, 2, cache.Len())
+
+	// Using a makes b the least recently used entry.
+	cached, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, etag, cached.etag)
+
+	cache.add("c", []byte("0123456789"))
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+
+	// Results larger than the cache are not cached.
+	cache.add("d", make([]byte, 100))
+	_, ok = cache.get("d")
+	assert.False(t, ok)
+
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestResponseCache_URIHandler(t *testing.T) {
+	type blockArgs struct {
+		H *int64 `json:"h"`
+	}
+	calls := 0
+	latest := int64(10)
+	fn := NewRPCFunc(func(ctx context.Context, arg *blockArgs) (string, error) {
+		calls++
+		return "block", nil
+	}).WithCache(NewResponseCache(1<<20), func(ctx context.Context, params interface{}) bool {
+		h := params.(*blockArgs).H
+		return h != nil && *h < latest
+	})
+
+	mux := http.NewServeMux()
+	RegisterRPCFuncs(mux, map[string]*RPCFunc{"block": fn}, log.NewNopLogger())
+
+	get := func(url, etag string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	// Immutable results are cached, and equivalent parameters share an entry.
+	res := get("http://localhost/block?h=5", "")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Contains(t, res.Header.Get("Cache-Control"), "immutable")
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	var result string
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, "block", result)
+
+	res2 := get(`http://localhost/block?h="5"`, "")
+	defer res2.Body.Close()
+	assert.Equal(t, etag, res2.Header.Get("ETag"))
+	assert.Equal(t, 1, calls)
+
+	res3 := get("http://localhost/block?h=5", etag)
+	defer res3.Body.Close()
+	assert.Equal(t, http.StatusNotModified, res3.StatusCode)
+	assert.Equal(t, 1, calls)
+
+	// Mutable results are neither cached nor tagged.
+	res4 := get("http://localhost/block", "")
+	defer res4.Body.Close()
+	assert.Empty(t, res4.Header.Get("ETag"))
+	assert.Empty(t, res4.Header.Get("Cache-Control"))
+	res5 := get("http://localhost/block?h=10", "")
+	defer res5.Body.Close()
+	assert.Empty(t, res5.Header.Get("ETag"))
+	assert.Equal(t, 3, calls)
+}
+
+type memExporter struct {
+	mtx   sync.Mutex
+	spans []trace.SpanData
+}
+
+func (e *memExporter) ExportSpans(ctx context.Context, batch *trace.Batch) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = append(e.spans, batch.Spans...)
+	return nil
+}
+
+func (e *memExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestResponseCache_SpanAttribute(t *testing.T) {
+	type blockArgs struct {
+		H int64 `json:"h"`
+	}
+	fn := NewRPCFunc(func(ctx context.Context, arg *blockArgs) (string, error) {
+		return "block", nil
+	}).WithCache(NewResponseCache(1<<20), func(ctx context.Context, params interface{}) bool {
+		return true
+	})
+
+	exp := &memExporter{}
+	tracer := trace.NewTracer("test", exp)
+	ctx := trace.ContextWithTracer(context.Background(), tracer)
+
+	// Only the second call is served from the cache, though both are tagged.
+	for i := 0; i < 2; i++ {
+		_, etag, err := fn.call(ctx, "block", json.RawMessage(`{"h":5}`))
+		require.NoError(t, err)
+		require.NotEmpty(t, etag)
+	}
+	require.NoError(t, tracer.Shutdown(ctx))
+
+	exp.mtx.Lock()
+	defer exp.mtx.Unlock()
+	require.Len(t, exp.spans, 2)
+	for i, cached := range []bool{false, true} {
+		assert.Contains(t, exp.spans[i].Attributes, trace.Attribute{Key: "cached", Value: cached})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"strings"
 
 	"github.com/bhojpur/state/pkg/libs/log"
+	"github.com/bhojpur/state/pkg/libs/trace"
 	rpctypes "github.com/bhojpur/state/pkg/rpc/jsonrpc/types"
 )
 
@@ -41,7 +42,7 @@ func RegisterRPCFuncs(mux *http.ServeMux, funcMap map[string]*RPCFunc, logger lo
 		if fn.ws {
 			continue // skip websocket endpoints, not usable via GET calls
 		}
-		mux.HandleFunc("/"+name, makeHTTPHandler(fn, logger))
+		mux.HandleFunc("/"+name, makeHTTPHandler(name, fn, logger))
 	}
 
 	// Endpoints for POST.
@@ -57,6 +58,9 @@ type RPCFunc struct {
 	result reflect.Type  // the non-error result type, or nil
 	args   []argInfo     // names and type information (for URL decoding)
 	ws     bool          // websocket only
+
+	cache       *ResponseCache // cache for immutable results, or nil
+	cachePolicy CachePolicy    // reports which calls have immutable results
 }
 
 // argInfo records the name of a field, along with a bit to tell whether the
@@ -76,6 +80,63 @@ func (rf *RPCFunc) Call(ctx context.Context, params json.RawMessage) (interface{
 	if err != nil {
 		return nil, err
 	}
+	return rf.invoke(args)
+}
+
+// call behaves as Call, but serves calls with immutable results from the
+// response cache of rf, if any. For such calls it returns the encoded result
+// along with its entity tag, which is empty otherwise. The call is recorded
+// as a span when the request context carries a tracer.
+func (rf *RPCFunc) call(ctx context.Context, method string, params json.RawMessage) (interface{}, string, error) {
+	ctx, span := trace.StartSpan(ctx, "rpc."+method)
+	result, etag, hit, err := rf.callCached(ctx, method, params)
+	span.SetAttributes("cached", hit)
+	span.RecordError(err)
+	span.End()
+	return result, etag, err
+}
+
+// callCached behaves as call, and also reports whether the result was served
+// from the cache.
+func (rf *RPCFunc) callCached(
+	ctx context.Context,
+	method string,
+	params json.RawMessage,
+) (result interface{}, etag string, hit bool, err error) {
+	args, err := rf.parseParams(ctx, params)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if rf.cache == nil || len(args) < 2 || !rf.cachePolicy(ctx, args[1].Interface()) {
+		result, err = rf.invoke(args)
+		return result, "", false, err
+	}
+
+	// The parameters are re-encoded from the parameter struct, so that equivalent
+	// requests share a cache entry.
+	canonical, err := json.Marshal(args[1].Interface())
+	if err != nil {
+		result, err = rf.invoke(args)
+		return result, "", false, err
+	}
+	key := method + "?" + string(canonical)
+	if cached, ok := rf.cache.get(key); ok {
+		return json.RawMessage(cached.result), cached.etag, true, nil
+	}
+
+	result, err = rf.invoke(args)
+	if err != nil {
+		return nil, "", false, err
+	}
+	bz, err := json.Marshal(result)
+	if err != nil {
+		return result, "", false, nil // reported when the response is constructed
+	}
+	return json.RawMessage(bz), rf.cache.add(key, bz), false, nil
+}
+
+// invoke calls the function wrapped by rf with the given argument values.
+func (rf *RPCFunc) invoke(args []reflect.Value) (interface{}, error) {
 	returns := rf.f.Call(args)
 
 	// Case 1: There is no non-error result type.
@@ -166,6 +227,33 @@ func NewWSRPCFunc(f interface{}) *RPCFunc {
 	return rf
 }
 
+// ParamType returns the parameter struct type of rf, or nil if rf does not
+// accept parameters.
+func (rf *RPCFunc) ParamType() reflect.Type { return rf.param }
+
+// ResultType returns the non-error result type of rf, or nil if rf has none.
+func (rf *RPCFunc) ResultType() reflect.Type { return rf.result }
+
+// ArgNames returns the names of the parameters of rf, in positional order.
+func (rf *RPCFunc) ArgNames() []string {
+	names := make([]string, len(rf.args))
+	for i, arg := range rf.args {
+		names[i] = arg.name
+	}
+	return names
+}
+
+// IsWebsocket reports whether rf is only available via websocket.
+func (rf *RPCFunc) IsWebsocket() bool { return rf.ws }
+
+// WithCache enables caching of the encoded results of rf in cache, for calls
+// that policy reports to have immutable results. It returns rf.
+func (rf *RPCFunc) WithCache(cache *ResponseCache, policy CachePolicy) *RPCFunc {
+	rf.cache = cache
+	rf.cachePolicy = policy
+	return rf
+}
+
 var (
 	ctxType = reflect.TypeOf((*context.Context)(nil)).Elem()
 	errType = reflect.TypeOf((*error)(nil)).Elem()
@@ -99,6 +99,13 @@ func (wm *WebsocketManager) WebsocketHandler(w http.ResponseWriter, r *http.Requ
 	conn := newWSConnection(wsConn, wm.funcMap, logger, wm.wsConnOptions...)
 	wm.logger.Info("New websocket connection", "remote", conn.remoteAddr)
 
+	// Calls on the connection are rate limited as the client of the upgrade request.
+	if client := getRateLimitClient(r.Context()); client != nil {
+		conn.rateLimitClient = client
+		client.limiter.addConn(client.id, conn.remoteAddr)
+		defer client.limiter.removeConn(client.id, conn.remoteAddr)
+	}
+
 	// starting the conn is blocking
 	if err = conn.Start(r.Context()); err != nil {
 		wm.logger.Error("Failed to start connection", "err", err)
@@ -143,6 +150,9 @@ type wsConnection struct {
 	// callback which is called upon disconnect
 	onDisconnect func(remoteAddr string)
 
+	// the client of the connection, if rate limited
+	rateLimitClient *rateLimitClient
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -351,8 +361,17 @@ func (wsc *wsConnection) readRoutine(ctx context.Context) {
 				RPCRequest: &request,
 				WSConn:     wsc,
 			})
+			fctx = withRateLimitClient(fctx, wsc.rateLimitClient)
+			if wait := rateLimitWait(fctx, request.Method); wait > 0 {
+				if err := wsc.WriteRPCResponse(writeCtx,
+					rateLimitError(request, request.Method, wait)); err != nil {
+					wsc.Logger.Error("error writing RPC response", "err", err)
+				}
+				continue
+			}
+
 			var resp rpctypes.RPCResponse
-			result, err := rpcFunc.Call(fctx, request.Params)
+			result, _, err := rpcFunc.call(fctx, request.Method, request.Params)
 			if err == nil {
 				resp = request.MakeResponse(result)
 			} else {
@@ -53,12 +53,18 @@ const (
 	CodeInternalError  ErrorCode = -32603 // Internal JSON-RPC error
 )
 
+// Constants defining implementation-defined server error codes.
+const (
+	CodeRateLimited ErrorCode = -32005 // The client exceeded its rate limit
+)
+
 var errorCodeString = map[ErrorCode]string{
 	CodeParseError:     "Parse error",
 	CodeInvalidRequest: "Invalid request",
 	CodeMethodNotFound: "Method not found",
 	CodeInvalidParams:  "Invalid params",
 	CodeInternalError:  "Internal error",
+	CodeRateLimited:    "Rate limit exceeded",
 }
 
 // REQUEST
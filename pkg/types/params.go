@@ -63,6 +63,7 @@ type ConsensusParams struct {
 	Version   VersionParams   `json:"version"`
 	Synchrony SynchronyParams `json:"synchrony"`
 	Timeout   TimeoutParams   `json:"timeout"`
+	ABCI      ABCIParams      `json:"abci"`
 }
 
 // HashedParams is a subset of ConsensusParams.
@@ -115,6 +116,21 @@ type TimeoutParams struct {
 	BypassCommitTimeout bool          `json:"bypass_commit_timeout"`
 }
 
+// ABCIParams configure ABCI functionality specific to the Application Blockchain
+// Interface.
+type ABCIParams struct {
+	VoteExtensionsEnableHeight int64 `json:"vote_extensions_enable_height,string"`
+}
+
+// VoteExtensionsEnabled returns true if vote extensions are enabled at height h
+// and false otherwise.
+func (a ABCIParams) VoteExtensionsEnabled(h int64) bool {
+	if a.VoteExtensionsEnableHeight == 0 {
+		return false
+	}
+	return a.VoteExtensionsEnableHeight <= h
+}
+
 // DefaultConsensusParams returns a default ConsensusParams.
 func DefaultConsensusParams() *ConsensusParams {
 	return &ConsensusParams{
@@ -124,6 +140,7 @@ func DefaultConsensusParams() *ConsensusParams {
 		Version:   DefaultVersionParams(),
 		Synchrony: DefaultSynchronyParams(),
 		Timeout:   DefaultTimeoutParams(),
+		ABCI:      DefaultABCIParams(),
 	}
 }
 
@@ -192,6 +209,13 @@ func DefaultTimeoutParams() TimeoutParams {
 	}
 }
 
+func DefaultABCIParams() ABCIParams {
+	return ABCIParams{
+		// When set to 0, vote extensions are not required.
+		VoteExtensionsEnableHeight: 0,
+	}
+}
+
 // TimeoutParamsOrDefaults returns the SynchronyParams, filling in any zero values
 // with the Bhojpur State defined default values.
 func (t TimeoutParams) TimeoutParamsOrDefaults() TimeoutParams {
@@ -321,6 +345,10 @@ func (params ConsensusParams) ValidateConsensusParams() error {
 		return fmt.Errorf("timeout.Commit must be greater than 0. Got: %d", params.Timeout.Commit)
 	}
 
+	if params.ABCI.VoteExtensionsEnableHeight < 0 {
+		return fmt.Errorf("ABCI.VoteExtensionsEnableHeight cannot be negative. Got: %d", params.ABCI.VoteExtensionsEnableHeight)
+	}
+
 	if len(params.Validator.PubKeyTypes) == 0 {
 		return errors.New("len(Validator.PubKeyTypes) must be greater than 0")
 	}
@@ -337,6 +365,27 @@ func (params ConsensusParams) ValidateConsensusParams() error {
 	return nil
 }
 
+// ValidateUpdate validates the updated consensus params against the current
+// ones at height h. Vote extensions can be enabled, or have their enable height
+// moved, only for a future height and only while they are not yet enabled.
+func (params ConsensusParams) ValidateUpdate(updated *v1.ConsensusParams, h int64) error {
+	if updated == nil || updated.Abci == nil {
+		return nil
+	}
+	if params.ABCI.VoteExtensionsEnableHeight == updated.Abci.VoteExtensionsEnableHeight {
+		return nil
+	}
+	if params.ABCI.VoteExtensionsEnabled(h) {
+		return fmt.Errorf("ABCI.VoteExtensionsEnableHeight cannot be modified once vote extensions have been enabled. "+
+			"Current enable height: %d, height: %d", params.ABCI.VoteExtensionsEnableHeight, h)
+	}
+	if updated.Abci.VoteExtensionsEnableHeight != 0 && updated.Abci.VoteExtensionsEnableHeight <= h {
+		return fmt.Errorf("ABCI.VoteExtensionsEnableHeight must be set to a future height. "+
+			"Updated enable height: %d, height: %d", updated.Abci.VoteExtensionsEnableHeight, h)
+	}
+	return nil
+}
+
 // Hash returns a hash of a subset of the parameters to store in the block header.
 // Only the Block.MaxBytes and Block.MaxGas are included in the hash.
 // This allows the ConsensusParams to evolve more without breaking the block
@@ -363,6 +412,7 @@ func (params *ConsensusParams) Equals(params2 *ConsensusParams) bool {
 		params.Version == params2.Version &&
 		params.Synchrony == params2.Synchrony &&
 		params.Timeout == params2.Timeout &&
+		params.ABCI == params2.ABCI &&
 		libstrings.StringSliceEqual(params.Validator.PubKeyTypes, params2.Validator.PubKeyTypes)
 }
 
@@ -419,6 +469,9 @@ func (params ConsensusParams) UpdateConsensusParams(params2 *v1.ConsensusParams)
 		}
 		res.Timeout.BypassCommitTimeout = params2.Timeout.GetBypassCommitTimeout()
 	}
+	if params2.Abci != nil {
+		res.ABCI.VoteExtensionsEnableHeight = params2.Abci.GetVoteExtensionsEnableHeight()
+	}
 	return res
 }
 
@@ -451,6 +504,9 @@ func (params *ConsensusParams) ToProto() v1.ConsensusParams {
 			Commit:              &params.Timeout.Commit,
 			BypassCommitTimeout: params.Timeout.BypassCommitTimeout,
 		},
+		Abci: &v1.ABCIParams{
+			VoteExtensionsEnableHeight: params.ABCI.VoteExtensionsEnableHeight,
+		},
 	}
 }
 
@@ -498,5 +554,8 @@ func ConsensusParamsFromProto(pbParams v1.ConsensusParams) ConsensusParams {
 		}
 		c.Timeout.BypassCommitTimeout = pbParams.Timeout.BypassCommitTimeout
 	}
+	if pbParams.Abci != nil {
+		c.ABCI.VoteExtensionsEnableHeight = pbParams.Abci.GetVoteExtensionsEnableHeight()
+	}
 	return c
 }
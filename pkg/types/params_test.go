@@ -182,6 +182,16 @@ func TestConsensusParamsValidation(t *testing.T) {
 				messageDelay: 1}),
 			valid: false,
 		},
+		{
+			name: "negative VoteExtensionsEnableHeight",
+			params: makeParams(makeParamsArgs{
+				blockBytes:          1,
+				evidenceAge:         2,
+				precision:           1,
+				messageDelay:        1,
+				abciExtensionHeight: -1}),
+			valid: false,
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -203,6 +213,7 @@ type makeParamsArgs struct {
 	precision           time.Duration
 	messageDelay        time.Duration
 	bypassCommitTimeout bool
+	abciExtensionHeight int64
 
 	propose      *time.Duration
 	proposeDelta *time.Duration
@@ -255,6 +266,9 @@ func makeParams(args makeParamsArgs) ConsensusParams {
 			Commit:              *args.commit,
 			BypassCommitTimeout: args.bypassCommitTimeout,
 		},
+		ABCI: ABCIParams{
+			VoteExtensionsEnableHeight: args.abciExtensionHeight,
+		},
 	}
 }
 
@@ -383,6 +397,16 @@ func TestConsensusParamsUpdate(t *testing.T) {
 				maxEvidenceBytes: 50,
 				pubkeyTypes:      valSr25519}),
 		},
+		{
+			// update vote extension enable height
+			intialParams: makeParams(makeParamsArgs{evidenceAge: 3}),
+			updates: &v1.ConsensusParams{
+				Abci: &v1.ABCIParams{
+					VoteExtensionsEnableHeight: 10,
+				},
+			},
+			updatedParams: makeParams(makeParamsArgs{evidenceAge: 3, abciExtensionHeight: 10}),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -401,6 +425,50 @@ func TestConsensusParamsUpdate_AppVersion(t *testing.T) {
 	assert.EqualValues(t, 1, updated.Version.AppVersion)
 }
 
+func TestConsensusParamsValidateUpdate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		height  int64
+		enabled int64
+		updated *v1.ConsensusParams
+		valid   bool
+	}{
+		{name: "no abci update", height: 3, enabled: 2, updated: &v1.ConsensusParams{}, valid: true},
+		{name: "unchanged enable height", height: 3, enabled: 2,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 2}}, valid: true},
+		{name: "enable at a future height", height: 3, enabled: 0,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 4}}, valid: true},
+		{name: "enable at the current height", height: 3, enabled: 0,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 3}}, valid: false},
+		{name: "move a pending enable height", height: 3, enabled: 5,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 10}}, valid: true},
+		{name: "cancel a pending enable height", height: 3, enabled: 5,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 0}}, valid: true},
+		{name: "disable once enabled", height: 3, enabled: 2,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 0}}, valid: false},
+		{name: "move once enabled", height: 3, enabled: 3,
+			updated: &v1.ConsensusParams{Abci: &v1.ABCIParams{VoteExtensionsEnableHeight: 10}}, valid: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := makeParams(makeParamsArgs{evidenceAge: 3, abciExtensionHeight: tc.enabled})
+			err := params.ValidateUpdate(tc.updated, tc.height)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestABCIParamsVoteExtensionsEnabled(t *testing.T) {
+	assert.False(t, ABCIParams{}.VoteExtensionsEnabled(1))
+	assert.False(t, ABCIParams{VoteExtensionsEnableHeight: 5}.VoteExtensionsEnabled(4))
+	assert.True(t, ABCIParams{VoteExtensionsEnableHeight: 5}.VoteExtensionsEnabled(5))
+	assert.True(t, ABCIParams{VoteExtensionsEnableHeight: 5}.VoteExtensionsEnabled(6))
+}
+
 func TestProto(t *testing.T) {
 	params := []ConsensusParams{
 		makeParams(makeParamsArgs{blockBytes: 4, blockGas: 2, evidenceAge: 3, maxEvidenceBytes: 1}),
@@ -413,6 +481,7 @@ func TestProto(t *testing.T) {
 		makeParams(makeParamsArgs{blockBytes: 4, blockGas: 6, evidenceAge: 5, maxEvidenceBytes: 1}),
 		makeParams(makeParamsArgs{precision: time.Second, messageDelay: time.Minute}),
 		makeParams(makeParamsArgs{precision: time.Nanosecond, messageDelay: time.Millisecond}),
+		makeParams(makeParamsArgs{abciExtensionHeight: 100}),
 	}
 
 	for i := range params {
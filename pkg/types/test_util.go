@@ -63,7 +63,9 @@ func signAddVote(ctx context.Context, privVal PrivValidator, vote *Vote, voteSet
 		return false, err
 	}
 	vote.Signature = v.Signature
-	vote.ExtensionSignature = v.ExtensionSignature
+	if voteSet.ExtensionsEnabled() {
+		vote.ExtensionSignature = v.ExtensionSignature
+	}
 	return voteSet.AddVote(vote)
 }
 
@@ -79,6 +79,11 @@ type VoteSet struct {
 	signedMsgType v1.SignedMsgType
 	valSet        *ValidatorSet
 
+	// If this is true, precommits must carry a signed vote extension.
+	// Otherwise, votes carrying vote extension data or an extension
+	// signature are rejected.
+	extensionsEnabled bool
+
 	mtx           sync.Mutex
 	votesBitArray *bits.BitArray
 	votes         []*Vote                // Primary votes to share
@@ -109,10 +114,26 @@ func NewVoteSet(chainID string, height int64, round int32,
 	}
 }
 
+// NewExtendedVoteSet constructs a vote set with additional vote verification
+// logic. The VoteSet constructed with NewExtendedVoteSet verifies the vote
+// extension data for every vote added to the set.
+func NewExtendedVoteSet(chainID string, height int64, round int32,
+	signedMsgType v1.SignedMsgType, valSet *ValidatorSet) *VoteSet {
+	vs := NewVoteSet(chainID, height, round, signedMsgType, valSet)
+	vs.extensionsEnabled = true
+	return vs
+}
+
 func (voteSet *VoteSet) ChainID() string {
 	return voteSet.chainID
 }
 
+// ExtensionsEnabled reports whether precommits added to this set must carry
+// a signed vote extension.
+func (voteSet *VoteSet) ExtensionsEnabled() bool {
+	return voteSet.extensionsEnabled
+}
+
 // Implements VoteSetReader.
 func (voteSet *VoteSet) GetHeight() int64 {
 	if voteSet == nil {
@@ -213,6 +234,15 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 		return false, fmt.Errorf("existing vote: %v; new vote: %v: %w", existing, vote, ErrVoteNonDeterministicSignature)
 	}
 
+	// Check vote extension presence against whether they are enabled.
+	if voteSet.extensionsEnabled {
+		if vote.Type == v1.PrecommitType && len(vote.ExtensionSignature) == 0 {
+			return false, fmt.Errorf("vote extension signature is missing: %w", ErrVoteInvalidExtension)
+		}
+	} else if len(vote.Extension) > 0 || len(vote.ExtensionSignature) > 0 {
+		return false, fmt.Errorf("unexpected vote extension data present in vote: %w", ErrVoteInvalidExtension)
+	}
+
 	// Check signature.
 	if err := vote.VerifyWithExtension(voteSet.chainID, val.PubKey); err != nil {
 		return false, fmt.Errorf("failed to verify vote with ChainID %s and PubKey %s: %w", voteSet.chainID, val.PubKey, err)
@@ -518,6 +518,65 @@ func TestVoteSet_MakeCommit(t *testing.T) {
 	}
 }
 
+func TestVoteSet_VoteExtensions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	height, round := int64(1), int32(0)
+	valSet, privValidators := randValidatorPrivValSet(ctx, t, 2, 1)
+	blockID := BlockID{crypto.CRandBytes(32), PartSetHeader{123, crypto.CRandBytes(32)}}
+
+	makeVote := func(i int32, ext []byte) *Vote {
+		pv, err := privValidators[i].GetPubKey(ctx)
+		require.NoError(t, err)
+		vote := &Vote{
+			ValidatorAddress: pv.Address(),
+			ValidatorIndex:   i,
+			Height:           height,
+			Round:            round,
+			Timestamp:        libtime.Now(),
+			Type:             v1.PrecommitType,
+			BlockID:          blockID,
+			Extension:        ext,
+		}
+		v := vote.ToProto()
+		require.NoError(t, privValidators[i].SignVote(ctx, "test_chain_id", v))
+		vote.Signature = v.Signature
+		vote.ExtensionSignature = v.ExtensionSignature
+		return vote
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		voteSet := NewVoteSet("test_chain_id", height, round, v1.PrecommitType, valSet)
+
+		_, err := voteSet.AddVote(makeVote(0, []byte("extension")))
+		require.ErrorIs(t, err, ErrVoteInvalidExtension)
+
+		// An extension signature without extension data is rejected too.
+		_, err = voteSet.AddVote(makeVote(0, nil))
+		require.ErrorIs(t, err, ErrVoteInvalidExtension)
+
+		vote := makeVote(1, nil)
+		vote.ExtensionSignature = nil
+		added, err := voteSet.AddVote(vote)
+		require.NoError(t, err)
+		require.True(t, added)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		voteSet := NewExtendedVoteSet("test_chain_id", height, round, v1.PrecommitType, valSet)
+
+		vote := makeVote(0, []byte("extension"))
+		vote.ExtensionSignature = nil
+		_, err := voteSet.AddVote(vote)
+		require.ErrorIs(t, err, ErrVoteInvalidExtension)
+
+		added, err := voteSet.AddVote(makeVote(1, []byte("extension")))
+		require.NoError(t, err)
+		require.True(t, added)
+	})
+}
+
 // NOTE: privValidators are in order
 func randVoteSet(
 	ctx context.Context,
@@ -26,16 +26,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 
 	"github.com/creachadair/tomledit"
-	"github.com/creachadair/tomledit/parser"
 	"github.com/creachadair/tomledit/transform"
+
+	"github.com/bhojpur/state/internal/confix"
 )
 
 var (
@@ -76,7 +74,7 @@ func main() {
 		_ = fix(context.Background(), lhs)
 		_ = fix(context.Background(), rhs)
 	}
-	diffDocs(os.Stdout, lhs, rhs)
+	confix.DiffKeys(os.Stdout, lhs, rhs)
 }
 
 func mustParse(path string) *tomledit.Document {
@@ -91,83 +89,3 @@ func mustParse(path string) *tomledit.Document {
 	}
 	return doc
 }
-
-func allKeys(s *tomledit.Section) []string {
-	var keys []string
-	s.Scan(func(key parser.Key, _ *tomledit.Entry) bool {
-		keys = append(keys, key.String())
-		return true
-	})
-	return keys
-}
-
-const (
-	delSection = "-S"
-	delMapping = "-M"
-	addSection = "+S"
-	addMapping = "+M"
-
-	delMapSep = "\n" + delMapping + " "
-	addMapSep = "\n" + addMapping + " "
-)
-
-func diffDocs(w io.Writer, lhs, rhs *tomledit.Document) {
-	diffSections(w, lhs.Global, rhs.Global)
-	lsec, rsec := lhs.Sections, rhs.Sections
-	transform.SortSectionsByName(lsec)
-	transform.SortSectionsByName(rsec)
-
-	i, j := 0, 0
-	for i < len(lsec) && j < len(rsec) {
-		if lsec[i].Name.Before(rsec[j].Name) {
-			fmt.Fprintln(w, delSection, lsec[i].Name)
-			fmt.Fprintln(w, delMapping, strings.Join(allKeys(lsec[i]), delMapSep))
-			i++
-		} else if rsec[j].Name.Before(lsec[i].Name) {
-			fmt.Fprintln(w, addSection, rsec[j].Name)
-			fmt.Fprintln(w, addMapping, strings.Join(allKeys(rsec[j]), addMapSep))
-			j++
-		} else {
-			diffSections(w, lsec[i], rsec[j])
-			i++
-			j++
-		}
-	}
-	for ; i < len(lsec); i++ {
-		fmt.Fprintln(w, delSection, lsec[i].Name)
-		fmt.Fprintln(w, delMapping, strings.Join(allKeys(lsec[i]), delMapSep))
-	}
-	for ; j < len(rsec); j++ {
-		fmt.Fprintln(w, addSection, rsec[j].Name)
-		fmt.Fprintln(w, addMapping, strings.Join(allKeys(rsec[j]), addMapSep))
-	}
-}
-
-func diffSections(w io.Writer, lhs, rhs *tomledit.Section) {
-	diffKeys(w, allKeys(lhs), allKeys(rhs))
-}
-
-func diffKeys(w io.Writer, lhs, rhs []string) {
-	sort.Strings(lhs)
-	sort.Strings(rhs)
-
-	i, j := 0, 0
-	for i < len(lhs) && j < len(rhs) {
-		if lhs[i] < rhs[j] {
-			fmt.Fprintln(w, delMapping, lhs[i])
-			i++
-		} else if lhs[i] > rhs[j] {
-			fmt.Fprintln(w, addMapping, rhs[j])
-			j++
-		} else {
-			i++
-			j++
-		}
-	}
-	for ; i < len(lhs); i++ {
-		fmt.Fprintln(w, delMapping, lhs[i])
-	}
-	for ; j < len(rhs); j++ {
-		fmt.Fprintln(w, addMapping, rhs[j])
-	}
-}
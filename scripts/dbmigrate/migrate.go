@@ -0,0 +1,222 @@
+// Package dbmigrate copies the contents of a database into another one,
+// usually backed by a different BackendType. Keys are copied in order using
+// batched writes, and the progress is recorded after every batch so that an
+// interrupted copy can be resumed. The result is verified by comparing the
+// key counts and the checksums of both databases.
+package dbmigrate
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bhojpur/state/internal/libs/tempfile"
+	dbm "github.com/bhojpur/state/pkg/database"
+)
+
+// DefaultBatchSize is the default number of keys written per batch.
+const DefaultBatchSize = 1000
+
+// Progress records how far the copy of a database got.
+type Progress struct {
+	// The last key written to the destination.
+	LastKey []byte `json:"last_key,omitempty"`
+	// The number of keys written so far.
+	Keys int64 `json:"keys,string"`
+	// Set once all keys have been copied.
+	Done bool `json:"done"`
+}
+
+// LoadProgress reads the progress file at path. It returns an empty
+// Progress if the file does not exist.
+func LoadProgress(path string) (*Progress, error) {
+	bz, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Progress{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p := &Progress{}
+	if err := json.Unmarshal(bz, p); err != nil {
+		return nil, fmt.Errorf("invalid progress file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save atomically writes the progress to path.
+func (p *Progress) Save(path string) error {
+	bz, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return tempfile.WriteFileAtomic(path, bz, 0600)
+}
+
+// Copy copies every key of src that sorts after progress.LastKey into dst,
+// batchSize keys at a time. After each batch is written, progress is updated
+// and passed to save, if not nil. Copy stops between batches when ctx is
+// canceled, and can then be resumed with the same progress.
+func Copy(ctx context.Context, src, dst dbm.DB, batchSize int, progress *Progress, save func(*Progress) error) error {
+	if progress.Done {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var start []byte
+	if progress.LastKey != nil {
+		// the smallest key sorting after LastKey
+		start = append(append([]byte{}, progress.LastKey...), 0)
+	}
+	iter, err := src.Iterator(start, nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	batch := dst.NewBatch()
+	defer func() { _ = batch.Close() }()
+
+	var (
+		pending int
+		lastKey []byte
+	)
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		if err := batch.WriteSync(); err != nil {
+			return err
+		}
+		if err := batch.Close(); err != nil {
+			return err
+		}
+		batch = dst.NewBatch()
+
+		progress.LastKey = lastKey
+		progress.Keys += int64(pending)
+		pending = 0
+		if save != nil {
+			return save(progress)
+		}
+		return nil
+	}
+
+	for ; iter.Valid(); iter.Next() {
+		if err := batch.Set(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+		lastKey = append(lastKey[:0:0], iter.Key()...)
+		pending++
+
+		if pending >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	progress.Done = true
+	if save != nil {
+		return save(progress)
+	}
+	return nil
+}
+
+// Summary describes the contents of a database.
+type Summary struct {
+	Keys     int64
+	Checksum []byte
+}
+
+// Checksum counts the keys of db and computes a SHA-256 checksum over all
+// keys and values, in iteration order.
+func Checksum(ctx context.Context, db dbm.DB) (Summary, error) {
+	iter, err := db.Iterator(nil, nil)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer iter.Close()
+
+	var (
+		sum    Summary
+		hasher = sha256.New()
+		length [binary.MaxVarintLen64]byte
+	)
+	for ; iter.Valid(); iter.Next() {
+		// length-prefix keys and values so that different splits of the
+		// same bytes produce different checksums
+		for _, bz := range [][]byte{iter.Key(), iter.Value()} {
+			n := binary.PutUvarint(length[:], uint64(len(bz)))
+			hasher.Write(length[:n])
+			hasher.Write(bz)
+		}
+		sum.Keys++
+
+		if sum.Keys%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return Summary{}, err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return Summary{}, err
+	}
+	sum.Checksum = hasher.Sum(nil)
+	return sum, nil
+}
+
+// Verify checks that src and dst hold the same keys and values. It returns
+// the summary of src.
+func Verify(ctx context.Context, src, dst dbm.DB) (Summary, error) {
+	srcSum, err := Checksum(ctx, src)
+	if err != nil {
+		return Summary{}, fmt.Errorf("checksumming source: %w", err)
+	}
+	dstSum, err := Checksum(ctx, dst)
+	if err != nil {
+		return Summary{}, fmt.Errorf("checksumming destination: %w", err)
+	}
+	if srcSum.Keys != dstSum.Keys {
+		return srcSum, fmt.Errorf("key count mismatch: source has %d keys, destination has %d", srcSum.Keys, dstSum.Keys)
+	}
+	if !bytes.Equal(srcSum.Checksum, dstSum.Checksum) {
+		return srcSum, fmt.Errorf("checksum mismatch: source %X, destination %X", srcSum.Checksum, dstSum.Checksum)
+	}
+	return srcSum, nil
+}
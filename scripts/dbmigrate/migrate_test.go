@@ -0,0 +1,99 @@
+package dbmigrate
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/bhojpur/state/pkg/database"
+)
+
+func makeDB(t *testing.T, n int) dbm.DB {
+	t.Helper()
+	db := dbm.NewMemDB()
+	for i := 0; i < n; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key-%04d", i)), []byte(fmt.Sprintf("value-%d", i))))
+	}
+	return db
+}
+
+func TestCopyResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := makeDB(t, 100)
+	dst := dbm.NewMemDB()
+	path := filepath.Join(t.TempDir(), "progress.json")
+
+	// interrupt the copy after the third batch
+	batches := 0
+	save := func(p *Progress) error {
+		batches++
+		if batches == 3 {
+			cancel()
+		}
+		return p.Save(path)
+	}
+	progress, err := LoadProgress(path)
+	require.NoError(t, err)
+	require.ErrorIs(t, Copy(ctx, src, dst, 7, progress, save), context.Canceled)
+
+	progress, err = LoadProgress(path)
+	require.NoError(t, err)
+	require.False(t, progress.Done)
+	require.EqualValues(t, 21, progress.Keys)
+	require.Equal(t, []byte("key-0020"), progress.LastKey)
+
+	_, err = Verify(context.Background(), src, dst)
+	require.Error(t, err)
+
+	require.NoError(t, Copy(context.Background(), src, dst, 7, progress, func(p *Progress) error { return p.Save(path) }))
+	progress, err = LoadProgress(path)
+	require.NoError(t, err)
+	require.True(t, progress.Done)
+	require.EqualValues(t, 100, progress.Keys)
+
+	sum, err := Verify(context.Background(), src, dst)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, sum.Keys)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	src := makeDB(t, 10)
+	dst := makeDB(t, 10)
+	_, err := Verify(ctx, src, dst)
+	require.NoError(t, err)
+
+	require.NoError(t, dst.Set([]byte("key-0003"), []byte("changed")))
+	_, err = Verify(ctx, src, dst)
+	require.ErrorContains(t, err, "checksum mismatch")
+
+	require.NoError(t, dst.Set([]byte("key-0010"), []byte("extra")))
+	_, err = Verify(ctx, src, dst)
+	require.ErrorContains(t, err, "key count mismatch")
+}
@@ -26,6 +26,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -33,6 +34,7 @@ import (
 	"os/signal"
 	"path/filepath"
 
+	liblog "github.com/bhojpur/state/pkg/libs/log"
 	"github.com/bhojpur/state/pkg/rpc/client/eventstream"
 	rpcclient "github.com/bhojpur/state/pkg/rpc/client/http"
 	"github.com/bhojpur/state/pkg/rpc/coretypes"
@@ -45,6 +47,10 @@ var (
 	numItems   = flag.Int("count", 0, "Number of items to read (0 to stream)")
 	waitTime   = flag.Duration("poll", 0, "Long poll interval")
 	rpcAddr    = flag.String("addr", "http://localhost:26657", "RPC service address")
+
+	checkpoint = flag.String("checkpoint", "", "Checkpoint file (enables consumer mode)")
+	outPath    = flag.String("out", "", "Output file of consumer mode")
+	webhookURL = flag.String("webhook", "", "Webhook URL of consumer mode")
 )
 
 func init() {
@@ -60,6 +66,12 @@ Use -count to stop polling after a certain number of events has been reported.
 Use -batch to override the default request batch size.
 Use -poll to override the default long-polling interval.
 
+Use -checkpoint to run as a consumer, which saves its progress to the given
+file and resumes from it when restarted. Each event is appended as JSON to the
+-out file, or posted to the -webhook URL, before the progress is saved. If the
+consumer falls behind the event log of the node, it delivers the results of the
+blocks it may have missed instead.
+
 Options:
 `, filepath.Base(os.Args[0]))
 		flag.PrintDefaults()
@@ -84,6 +96,13 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	if *checkpoint != "" {
+		if err := runConsumer(ctx, cli); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Consumer failed: %v", err)
+		}
+		return
+	}
+
 	var nr int
 	if err := stream.Run(ctx, func(itm *coretypes.EventItem) error {
 		nr++
@@ -100,3 +119,34 @@ func main() {
 		log.Fatalf("Stream failed: %v", err)
 	}
 }
+
+// runConsumer delivers the events matching the query to the sink given by the
+// flags, until ctx ends.
+func runConsumer(ctx context.Context, cli *rpcclient.HTTP) error {
+	var sink eventstream.Sink
+	switch {
+	case *webhookURL != "":
+		sink = eventstream.NewWebhookSink(*webhookURL, nil)
+	case *outPath != "":
+		fs, err := eventstream.NewFileSink(*outPath)
+		if err != nil {
+			return err
+		}
+		defer fs.Close()
+		sink = fs
+	default:
+		return errors.New("consumer mode requires -out or -webhook")
+	}
+
+	logger, err := liblog.NewDefaultLogger(liblog.LogFormatPlain, liblog.LogLevelInfo)
+	if err != nil {
+		return err
+	}
+	consumer := eventstream.NewConsumer(cli, *query, sink, eventstream.NewFileCheckpointStore(*checkpoint),
+		&eventstream.ConsumerOptions{
+			BatchSize: *batchSize,
+			WaitTime:  *waitTime,
+			Logger:    logger,
+		})
+	return consumer.Run(ctx)
+}